@@ -0,0 +1,87 @@
+// functrace-collector 接收多个进程经 remote 后端发送的跟踪数据，写入同一个 SQLite 数据库
+//
+//	functrace-collector -listen tcp://0.0.0.0:7070,unix:///tmp/functrace.sock -db ./collected.db
+//
+// 被跟踪进程设置 FUNCTRACE_DB_TYPE=remote 与 FUNCTRACE_REMOTE_ADDR 即可上报
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/persistence/remote"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+func main() {
+	listen := flag.String("listen", "tcp://127.0.0.1:7070", "comma separated listen addresses (tcp://host:port, unix:///path)")
+	dbPath := flag.String("db", "./functrace_collector.db", "SQLite database to write, created if missing")
+	verbose := flag.Bool("v", false, "enable debug logging")
+	flag.Parse()
+
+	if err := run(*listen, *dbPath, *verbose); err != nil {
+		fmt.Fprintf(os.Stderr, "functrace-collector: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(listen, dbPath string, verbose bool) error {
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	db, err := sqlite.Open(dbPath, logger)
+	if err != nil {
+		return err
+	}
+	server := remote.NewServer(db, logger)
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 1)
+	for _, addr := range strings.Split(listen, ",") {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		l, err := remote.Listen(addr)
+		if err != nil {
+			server.Close()
+			db.Close()
+			return err
+		}
+		logger.Infof("listening on %s", addr)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Serve(l); err != nil {
+				select {
+				case errCh <- err:
+				default:
+				}
+			}
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case s := <-sig:
+		logger.Infof("received %s, shutting down", s)
+	case err = <-errCh:
+	}
+
+	// 先停止接收并等待正在写入的批次，再关闭数据库
+	server.Close()
+	wg.Wait()
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
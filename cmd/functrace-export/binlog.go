@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/toheart/functrace/persistence/binlog"
+)
+
+// runBinlog2SQLite 将 binlog 后端写出的段文件转换为新的 SQLite 数据库，供其他子命令分析
+func runBinlog2SQLite(args []string) error {
+	fs := flag.NewFlagSet("binlog2sqlite", flag.ExitOnError)
+	dir := fs.String("dir", "", "directory holding the .ftlog segments of one run")
+	output := fs.String("o", "", "SQLite database to create")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: functrace-export binlog2sqlite -o out.db [-dir dir | segment.ftlog ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if *dir != "" {
+		listed, err := binlog.ListSegments(*dir)
+		if err != nil {
+			return err
+		}
+		files = append(files, listed...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no binlog segments given")
+	}
+
+	db, err := createSQLite(*output)
+	if err != nil {
+		return err
+	}
+	n, err := binlog.Load(files, db)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "converted %d records from %d segments\n", n, len(files))
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/toheart/functrace/exporter/chrome"
+)
+
+// runChrome 将数据库导出为 Perfetto / chrome://tracing 可打开的 JSON
+func runChrome(args []string) error {
+	fs := flag.NewFlagSet("chrome", flag.ExitOnError)
+	dbPath := fs.String("db", "", "functrace database file")
+	output := fs.String("o", "-", "output file, '-' for stdout; a .gz suffix enables gzip")
+	params := fs.Bool("params", true, "include recorded params as slice args")
+	maxParam := fs.Int("max-param-length", chrome.DefaultMaxParamLength, "truncate params longer than this, -1 for no limit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	out, closeOut, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	n, err := chrome.Export(out, db, chrome.Options{
+		Params:         *params,
+		MaxParamLength: *maxParam,
+		ProcessName:    strings.TrimSuffix(filepath.Base(*dbPath), ".db"),
+	})
+	if cerr := closeOut(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d calls\n", n)
+	return nil
+}
+
+// createOutput 打开输出目标，路径以 .gz 结尾时进行 gzip 压缩
+func createOutput(path string) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create output error: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, f.Close, nil
+	}
+	zw := gzip.NewWriter(f)
+	return zw, func() error {
+		if err := zw.Close(); err != nil {
+			f.Close()
+			return fmt.Errorf("close gzip output error: %w", err)
+		}
+		return f.Close()
+	}, nil
+}
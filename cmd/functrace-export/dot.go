@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/toheart/functrace/exporter/callgraph"
+)
+
+// runDot 将整个运行聚合为 Graphviz 调用图
+func runDot(args []string) error {
+	fs := flag.NewFlagSet("dot", flag.ExitOnError)
+	dbPath := fs.String("db", "", "functrace database file")
+	output := fs.String("o", "-", "output file, '-' for stdout")
+	nodeFraction := fs.Float64("node-fraction", callgraph.DefaultNodeFraction, "hide nodes below this fraction of total time, -1 to keep all")
+	edgeFraction := fs.Float64("edge-fraction", callgraph.DefaultEdgeFraction, "hide edges below this fraction of total time, -1 to keep all")
+	maxNodes := fs.Int("max-nodes", callgraph.DefaultMaxNodes, "maximum number of nodes, -1 for unlimited")
+	cluster := fs.Bool("cluster", false, "group nodes into one cluster per package")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	g, err := callgraph.Collect(db)
+	if err != nil {
+		return err
+	}
+	out, closeOut, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	err = g.WriteDOT(out, callgraph.Options{
+		NodeFraction:    *nodeFraction,
+		EdgeFraction:    *edgeFraction,
+		MaxNodes:        *maxNodes,
+		ClusterPackages: *cluster,
+	})
+	if cerr := closeOut(); err == nil {
+		err = cerr
+	}
+	return err
+}
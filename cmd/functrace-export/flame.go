@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/toheart/functrace/exporter/flame"
+)
+
+// runFolded 导出 folded-stack 文本，供 flamegraph.pl、speedscope 等工具使用
+func runFolded(args []string) error {
+	fs := flag.NewFlagSet("folded", flag.ExitOnError)
+	dbPath := fs.String("db", "", "functrace database file")
+	output := fs.String("o", "-", "output file, '-' for stdout")
+	weight := fs.String("value", string(flame.WeightSelf), "line value: self (ns), wall (ns) or count")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p, err := collectProfile(*dbPath)
+	if err != nil {
+		return err
+	}
+	out, closeOut, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	err = p.WriteFolded(out, flame.Weight(*weight))
+	if cerr := closeOut(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// runPProf 导出 pprof profile，供 go tool pprof 使用
+func runPProf(args []string) error {
+	fs := flag.NewFlagSet("pprof", flag.ExitOnError)
+	dbPath := fs.String("db", "", "functrace database file")
+	output := fs.String("o", "functrace.pb.gz", "output file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p, err := collectProfile(*dbPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("create output error: %w", err)
+	}
+	if err := p.WritePProf(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close output error: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s, view with: go tool pprof -http=: %s\n", *output, *output)
+	return nil
+}
+
+// collectProfile 打开数据库并按调用栈汇总
+func collectProfile(dbPath string) (*flame.Profile, error) {
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return flame.Collect(db)
+}
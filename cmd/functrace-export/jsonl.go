@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/toheart/functrace/persistence/jsonl"
+)
+
+// runJSONL2SQLite 将 jsonl 后端写出的事件流导入新的 SQLite 数据库，供其他子命令分析
+func runJSONL2SQLite(args []string) error {
+	fs := flag.NewFlagSet("jsonl2sqlite", flag.ExitOnError)
+	dir := fs.String("dir", "", "directory holding the .jsonl / .jsonl.zst files of one run")
+	output := fs.String("o", "", "SQLite database to create")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: functrace-export jsonl2sqlite -o out.db [-dir dir | file.jsonl[.zst] ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if *dir != "" {
+		listed, err := jsonl.ListFiles(*dir)
+		if err != nil {
+			return err
+		}
+		files = append(files, listed...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no jsonl files given")
+	}
+
+	db, err := createSQLite(*output)
+	if err != nil {
+		return err
+	}
+	n, err := jsonl.LoadFiles(files, db)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "loaded %d records from %d files\n", n, len(files))
+	return nil
+}
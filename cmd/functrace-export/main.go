@@ -0,0 +1,117 @@
+// functrace-export 将 functrace 记录的数据库离线转换为其他格式
+//
+//	functrace-export otlp -db ./app_20250101120000.db -endpoint localhost:4318
+//	functrace-export chrome -db ./app_20250101120000.db -o trace.json.gz
+//	functrace-export jsonl2sqlite -dir ./traces -o app.db
+//	functrace-export binlog2sqlite -dir ./traces -o app.db
+//	functrace-export replay -db ./app_20250101120000.db -dir .
+//	functrace-export merge -o merged.db ./host1.db ./host2.db
+//	functrace-export summarize -db ./app_20250101120000.db
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+// command 子命令
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+// commands 全部子命令
+var commands = map[string]command{
+	"otlp":     {summary: "send recorded calls as spans to an OTLP collector", run: runOTLP},
+	"chrome":   {summary: "write Trace Event Format JSON for Perfetto / chrome://tracing", run: runChrome},
+	"dot":      {summary: "write a Graphviz call graph aggregated by function", run: runDot},
+	"folded":   {summary: "write folded stacks for flame graph tools", run: runFolded},
+	"pprof":    {summary: "write a pprof profile with wall and self time samples", run: runPProf},
+	"sequence": {summary: "render one root call as a Mermaid or PlantUML sequence diagram", run: runSequence},
+
+	"jsonl2sqlite":  {summary: "load files written by the jsonl backend into a new SQLite database", run: runJSONL2SQLite},
+	"binlog2sqlite": {summary: "convert segments written by the binlog backend into a new SQLite database", run: runBinlog2SQLite},
+	"replay":        {summary: "write records spilled after failed writes into an existing SQLite database", run: runReplay},
+	"merge":         {summary: "merge several SQLite databases into one, each source run becoming a new run", run: runMerge},
+	"summarize":     {summary: "compute per-function, call edge, goroutine and root call summary tables in a SQLite database", run: runSummarize},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		usage(os.Stdout)
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "functrace-export %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// usage 打印子命令列表
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: functrace-export <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-13s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run 'functrace-export <command> -h' for command flags")
+}
+
+// openDB 以只读方式打开已有的跟踪数据库，旧版本文件在临时副本上升级后读取
+func openDB(path string) (*sqlite.SQLiteDatabase, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-db is required")
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return sqlite.OpenReadOnly(path, logger)
+}
+
+// openSQLite 以读写方式打开已有的跟踪数据库，旧版本文件会被升级
+func openSQLite(path string) (*sqlite.SQLiteDatabase, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-db is required")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open %s error: %w", path, err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return sqlite.Open(path, logger)
+}
+
+// createSQLite 创建新的 SQLite 数据库作为转换目标，已存在时报错以免混入旧数据
+func createSQLite(path string) (*sqlite.SQLiteDatabase, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-o is required")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("output %s already exists", path)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return sqlite.Open(path, logger)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+// runMerge 将多个跟踪数据库合并为一个，目标库已存在时追加
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("o", "", "target SQLite database, created if missing")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: functrace-export merge -o merged.db a.db b.db ...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no source databases given")
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db, err := sqlite.Open(*out, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, path := range fs.Args() {
+		merged, err := db.Merge(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range merged {
+			fmt.Fprintf(os.Stderr, "%s: run %d -> run %d, %d traces, %d goroutines, %d params\n",
+				m.Source, m.SourceRunID, m.RunID, m.Traces, m.Goroutines, m.Params)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/exporter/otlp"
+)
+
+// headerFlags 可重复的 -header k=v
+type headerFlags map[string]string
+
+func (h headerFlags) String() string { return fmt.Sprint(map[string]string(h)) }
+
+func (h headerFlags) Set(v string) error {
+	k, val, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("header must be key=value: %s", v)
+	}
+	h[k] = val
+	return nil
+}
+
+// runOTLP 将数据库中的调用发送到 OTLP 收集器
+func runOTLP(args []string) error {
+	cfg := otlp.ConfigFromEnv()
+	if cfg.Headers == nil {
+		cfg.Headers = make(map[string]string)
+	}
+
+	fs := flag.NewFlagSet("otlp", flag.ExitOnError)
+	dbPath := fs.String("db", "", "functrace database file")
+	fs.StringVar(&cfg.Endpoint, "endpoint", cfg.Endpoint, "collector endpoint (default localhost:4318 for http, localhost:4317 for grpc)")
+	fs.StringVar(&cfg.Protocol, "protocol", cfg.Protocol, "otlp protocol: http/protobuf or grpc")
+	fs.BoolVar(&cfg.Insecure, "insecure", cfg.Insecure, "disable TLS")
+	fs.StringVar(&cfg.ServiceName, "service", cfg.ServiceName, "service.name resource attribute (default database file name)")
+	fs.BoolVar(&cfg.CaptureParams, "params", true, "export recorded params as span attributes")
+	fs.IntVar(&cfg.MaxParamLength, "max-param-length", otlp.DefaultMaxParamLength, "truncate param attributes longer than this, -1 for no limit")
+	fs.IntVar(&cfg.BatchSize, "batch", otlp.DefaultBatchSize, "spans per request")
+	fs.Var(headerFlags(cfg.Headers), "header", "extra request header key=value, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = strings.TrimSuffix(filepath.Base(*dbPath), ".db")
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	cfg.Logger = logger
+	exp, err := otlp.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer exp.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	n, err := otlp.ExportRepository(ctx, db, exp)
+	if err != nil {
+		return err
+	}
+	stats := exp.Stats()
+	fmt.Printf("exported %d spans (sent %d, failed %d)\n", n, stats.Exported, stats.Failed)
+	if stats.Failed > 0 {
+		return fmt.Errorf("%d spans could not be delivered", stats.Failed)
+	}
+	return nil
+}
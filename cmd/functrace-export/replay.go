@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/toheart/functrace/trace"
+)
+
+// runReplay 将写入失败后溢出的记录导入已有的 SQLite 数据库
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	dbPath := fs.String("db", "", "SQLite database the spilled records belong to")
+	dir := fs.String("dir", "", "directory holding *"+trace.SpillFileExt+" files")
+	keep := fs.Bool("keep", false, "leave spill files untouched instead of renaming them to *"+trace.SpillFileExt+trace.ReplayedFileExt+" or keeping only the records that failed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: functrace-export replay -db app.db [-dir dir | file"+trace.SpillFileExt+" ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if *dir != "" {
+		listed, err := trace.ListSpillFiles(*dir)
+		if err != nil {
+			return err
+		}
+		files = append(files, listed...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no spill files given")
+	}
+
+	db, err := openSQLite(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, path := range files {
+		res, err := trace.ReplaySpillFile(path, db)
+		if err != nil {
+			return err
+		}
+		if !*keep {
+			if err := trace.FinishSpillFile(path, res); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%s: applied %d records, skipped %d\n", path, res.Applied, res.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/toheart/functrace/exporter/sequence"
+)
+
+// listFlag 逗号分隔的列表
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// runSequence 将一次根调用渲染为时序图
+func runSequence(args []string) error {
+	var include, exclude listFlag
+	fs := flag.NewFlagSet("sequence", flag.ExitOnError)
+	dbPath := fs.String("db", "", "functrace database file")
+	root := fs.Int64("root", 0, "trace ID of the root call")
+	format := fs.String("format", "mermaid", "output format: mermaid or plantuml")
+	output := fs.String("o", "-", "output file, '-' for stdout")
+	depth := fs.Int("depth", 0, "maximum call depth below the root, 0 for unlimited")
+	grouping := fs.String("group", string(sequence.GroupByType), "participants: type (receiver type or package) or package")
+	params := fs.Bool("params", false, "show abbreviated params on messages")
+	noAsync := fs.Bool("no-async", false, "hide goroutines spawned during the call")
+	fs.Var(&include, "include", "only show participants matching these patterns (comma separated, repeatable)")
+	fs.Var(&exclude, "exclude", "hide participants matching these patterns (comma separated, repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *root == 0 {
+		return fmt.Errorf("-root is required")
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	d, err := sequence.Build(db, *root, sequence.Options{
+		MaxDepth: *depth,
+		Grouping: sequence.Grouping(*grouping),
+		Include:  include,
+		Exclude:  exclude,
+		Params:   *params,
+		NoAsync:  *noAsync,
+	})
+	if err != nil {
+		return err
+	}
+
+	out, closeOut, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "mermaid":
+		err = d.WriteMermaid(out)
+	case "plantuml":
+		err = d.WritePlantUML(out)
+	default:
+		err = fmt.Errorf("unknown format: %s", *format)
+	}
+	if cerr := closeOut(); err == nil {
+		err = cerr
+	}
+	return err
+}
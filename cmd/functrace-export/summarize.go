@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runSummarize 在已有的 SQLite 数据库中为全部运行重新计算分析汇总表
+func runSummarize(args []string) error {
+	fs := flag.NewFlagSet("summarize", flag.ExitOnError)
+	dbPath := fs.String("db", "", "SQLite database to summarize")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: functrace-export summarize -db app.db")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openSQLite(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	start := time.Now()
+	if err := db.Summarize(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: wrote FuncSummary, CallEdge, GoroutineSummary and RootCallSummary in %s\n", *dbPath, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,35 @@
+package model
+
+// 跟踪事件类型
+const (
+	// EventKindSlowCall 调用在返回前超过了截止时间
+	EventKindSlowCall = "slow_call"
+)
+
+// TraceEvent 附加在某次调用上的事件
+type TraceEvent struct {
+	ID        int64  `json:"id"`        // 自增ID
+	TraceID   int64  `json:"traceId"`   // 关联的TraceData ID
+	GID       uint64 `json:"gid"`       // 协程自增ID
+	Kind      string `json:"kind"`      // 事件类型
+	Message   string `json:"message"`   // 事件描述
+	Stack     string `json:"stack"`     // 事件发生时的运行时调用栈
+	CreatedAt string `json:"createdAt"` // 事件时间
+}
+
+// NewTraceEvent 创建一个新的跟踪事件
+func NewTraceEvent(traceId int64, gid uint64, kind string, message string, createdAt string) *TraceEvent {
+	return &TraceEvent{
+		TraceID:   traceId,
+		GID:       gid,
+		Kind:      kind,
+		Message:   message,
+		CreatedAt: createdAt,
+	}
+}
+
+// WithStack 设置调用栈
+func (e *TraceEvent) WithStack(stack string) *TraceEvent {
+	e.Stack = stack
+	return e
+}
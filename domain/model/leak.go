@@ -0,0 +1,18 @@
+package model
+
+// LeakReport 关闭时仍存活的被跟踪 goroutine
+type LeakReport struct {
+	ID           int64  `json:"id"`           // 自增ID
+	GoroutineID  int64  `json:"goroutineId"`  // 关联的GoroutineTrace ID
+	OriginGID    uint64 `json:"originGid"`    // 原始Goroutine ID
+	InitFuncName string `json:"initFuncName"` // 初始函数名
+	CreatorGID   uint64 `json:"creatorGid"`   // 创建者的原始Goroutine ID
+	CreatorFunc  string `json:"creatorFunc"`  // 执行 go 语句的函数名
+	CreateTime   string `json:"createTime"`   // 创建时间
+	Age          string `json:"age"`          // 报告时已存活时长
+	LastTraceID  int64  `json:"lastTraceId"`  // 最后一个被跟踪调用的ID
+	LastFuncName string `json:"lastFuncName"` // 最后一个被跟踪调用的函数名
+	LastFinished int    `json:"lastFinished"` // 最后一个被跟踪调用是否已退出
+	Stack        string `json:"stack"`        // 报告时的运行时调用栈
+	ReportedAt   string `json:"reportedAt"`   // 报告时间
+}
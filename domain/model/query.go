@@ -0,0 +1,110 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 分页默认值
+const (
+	DefaultPageLimit = 100  // 未指定 Limit 时的每页条数
+	MaxPageLimit     = 1000 // 每页条数上限
+)
+
+// PageRequest 游标分页参数
+type PageRequest struct {
+	Cursor string // 上一页返回的 NextCursor，空表示第一页
+	Limit  int    // 每页条数，<=0 时使用 DefaultPageLimit，超过 MaxPageLimit 时截断
+}
+
+// PageLimit 返回规范化后的每页条数
+func (p PageRequest) PageLimit() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// TracePage 一页跟踪数据
+type TracePage struct {
+	Traces     []TraceData
+	NextCursor string // 下一页游标，空表示没有更多数据
+}
+
+// GoroutinePage 一页协程数据
+type GoroutinePage struct {
+	Goroutines []GoroutineTrace
+	NextCursor string // 下一页游标，空表示没有更多数据
+}
+
+// TraceQuery 跟踪数据查询条件，零值字段不参与过滤
+type TraceQuery struct {
+	Name        string        // 函数全名，精确匹配
+	GID         uint64        // 协程ID
+	Since       time.Time     // 调用开始时间下限（含）
+	Until       time.Time     // 调用开始时间上限（不含）
+	MinDuration time.Duration // 耗时下限（含），大于 0 时只匹配已完成的调用
+}
+
+// GoroutineState 协程状态过滤条件
+type GoroutineState int
+
+const (
+	GoroutineStateAll      GoroutineState = iota // 全部协程
+	GoroutineStateRunning                        // 尚未结束的协程
+	GoroutineStateFinished                       // 已结束的协程
+)
+
+// GoroutineQuery 协程查询条件，零值字段不参与过滤
+type GoroutineQuery struct {
+	InitFuncName string         // 初始函数名，精确匹配
+	OriginGID    uint64         // 原始Goroutine ID
+	CreatorGID   uint64         // 创建者的原始Goroutine ID
+	State        GoroutineState // 协程状态
+}
+
+// EncodeCursor 将排序键编码为不透明的游标
+func EncodeCursor(keys ...int64) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = strconv.FormatInt(k, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ",")))
+}
+
+// DecodeCursor 解码 EncodeCursor 生成的游标，n 为期望的排序键个数
+func DecodeCursor(cursor string, n int) ([]int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	parts := strings.Split(string(raw), ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("invalid cursor: want %d keys, got %d", n, len(parts))
+	}
+	keys := make([]int64, n)
+	for i, p := range parts {
+		if keys[i], err = strconv.ParseInt(p, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+	return keys, nil
+}
+
+// ParseTimeCost 将 TimeCost 解析为耗时，未完成或无法解析时返回 false
+func ParseTimeCost(timeCost string) (time.Duration, bool) {
+	if timeCost == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(timeCost)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,47 @@
+package model
+
+// RunIDShift 同一个库中各次运行的ID命名空间：写入的ID = 进程内ID + Run.IDBase()
+const RunIDShift = 40
+
+// 运行状态
+const (
+	RunStatusRunning = "running" // 运行中；进程退出后仍为该状态表示未正常关闭（崩溃或被强制结束）
+	RunStatusClean   = "clean"   // 已正常关闭
+	RunStatusRotated = "rotated" // 数据库已滚动，运行在下一个文件中继续
+	RunStatusUnknown = "unknown" // 升级结构前写入的数据，没有运行元数据
+)
+
+// Run 一次被跟踪进程的运行，初始化时登记，关闭时记录结束时间与状态
+type Run struct {
+	ID            int64  `json:"id"`            // 自增ID，同时决定该运行数据的ID命名空间
+	UUID          string `json:"uuid"`          // 运行UUID
+	Executable    string `json:"executable"`    // 可执行文件路径
+	Args          string `json:"args"`          // 命令行参数（JSON 数组）
+	Hostname      string `json:"hostname"`      // 主机名
+	PID           int    `json:"pid"`           // 进程ID
+	GoVersion     string `json:"goVersion"`     // 编译使用的 Go 版本
+	ModulePath    string `json:"modulePath"`    // 主模块路径
+	ModuleVersion string `json:"moduleVersion"` // 主模块版本
+	VCSRevision   string `json:"vcsRevision"`   // 版本控制修订号
+	VCSTime       string `json:"vcsTime"`       // 修订提交时间
+	VCSModified   bool   `json:"vcsModified"`   // 构建时工作区是否有未提交的修改
+	BuildInfo     string `json:"buildInfo"`     // debug.ReadBuildInfo 的完整文本，含依赖模块版本
+	Config        string `json:"config"`        // 生效配置（JSON）
+	StartedAt     string `json:"startedAt"`     // 开始时间
+	EndedAt       string `json:"endedAt"`       // 结束时间，未正常关闭时为空
+	Status        string `json:"status"`        // 运行状态
+	Origin        string `json:"origin"`        // 合并导入时的来源文件，由本进程直接写入时为空
+}
+
+// IDBase 返回该运行写入的全部ID的偏移，库中第一次运行不偏移
+func (r *Run) IDBase() int64 {
+	if r.ID <= 1 {
+		return 0
+	}
+	return (r.ID - 1) << RunIDShift
+}
+
+// RunIDOf 返回ID所在命名空间对应的运行ID
+func RunIDOf(id int64) int64 {
+	return id>>RunIDShift + 1
+}
@@ -0,0 +1,16 @@
+package model
+
+// Source 汇聚到同一个库中的一个数据来源的连接与写入进度
+// 来源数据的ID命名空间由同一运行UUID登记的 Run 决定
+type Source struct {
+	ID          int64  `json:"id"`          // 自增ID
+	RunID       string `json:"runId"`       // 运行ID，由被跟踪进程在启动时生成
+	Process     string `json:"process"`     // 进程名
+	Host        string `json:"host"`        // 主机名
+	PID         int    `json:"pid"`         // 进程ID
+	RemoteAddr  string `json:"remoteAddr"`  // 连接的对端地址
+	ConnectedAt string `json:"connectedAt"` // 首次连接时间
+	LastSeenAt  string `json:"lastSeenAt"`  // 最后一次收到数据的时间
+	LastSeq     int64  `json:"lastSeq"`     // 已写入的最后一个批次序号
+	Records     int64  `json:"records"`     // 已写入的记录数
+}
@@ -0,0 +1,25 @@
+package model
+
+// FuncStats 函数维度的聚合统计快照
+type FuncStats struct {
+	RunID      int64  `json:"runId,omitempty"` // 所属运行ID，未登记运行时为0
+	Name       string `json:"name"`            // 函数名称
+	Count      int64  `json:"count"`           // 调用次数
+	ErrorCount int64  `json:"errorCount"`      // 返回错误的调用次数
+	TotalTime  int64  `json:"totalTime"`       // 总耗时（纳秒）
+	SelfTime   int64  `json:"selfTime"`        // 自身耗时（纳秒，不含被跟踪的子调用）
+	MaxTime    int64  `json:"maxTime"`         // 最大单次耗时（纳秒）
+	P50        int64  `json:"p50"`             // 50分位耗时（纳秒）
+	P95        int64  `json:"p95"`             // 95分位耗时（纳秒）
+	P99        int64  `json:"p99"`             // 99分位耗时（纳秒）
+	Sketch     []byte `json:"-"`               // 编码后的延迟分布，可用于跨快照合并
+	UpdatedAt  string `json:"updatedAt"`       // 快照时间
+}
+
+// AvgTime 返回平均耗时（纳秒）
+func (s *FuncStats) AvgTime() int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalTime / s.Count
+}
@@ -13,16 +13,21 @@ type TraceData struct {
 	IsFinished  int    `json:"isFinished"`  // 是否完成
 	Seq         string `json:"seq"`         // 序列号
 	MethodType  int    `json:"-"`           // 方法类型
+
+	Params []interface{} `json:"-"` // 原始参数（仅在调用观察者需要时保留，不持久化）
 }
 
 // GoroutineTrace 存储goroutine信息的结构体
 type GoroutineTrace struct {
-	ID           int64  `json:"id"`           // 自增ID
-	OriginGID    uint64 `json:"originGid"`    // 原始Goroutine ID
-	TimeCost     string `json:"timeCost"`     // 执行时间
-	CreateTime   string `json:"createTime"`   // 创建时间
-	IsFinished   int    `json:"isFinished"`   // 是否完成
-	InitFuncName string `json:"initFuncName"` // 初始函数名
+	ID            int64  `json:"id"`            // 自增ID
+	OriginGID     uint64 `json:"originGid"`     // 原始Goroutine ID
+	TimeCost      string `json:"timeCost"`      // 执行时间
+	CreateTime    string `json:"createTime"`    // 创建时间
+	IsFinished    int    `json:"isFinished"`    // 是否完成
+	InitFuncName  string `json:"initFuncName"`  // 初始函数名
+	CreatorGID    uint64 `json:"creatorGid"`    // 创建者的原始Goroutine ID（0表示未知）
+	CreatorFunc   string `json:"creatorFunc"`   // 执行 go 语句的函数名
+	ParentTraceID int64  `json:"parentTraceId"` // 创建时创建者正在执行的跟踪ID（0表示未跟踪）
 }
 
 // TraceIndent 存储函数调用的缩进信息和父函数名称
@@ -75,6 +80,14 @@ func (g *GoroutineTrace) WithTimeCost(timeCost string) *GoroutineTrace {
 	return g
 }
 
+// WithCreator 设置创建者信息
+func (g *GoroutineTrace) WithCreator(creatorGid uint64, creatorFunc string, parentTraceId int64) *GoroutineTrace {
+	g.CreatorGID = creatorGid
+	g.CreatorFunc = creatorFunc
+	g.ParentTraceID = parentTraceId
+	return g
+}
+
 // SetFinished 设置是否完成
 func (g *GoroutineTrace) SetFinished(isFinished int) *GoroutineTrace {
 	g.IsFinished = isFinished
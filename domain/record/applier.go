@@ -0,0 +1,142 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// applyParamBatchSize 参数批量写入的大小
+const applyParamBatchSize = 500
+
+// Applier 将单条事件映射为目标仓储的调用，参数插入合并为批量写入
+type Applier struct {
+	// IDOffset 非零时为跟踪、参数、协程ID及其引用（ParentId、GID、BaseID、ParentTraceID）加上偏移，
+	// 用于将多个进程的数据写入同一个库
+	IDOffset int64
+	// SkipParamCache 跳过参数缓存事件（参数缓存只是运行时状态，地址在不同进程间会冲突）
+	SkipParamCache bool
+
+	traces     domain.TraceRepository
+	params     domain.ParamRepository
+	goroutines domain.GoroutineRepository
+	pending    []*model.ParamStoreData
+}
+
+// NewApplier 创建写入目标仓储的事件应用器
+func NewApplier(target domain.RepositoryFactory) *Applier {
+	return &Applier{
+		traces:     target.GetTraceRepository(),
+		params:     target.GetParamRepository(),
+		goroutines: target.GetGoroutineRepository(),
+	}
+}
+
+// shift 为非零ID加上偏移
+func (a *Applier) shift(id int64) int64 {
+	if id == 0 {
+		return 0
+	}
+	return id + a.IDOffset
+}
+
+// Apply 解码一行事件并应用到目标仓储，参数插入在缓冲满或 Flush 时写入
+func (a *Applier) Apply(line []byte) error {
+	var rec Record
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return fmt.Errorf("%w: unmarshal record error: %v", ErrInvalid, err)
+	}
+
+	switch {
+	case rec.Table == TableTrace && rec.Op == OpInsert:
+		var td model.TraceData
+		if err := json.Unmarshal(rec.Data, &td); err != nil {
+			return fmt.Errorf("%w: unmarshal trace error: %v", ErrInvalid, err)
+		}
+		td.ID, td.ParentId, td.GID = a.shift(td.ID), a.shift(td.ParentId), uint64(a.shift(int64(td.GID)))
+		_, err := a.traces.SaveTrace(&td)
+		return err
+	case rec.Table == TableTrace && rec.Op == OpUpsert:
+		var td model.TraceData
+		if err := json.Unmarshal(rec.Data, &td); err != nil {
+			return fmt.Errorf("%w: unmarshal trace error: %v", ErrInvalid, err)
+		}
+		td.ID, td.ParentId, td.GID = a.shift(td.ID), a.shift(td.ParentId), uint64(a.shift(int64(td.GID)))
+		if w, ok := a.traces.(domain.TraceBatchWriter); ok {
+			return w.SaveTracesBatch([]*model.TraceData{&td})
+		}
+		if _, err := a.traces.SaveTrace(&td); err != nil {
+			return err
+		}
+		if td.IsFinished == 1 {
+			return a.traces.UpdateTraceTimeCost(td.ID, td.TimeCost)
+		}
+		return nil
+	case rec.Table == TableTrace && rec.Op == OpUpdate:
+		var u TraceUpdate
+		if err := json.Unmarshal(rec.Data, &u); err != nil {
+			return fmt.Errorf("%w: unmarshal trace update error: %v", ErrInvalid, err)
+		}
+		return a.traces.UpdateTraceTimeCost(a.shift(u.ID), u.TimeCost)
+	case rec.Table == TableGoroutine && rec.Op == OpInsert:
+		var g model.GoroutineTrace
+		if err := json.Unmarshal(rec.Data, &g); err != nil {
+			return fmt.Errorf("%w: unmarshal goroutine error: %v", ErrInvalid, err)
+		}
+		g.ID, g.ParentTraceID = a.shift(g.ID), a.shift(g.ParentTraceID)
+		_, err := a.goroutines.SaveGoroutine(&g)
+		return err
+	case rec.Table == TableGoroutine && rec.Op == OpUpdate:
+		var u GoroutineUpdate
+		if err := json.Unmarshal(rec.Data, &u); err != nil {
+			return fmt.Errorf("%w: unmarshal goroutine update error: %v", ErrInvalid, err)
+		}
+		return a.goroutines.UpdateGoroutineTimeCost(a.shift(u.ID), u.TimeCost, u.IsFinished)
+	case rec.Table == TableParam && rec.Op == OpInsert:
+		var p model.ParamStoreData
+		if err := json.Unmarshal(rec.Data, &p); err != nil {
+			return fmt.Errorf("%w: unmarshal param error: %v", ErrInvalid, err)
+		}
+		p.ID, p.TraceID, p.BaseID = a.shift(p.ID), a.shift(p.TraceID), a.shift(p.BaseID)
+		// 先写入已满的缓冲再加入本条，返回错误时本条未被接收
+		if len(a.pending) >= applyParamBatchSize {
+			if err := a.Flush(); err != nil {
+				return err
+			}
+		}
+		a.pending = append(a.pending, &p)
+		return nil
+	case rec.Table == TableParamCache && a.SkipParamCache:
+		return nil
+	case rec.Table == TableParamCache && rec.Op == OpInsert:
+		var c model.ParamCache
+		if err := json.Unmarshal(rec.Data, &c); err != nil {
+			return fmt.Errorf("%w: unmarshal param cache error: %v", ErrInvalid, err)
+		}
+		c.BaseID = a.shift(c.BaseID)
+		_, err := a.params.SaveParamCache(&c)
+		return err
+	case rec.Table == TableParamCache && rec.Op == OpDelete:
+		var d ParamCacheDelete
+		if err := json.Unmarshal(rec.Data, &d); err != nil {
+			return fmt.Errorf("%w: unmarshal param cache delete error: %v", ErrInvalid, err)
+		}
+		return a.params.DeleteParamCacheByAddr(d.Addr)
+	default:
+		return fmt.Errorf("%w: unknown record: op=%s table=%s", ErrInvalid, rec.Op, rec.Table)
+	}
+}
+
+// Flush 批量写入缓冲的参数，失败时保留缓冲以便重试
+func (a *Applier) Flush() error {
+	if len(a.pending) == 0 {
+		return nil
+	}
+	if err := a.params.SaveParamsBatch(a.pending); err != nil {
+		return err
+	}
+	a.pending = a.pending[:0]
+	return nil
+}
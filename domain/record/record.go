@@ -0,0 +1,69 @@
+// Package record 定义跟踪事件的行格式，JSON Lines 后端、收集器协议与溢出文件共用
+package record
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 记录操作类型
+const (
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpUpsert = "upsert" // 完整的跟踪数据行，ID 已存在时整行覆盖
+)
+
+// 记录对应的表
+const (
+	TableTrace      = "trace"
+	TableGoroutine  = "goroutine"
+	TableParam      = "param"
+	TableParamCache = "paramCache"
+)
+
+// ErrInvalid 记录无法解码或不受支持，重试不会成功
+var ErrInvalid = errors.New("invalid record")
+
+// Record 一行事件
+type Record struct {
+	Op    string          `json:"op"`    // OpInsert / OpUpdate / OpDelete / OpUpsert
+	Table string          `json:"table"` // TableTrace / TableGoroutine / TableParam / TableParamCache
+	Data  json.RawMessage `json:"data"`  // 插入时为完整模型，更新/删除时为 TraceUpdate 等
+}
+
+// TraceUpdate 跟踪数据的更新事件
+type TraceUpdate struct {
+	ID         int64  `json:"id"`
+	TimeCost   string `json:"timeCost"`
+	IsFinished int    `json:"isFinished"`
+}
+
+// GoroutineUpdate 协程数据的更新事件
+type GoroutineUpdate struct {
+	ID         int64  `json:"id"`
+	TimeCost   string `json:"timeCost"`
+	IsFinished int    `json:"isFinished"`
+}
+
+// ParamCacheDelete 参数缓存的删除事件
+type ParamCacheDelete struct {
+	Addr string `json:"addr"`
+}
+
+// encoded 编码时使用的事件结构
+type encoded struct {
+	Op    string      `json:"op"`
+	Table string      `json:"table"`
+	Data  interface{} `json:"data"`
+}
+
+// Encode 将一条事件编码为一行 JSON（不含换行符）
+func Encode(op, table string, data interface{}) ([]byte, error) {
+	line, err := json.Marshal(encoded{Op: op, Table: table, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s %s error: %w", op, table, err)
+	}
+	return line, nil
+}
@@ -16,6 +16,12 @@ type TraceRepository interface {
 	FindRootFunctionsByGID(gid uint64) ([]model.TraceData, error)
 }
 
+// TraceBatchWriter 可选能力：由跟踪数据仓储实现，调用退出后整行写入，省去插入后的单独更新
+type TraceBatchWriter interface {
+	// SaveTracesBatch 批量写入完整的跟踪数据（单事务），ID 已存在时整行覆盖
+	SaveTracesBatch(traces []*model.TraceData) error
+}
+
 // ParamRepository 参数数据仓储接口
 type ParamRepository interface {
 	// SaveParam 保存参数数据
@@ -66,3 +72,145 @@ type RepositoryFactory interface {
 	// 关闭数据库连接
 	Close() error
 }
+
+// StatsRepository 函数统计快照仓储接口
+type StatsRepository interface {
+	// SaveFuncStats 批量保存函数统计快照（单事务，同名函数覆盖）
+	SaveFuncStats(stats []*model.FuncStats) error
+
+	// FindAllFuncStats 查询全部函数统计快照
+	FindAllFuncStats() ([]model.FuncStats, error)
+}
+
+// StatsRepositoryProvider 可选能力：支持持久化函数统计的仓储工厂
+type StatsRepositoryProvider interface {
+	// GetStatsRepository 获取函数统计仓储
+	GetStatsRepository() StatsRepository
+}
+
+// EventRepository 跟踪事件仓储接口
+type EventRepository interface {
+	// SaveEvent 保存跟踪事件
+	SaveEvent(event *model.TraceEvent) (int64, error)
+
+	// FindEventsByTraceID 根据跟踪ID查找事件
+	FindEventsByTraceID(traceId int64) ([]model.TraceEvent, error)
+}
+
+// EventRepositoryProvider 可选能力：支持持久化跟踪事件的仓储工厂
+type EventRepositoryProvider interface {
+	// GetEventRepository 获取跟踪事件仓储
+	GetEventRepository() EventRepository
+}
+
+// LeakRepository goroutine 泄漏报告仓储接口
+type LeakRepository interface {
+	// SaveLeakReports 批量保存泄漏报告（单事务）
+	SaveLeakReports(reports []*model.LeakReport) error
+
+	// FindAllLeakReports 查询全部泄漏报告
+	FindAllLeakReports() ([]model.LeakReport, error)
+}
+
+// LeakRepositoryProvider 可选能力：支持持久化泄漏报告的仓储工厂
+type LeakRepositoryProvider interface {
+	// GetLeakRepository 获取泄漏报告仓储
+	GetLeakRepository() LeakRepository
+}
+
+// TraceScanner 可选能力：按ID升序流式遍历全部记录，供离线导出使用
+// 同一 goroutine 内的ID单调递增，因此父调用总是先于子调用出现
+type TraceScanner interface {
+	// ScanTraces 遍历全部跟踪数据，fn 返回错误时终止遍历并返回该错误
+	ScanTraces(fn func(trace *model.TraceData) error) error
+
+	// ScanGoroutines 遍历全部协程数据，fn 返回错误时终止遍历并返回该错误
+	ScanGoroutines(fn func(goroutine *model.GoroutineTrace) error) error
+}
+
+// TraceTreeReader 可选能力：按调用树读取跟踪数据
+type TraceTreeReader interface {
+	// FindTraceByID 根据ID查找跟踪数据
+	FindTraceByID(id int64) (*model.TraceData, error)
+
+	// FindTraceChildren 查找直接子调用，按ID升序
+	FindTraceChildren(parentId int64) ([]model.TraceData, error)
+
+	// FindGoroutinesSpawnedBy 查找在指定调用执行期间创建的协程
+	FindGoroutinesSpawnedBy(traceId int64) ([]model.GoroutineTrace, error)
+
+	// FindRootTracesByGID 查找协程的全部根调用，按ID升序
+	FindRootTracesByGID(gid uint64) ([]model.TraceData, error)
+}
+
+// TraceQueryReader 可选能力：按条件查询跟踪与协程数据，列表查询使用游标分页
+// 供离线工具以与存储后端无关的方式读取数据
+type TraceQueryReader interface {
+	TraceTreeReader
+
+	// ListTraceChildren 分页查询直接子调用，按ID升序
+	ListTraceChildren(parentId int64, page model.PageRequest) (*model.TracePage, error)
+
+	// ListSubtree 分页查询以 rootId 为根的完整调用子树（含根），按ID升序
+	ListSubtree(rootId int64, page model.PageRequest) (*model.TracePage, error)
+
+	// FindTraces 按条件分页查询跟踪数据，按ID升序
+	FindTraces(query model.TraceQuery, page model.PageRequest) (*model.TracePage, error)
+
+	// FindSlowestTraces 按条件分页查询已完成的调用，按耗时降序；第一页即最慢的 N 次调用
+	FindSlowestTraces(query model.TraceQuery, page model.PageRequest) (*model.TracePage, error)
+
+	// FindGoroutines 按条件分页查询协程，按ID升序
+	FindGoroutines(query model.GoroutineQuery, page model.PageRequest) (*model.GoroutinePage, error)
+}
+
+// SourceRepository 数据来源仓储接口（汇聚多个进程的数据时使用）
+type SourceRepository interface {
+	// SaveSource 保存数据来源，返回自增ID
+	SaveSource(source *model.Source) (int64, error)
+
+	// UpdateSourceProgress 更新已写入的批次序号、记录数与最后活动时间
+	UpdateSourceProgress(id int64, lastSeq int64, records int64, lastSeenAt string) error
+
+	// FindSourceByRunID 根据运行ID查找数据来源，未找到时返回 nil
+	FindSourceByRunID(runId string) (*model.Source, error)
+
+	// FindAllSources 查询全部数据来源
+	FindAllSources() ([]model.Source, error)
+}
+
+// SourceRepositoryProvider 可选能力：支持记录数据来源的仓储工厂
+type SourceRepositoryProvider interface {
+	// GetSourceRepository 获取数据来源仓储
+	GetSourceRepository() SourceRepository
+}
+
+// RunRepository 运行记录仓储接口
+type RunRepository interface {
+	// StartRun 登记一次运行，返回自增ID；run.ID 非0时按指定ID写入（如滚动到新文件）
+	StartRun(run *model.Run) (int64, error)
+
+	// EndRun 记录运行的结束时间与状态
+	EndRun(id int64, endedAt string, status string) error
+
+	// FindRunByID 根据ID查找运行记录，未找到时返回 nil
+	FindRunByID(id int64) (*model.Run, error)
+
+	// FindAllRuns 查询全部运行记录，按ID升序
+	FindAllRuns() ([]model.Run, error)
+}
+
+// RunRepositoryProvider 可选能力：支持记录运行元数据的仓储工厂
+type RunRepositoryProvider interface {
+	// GetRunRepository 获取运行记录仓储
+	GetRunRepository() RunRepository
+}
+
+// Summarizer 可选能力：从跟踪数据计算并保存分析汇总表，供查看工具与脚本直接读取
+type Summarizer interface {
+	// Summarize 重新计算全部运行的汇总表（单事务），替换已有的汇总结果
+	Summarize() error
+
+	// SummarizeRun 只重新计算指定运行的汇总表（单事务），其他运行的汇总结果保持不变
+	SummarizeRun(runID int64) error
+}
@@ -0,0 +1,296 @@
+// Package callgraph 将整个运行按函数名聚合为调用图，并输出 Graphviz DOT
+// 节点大小反映自身耗时，边上标注调用次数与总耗时，剪枝规则与 go tool pprof -dot 类似
+package callgraph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter"
+	"github.com/toheart/functrace/trace"
+)
+
+// 默认剪枝阈值，与 pprof 一致
+const (
+	DefaultNodeFraction = 0.005
+	DefaultEdgeFraction = 0.001
+	DefaultMaxNodes     = 80
+)
+
+// Node 函数节点
+type Node struct {
+	Name  string
+	Count int64         // 调用次数
+	Wall  time.Duration // 总耗时（递归调用会重复计入）
+	Self  time.Duration // 自身耗时
+}
+
+// Edge 调用方到被调用方的聚合边
+type Edge struct {
+	Caller string
+	Callee string
+	Count  int64         // 调用次数
+	Wall   time.Duration // 被调用方在该边上的总耗时
+}
+
+// edgeKey 边的键
+type edgeKey struct{ caller, callee string }
+
+// Graph 聚合后的调用图
+type Graph struct {
+	Total time.Duration // 全部根调用的总耗时，作为百分比的基准
+	nodes map[string]*Node
+	edges map[edgeKey]*Edge
+}
+
+// Options DOT 输出选项
+type Options struct {
+	NodeFraction    float64 // 总耗时低于 Total*NodeFraction 的节点被剪除，负数表示不剪枝
+	EdgeFraction    float64 // 总耗时低于 Total*EdgeFraction 的边被剪除，负数表示不剪枝
+	MaxNodes        int     // 最多保留的节点数（按总耗时），负数表示不限制
+	ClusterPackages bool    // 按包分组为子图
+}
+
+// Collect 遍历仓储中的全部调用，按函数名聚合父子边
+// 仓储需实现 domain.TraceScanner；内存占用只与函数与边的数量相关
+func Collect(factory domain.RepositoryFactory) (*Graph, error) {
+	scanner, err := exporter.Scanner(factory)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{nodes: make(map[string]*Node), edges: make(map[edgeKey]*Edge)}
+	stacks := exporter.NewStacks()
+	err = scanner.ScanTraces(func(td *model.TraceData) error {
+		ancestors := stacks.Push(td)
+		cost := exporter.Duration(td)
+
+		n := g.node(td.Name)
+		n.Count++
+		n.Wall += cost
+		n.Self += cost
+		if len(ancestors) == 0 {
+			g.Total += cost
+			return nil
+		}
+		parent := ancestors[len(ancestors)-1].Name
+		g.node(parent).Self -= cost
+		key := edgeKey{caller: parent, callee: td.Name}
+		e, ok := g.edges[key]
+		if !ok {
+			e = &Edge{Caller: parent, Callee: td.Name}
+			g.edges[key] = e
+		}
+		e.Count++
+		e.Wall += cost
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *Graph) node(name string) *Node {
+	n, ok := g.nodes[name]
+	if !ok {
+		n = &Node{Name: name}
+		g.nodes[name] = n
+	}
+	return n
+}
+
+// Nodes 返回全部节点，按总耗时降序
+func (g *Graph) Nodes() []*Node {
+	result := make([]*Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		result = append(result, n)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Wall != result[j].Wall {
+			return result[i].Wall > result[j].Wall
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Edges 返回全部边，按总耗时降序
+func (g *Graph) Edges() []*Edge {
+	result := make([]*Edge, 0, len(g.edges))
+	for _, e := range g.edges {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Wall != result[j].Wall {
+			return result[i].Wall > result[j].Wall
+		}
+		if result[i].Caller != result[j].Caller {
+			return result[i].Caller < result[j].Caller
+		}
+		return result[i].Callee < result[j].Callee
+	})
+	return result
+}
+
+// WriteDOT 按选项剪枝后输出 DOT
+func (g *Graph) WriteDOT(w io.Writer, opts Options) error {
+	if opts.NodeFraction == 0 {
+		opts.NodeFraction = DefaultNodeFraction
+	}
+	if opts.EdgeFraction == 0 {
+		opts.EdgeFraction = DefaultEdgeFraction
+	}
+	if opts.MaxNodes == 0 {
+		opts.MaxNodes = DefaultMaxNodes
+	}
+
+	// 节点剪枝：阈值 + 数量上限
+	ids := make(map[string]string)
+	var kept []*Node
+	var maxSelf time.Duration
+	for _, n := range g.Nodes() {
+		if opts.NodeFraction > 0 && float64(n.Wall) < float64(g.Total)*opts.NodeFraction {
+			continue
+		}
+		if opts.MaxNodes > 0 && len(kept) >= opts.MaxNodes {
+			break
+		}
+		kept = append(kept, n)
+		ids[n.Name] = fmt.Sprintf("N%d", len(kept))
+		if s := clampSelf(n.Self); s > maxSelf {
+			maxSelf = s
+		}
+	}
+
+	// 边剪枝：两端节点均保留且超过阈值
+	var edges []*Edge
+	var maxEdge time.Duration
+	for _, e := range g.Edges() {
+		if ids[e.Caller] == "" || ids[e.Callee] == "" {
+			continue
+		}
+		if opts.EdgeFraction > 0 && float64(e.Wall) < float64(g.Total)*opts.EdgeFraction {
+			continue
+		}
+		edges = append(edges, e)
+		if e.Wall > maxEdge {
+			maxEdge = e.Wall
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph functrace {")
+	fmt.Fprintln(bw, `  node [shape=box style=filled fillcolor="#f8f8f8" fontname="Helvetica"];`)
+	fmt.Fprintln(bw, `  edge [fontname="Helvetica" fontsize=9];`)
+	fmt.Fprintf(bw, "  label=%s; labelloc=t;\n", quote(fmt.Sprintf("functrace call graph\ntotal %s, showing %d of %d nodes, %d of %d edges",
+		g.Total, len(kept), len(g.nodes), len(edges), len(g.edges))))
+
+	writeNode := func(indent string, n *Node) {
+		fmt.Fprintf(bw, "%s%s [label=%s fontsize=%.1f];\n", indent, ids[n.Name], quote(g.nodeLabel(n)), fontSize(clampSelf(n.Self), maxSelf))
+	}
+	if opts.ClusterPackages {
+		byPkg := make(map[string][]*Node)
+		var pkgs []string
+		for _, n := range kept {
+			pkg := packageOf(n.Name)
+			if _, ok := byPkg[pkg]; !ok {
+				pkgs = append(pkgs, pkg)
+			}
+			byPkg[pkg] = append(byPkg[pkg], n)
+		}
+		sort.Strings(pkgs)
+		for i, pkg := range pkgs {
+			fmt.Fprintf(bw, "  subgraph cluster_%d {\n    label=%s; style=rounded; color=\"#999999\";\n", i, quote(pkg))
+			for _, n := range byPkg[pkg] {
+				writeNode("    ", n)
+			}
+			fmt.Fprintln(bw, "  }")
+		}
+	} else {
+		for _, n := range kept {
+			writeNode("  ", n)
+		}
+	}
+	for _, e := range edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s penwidth=%.2f weight=%d];\n",
+			ids[e.Caller], ids[e.Callee], quote(fmt.Sprintf("%d calls\n%s", e.Count, e.Wall)), penWidth(e.Wall, maxEdge), weight(e.Wall, g.Total))
+	}
+	fmt.Fprintln(bw, "}")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write dot error: %w", err)
+	}
+	return nil
+}
+
+// nodeLabel 节点文本：短名、自身耗时与总耗时及占比
+func (g *Graph) nodeLabel(n *Node) string {
+	return fmt.Sprintf("%s\nself %s (%s)\nof %s (%s)\n%d calls",
+		shortName(n.Name), clampSelf(n.Self), percent(clampSelf(n.Self), g.Total), n.Wall, percent(n.Wall, g.Total), n.Count)
+}
+
+// clampSelf 自身耗时为负（计时误差）时按0处理
+func clampSelf(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func percent(d, total time.Duration) string {
+	if total <= 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.2f%%", 100*float64(d)/float64(total))
+}
+
+// fontSize 按自身耗时的平方根缩放字号，与 pprof 相同
+func fontSize(self, maxSelf time.Duration) float64 {
+	if maxSelf <= 0 {
+		return 8
+	}
+	return 8 + 24*math.Sqrt(float64(self)/float64(maxSelf))
+}
+
+func penWidth(d, maxEdge time.Duration) float64 {
+	if maxEdge <= 0 {
+		return 1
+	}
+	return 1 + 5*float64(d)/float64(maxEdge)
+}
+
+// weight 让耗时大的边在布局中更短更直
+func weight(d, total time.Duration) int {
+	if total <= 0 {
+		return 1
+	}
+	return 1 + int(100*float64(d)/float64(total))
+}
+
+// packageOf 函数所属的包路径
+func packageOf(name string) string {
+	if info := trace.ParseFuncName(name); info.Package != "" {
+		return info.Package
+	}
+	return name
+}
+
+// shortName 去掉包路径的目录部分，保留 pkg.Type.Method
+func shortName(name string) string {
+	return path.Base(name)
+}
+
+// quote 生成 DOT 字符串，换行转为 \n
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,79 @@
+package callgraph
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toheart/functrace/exporter/internal/exportertest"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+const (
+	fnMain   = "main.main"
+	fnLoad   = "github.com/acme/store.Load"
+	fnParse  = "github.com/acme/store.parse"
+	fnWorker = "main.worker"
+)
+
+// newTestDB main(10ms) -> Load(3ms) -> parse(1ms)，main -> Load(2ms)，另一个 goroutine 上 worker(4ms)
+func newTestDB(t *testing.T) *sqlite.SQLiteDatabase {
+	return exportertest.NewDB(t,
+		exportertest.Call{ID: 1, Name: fnMain, GID: 1, Cost: 10 * time.Millisecond},
+		exportertest.Call{ID: 3, Name: fnLoad, GID: 1, Indent: 1, Parent: 1, Cost: 3 * time.Millisecond},
+		exportertest.Call{ID: 5, Name: fnParse, GID: 1, Indent: 2, Parent: 3, Cost: time.Millisecond},
+		exportertest.Call{ID: 7, Name: fnLoad, GID: 1, Indent: 1, Parent: 1, Cost: 2 * time.Millisecond},
+		exportertest.Call{ID: 2, Name: fnWorker, GID: 2, Cost: 4 * time.Millisecond},
+	)
+}
+
+func TestCollect(t *testing.T) {
+	g, err := Collect(newTestDB(t))
+	require.NoError(t, err)
+	assert.Equal(t, 14*time.Millisecond, g.Total)
+
+	nodes := g.Nodes()
+	require.Len(t, nodes, 4)
+	assert.Equal(t, Node{Name: fnMain, Count: 1, Wall: 10 * time.Millisecond, Self: 5 * time.Millisecond}, *nodes[0])
+	assert.Equal(t, Node{Name: fnLoad, Count: 2, Wall: 5 * time.Millisecond, Self: 4 * time.Millisecond}, *nodes[1])
+
+	edges := g.Edges()
+	require.Len(t, edges, 2)
+	assert.Equal(t, Edge{Caller: fnMain, Callee: fnLoad, Count: 2, Wall: 5 * time.Millisecond}, *edges[0])
+	assert.Equal(t, Edge{Caller: fnLoad, Callee: fnParse, Count: 1, Wall: time.Millisecond}, *edges[1])
+}
+
+func TestWriteDOT(t *testing.T) {
+	g, err := Collect(newTestDB(t))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, g.WriteDOT(&buf, Options{}))
+	out := buf.String()
+	assert.Contains(t, out, "digraph functrace {")
+	assert.Contains(t, out, `N1 [label="main.main\nself 5ms (35.71%)\nof 10ms (71.43%)\n1 calls" fontsize=32.0];`)
+	assert.Contains(t, out, `N2 -> N4 [label="1 calls\n1ms"`)
+	assert.Contains(t, out, `N1 -> N2 [label="2 calls\n5ms" penwidth=6.00`)
+	assert.Contains(t, out, "showing 4 of 4 nodes, 2 of 2 edges")
+}
+
+func TestWriteDOT_PruneAndCluster(t *testing.T) {
+	g, err := Collect(newTestDB(t))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	// 阈值 1.4ms 剪除 parse 及其入边
+	require.NoError(t, g.WriteDOT(&buf, Options{NodeFraction: 0.1, ClusterPackages: true}))
+	out := buf.String()
+	assert.NotContains(t, out, "store.parse")
+	assert.Contains(t, out, "showing 3 of 4 nodes, 1 of 2 edges")
+	assert.Contains(t, out, `label="github.com/acme/store"`)
+	assert.Contains(t, out, `label="main"`)
+
+	buf.Reset()
+	require.NoError(t, g.WriteDOT(&buf, Options{MaxNodes: 1}))
+	assert.Contains(t, buf.String(), "showing 1 of 4 nodes, 0 of 2 edges")
+}
@@ -0,0 +1,213 @@
+// Package chrome 将跟踪数据流式导出为 Trace Event Format JSON，可直接在 Perfetto 或 chrome://tracing 中打开
+// 每个 goroutine 一条轨道，调用为嵌套的切片，goroutine 的创建关系为 flow 箭头
+package chrome
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter"
+	"github.com/toheart/functrace/trace"
+)
+
+// DefaultMaxParamLength 参数文本默认截断长度
+const DefaultMaxParamLength = 1024
+
+// processID 全部轨道所属的进程ID
+const processID = 1
+
+// Options 导出选项
+type Options struct {
+	Params         bool   // 将参数写入切片的 args
+	MaxParamLength int    // 单个参数的最大长度，0 使用默认值，负数表示不截断
+	ProcessName    string // 进程轨道名称
+}
+
+// event Trace Event Format 的单个事件
+type event struct {
+	Name string                 `json:"name"`
+	Cat  string                 `json:"cat,omitempty"`
+	Ph   string                 `json:"ph"`
+	Ts   float64                `json:"ts"`
+	Dur  *float64               `json:"dur,omitempty"`
+	Pid  int                    `json:"pid"`
+	Tid  uint64                 `json:"tid"`
+	ID   string                 `json:"id,omitempty"`
+	Bp   string                 `json:"bp,omitempty"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// writer 逐个写出事件，不在内存中保留整个数组
+type writer struct {
+	w     *bufio.Writer
+	count int
+	err   error
+}
+
+func (w *writer) write(ev *event) {
+	if w.err != nil {
+		return
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		w.err = fmt.Errorf("marshal trace event error: %w", err)
+		return
+	}
+	if w.count > 0 {
+		_ = w.w.WriteByte(',')
+	}
+	_ = w.w.WriteByte('\n')
+	_, w.err = w.w.Write(data)
+	w.count++
+}
+
+// Export 将仓储中的全部调用写为 Trace Event Format JSON，返回导出的调用数
+// 仓储需实现 domain.TraceScanner；跟踪数据逐行流式写出，内存占用与数据库大小无关
+func Export(out io.Writer, factory domain.RepositoryFactory, opts Options) (int, error) {
+	scanner, err := exporter.Scanner(factory)
+	if err != nil {
+		return 0, err
+	}
+	if opts.MaxParamLength == 0 {
+		opts.MaxParamLength = DefaultMaxParamLength
+	}
+	if opts.ProcessName == "" {
+		opts.ProcessName = "functrace"
+	}
+
+	// goroutine 表规模很小，先整体读入：确定时间基准、轨道名称与创建关系
+	var (
+		base     time.Time
+		spawns   = make(map[int64][]*model.GoroutineTrace) // 创建者调用ID -> 被创建的 goroutine
+		spawned  = make(map[uint64]*model.GoroutineTrace)  // goroutine ID -> 有创建者调用的 goroutine
+		threads  []*model.GoroutineTrace
+		flowDone = make(map[uint64]bool)
+	)
+	if err := scanner.ScanGoroutines(func(g *model.GoroutineTrace) error {
+		if created, err := time.Parse(trace.TimeFormat, g.CreateTime); err == nil && (base.IsZero() || created.Before(base)) {
+			base = created
+		}
+		if g.ParentTraceID != 0 {
+			spawns[g.ParentTraceID] = append(spawns[g.ParentTraceID], g)
+			spawned[uint64(g.ID)] = g
+		}
+		threads = append(threads, g)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	ts := func(t time.Time) float64 {
+		if base.IsZero() {
+			base = t
+		}
+		return float64(t.Sub(base).Nanoseconds()) / 1e3
+	}
+
+	bw := bufio.NewWriterSize(out, 64<<10)
+	w := &writer{w: bw}
+	if _, err := bw.WriteString(`{"displayTimeUnit":"ms","traceEvents":[`); err != nil {
+		return 0, fmt.Errorf("write trace header error: %w", err)
+	}
+
+	w.write(&event{Name: "process_name", Ph: "M", Pid: processID, Args: map[string]interface{}{"name": opts.ProcessName}})
+	for _, g := range threads {
+		w.write(&event{Name: "thread_name", Ph: "M", Pid: processID, Tid: uint64(g.ID), Args: map[string]interface{}{
+			"name": fmt.Sprintf("goroutine %d (gid %d) %s", g.ID, g.OriginGID, g.InitFuncName),
+		}})
+		w.write(&event{Name: "thread_sort_index", Ph: "M", Pid: processID, Tid: uint64(g.ID), Args: map[string]interface{}{"sort_index": g.ID}})
+	}
+
+	params := factory.GetParamRepository()
+	count := 0
+	err = scanner.ScanTraces(func(td *model.TraceData) error {
+		start, err := exporter.StartTime(td)
+		if err != nil {
+			return err
+		}
+		startTs := ts(start)
+		cost := exporter.Duration(td)
+
+		args := map[string]interface{}{"id": td.ID}
+		if td.ParentId != 0 {
+			args["parentId"] = td.ParentId
+		}
+		if opts.Params && td.ParamsCount > 0 {
+			list, err := params.FindParamsByTraceID(td.ID)
+			if err != nil {
+				return fmt.Errorf("find params of trace %d error: %w", td.ID, err)
+			}
+			for _, p := range list {
+				args["param"+strconv.Itoa(p.Position)] = truncate(trace.DecodeParamData(p.Data), opts.MaxParamLength)
+			}
+		}
+
+		ev := &event{Name: td.Name, Cat: "call", Pid: processID, Tid: td.GID, Ts: startTs, Args: args}
+		if td.IsFinished == 1 {
+			dur := float64(cost.Nanoseconds()) / 1e3
+			ev.Ph, ev.Dur = "X", &dur
+		} else {
+			// 未返回的调用只有开始事件，查看器会将其延伸到轨道末尾
+			ev.Ph = "B"
+			args["unfinished"] = true
+		}
+		w.write(ev)
+
+		// goroutine 在该调用内被创建：flow 起点位于创建时刻（限制在调用区间内）
+		for _, g := range spawns[td.ID] {
+			at := startTs
+			if created, err := time.Parse(trace.TimeFormat, g.CreateTime); err == nil {
+				at = clamp(ts(created), startTs, startTs+float64(cost.Nanoseconds())/1e3)
+			}
+			w.write(&event{Name: "go", Cat: "spawn", Ph: "s", Pid: processID, Tid: td.GID, Ts: at, ID: flowID(g.ID)})
+		}
+		// 被创建 goroutine 的第一个根调用为 flow 终点
+		if g, ok := spawned[td.GID]; ok && td.ParentId == 0 && !flowDone[td.GID] {
+			flowDone[td.GID] = true
+			w.write(&event{Name: "go", Cat: "spawn", Ph: "f", Bp: "e", Pid: processID, Tid: td.GID, Ts: startTs, ID: flowID(g.ID)})
+		}
+
+		count++
+		return w.err
+	})
+	if err != nil {
+		return count, err
+	}
+	if w.err != nil {
+		return count, w.err
+	}
+	if _, err := bw.WriteString("\n]}\n"); err != nil {
+		return count, fmt.Errorf("write trace footer error: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return count, fmt.Errorf("flush trace events error: %w", err)
+	}
+	return count, nil
+}
+
+// flowID goroutine 创建关系的 flow ID
+func flowID(goroutineID int64) string {
+	return "g" + strconv.FormatInt(goroutineID, 10)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func truncate(s string, max int) string {
+	if max < 0 || len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,88 @@
+package chrome
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter/internal/exportertest"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+func newTestDB(t *testing.T) *sqlite.SQLiteDatabase {
+	db := exportertest.NewDB(t,
+		exportertest.Call{ID: 64, Name: "main.main", GID: 1, Cost: 10 * time.Millisecond},
+		exportertest.Call{ID: 128, Name: "main.load", GID: 1, Indent: 1, Params: 1, Parent: 64, Start: time.Millisecond, Cost: 500 * time.Microsecond},
+		exportertest.Call{ID: 65, Name: "main.worker", GID: 2, Start: 3 * time.Millisecond},
+	)
+
+	goroutines := db.GetGoroutineRepository()
+	_, err := goroutines.SaveGoroutine(model.NewGoroutineTrace(1, 1, exportertest.At(0), 0, "main.main"))
+	require.NoError(t, err)
+	_, err = goroutines.SaveGoroutine(model.NewGoroutineTrace(2, 20, exportertest.At(2*time.Millisecond), 0, "main.worker").WithCreator(1, "main.main", 64))
+	require.NoError(t, err)
+
+	_, err = db.GetParamRepository().SaveParam(&model.ParamStoreData{ID: 1, TraceID: 128, Position: 0, Data: []byte(`"config.yaml"`)})
+	require.NoError(t, err)
+	return db
+}
+
+func TestExport(t *testing.T) {
+	db := newTestDB(t)
+	var buf bytes.Buffer
+	n, err := Export(&buf, db, Options{Params: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	var doc struct {
+		TraceEvents []event `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	byPh := make(map[string][]event)
+	for _, ev := range doc.TraceEvents {
+		byPh[ev.Ph] = append(byPh[ev.Ph], ev)
+	}
+
+	// 进程名 + 每个 goroutine 的名称与排序
+	assert.Len(t, byPh["M"], 5)
+
+	require.Len(t, byPh["X"], 2)
+	main, load := byPh["X"][0], byPh["X"][1]
+	assert.Equal(t, "main.main", main.Name)
+	assert.Equal(t, 0.0, main.Ts)
+	assert.Equal(t, 10000.0, *main.Dur)
+	assert.Equal(t, "main.load", load.Name)
+	assert.Equal(t, 1000.0, load.Ts)
+	assert.Equal(t, 500.0, *load.Dur)
+	assert.Equal(t, uint64(1), load.Tid)
+	assert.Equal(t, `"config.yaml"`, load.Args["param0"])
+
+	require.Len(t, byPh["B"], 1)
+	assert.Equal(t, "main.worker", byPh["B"][0].Name)
+	assert.Equal(t, uint64(2), byPh["B"][0].Tid)
+	assert.Equal(t, true, byPh["B"][0].Args["unfinished"])
+
+	// 创建关系：起点在创建者调用内的创建时刻，终点在被创建 goroutine 的首个根调用
+	require.Len(t, byPh["s"], 1)
+	require.Len(t, byPh["f"], 1)
+	assert.Equal(t, byPh["s"][0].ID, byPh["f"][0].ID)
+	assert.Equal(t, uint64(1), byPh["s"][0].Tid)
+	assert.Equal(t, 2000.0, byPh["s"][0].Ts)
+	assert.Equal(t, uint64(2), byPh["f"][0].Tid)
+	assert.Equal(t, 3000.0, byPh["f"][0].Ts)
+}
+
+func TestExport_WithoutParams(t *testing.T) {
+	db := newTestDB(t)
+	var buf bytes.Buffer
+	_, err := Export(&buf, db, Options{})
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "config.yaml")
+	assert.True(t, json.Valid(buf.Bytes()))
+}
@@ -0,0 +1,68 @@
+// Package exporter 提供将已记录的跟踪数据转换为外部格式时共用的工具
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/trace"
+)
+
+// Scanner 从仓储工厂中取出离线遍历能力
+func Scanner(factory domain.RepositoryFactory) (domain.TraceScanner, error) {
+	scanner, ok := factory.(domain.TraceScanner)
+	if !ok {
+		return nil, fmt.Errorf("repository %T does not support scanning", factory)
+	}
+	return scanner, nil
+}
+
+// StartTime 解析调用的开始时间
+func StartTime(td *model.TraceData) (time.Time, error) {
+	start, err := time.Parse(trace.TimeFormat, td.CreatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse createdAt of trace %d error: %w", td.ID, err)
+	}
+	return start, nil
+}
+
+// Duration 解析调用耗时，未完成或无法解析时返回0
+func Duration(td *model.TraceData) time.Duration {
+	if td.TimeCost == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(td.TimeCost)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// Stacks 按 goroutine 维护每行记录的祖先调用链
+// 输入需按ID升序（同一 goroutine 内父调用先于子调用），内存占用只与 goroutine 数量和调用深度相关
+type Stacks struct {
+	byGID map[uint64][]*model.TraceData
+}
+
+// NewStacks 创建祖先调用链跟踪器
+func NewStacks() *Stacks {
+	return &Stacks{byGID: make(map[uint64][]*model.TraceData)}
+}
+
+// Push 放入一行记录并返回其祖先链（根在前，不含自身）
+// 返回的切片在下一次调用 Push 前有效；父调用缺失时视为新的根调用
+func (s *Stacks) Push(td *model.TraceData) []*model.TraceData {
+	stack := s.byGID[td.GID]
+	if td.ParentId == 0 {
+		stack = stack[:0]
+	} else {
+		for len(stack) > 0 && stack[len(stack)-1].ID != td.ParentId {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	ancestors := stack
+	s.byGID[td.GID] = append(stack, td)
+	return ancestors
+}
@@ -0,0 +1,36 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toheart/functrace/domain/model"
+)
+
+func TestStacks_Push(t *testing.T) {
+	s := NewStacks()
+	rows := []*model.TraceData{
+		{ID: 1, GID: 1, Name: "main.main"},
+		{ID: 2, GID: 1, Name: "main.a", ParentId: 1},
+		{ID: 3, GID: 2, Name: "main.worker"},
+		{ID: 4, GID: 1, Name: "main.b", ParentId: 2},
+		{ID: 5, GID: 1, Name: "main.c", ParentId: 1},
+		{ID: 6, GID: 1, Name: "main.orphan", ParentId: 99},
+	}
+	var got [][]int64
+	for _, td := range rows {
+		var ids []int64
+		for _, a := range s.Push(td) {
+			ids = append(ids, a.ID)
+		}
+		got = append(got, ids)
+	}
+	assert.Equal(t, [][]int64{nil, {1}, nil, {1, 2}, {1}, nil}, got)
+}
+
+func TestDuration(t *testing.T) {
+	assert.Equal(t, 1500*time.Microsecond, Duration(&model.TraceData{TimeCost: "1.5ms"}))
+	assert.Zero(t, Duration(&model.TraceData{}))
+	assert.Zero(t, Duration(&model.TraceData{TimeCost: "bogus"}))
+}
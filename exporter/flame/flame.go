@@ -0,0 +1,209 @@
+// Package flame 将记录的调用树汇总为火焰图输入：Brendan Gregg folded-stack 文本与 pprof profile.proto
+// 调用栈由 ParentId 链构成，无需重新采样
+package flame
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter"
+)
+
+// Weight folded-stack 每行的取值
+type Weight string
+
+const (
+	// WeightSelf 自身耗时（纳秒），火焰图逐层累加后即为总耗时，适用于标准火焰图工具
+	WeightSelf Weight = "self"
+	// WeightWall 总耗时（纳秒），每行已包含子调用，只适合单独查看某条调用栈
+	WeightWall Weight = "wall"
+	// WeightCount 调用次数
+	WeightCount Weight = "count"
+)
+
+// keySep 调用栈键的分隔符，与 folded 格式一致
+const keySep = ";"
+
+// frameReplacer 去掉会破坏 folded 格式的字符
+var frameReplacer = strings.NewReplacer(keySep, ":", " ", "_", "\n", "_")
+
+// Stack 一条调用栈（根在前）的汇总
+type Stack struct {
+	Frames []string      // 函数名，根在前
+	Count  int64         // 调用次数
+	Wall   time.Duration // 总耗时（含子调用）
+	Self   time.Duration // 自身耗时（不含被跟踪的子调用）
+}
+
+// Profile 按调用栈汇总的结果
+type Profile struct {
+	Start  time.Time // 最早的调用开始时间
+	End    time.Time // 最晚的调用结束时间
+	stacks map[string]*Stack
+}
+
+// Collect 遍历仓储中的全部调用并按调用栈汇总
+// 仓储需实现 domain.TraceScanner；内存占用只与不同调用栈的数量相关
+func Collect(factory domain.RepositoryFactory) (*Profile, error) {
+	scanner, err := exporter.Scanner(factory)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{stacks: make(map[string]*Stack)}
+	stacks := exporter.NewStacks()
+	var frames []string
+	err = scanner.ScanTraces(func(td *model.TraceData) error {
+		ancestors := stacks.Push(td)
+		frames = frames[:0]
+		for _, a := range ancestors {
+			frames = append(frames, frameName(a.Name))
+		}
+		cost := exporter.Duration(td)
+		if start, err := exporter.StartTime(td); err == nil {
+			if p.Start.IsZero() || start.Before(p.Start) {
+				p.Start = start
+			}
+			if end := start.Add(cost); end.After(p.End) {
+				p.End = end
+			}
+		}
+
+		// 子调用的耗时从父调用栈的自身耗时中扣除
+		if len(frames) > 0 {
+			p.stack(frames).Self -= cost
+		}
+		s := p.stack(append(frames, frameName(td.Name)))
+		s.Count++
+		s.Wall += cost
+		s.Self += cost
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// stack 获取或创建调用栈汇总项
+func (p *Profile) stack(frames []string) *Stack {
+	key := strings.Join(frames, keySep)
+	s, ok := p.stacks[key]
+	if !ok {
+		s = &Stack{Frames: append([]string(nil), frames...)}
+		p.stacks[key] = s
+	}
+	return s
+}
+
+// frameName 规范化函数名，使其可作为 folded 格式的一帧
+func frameName(name string) string {
+	return frameReplacer.Replace(name)
+}
+
+// Stacks 返回按调用栈排序的汇总结果
+func (p *Profile) Stacks() []*Stack {
+	keys := make([]string, 0, len(p.stacks))
+	for k := range p.stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]*Stack, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, p.stacks[k])
+	}
+	return result
+}
+
+// value 按取值方式返回调用栈的值，自身耗时为负（计时误差）时按0处理
+func (s *Stack) value(weight Weight) int64 {
+	switch weight {
+	case WeightWall:
+		return int64(s.Wall)
+	case WeightCount:
+		return s.Count
+	default:
+		if s.Self < 0 {
+			return 0
+		}
+		return int64(s.Self)
+	}
+}
+
+// WriteFolded 写出 folded-stack 文本（"a;b;c 值"），值为0的调用栈被省略
+func (p *Profile) WriteFolded(w io.Writer, weight Weight) error {
+	switch weight {
+	case WeightSelf, WeightWall, WeightCount:
+	default:
+		return fmt.Errorf("unknown folded weight: %s", weight)
+	}
+	bw := bufio.NewWriter(w)
+	for _, s := range p.Stacks() {
+		v := s.value(weight)
+		if v <= 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(bw, "%s %d\n", strings.Join(s.Frames, keySep), v); err != nil {
+			return fmt.Errorf("write folded stacks error: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write folded stacks error: %w", err)
+	}
+	return nil
+}
+
+// PProf 构造 pprof profile，样本值依次为调用次数、总耗时与自身耗时，默认样本类型为自身耗时
+// 自身耗时的 flat/cum 与 CPU profile 含义一致；总耗时的 flat 即函数在该调用栈上的总耗时
+func (p *Profile) PProf() *profile.Profile {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "calls", Unit: "count"},
+			{Type: "wall", Unit: "nanoseconds"},
+			{Type: "self", Unit: "nanoseconds"},
+		},
+		DefaultSampleType: "self",
+		PeriodType:        &profile.ValueType{Type: "wall", Unit: "nanoseconds"},
+		Period:            1,
+		TimeNanos:         p.Start.UnixNano(),
+		DurationNanos:     int64(p.End.Sub(p.Start)),
+	}
+
+	locations := make(map[string]*profile.Location)
+	location := func(name string) *profile.Location {
+		if loc, ok := locations[name]; ok {
+			return loc
+		}
+		fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name, SystemName: name}
+		prof.Function = append(prof.Function, fn)
+		loc := &profile.Location{ID: uint64(len(prof.Location) + 1), Line: []profile.Line{{Function: fn}}}
+		prof.Location = append(prof.Location, loc)
+		locations[name] = loc
+		return loc
+	}
+
+	for _, s := range p.Stacks() {
+		sample := &profile.Sample{Value: []int64{s.Count, int64(s.Wall), s.value(WeightSelf)}}
+		// pprof 的调用栈叶子在前
+		for i := len(s.Frames) - 1; i >= 0; i-- {
+			sample.Location = append(sample.Location, location(s.Frames[i]))
+		}
+		prof.Sample = append(prof.Sample, sample)
+	}
+	return prof
+}
+
+// WritePProf 写出 gzip 压缩的 profile.proto，可直接用于 go tool pprof
+func (p *Profile) WritePProf(w io.Writer) error {
+	if err := p.PProf().Write(w); err != nil {
+		return fmt.Errorf("write pprof profile error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package flame
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toheart/functrace/exporter/internal/exportertest"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+// newTestDB main.main(10ms) -> load(3ms) -> parse(1ms)，main.main -> load(2ms)，另一个 goroutine 上 worker(4ms)
+func newTestDB(t *testing.T) *sqlite.SQLiteDatabase {
+	return exportertest.NewDB(t,
+		exportertest.Call{ID: 1, Name: "main.main", GID: 1, Cost: 10 * time.Millisecond},
+		exportertest.Call{ID: 3, Name: "main.load", GID: 1, Indent: 1, Parent: 1, Start: time.Millisecond, Cost: 3 * time.Millisecond},
+		exportertest.Call{ID: 5, Name: "main.parse", GID: 1, Indent: 2, Parent: 3, Start: 2 * time.Millisecond, Cost: time.Millisecond},
+		exportertest.Call{ID: 7, Name: "main.load", GID: 1, Indent: 1, Parent: 1, Start: 5 * time.Millisecond, Cost: 2 * time.Millisecond},
+		exportertest.Call{ID: 2, Name: "main.worker", GID: 2, Cost: 4 * time.Millisecond},
+	)
+}
+
+func TestWriteFolded(t *testing.T) {
+	p, err := Collect(newTestDB(t))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WriteFolded(&buf, WeightSelf))
+	assert.Equal(t, "main.main 5000000\n"+
+		"main.main;main.load 4000000\n"+
+		"main.main;main.load;main.parse 1000000\n"+
+		"main.worker 4000000\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, p.WriteFolded(&buf, WeightCount))
+	assert.Contains(t, buf.String(), "main.main;main.load 2\n")
+
+	assert.Error(t, p.WriteFolded(&buf, Weight("bogus")))
+}
+
+func TestWritePProf(t *testing.T) {
+	p, err := Collect(newTestDB(t))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WritePProf(&buf))
+	prof, err := profile.Parse(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "self", prof.DefaultSampleType)
+	require.Len(t, prof.Sample, 4)
+	var self, wall int64
+	for _, s := range prof.Sample {
+		self += s.Value[2]
+		if len(s.Location) == 1 && s.Location[0].Line[0].Function.Name == "main.main" {
+			wall = s.Value[1]
+		}
+		if s.Location[0].Line[0].Function.Name == "main.parse" {
+			// 叶子在前
+			require.Len(t, s.Location, 3)
+			assert.Equal(t, "main.main", s.Location[2].Line[0].Function.Name)
+		}
+	}
+	assert.Equal(t, int64(14*time.Millisecond), self)
+	assert.Equal(t, int64(10*time.Millisecond), wall)
+	assert.Equal(t, int64(10*time.Millisecond), prof.DurationNanos)
+}
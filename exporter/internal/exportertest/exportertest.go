@@ -0,0 +1,57 @@
+// Package exportertest 为导出器测试构建 SQLite 中的调用树
+package exportertest
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/sqlite"
+	"github.com/toheart/functrace/trace"
+)
+
+// Base 测试数据的起始时间，Call.Start 相对于它
+var Base = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Call 调用树中的一次调用，Cost 为 0 时保持未完成
+type Call struct {
+	ID     int64
+	Name   string
+	GID    uint64
+	Indent int
+	Params int // 参数个数
+	Parent int64
+	Start  time.Duration
+	Cost   time.Duration
+}
+
+// At 返回 Base 之后 d 的时间，格式与跟踪数据一致
+func At(d time.Duration) string {
+	return Base.Add(d).Format(trace.TimeFormat)
+}
+
+// NewDB 在临时目录创建 SQLite 库并按顺序写入调用，测试结束时关闭
+// 协程与参数等导出器特有的数据由调用方继续写入
+func NewDB(t testing.TB, calls ...Call) *sqlite.SQLiteDatabase {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "trace.db"), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	traces := db.GetTraceRepository()
+	for _, c := range calls {
+		_, err := traces.SaveTrace(model.NewTraceData(c.ID, c.Name, c.GID, c.Indent, c.Params, c.Parent, At(c.Start), "0.00"))
+		require.NoError(t, err)
+		if c.Cost > 0 {
+			require.NoError(t, traces.UpdateTraceTimeCost(c.ID, c.Cost.String()))
+		}
+	}
+	return db
+}
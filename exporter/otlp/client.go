@@ -0,0 +1,178 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// client OTLP 传输层
+type client interface {
+	export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error
+	close() error
+}
+
+// retryableError 可重试的发送错误，after 为服务端建议的等待时间（可为0）
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// newClient 按协议创建传输层
+func newClient(cfg Config) (client, error) {
+	switch cfg.Protocol {
+	case ProtocolHTTP, "http":
+		return newHTTPClient(cfg)
+	case ProtocolGRPC:
+		return newGRPCClient(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol: %s", cfg.Protocol)
+	}
+}
+
+// httpClient OTLP/HTTP（protobuf 编码）
+type httpClient struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPClient(cfg Config) (*httpClient, error) {
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https://"
+		if cfg.Insecure {
+			scheme = "http://"
+		}
+		endpoint = scheme + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse otlp endpoint error: %w", err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return &httpClient{
+		url:     u.String(),
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (c *httpClient) export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal otlp request error: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create otlp request error: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		// 网络错误均可重试
+		return &retryableError{err: fmt.Errorf("send otlp request error: %w", err)}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("otlp collector returned status %d", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &retryableError{err: err, after: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return err
+}
+
+func (c *httpClient) close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// parseRetryAfter 解析以秒为单位的 Retry-After
+func parseRetryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// grpcClient OTLP/gRPC
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	svc     coltracepb.TraceServiceClient
+	headers metadata.MD
+}
+
+func newGRPCClient(cfg Config) (*grpcClient, error) {
+	target := cfg.Endpoint
+	if i := strings.Index(target, "://"); i >= 0 {
+		target = target[i+3:]
+	}
+	target = strings.TrimSuffix(target, "/")
+
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("create otlp grpc client error: %w", err)
+	}
+	return &grpcClient{
+		conn:    conn,
+		svc:     coltracepb.NewTraceServiceClient(conn),
+		headers: metadata.New(cfg.Headers),
+	}, nil
+}
+
+func (c *grpcClient) export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error {
+	if len(c.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, c.headers)
+	}
+	_, err := c.svc.Export(ctx, req)
+	if err == nil {
+		return nil
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted, codes.Canceled:
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return err
+		}
+		return &retryableError{err: fmt.Errorf("send otlp grpc request error: %w", err)}
+	}
+	return fmt.Errorf("send otlp grpc request error: %w", err)
+}
+
+func (c *grpcClient) close() error {
+	return c.conn.Close()
+}
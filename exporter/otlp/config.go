@@ -0,0 +1,137 @@
+// Package otlp 将 functrace 的调用记录导出为 OpenTelemetry span，经 OTLP/HTTP 或 OTLP/gRPC 发送
+package otlp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 传输协议
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// 默认值
+const (
+	DefaultBatchSize      = 512
+	DefaultQueueSize      = 8192
+	DefaultFlushInterval  = 2 * time.Second
+	DefaultTimeout        = 10 * time.Second
+	DefaultMaxRetries     = 5
+	DefaultRetryBackoff   = 200 * time.Millisecond
+	DefaultMaxParamLength = 1024
+
+	maxRetryBackoff = 5 * time.Second
+)
+
+// Config 导出器配置
+type Config struct {
+	Endpoint       string            // 收集器地址，如 localhost:4318、https://collector/v1/traces、localhost:4317
+	Protocol       string            // ProtocolHTTP 或 ProtocolGRPC
+	Insecure       bool              // 不使用 TLS
+	Headers        map[string]string // 附加请求头（gRPC 为 metadata）
+	ServiceName    string            // service.name，默认为可执行文件名
+	BatchSize      int               // 每批最多 span 数
+	QueueSize      int               // 待发送队列长度，实时模式下队列满时丢弃
+	FlushInterval  time.Duration     // 批次未满时的最长等待时间
+	Timeout        time.Duration     // 单次发送超时
+	MaxRetries     int               // 可重试错误的最大重试次数，负数表示不重试
+	RetryBackoff   time.Duration     // 首次重试间隔，之后指数增长
+	CaptureParams  bool              // 将参数作为 span 属性导出
+	MaxParamLength int               // 单个参数属性的最大长度，负数表示不截断
+	Logger         *logrus.Logger    // 日志，默认为 logrus 标准日志
+}
+
+// ConfigFromEnv 按 OpenTelemetry 标准环境变量构造配置
+// 支持 OTEL_EXPORTER_OTLP_[TRACES_]ENDPOINT/PROTOCOL/HEADERS/INSECURE 与 OTEL_SERVICE_NAME
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Endpoint:    otlpEnv("ENDPOINT"),
+		Protocol:    otlpEnv("PROTOCOL"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Headers:     parseHeaders(otlpEnv("HEADERS")),
+	}
+	if v, err := strconv.ParseBool(otlpEnv("INSECURE")); err == nil {
+		cfg.Insecure = v
+	}
+	return cfg
+}
+
+// otlpEnv 读取 OTLP 环境变量，traces 专用变量优先
+func otlpEnv(name string) string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_" + name); v != "" {
+		return v
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_" + name)
+}
+
+// parseHeaders 解析 "k1=v1,k2=v2" 形式的请求头
+func parseHeaders(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers
+}
+
+// withDefaults 填充默认值
+func (c Config) withDefaults() Config {
+	if c.Protocol == "" {
+		c.Protocol = ProtocolHTTP
+	}
+	if c.Endpoint == "" {
+		if c.Protocol == ProtocolGRPC {
+			c.Endpoint = "localhost:4317"
+		} else {
+			c.Endpoint = "localhost:4318"
+		}
+		c.Insecure = true
+	}
+	if c.ServiceName == "" {
+		if exe, err := os.Executable(); err == nil {
+			c.ServiceName = filepath.Base(exe)
+		} else {
+			c.ServiceName = "functrace"
+		}
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = DefaultRetryBackoff
+	}
+	if c.MaxParamLength == 0 {
+		c.MaxParamLength = DefaultMaxParamLength
+	}
+	if c.Logger == nil {
+		c.Logger = logrus.StandardLogger()
+	}
+	return c
+}
@@ -0,0 +1,274 @@
+package otlp
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"github.com/toheart/functrace/trace"
+)
+
+// scopeName 导出 span 的 instrumentation scope
+const scopeName = "github.com/toheart/functrace"
+
+// ErrClosed 导出器已关闭
+var ErrClosed = errors.New("otlp exporter closed")
+
+// 确保 Exporter 可作为实时调用观察者
+var _ trace.CallObserver = (*Exporter)(nil)
+var _ trace.ParamsObserver = (*Exporter)(nil)
+
+// ExportStats 导出计数
+type ExportStats struct {
+	Exported uint64 `json:"exported"` // 已成功发送的 span 数
+	Dropped  uint64 `json:"dropped"`  // 队列满时丢弃的 span 数
+	Failed   uint64 `json:"failed"`   // 重试耗尽后放弃的 span 数
+}
+
+// Exporter 批量发送 span 的 OTLP 导出器
+// 实时模式下作为 trace.CallObserver 注册（队列满时丢弃），离线模式下由 ExportRepository 阻塞写入
+type Exporter struct {
+	cfg      Config
+	client   client
+	log      *logrus.Logger
+	resource *resourcepb.Resource
+	runID    [8]byte // 写入 OTel TraceId 高8字节，区分不同进程的同号调用树
+
+	queue   chan queuedSpan
+	flushCh chan chan error
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+
+	exported atomic.Uint64
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+// queuedSpan 待发送的 span；实时调用的参数在后台发送协程上格式化
+type queuedSpan struct {
+	span   *tracepb.Span
+	params []interface{}
+}
+
+// New 创建导出器并启动后台发送协程
+func New(cfg Config) (*Exporter, error) {
+	cfg = cfg.withDefaults()
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e := &Exporter{
+		cfg:    cfg,
+		client: c,
+		log:    cfg.Logger,
+		resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			stringAttr("service.name", cfg.ServiceName),
+			stringAttr("telemetry.sdk.name", "functrace"),
+			intAttr("process.pid", int64(os.Getpid())),
+		}},
+		queue:   make(chan queuedSpan, cfg.QueueSize),
+		flushCh: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	if _, err := rand.Read(e.runID[:]); err != nil {
+		return nil, fmt.Errorf("generate run id error: %w", err)
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e, nil
+}
+
+// OnCall 实现 trace.CallObserver：转换为 span 并非阻塞入队，参数留给后台发送协程格式化
+func (e *Exporter) OnCall(rec trace.CallRecord) {
+	if e.closed.Load() {
+		return
+	}
+	q := queuedSpan{span: e.spanFromCall(rec)}
+	if e.cfg.CaptureParams {
+		q.params = rec.Params
+	}
+	select {
+	case e.queue <- q:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// WantParams 实现 trace.ParamsObserver
+func (e *Exporter) WantParams() bool {
+	return e.cfg.CaptureParams
+}
+
+// enqueueWait 阻塞入队，用于离线导出
+func (e *Exporter) enqueueWait(ctx context.Context, span *tracepb.Span) error {
+	if e.closed.Load() {
+		return ErrClosed
+	}
+	select {
+	case e.queue <- queuedSpan{span: span}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.done:
+		return ErrClosed
+	}
+}
+
+// Flush 发送队列中已有的全部 span，返回最后一次发送失败的错误
+func (e *Exporter) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case e.flushCh <- reply:
+	case <-e.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 发送剩余 span 并关闭连接，可重复调用
+func (e *Exporter) Close() error {
+	var err error
+	e.closeOnce.Do(func() {
+		e.closed.Store(true)
+		close(e.done)
+		e.wg.Wait()
+		err = e.client.close()
+		e.log.WithFields(logrus.Fields{
+			"exported": e.exported.Load(),
+			"dropped":  e.dropped.Load(),
+			"failed":   e.failed.Load(),
+		}).Info("otlp exporter closed")
+	})
+	return err
+}
+
+// Stats 返回导出计数
+func (e *Exporter) Stats() ExportStats {
+	return ExportStats{
+		Exported: e.exported.Load(),
+		Dropped:  e.dropped.Load(),
+		Failed:   e.failed.Load(),
+	}
+}
+
+// run 后台批量发送
+func (e *Exporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*tracepb.Span, 0, e.cfg.BatchSize)
+	var lastErr error
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			lastErr = err
+		}
+		batch = make([]*tracepb.Span, 0, e.cfg.BatchSize)
+	}
+	// drain 取出队列中当前全部 span 并发送
+	drain := func() {
+		for {
+			select {
+			case q := <-e.queue:
+				batch = append(batch, e.build(q))
+				if len(batch) >= e.cfg.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case q := <-e.queue:
+			batch = append(batch, e.build(q))
+			if len(batch) >= e.cfg.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case reply := <-e.flushCh:
+			lastErr = nil
+			drain()
+			reply <- lastErr
+		case <-e.done:
+			drain()
+			return
+		}
+	}
+}
+
+// build 补全待发送 span 的参数属性
+func (e *Exporter) build(q queuedSpan) *tracepb.Span {
+	if len(q.params) > 0 {
+		e.appendParams(q.span, q.params)
+	}
+	return q.span
+}
+
+// send 发送一批 span，可重试错误按指数退避重试
+func (e *Exporter) send(spans []*tracepb.Span) error {
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: scopeName},
+				Spans: spans,
+			}},
+		}},
+	}
+
+	backoff := e.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+		err := e.client.export(ctx, req)
+		cancel()
+		if err == nil {
+			e.exported.Add(uint64(len(spans)))
+			return nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= e.cfg.MaxRetries {
+			e.failed.Add(uint64(len(spans)))
+			e.log.WithFields(logrus.Fields{"error": err, "spans": len(spans), "attempts": attempt + 1}).Error("otlp export failed")
+			return err
+		}
+		wait := backoff
+		if retryable.after > wait {
+			wait = retryable.after
+		}
+		e.log.WithFields(logrus.Fields{"error": err, "retryIn": wait}).Warn("otlp export failed, retrying")
+		time.Sleep(wait)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
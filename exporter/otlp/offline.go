@@ -0,0 +1,58 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter"
+)
+
+// ExportRepository 将仓储中已记录的全部调用作为 span 发送，返回发送的 span 数
+// 仓储需实现 domain.TraceScanner；队列满时阻塞等待而不是丢弃
+func ExportRepository(ctx context.Context, factory domain.RepositoryFactory, e *Exporter) (int, error) {
+	scanner, err := exporter.Scanner(factory)
+	if err != nil {
+		return 0, err
+	}
+
+	goroutines := make(map[uint64]*model.GoroutineTrace)
+	if err := scanner.ScanGoroutines(func(g *model.GoroutineTrace) error {
+		goroutines[uint64(g.ID)] = g
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	params := factory.GetParamRepository()
+	stacks := exporter.NewStacks()
+	count := 0
+	err = scanner.ScanTraces(func(td *model.TraceData) error {
+		ancestors := stacks.Push(td)
+		rootID := td.ID
+		if len(ancestors) > 0 {
+			rootID = ancestors[0].ID
+		}
+		start, err := exporter.StartTime(td)
+		if err != nil {
+			return err
+		}
+		var tdParams []model.ParamStoreData
+		if e.cfg.CaptureParams && td.ParamsCount > 0 {
+			if tdParams, err = params.FindParamsByTraceID(td.ID); err != nil {
+				return fmt.Errorf("find params of trace %d error: %w", td.ID, err)
+			}
+		}
+		span := e.spanFromTrace(td, rootID, start, exporter.Duration(td), goroutines[td.GID], tdParams)
+		if err := e.enqueueWait(ctx, span); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, e.Flush(ctx)
+}
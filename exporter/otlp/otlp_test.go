@@ -0,0 +1,248 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/sqlite"
+	"github.com/toheart/functrace/trace"
+)
+
+// stubCollector 记录收到的 span，前 failFirst 次请求返回可重试错误
+type stubCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu        sync.Mutex
+	requests  int
+	failFirst int
+	spans     []*tracepb.Span
+}
+
+func (c *stubCollector) accept(req *coltracepb.ExportTraceServiceRequest) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	if c.requests <= c.failFirst {
+		return false
+	}
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	return true
+}
+
+func (c *stubCollector) snapshot() (int, []*tracepb.Span) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests, append([]*tracepb.Span(nil), c.spans...)
+}
+
+func (c *stubCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req coltracepb.ExportTraceServiceRequest
+	if r.URL.Path != "/v1/traces" || proto.Unmarshal(body, &req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !c.accept(&req) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *stubCollector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	if !c.accept(req) {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func testConfig(endpoint, protocol string) Config {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return Config{
+		Endpoint:      endpoint,
+		Protocol:      protocol,
+		Insecure:      true,
+		ServiceName:   "test",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+		CaptureParams: true,
+		Logger:        logger,
+	}
+}
+
+func callTree() []trace.CallRecord {
+	start := time.Now()
+	return []trace.CallRecord{
+		{TraceID: 130, ParentID: 66, RootID: 66, GoroutineID: 1, Name: "main.child", Depth: 1, Start: start, Duration: time.Millisecond, Params: []interface{}{42}},
+		{TraceID: 66, RootID: 66, GoroutineID: 1, Name: "main.root", Start: start, Duration: 2 * time.Millisecond, Err: errors.New("boom")},
+		{TraceID: 67, RootID: 67, GoroutineID: 2, Name: "main.other", Start: start, Duration: time.Millisecond},
+	}
+}
+
+func attr(span *tracepb.Span, key string) interface{} {
+	for _, kv := range span.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		if s, ok := kv.Value.Value.(*commonpb.AnyValue_StringValue); ok {
+			return s.StringValue
+		}
+		return kv.Value.GetIntValue()
+	}
+	return nil
+}
+
+func assertCallTree(t *testing.T, spans []*tracepb.Span) {
+	require.Len(t, spans, 3)
+	byName := make(map[string]*tracepb.Span)
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	root, child, other := byName["main.root"], byName["main.child"], byName["main.other"]
+	require.NotNil(t, root)
+	require.NotNil(t, child)
+	require.NotNil(t, other)
+
+	assert.Equal(t, root.SpanId, child.ParentSpanId)
+	assert.Equal(t, root.TraceId, child.TraceId)
+	assert.NotEqual(t, root.TraceId, other.TraceId)
+	assert.Empty(t, root.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, root.GetStatus().GetCode())
+	assert.Equal(t, "42", attr(child, "functrace.param.0"))
+	assert.Equal(t, int64(1), attr(child, "functrace.depth"))
+	assert.Equal(t, uint64(time.Millisecond), child.EndTimeUnixNano-child.StartTimeUnixNano)
+}
+
+func TestExporter_HTTPRetryAndBatch(t *testing.T) {
+	collector := &stubCollector{failFirst: 1}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	exp, err := New(testConfig(srv.URL, ProtocolHTTP))
+	require.NoError(t, err)
+	for _, rec := range callTree() {
+		exp.OnCall(rec)
+	}
+	require.NoError(t, exp.Close())
+
+	requests, spans := collector.snapshot()
+	assertCallTree(t, spans)
+	// 2 + 1 两批，第一批首次失败后重试
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, ExportStats{Exported: 3}, exp.Stats())
+}
+
+func TestExporter_GRPC(t *testing.T) {
+	collector := &stubCollector{failFirst: 1}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, collector)
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	exp, err := New(testConfig(lis.Addr().String(), ProtocolGRPC))
+	require.NoError(t, err)
+	defer exp.Close()
+	for _, rec := range callTree() {
+		exp.OnCall(rec)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, exp.Flush(ctx))
+
+	_, spans := collector.snapshot()
+	assertCallTree(t, spans)
+}
+
+func TestExporter_GiveUpOnPermanentError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	exp, err := New(testConfig(srv.URL, ProtocolHTTP))
+	require.NoError(t, err)
+	exp.OnCall(callTree()[0])
+	assert.Error(t, exp.Flush(context.Background()))
+	require.NoError(t, exp.Close())
+	assert.Equal(t, uint64(1), exp.Stats().Failed)
+}
+
+func TestExportRepository(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "trace.db"), logrus.New())
+	require.NoError(t, err)
+	defer db.Close()
+
+	start := time.Now().Format(trace.TimeFormat)
+	_, err = db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(2, 77, start, 0, "main.other").WithCreator(10, "main.root", 66))
+	require.NoError(t, err)
+	traces := db.GetTraceRepository()
+	for _, td := range []*model.TraceData{
+		model.NewTraceData(66, "main.root", 1, 0, 0, 0, start, "0.00"),
+		model.NewTraceData(67, "main.other", 2, 0, 0, 0, start, "0.00"),
+		model.NewTraceData(130, "main.child", 1, 1, 1, 66, start, "0.00"),
+	} {
+		_, err := traces.SaveTrace(td)
+		require.NoError(t, err)
+		require.NoError(t, traces.UpdateTraceTimeCost(td.ID, time.Millisecond.String()))
+	}
+	_, err = db.GetParamRepository().SaveParam(&model.ParamStoreData{ID: 1, TraceID: 130, Position: 0, Data: []byte("42")})
+	require.NoError(t, err)
+
+	collector := &stubCollector{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+	exp, err := New(testConfig(srv.URL, ProtocolHTTP))
+	require.NoError(t, err)
+	defer exp.Close()
+
+	n, err := ExportRepository(context.Background(), db, exp)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	_, spans := collector.snapshot()
+	require.Len(t, spans, 3)
+	var root, child, other *tracepb.Span
+	for _, s := range spans {
+		switch s.Name {
+		case "main.root":
+			root = s
+		case "main.child":
+			child = s
+		case "main.other":
+			other = s
+		}
+	}
+	assert.Equal(t, root.SpanId, child.ParentSpanId)
+	assert.Equal(t, root.TraceId, child.TraceId)
+	assert.Equal(t, "42", attr(child, "functrace.param.0"))
+	assert.Equal(t, "main.root", attr(other, "functrace.spawn.creator"))
+	assert.Equal(t, int64(77), attr(other, "functrace.goroutine.origin_gid"))
+}
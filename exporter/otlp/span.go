@@ -0,0 +1,136 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/trace"
+)
+
+// span 属性名
+const (
+	attrFunction     = "code.function"
+	attrTraceID      = "functrace.trace_id"
+	attrParentID     = "functrace.parent_id"
+	attrGoroutineID  = "functrace.goroutine.id"
+	attrOriginGID    = "functrace.goroutine.origin_gid"
+	attrDepth        = "functrace.depth"
+	attrSelfNanos    = "functrace.self_ns"
+	attrParamsCount  = "functrace.params_count"
+	attrFinished     = "functrace.finished"
+	attrSpawnParent  = "functrace.spawn.parent_trace_id"
+	attrSpawnCreator = "functrace.spawn.creator"
+	attrParamPrefix  = "functrace.param."
+)
+
+// traceID 由运行ID与根调用ID组成，同一调用树的 span 共享 TraceId
+func (e *Exporter) traceID(rootID int64) []byte {
+	id := make([]byte, 16)
+	copy(id, e.runID[:])
+	binary.BigEndian.PutUint64(id[8:], uint64(rootID))
+	return id
+}
+
+// spanID functrace 跟踪ID即 SpanId
+func spanID(id int64) []byte {
+	if id == 0 {
+		return nil
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+// newSpan 创建 span 的公共部分
+func (e *Exporter) newSpan(id, parentID, rootID int64, gid uint64, name string, depth int, start time.Time, cost time.Duration) *tracepb.Span {
+	return &tracepb.Span{
+		TraceId:           e.traceID(rootID),
+		SpanId:            spanID(id),
+		ParentSpanId:      spanID(parentID),
+		Name:              name,
+		Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: uint64(start.UnixNano()),
+		EndTimeUnixNano:   uint64(start.Add(cost).UnixNano()),
+		Attributes: []*commonpb.KeyValue{
+			stringAttr(attrFunction, name),
+			intAttr(attrTraceID, id),
+			intAttr(attrParentID, parentID),
+			intAttr(attrGoroutineID, int64(gid)),
+			intAttr(attrDepth, int64(depth)),
+		},
+	}
+}
+
+// spanFromCall 将实时调用记录转换为 span
+func (e *Exporter) spanFromCall(rec trace.CallRecord) *tracepb.Span {
+	span := e.newSpan(rec.TraceID, rec.ParentID, rec.RootID, rec.GoroutineID, rec.Name, rec.Depth, rec.Start, rec.Duration)
+	span.Attributes = append(span.Attributes,
+		intAttr(attrOriginGID, int64(rec.OriginGID)),
+		intAttr(attrSelfNanos, int64(rec.Self)),
+		intAttr(attrParamsCount, int64(rec.ParamsCount)),
+	)
+	if rec.Err != nil {
+		span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: rec.Err.Error()}
+	}
+	return span
+}
+
+// appendParams 将原始参数格式化为 span 属性，在后台发送协程上执行以免阻塞被跟踪的调用
+func (e *Exporter) appendParams(span *tracepb.Span, params []interface{}) {
+	for i, p := range params {
+		span.Attributes = append(span.Attributes, stringAttr(attrParamPrefix+strconv.Itoa(i), e.truncate(fmt.Sprintf("%+v", p))))
+	}
+}
+
+// spanFromTrace 将已持久化的跟踪数据转换为 span
+func (e *Exporter) spanFromTrace(td *model.TraceData, rootID int64, start time.Time, cost time.Duration, g *model.GoroutineTrace, params []model.ParamStoreData) *tracepb.Span {
+	span := e.newSpan(td.ID, td.ParentId, rootID, td.GID, td.Name, td.Indent, start, cost)
+	span.Attributes = append(span.Attributes,
+		intAttr(attrParamsCount, int64(td.ParamsCount)),
+		boolAttr(attrFinished, td.IsFinished == 1),
+	)
+	if g != nil {
+		span.Attributes = append(span.Attributes, intAttr(attrOriginGID, int64(g.OriginGID)))
+		if td.ParentId == 0 && g.CreatorFunc != "" {
+			span.Attributes = append(span.Attributes,
+				stringAttr(attrSpawnCreator, g.CreatorFunc),
+				intAttr(attrSpawnParent, g.ParentTraceID),
+			)
+		}
+	}
+	for _, p := range params {
+		key := attrParamPrefix + strconv.Itoa(p.Position)
+		span.Attributes = append(span.Attributes, stringAttr(key, e.truncate(trace.DecodeParamData(p.Data))))
+		if p.BaseID != 0 {
+			// 接收者以 JSON merge patch 形式存储，记录其基准参数
+			span.Attributes = append(span.Attributes, intAttr(key+".base_id", p.BaseID))
+		}
+	}
+	return span
+}
+
+// truncate 按配置截断参数文本
+func (e *Exporter) truncate(s string) string {
+	if e.cfg.MaxParamLength < 0 || len(s) <= e.cfg.MaxParamLength {
+		return s
+	}
+	return s[:e.cfg.MaxParamLength] + "..."
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
+
+func boolAttr(key string, value bool) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: value}}}
+}
@@ -0,0 +1,281 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/toheart/functrace/trace"
+)
+
+// ContentType Prometheus 文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 确保 Collector 可作为实时调用观察者与 HTTP 处理器
+var _ trace.CallObserver = (*Collector)(nil)
+var _ http.Handler = (*Collector)(nil)
+
+// series 单个 function 标签组合的计数，全部为原子操作
+type series struct {
+	labels  string // 已渲染的标签，如 function="main.run",package="main"
+	errors  atomic.Uint64
+	sumNs   atomic.Int64
+	buckets []atomic.Uint64 // 非累计计数，最后一个为 +Inf
+}
+
+// Collector 由调用完成事件驱动的指标收集器
+// 热路径只读取 sync.Map 并做原子累加；仅在首次遇到函数时加锁创建序列
+type Collector struct {
+	cfg      Config
+	boundsNs []int64
+
+	series sync.Map // 函数全名 -> *series
+	mu     sync.Mutex
+	count  int // 已创建的函数序列数，不含 OtherFunction
+	other  *series
+}
+
+// New 创建指标收集器
+func New(cfg Config) *Collector {
+	cfg = cfg.withDefaults()
+	c := &Collector{cfg: cfg, boundsNs: make([]int64, len(cfg.Buckets))}
+	for i, b := range cfg.Buckets {
+		c.boundsNs[i] = int64(b * float64(time.Second))
+	}
+	c.other = c.newSeries(OtherFunction, trace.FuncInfo{})
+	return c
+}
+
+// OnCall 实现 trace.CallObserver
+func (c *Collector) OnCall(rec trace.CallRecord) {
+	s := c.get(rec.Name)
+	d := int64(rec.Duration)
+	i := sort.Search(len(c.boundsNs), func(i int) bool { return c.boundsNs[i] >= d })
+	s.buckets[i].Add(1)
+	s.sumNs.Add(d)
+	if rec.Err != nil {
+		s.errors.Add(1)
+	}
+}
+
+// get 获取函数的序列，超过基数上限时返回 OtherFunction 序列
+func (c *Collector) get(name string) *series {
+	if v, ok := c.series.Load(name); ok {
+		return v.(*series)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.series.Load(name); ok {
+		return v.(*series)
+	}
+	if c.cfg.MaxFunctions > 0 && c.count >= c.cfg.MaxFunctions {
+		return c.other
+	}
+	s := c.newSeries(name, trace.ParseFuncName(name))
+	c.series.Store(name, s)
+	c.count++
+	return s
+}
+
+// newSeries 创建序列并渲染标签
+func (c *Collector) newSeries(name string, info trace.FuncInfo) *series {
+	labels := []string{label("function", name)}
+	if c.cfg.PackageLabel {
+		labels = append(labels, label("package", info.Package))
+	}
+	if c.cfg.ReceiverLabel {
+		receiver := info.StructName
+		if receiver != "" && info.Type == trace.MethodTypePointer {
+			receiver = "*" + receiver
+		}
+		labels = append(labels, label("receiver", receiver))
+	}
+	return &series{labels: strings.Join(labels, ","), buckets: make([]atomic.Uint64, len(c.boundsNs)+1)}
+}
+
+// ServeHTTP 以 Prometheus 文本格式输出当前指标
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if _, err := c.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteTo 将当前指标以 Prometheus 文本格式写入 w
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	c.writeCalls(cw)
+	c.writeRuntime(cw)
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// snapshot 返回按标签排序的全部序列，OtherFunction 有数据时排在最后
+func (c *Collector) snapshot() []*series {
+	var list []*series
+	c.series.Range(func(_, v interface{}) bool {
+		list = append(list, v.(*series))
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].labels < list[j].labels })
+	var total uint64
+	for i := range c.other.buckets {
+		total += c.other.buckets[i].Load()
+	}
+	if total > 0 {
+		list = append(list, c.other)
+	}
+	return list
+}
+
+// writeCalls 输出调用次数、错误次数与耗时直方图
+func (c *Collector) writeCalls(w *countingWriter) {
+	list := c.snapshot()
+	type snap struct {
+		buckets []uint64 // 累计计数
+		sum     float64
+		errors  uint64
+	}
+	snaps := make([]snap, len(list))
+	for i, s := range list {
+		cum := make([]uint64, len(s.buckets))
+		var acc uint64
+		for j := range s.buckets {
+			acc += s.buckets[j].Load()
+			cum[j] = acc
+		}
+		snaps[i] = snap{buckets: cum, sum: time.Duration(s.sumNs.Load()).Seconds(), errors: s.errors.Load()}
+	}
+
+	calls := c.cfg.Namespace + "_calls_total"
+	w.header(calls, "counter", "Number of returned traced calls.")
+	for i, s := range list {
+		w.printf("%s{%s} %d\n", calls, s.labels, snaps[i].buckets[len(snaps[i].buckets)-1])
+	}
+
+	errs := c.cfg.Namespace + "_call_errors_total"
+	w.header(errs, "counter", "Number of traced calls that returned a non-nil error.")
+	for i, s := range list {
+		w.printf("%s{%s} %d\n", errs, s.labels, snaps[i].errors)
+	}
+
+	hist := c.cfg.Namespace + "_call_duration_seconds"
+	w.header(hist, "histogram", "Wall time of traced calls including callees.")
+	for i, s := range list {
+		for j, b := range c.cfg.Buckets {
+			w.printf("%s_bucket{%s,le=\"%s\"} %d\n", hist, s.labels, formatFloat(b), snaps[i].buckets[j])
+		}
+		n := snaps[i].buckets[len(snaps[i].buckets)-1]
+		w.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", hist, s.labels, n)
+		w.printf("%s_sum{%s} %s\n", hist, s.labels, formatFloat(snaps[i].sum))
+		w.printf("%s_count{%s} %d\n", hist, s.labels, n)
+	}
+
+	c.mu.Lock()
+	count := c.count
+	c.mu.Unlock()
+	name := c.cfg.Namespace + "_metric_functions"
+	w.header(name, "gauge", "Distinct function label values; calls beyond the limit are counted as "+OtherFunction+".")
+	w.printf("%s %d\n", name, count)
+}
+
+// writeRuntime 输出 goroutine 数量与写入管道指标
+func (c *Collector) writeRuntime(w *countingWriter) {
+	name := c.cfg.Namespace + "_process_goroutines"
+	w.header(name, "gauge", "Goroutines in the process.")
+	w.printf("%s %d\n", name, runtime.NumGoroutine())
+
+	src := c.cfg.Runtime
+	if src == nil {
+		if inst := trace.GetTraceInstance(); inst != nil {
+			src = inst
+		}
+	}
+	if src == nil {
+		return
+	}
+
+	name = c.cfg.Namespace + "_goroutines_active"
+	w.header(name, "gauge", "Traced goroutines that have not finished.")
+	w.printf("%s %d\n", name, len(src.GetGoroutineRunning()))
+
+	depths := src.QueueDepths()
+	name = c.cfg.Namespace + "_queue_depth"
+	w.header(name, "gauge", "Events waiting to be written, by queue.")
+	w.printf("%s{queue=\"op\"} %d\n", name, depths.OpChan)
+	w.printf("%s{queue=\"trace\"} %d\n", name, depths.Pipelines.Trace)
+	w.printf("%s{queue=\"param\"} %d\n", name, depths.Pipelines.Param)
+	w.printf("%s{queue=\"goroutine\"} %d\n", name, depths.Pipelines.Goroutine)
+
+	stats := src.PipelineStats()
+	pipelines := []struct {
+		name     string
+		counters trace.PipelineCounters
+	}{
+		{"trace", stats.Trace},
+		{"param", stats.Param},
+		{"goroutine", stats.Goroutine},
+	}
+	name = c.cfg.Namespace + "_pipeline_overflow_total"
+	w.header(name, "counter", "Events written synchronously because the pipeline queue was full.")
+	for _, p := range pipelines {
+		w.printf("%s{pipeline=\"%s\"} %d\n", name, p.name, p.counters.Overflow)
+	}
+	name = c.cfg.Namespace + "_pipeline_failed_total"
+	w.header(name, "counter", "Failed repository writes, including retries.")
+	for _, p := range pipelines {
+		w.printf("%s{pipeline=\"%s\"} %d\n", name, p.name, p.counters.Failed)
+	}
+	name = c.cfg.Namespace + "_pipeline_spilled_total"
+	w.header(name, "counter", "Events written to the local spill file after retries were exhausted.")
+	for _, p := range pipelines {
+		w.printf("%s{pipeline=\"%s\"} %d\n", name, p.name, p.counters.Spilled)
+	}
+	name = c.cfg.Namespace + "_pipeline_dropped_total"
+	w.header(name, "counter", "Events lost because neither the repository nor the spill file accepted them.")
+	for _, p := range pipelines {
+		w.printf("%s{pipeline=\"%s\"} %d\n", name, p.name, p.counters.Dropped)
+	}
+}
+
+// countingWriter 记录写入字节数与第一个错误
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) header(name, kind, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// label 渲染一个标签，按文本格式转义值
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatFloat 以最短形式输出浮点数
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
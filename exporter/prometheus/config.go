@@ -0,0 +1,86 @@
+// Package prometheus 将被跟踪函数的调用次数、错误次数与耗时分布以 Prometheus 文本格式暴露，
+// 并附带 goroutine 数量与写入管道的积压、降级、丢弃指标
+package prometheus
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/toheart/functrace/trace"
+)
+
+// 环境变量
+const (
+	EnvMaxFunctions = "FUNCTRACE_PROM_MAX_FUNCTIONS" // function 标签的最大取值数
+	EnvLabels       = "FUNCTRACE_PROM_LABELS"        // 附加标签，逗号分隔：package、receiver
+	EnvBuckets      = "FUNCTRACE_PROM_BUCKETS"       // 耗时直方图的桶上界（秒），逗号分隔
+)
+
+// 默认值
+const (
+	DefaultNamespace    = "functrace"
+	DefaultMaxFunctions = 500
+
+	// OtherFunction 超过 MaxFunctions 后新出现的函数统一计入该标签值
+	OtherFunction = "__other__"
+)
+
+// DefaultBuckets 默认耗时桶上界（秒），覆盖微秒级到十秒级调用
+var DefaultBuckets = []float64{0.000001, 0.00001, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// RuntimeSource 运行时指标来源，*trace.TraceInstance 实现了该接口
+type RuntimeSource interface {
+	QueueDepths() trace.QueueDepths
+	PipelineStats() trace.PipelineStats
+	GetGoroutineRunning() map[uint64]*trace.GoroutineInfo
+}
+
+// Config 指标收集器配置
+type Config struct {
+	Namespace     string        // 指标名前缀，默认 functrace
+	Buckets       []float64     // 耗时直方图的桶上界（秒）
+	MaxFunctions  int           // function 标签的最大取值数，超出后计入 OtherFunction，负数表示不限制
+	PackageLabel  bool          // 附加 package 标签
+	ReceiverLabel bool          // 附加 receiver 标签（方法的接收者类型）
+	Runtime       RuntimeSource // 运行时指标来源，默认为当前跟踪实例
+}
+
+// ConfigFromEnv 读取 FUNCTRACE_PROM_* 环境变量构造配置
+func ConfigFromEnv() Config {
+	var cfg Config
+	if v, err := strconv.Atoi(os.Getenv(EnvMaxFunctions)); err == nil {
+		cfg.MaxFunctions = v
+	}
+	for _, label := range strings.Split(os.Getenv(EnvLabels), ",") {
+		switch strings.TrimSpace(label) {
+		case "package":
+			cfg.PackageLabel = true
+		case "receiver":
+			cfg.ReceiverLabel = true
+		}
+	}
+	for _, b := range strings.Split(os.Getenv(EnvBuckets), ",") {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(b), 64); err == nil && v > 0 {
+			cfg.Buckets = append(cfg.Buckets, v)
+		}
+	}
+	return cfg
+}
+
+// withDefaults 为未设置的字段填充默认值
+func (c Config) withDefaults() Config {
+	if c.Namespace == "" {
+		c.Namespace = DefaultNamespace
+	}
+	if len(c.Buckets) == 0 {
+		c.Buckets = DefaultBuckets
+	}
+	c.Buckets = append([]float64(nil), c.Buckets...)
+	sort.Float64s(c.Buckets)
+	if c.MaxFunctions == 0 {
+		c.MaxFunctions = DefaultMaxFunctions
+	}
+	return c
+}
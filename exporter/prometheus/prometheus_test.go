@@ -0,0 +1,126 @@
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/trace"
+)
+
+type fakeRuntime struct{}
+
+func (fakeRuntime) QueueDepths() trace.QueueDepths {
+	return trace.QueueDepths{OpChan: 3, Pipelines: trace.PipelineDepths{Trace: 7}}
+}
+
+func (fakeRuntime) PipelineStats() trace.PipelineStats {
+	return trace.PipelineStats{Param: trace.PipelineCounters{Overflow: 4, Failed: 9, Spilled: 3, Dropped: 2}}
+}
+
+func (fakeRuntime) GetGoroutineRunning() map[uint64]*trace.GoroutineInfo {
+	return map[uint64]*trace.GoroutineInfo{1: {}, 2: {}}
+}
+
+func scrape(t *testing.T, c *Collector) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	return rec.Body.String()
+}
+
+func TestCallMetrics(t *testing.T) {
+	c := New(Config{Buckets: []float64{0.001, 0.01}, PackageLabel: true, ReceiverLabel: true, Runtime: fakeRuntime{}})
+	name := "github.com/acme/shop/order.(*Service).Create"
+	c.OnCall(trace.CallRecord{Name: name, Duration: 500 * time.Microsecond})
+	c.OnCall(trace.CallRecord{Name: name, Duration: 5 * time.Millisecond, Err: errors.New("boom")})
+	c.OnCall(trace.CallRecord{Name: name, Duration: time.Second})
+
+	out := scrape(t, c)
+	labels := `function="github.com/acme/shop/order.(*Service).Create",package="github.com/acme/shop/order",receiver="*Service"`
+	for _, line := range []string{
+		"# TYPE functrace_call_duration_seconds histogram",
+		`functrace_calls_total{` + labels + `} 3`,
+		`functrace_call_errors_total{` + labels + `} 1`,
+		`functrace_call_duration_seconds_bucket{` + labels + `,le="0.001"} 1`,
+		`functrace_call_duration_seconds_bucket{` + labels + `,le="0.01"} 2`,
+		`functrace_call_duration_seconds_bucket{` + labels + `,le="+Inf"} 3`,
+		`functrace_call_duration_seconds_sum{` + labels + `} 1.0055`,
+		`functrace_call_duration_seconds_count{` + labels + `} 3`,
+		`functrace_goroutines_active 2`,
+		`functrace_queue_depth{queue="op"} 3`,
+		`functrace_queue_depth{queue="trace"} 7`,
+		`functrace_pipeline_overflow_total{pipeline="param"} 4`,
+		`functrace_pipeline_failed_total{pipeline="param"} 9`,
+		`functrace_pipeline_spilled_total{pipeline="param"} 3`,
+		`functrace_pipeline_dropped_total{pipeline="param"} 2`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestCardinalityLimit(t *testing.T) {
+	c := New(Config{MaxFunctions: 2, Runtime: fakeRuntime{}})
+	for i := 0; i < 5; i++ {
+		c.OnCall(trace.CallRecord{Name: fmt.Sprintf("main.f%d", i), Duration: time.Millisecond})
+	}
+	c.OnCall(trace.CallRecord{Name: "main.f0", Duration: time.Millisecond})
+
+	out := scrape(t, c)
+	assert.Contains(t, out, `functrace_calls_total{function="main.f0"} 2`+"\n")
+	assert.Contains(t, out, `functrace_calls_total{function="main.f1"} 1`+"\n")
+	assert.Contains(t, out, `functrace_calls_total{function="`+OtherFunction+`"} 3`+"\n")
+	assert.NotContains(t, out, `function="main.f2"`)
+	assert.Contains(t, out, "functrace_metric_functions 2\n")
+}
+
+func TestLabelEscaping(t *testing.T) {
+	c := New(Config{Runtime: fakeRuntime{}})
+	c.OnCall(trace.CallRecord{Name: "main.\"odd\"\\name"})
+	assert.Contains(t, scrape(t, c), `functrace_calls_total{function="main.\"odd\"\\name"} 1`)
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvMaxFunctions, "10")
+	t.Setenv(EnvLabels, "receiver")
+	t.Setenv(EnvBuckets, "0.5, 0.1,bad")
+	cfg := ConfigFromEnv().withDefaults()
+	assert.Equal(t, 10, cfg.MaxFunctions)
+	assert.False(t, cfg.PackageLabel)
+	assert.True(t, cfg.ReceiverLabel)
+	assert.Equal(t, []float64{0.1, 0.5}, cfg.Buckets)
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	c := New(Config{MaxFunctions: 8, Runtime: fakeRuntime{}})
+	done := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		go func(g int) {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 1000; i++ {
+				c.OnCall(trace.CallRecord{Name: fmt.Sprintf("main.f%d", i%16), Duration: time.Duration(i)})
+			}
+		}(g)
+	}
+	for g := 0; g < 8; g++ {
+		<-done
+	}
+	out := scrape(t, c)
+	var total int
+	for _, line := range strings.Split(out, "\n") {
+		var n int
+		if strings.HasPrefix(line, "functrace_calls_total{") {
+			_, err := fmt.Sscanf(line[strings.LastIndexByte(line, ' ')+1:], "%d", &n)
+			require.NoError(t, err)
+			total += n
+		}
+	}
+	assert.Equal(t, 8000, total)
+	assert.Contains(t, out, "functrace_metric_functions 8\n")
+}
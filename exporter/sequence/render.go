@@ -0,0 +1,63 @@
+package sequence
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// labelReplacer 去掉会破坏两种语法的字符
+var labelReplacer = strings.NewReplacer("\n", " ", "\r", " ", ";", ",", "#", "", "\"", "'")
+
+// WriteMermaid 渲染为 Mermaid sequenceDiagram
+func (d *Diagram) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+	fmt.Fprintf(&b, "    title %s\n", labelReplacer.Replace(d.Title))
+	for i, p := range d.Participants {
+		kind := "participant"
+		if i == 0 {
+			kind = "actor"
+		}
+		fmt.Fprintf(&b, "    %s P%d as %s\n", kind, i, labelReplacer.Replace(p))
+	}
+	for _, m := range d.Messages {
+		arrow := "->>"
+		switch m.Kind {
+		case MessageReturn:
+			arrow = "-->>"
+		case MessageAsync:
+			arrow = "-)"
+		}
+		fmt.Fprintf(&b, "    P%d%sP%d: %s\n", m.From, arrow, m.To, labelReplacer.Replace(m.Label))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePlantUML 渲染为 PlantUML 时序图
+func (d *Diagram) WritePlantUML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+	fmt.Fprintf(&b, "title %s\n", labelReplacer.Replace(d.Title))
+	for i, p := range d.Participants {
+		kind := "participant"
+		if i == 0 {
+			kind = "actor"
+		}
+		fmt.Fprintf(&b, "%s \"%s\" as P%d\n", kind, labelReplacer.Replace(p), i)
+	}
+	for _, m := range d.Messages {
+		arrow := "->"
+		switch m.Kind {
+		case MessageReturn:
+			arrow = "-->"
+		case MessageAsync:
+			arrow = "->>"
+		}
+		fmt.Fprintf(&b, "P%d %s P%d : %s\n", m.From, arrow, m.To, labelReplacer.Replace(m.Label))
+	}
+	b.WriteString("@enduml\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,285 @@
+// Package sequence 将一次根调用渲染为 Mermaid 或 PlantUML 时序图
+// 参与者为接收者类型或包，消息为方法调用，goroutine 的创建为异步箭头
+package sequence
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter"
+	"github.com/toheart/functrace/trace"
+)
+
+// Grouping 参与者的划分方式
+type Grouping string
+
+const (
+	// GroupByType 方法按接收者类型（包名.类型），普通函数按包
+	GroupByType Grouping = "type"
+	// GroupByPackage 全部按包
+	GroupByPackage Grouping = "package"
+)
+
+// DefaultMaxParamLength 缩略参数的默认长度
+const DefaultMaxParamLength = 24
+
+// callerName 根调用的发起方
+const callerName = "caller"
+
+// Options 生成选项
+type Options struct {
+	MaxDepth       int      // 相对根调用的最大深度，0 表示不限制
+	Grouping       Grouping // 参与者划分方式，默认 GroupByType
+	Include        []string // 只保留名称匹配的参与者（path.Match 通配），为空表示全部
+	Exclude        []string // 隐藏名称匹配的参与者；隐藏参与者内部的调用视为由其调用方发出
+	Params         bool     // 在消息中附带缩略参数
+	MaxParamLength int      // 单个参数的缩略长度，0 使用默认值
+	NoAsync        bool     // 不展示调用期间创建的 goroutine
+}
+
+// MessageKind 消息类型
+type MessageKind int
+
+const (
+	MessageCall   MessageKind = iota // 同步调用
+	MessageReturn                    // 返回
+	MessageAsync                     // 创建 goroutine
+)
+
+// Message 时序图中的一条消息
+type Message struct {
+	From  int // 发送方参与者下标
+	To    int // 接收方参与者下标
+	Kind  MessageKind
+	Label string
+}
+
+// Diagram 与渲染格式无关的时序图
+type Diagram struct {
+	Title        string
+	Participants []string // 下标0为调用方
+	Messages     []Message
+}
+
+// node 调用树节点
+type node struct {
+	td       model.TraceData
+	start    time.Time
+	depth    int
+	async    bool // 由 goroutine 创建产生的根调用
+	children []*node
+}
+
+// builder 时序图构造器
+type builder struct {
+	reader  domain.TraceTreeReader
+	params  domain.ParamRepository
+	opts    Options
+	diagram *Diagram
+	index   map[string]int
+}
+
+// Build 读取以 rootID 为根的调用树并生成时序图
+// 仓储需实现 domain.TraceTreeReader
+func Build(factory domain.RepositoryFactory, rootID int64, opts Options) (*Diagram, error) {
+	reader, ok := factory.(domain.TraceTreeReader)
+	if !ok {
+		return nil, fmt.Errorf("repository %T does not support trace tree queries", factory)
+	}
+	if opts.Grouping == "" {
+		opts.Grouping = GroupByType
+	}
+	if opts.Grouping != GroupByType && opts.Grouping != GroupByPackage {
+		return nil, fmt.Errorf("unknown participant grouping: %s", opts.Grouping)
+	}
+	if opts.MaxParamLength <= 0 {
+		opts.MaxParamLength = DefaultMaxParamLength
+	}
+
+	td, err := reader.FindTraceByID(rootID)
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{
+		reader:  reader,
+		params:  factory.GetParamRepository(),
+		opts:    opts,
+		diagram: &Diagram{Title: fmt.Sprintf("%s (trace %d)", td.Name, td.ID), Participants: []string{callerName}},
+		index:   map[string]int{callerName: 0},
+	}
+	root, err := b.load(*td, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.emit(root, 0); err != nil {
+		return nil, err
+	}
+	return b.diagram, nil
+}
+
+// load 递归读取调用树，子调用与创建的 goroutine 按开始时间排序
+func (b *builder) load(td model.TraceData, depth int, async bool) (*node, error) {
+	start, err := exporter.StartTime(&td)
+	if err != nil {
+		return nil, err
+	}
+	n := &node{td: td, start: start, depth: depth, async: async}
+	if b.opts.MaxDepth > 0 && depth >= b.opts.MaxDepth {
+		return n, nil
+	}
+
+	children, err := b.reader.FindTraceChildren(td.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range children {
+		child, err := b.load(c, depth+1, false)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, child)
+	}
+
+	if !b.opts.NoAsync {
+		spawned, err := b.reader.FindGoroutinesSpawnedBy(td.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range spawned {
+			roots, err := b.reader.FindRootTracesByGID(uint64(g.ID))
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range roots {
+				child, err := b.load(r, depth+1, true)
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, child)
+			}
+		}
+	}
+
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return n.children[i].start.Before(n.children[j].start)
+	})
+	return n, nil
+}
+
+// emit 生成节点的消息；from 为最近的可见调用方
+func (b *builder) emit(n *node, from int) error {
+	name := b.participantName(n.td.Name)
+	if !b.visible(name) {
+		// 隐藏的参与者：其子调用直接由调用方发出
+		for _, c := range n.children {
+			if err := b.emit(c, from); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	to := b.participant(name)
+	label, err := b.label(n)
+	if err != nil {
+		return err
+	}
+	kind := MessageCall
+	if n.async {
+		kind = MessageAsync
+		label = "go " + label
+	}
+	b.diagram.Messages = append(b.diagram.Messages, Message{From: from, To: to, Kind: kind, Label: label})
+	for _, c := range n.children {
+		if err := b.emit(c, to); err != nil {
+			return err
+		}
+	}
+	if !n.async {
+		ret := "unfinished"
+		if n.td.IsFinished == 1 {
+			ret = exporter.Duration(&n.td).String()
+		}
+		b.diagram.Messages = append(b.diagram.Messages, Message{From: to, To: from, Kind: MessageReturn, Label: ret})
+	}
+	return nil
+}
+
+// participantName 按划分方式计算函数所属的参与者
+func (b *builder) participantName(fullName string) string {
+	info := trace.ParseFuncName(fullName)
+	if info.Type == trace.MethodTypeUnknown {
+		return fullName
+	}
+	pkg := path.Base(info.Package)
+	if b.opts.Grouping == GroupByType && info.StructName != "" {
+		return pkg + "." + info.StructName
+	}
+	return pkg
+}
+
+// visible 判断参与者是否通过包含/排除过滤
+func (b *builder) visible(name string) bool {
+	if matchAny(b.opts.Exclude, name) {
+		return false
+	}
+	return len(b.opts.Include) == 0 || matchAny(b.opts.Include, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok || p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// participant 返回参与者下标，首次出现时追加
+func (b *builder) participant(name string) int {
+	if i, ok := b.index[name]; ok {
+		return i
+	}
+	b.diagram.Participants = append(b.diagram.Participants, name)
+	b.index[name] = len(b.diagram.Participants) - 1
+	return b.index[name]
+}
+
+// label 消息文本：函数名与可选的缩略参数
+func (b *builder) label(n *node) (string, error) {
+	info := trace.ParseFuncName(n.td.Name)
+	fn := info.FuncName
+	if fn == "" {
+		fn = n.td.Name
+	}
+	if !b.opts.Params || n.td.ParamsCount == 0 {
+		return fn + "()", nil
+	}
+	params, err := b.params.FindParamsByTraceID(n.td.ID)
+	if err != nil {
+		return "", fmt.Errorf("find params of trace %d error: %w", n.td.ID, err)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Position < params[j].Position })
+	var args []string
+	for _, p := range params {
+		if p.IsReceiver {
+			continue
+		}
+		args = append(args, abbreviate(trace.DecodeParamData(p.Data), b.opts.MaxParamLength))
+	}
+	return fn + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// abbreviate 压缩空白并截断参数文本
+func abbreviate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package sequence
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/exporter/internal/exportertest"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+const (
+	fnHandle = "github.com/acme/app/server.(*Server).Handle"
+	fnLoad   = "github.com/acme/app/store.(*Store).Load"
+	fnDecode = "github.com/acme/app/store.decode"
+	fnLog    = "github.com/acme/app/log.Infof"
+	fnAudit  = "github.com/acme/app/server.(*Server).audit"
+)
+
+// newTestDB Handle -> Load -> decode，Handle -> log.Infof，Handle 期间创建 goroutine 执行 audit
+func newTestDB(t *testing.T) *sqlite.SQLiteDatabase {
+	db := exportertest.NewDB(t,
+		exportertest.Call{ID: 64, Name: fnHandle, GID: 1, Params: 1, Cost: 10 * time.Millisecond},
+		exportertest.Call{ID: 128, Name: fnLoad, GID: 1, Indent: 1, Params: 2, Parent: 64, Start: time.Millisecond, Cost: 2 * time.Millisecond},
+		exportertest.Call{ID: 192, Name: fnDecode, GID: 1, Indent: 2, Parent: 128, Start: 2 * time.Millisecond, Cost: time.Millisecond},
+		exportertest.Call{ID: 256, Name: fnLog, GID: 1, Indent: 1, Parent: 64, Start: 4 * time.Millisecond, Cost: time.Millisecond},
+		exportertest.Call{ID: 65, Name: fnAudit, GID: 2, Start: 3 * time.Millisecond, Cost: time.Millisecond},
+	)
+
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(2, 30, exportertest.At(3*time.Millisecond), 1, fnAudit).WithCreator(1, fnHandle, 64))
+	require.NoError(t, err)
+
+	params := db.GetParamRepository()
+	_, err = params.SaveParam(&model.ParamStoreData{ID: 1, TraceID: 128, Position: 0, Data: []byte(`{"cache":true}`), IsReceiver: true})
+	require.NoError(t, err)
+	_, err = params.SaveParam(&model.ParamStoreData{ID: 2, TraceID: 128, Position: 1, Data: []byte("\"user-0000000000000000042\"")})
+	require.NoError(t, err)
+	return db
+}
+
+func TestMermaid(t *testing.T) {
+	d, err := Build(newTestDB(t), 64, Options{Params: true})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, d.WriteMermaid(&buf))
+	assert.Equal(t, `sequenceDiagram
+    title github.com/acme/app/server.(*Server).Handle (trace 64)
+    actor P0 as caller
+    participant P1 as server.Server
+    participant P2 as store.Store
+    participant P3 as store
+    participant P4 as log
+    P0->>P1: Handle()
+    P1->>P2: Load('user-000000000000000004…)
+    P2->>P3: decode()
+    P3-->>P2: 1ms
+    P2-->>P1: 2ms
+    P1-)P1: go audit()
+    P1->>P4: Infof()
+    P4-->>P1: 1ms
+    P1-->>P0: 10ms
+`, buf.String())
+}
+
+func TestPlantUML_Filters(t *testing.T) {
+	d, err := Build(newTestDB(t), 64, Options{
+		Grouping: GroupByPackage,
+		Exclude:  []string{"lo*"},
+		MaxDepth: 1,
+		NoAsync:  true,
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, d.WritePlantUML(&buf))
+	assert.Equal(t, `@startuml
+title github.com/acme/app/server.(*Server).Handle (trace 64)
+actor "caller" as P0
+participant "server" as P1
+participant "store" as P2
+P0 -> P1 : Handle()
+P1 -> P2 : Load()
+P2 --> P1 : 2ms
+P1 --> P0 : 10ms
+@enduml
+`, buf.String())
+}
+
+func TestBuild_HiddenParticipantCollapses(t *testing.T) {
+	// 隐藏 store.Store 后，decode 视为由 server.Server 直接调用
+	d, err := Build(newTestDB(t), 64, Options{Exclude: []string{"store.Store"}, NoAsync: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"caller", "server.Server", "store", "log"}, d.Participants)
+	assert.Equal(t, Message{From: 1, To: 2, Kind: MessageCall, Label: "decode()"}, d.Messages[1])
+}
+
+func TestBuild_UnknownRoot(t *testing.T) {
+	_, err := Build(newTestDB(t), 999, Options{})
+	assert.Error(t, err)
+}
@@ -9,26 +9,52 @@ import (
 	"sync"
 
 	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain/model"
 	"github.com/toheart/functrace/trace"
 )
 
 // Trace 是一个装饰器，用于跟踪函数的进入和退出
 func Trace(params []interface{}) func() {
+	exit := enter(params)
+	return func() {
+		exit(nil)
+	}
+}
+
+// TraceWithError 与 Trace 相同，但在退出时读取 err 指向的返回错误，非空时计入函数的错误次数
+//
+//	func Do() (err error) {
+//		defer functrace.TraceWithError([]interface{}{})(&err)
+//		...
+//	}
+func TraceWithError(params []interface{}) func(err *error) {
+	exit := enter(params)
+	return func(err *error) {
+		if err != nil {
+			exit(*err)
+			return
+		}
+		exit(nil)
+	}
+}
+
+// enter 记录函数进入并返回记录退出的闭包
+func enter(params []interface{}) func(error) {
 	// 获取 TraceInstance 单例
 	instance := trace.NewTraceInstance()
 
 	// 获取调用者信息（PC）
-	pc, _, _, ok := runtime.Caller(1)
+	pc, _, _, ok := runtime.Caller(2)
 	if !ok {
 		instance.GetLogger().WithFields(nil).Error("can't get caller info")
-		return func() {}
+		return func(error) {}
 	}
 
 	// 基于 PC 的快速跳过判断
 	skip, name := trace.ShouldSkipPC(pc, instance.SkipFunction)
 	if skip {
 		instance.GetLogger().WithFields(logrus.Fields{"name": name}).Info("skip function")
-		return func() {}
+		return func(error) {}
 	}
 	if name == "" {
 		if fn := runtime.FuncForPC(pc); fn != nil {
@@ -47,8 +73,8 @@ func Trace(params []interface{}) func() {
 	traceData, startTime := instance.EnterTrace(info.ID, name, params)
 
 	// 返回用于记录函数退出的闭包
-	return func() {
-		instance.ExitTrace(info, traceData, startTime)
+	return func(err error) {
+		instance.ExitTraceWithError(info, traceData, startTime, err)
 	}
 }
 
@@ -57,6 +83,43 @@ func CloseTraceInstance() error {
 	return trace.GetTraceInstance().Close()
 }
 
+// Stats 返回进程内按函数聚合的实时统计（调用次数、错误次数、总耗时/自身耗时与分位数）
+func Stats() []*model.FuncStats {
+	instance := trace.GetTraceInstance()
+	if instance == nil {
+		return nil
+	}
+	return instance.Stats()
+}
+
+// InFlight 返回每个被跟踪 goroutine 当前尚未退出的调用栈，按运行时长降序
+func InFlight() []trace.SessionStack {
+	instance := trace.GetTraceInstance()
+	if instance == nil {
+		return nil
+	}
+	return instance.InFlight()
+}
+
+// Run 返回本次运行的元数据（运行ID、构建信息、主机与生效配置），仓储不支持运行记录时返回 nil
+func Run() *model.Run {
+	instance := trace.GetTraceInstance()
+	if instance == nil {
+		return nil
+	}
+	return instance.Run()
+}
+
+// OnSlowCall 注册慢调用回调：调用运行超过配置的截止时间且尚未返回时触发，每次调用至多一次
+func OnSlowCall(fn trace.SlowCallHandler) {
+	trace.NewTraceInstance().OnSlowCall(fn)
+}
+
+// AddCallObserver 注册调用完成观察者，每个被跟踪调用返回时同步通知（如 OTLP 导出器）
+func AddCallObserver(obs trace.CallObserver) {
+	trace.NewTraceInstance().AddCallObserver(obs)
+}
+
 // GetLogger 获取日志实例
 func GetLogger() *logrus.Logger {
 	return trace.GetTraceInstance().GetLogger()
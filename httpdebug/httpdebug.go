@@ -0,0 +1,175 @@
+// Package httpdebug 提供 functrace 的在线调试 HTTP 接口
+//
+// 与 net/http/pprof 类似，可挂载到任意 http.ServeMux：
+//
+//	mux := http.NewServeMux()
+//	httpdebug.Register(mux)
+//
+// 挂载后 /debug/functrace/ 提供 HTML 概览，以下路径返回 JSON：
+// stats（函数统计）、goroutines（GoroutineRunning）、stacks（各会话打开的调用栈）、
+// queues（写入队列积压）、config（当前配置）。
+package httpdebug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/trace"
+)
+
+// Prefix 默认挂载路径
+const Prefix = "/debug/functrace/"
+
+// Source 调试数据来源，*trace.TraceInstance 实现了该接口
+type Source interface {
+	Stats() []*model.FuncStats
+	GetGoroutineRunning() map[uint64]*trace.GoroutineInfo
+	OpenStacks() []trace.SessionStack
+	QueueDepths() trace.QueueDepths
+	GetConfig() trace.Config
+}
+
+// Register 将调试处理器挂载到 mux 的 Prefix 路径下
+func Register(mux *http.ServeMux) {
+	mux.Handle(Prefix, http.StripPrefix(strings.TrimSuffix(Prefix, "/"), Handler()))
+}
+
+// Handler 返回基于全局 TraceInstance 的调试处理器
+func Handler() http.Handler {
+	return NewHandler(nil)
+}
+
+// NewHandler 基于指定数据来源创建调试处理器，src 为 nil 时在每次请求时读取全局 TraceInstance
+func NewHandler(src Source) http.Handler {
+	h := &handler{src: src}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.index)
+	mux.HandleFunc("/stats", h.serveJSON(func(s Source) interface{} { return s.Stats() }))
+	mux.HandleFunc("/goroutines", h.serveJSON(func(s Source) interface{} { return s.GetGoroutineRunning() }))
+	mux.HandleFunc("/stacks", h.serveJSON(func(s Source) interface{} { return s.OpenStacks() }))
+	mux.HandleFunc("/queues", h.serveJSON(func(s Source) interface{} { return s.QueueDepths() }))
+	mux.HandleFunc("/config", h.serveJSON(func(s Source) interface{} { return s.GetConfig() }))
+	return mux
+}
+
+type handler struct {
+	src Source
+}
+
+// source 返回当前可用的数据来源，未初始化时返回 nil
+func (h *handler) source() Source {
+	if h.src != nil {
+		return h.src
+	}
+	if inst := trace.GetTraceInstance(); inst != nil {
+		return inst
+	}
+	return nil
+}
+
+func (h *handler) serveJSON(fn func(Source) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		src := h.source()
+		if src == nil {
+			http.Error(w, "functrace is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(fn(src)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// indexData HTML 概览页的数据
+type indexData struct {
+	Now        time.Time
+	Stats      []*model.FuncStats
+	Goroutines map[uint64]*trace.GoroutineInfo
+	Stacks     []trace.SessionStack
+	Queues     trace.QueueDepths
+	Config     trace.Config
+}
+
+func (h *handler) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "" {
+		http.NotFound(w, r)
+		return
+	}
+	src := h.source()
+	if src == nil {
+		http.Error(w, "functrace is not initialized", http.StatusServiceUnavailable)
+		return
+	}
+	data := indexData{
+		Now:        time.Now(),
+		Stats:      src.Stats(),
+		Goroutines: src.GetGoroutineRunning(),
+		Stacks:     src.OpenStacks(),
+		Queues:     src.QueueDepths(),
+		Config:     src.GetConfig(),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var indexTmpl = template.Must(template.New("index").Funcs(template.FuncMap{
+	"ns": func(v int64) string { return time.Duration(v).String() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>functrace</title>
+<style>
+body { font-family: monospace; margin: 1em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+th { background: #eee; }
+</style>
+</head>
+<body>
+<h1>functrace</h1>
+<p>{{.Now.Format "2006-01-02 15:04:05"}} &middot;
+<a href="stats">stats</a> &middot; <a href="goroutines">goroutines</a> &middot;
+<a href="stacks">stacks</a> &middot; <a href="queues">queues</a> &middot; <a href="config">config</a></p>
+
+<h2>Function stats</h2>
+<table>
+<tr><th>function</th><th>count</th><th>errors</th><th>total</th><th>self</th><th>p50</th><th>p95</th><th>p99</th><th>max</th></tr>
+{{range .Stats}}<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{.ErrorCount}}</td><td>{{ns .TotalTime}}</td><td>{{ns .SelfTime}}</td><td>{{ns .P50}}</td><td>{{ns .P95}}</td><td>{{ns .P99}}</td><td>{{ns .MaxTime}}</td></tr>
+{{end}}</table>
+
+<h2>Open call stacks</h2>
+{{range .Stacks}}<h3>goroutine {{.GoroutineID}} (gid {{.OriginGID}})</h3>
+<table>
+<tr><th>depth</th><th>trace id</th><th>function</th><th>elapsed</th></tr>
+{{range .Frames}}<tr><td>{{.Depth}}</td><td>{{.TraceID}}</td><td>{{.Name}}</td><td>{{.Elapsed}}</td></tr>
+{{end}}</table>
+{{else}}<p>none</p>
+{{end}}
+
+<h2>Running goroutines</h2>
+<table>
+<tr><th>gid</th><th>id</th><th>last update</th></tr>
+{{range $gid, $info := .Goroutines}}<tr><td>{{$gid}}</td><td>{{$info.ID}}</td><td>{{$info.LastUpdateTime}}</td></tr>
+{{end}}</table>
+
+<h2>Queues</h2>
+<table>
+<tr><th>op chan</th><th>trace</th><th>param</th><th>goroutine</th></tr>
+<tr><td>{{.Queues.OpChan}}</td><td>{{.Queues.Pipelines.Trace}}</td><td>{{.Queues.Pipelines.Param}}</td><td>{{.Queues.Pipelines.Goroutine}}</td></tr>
+</table>
+
+<h2>Config</h2>
+<pre>{{printf "%+v" .Config}}</pre>
+</body>
+</html>
+`))
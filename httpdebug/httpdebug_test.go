@@ -0,0 +1,78 @@
+package httpdebug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/trace"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Stats() []*model.FuncStats {
+	return []*model.FuncStats{{Name: "main.work", Count: 3, TotalTime: int64(3 * time.Millisecond)}}
+}
+
+func (fakeSource) GetGoroutineRunning() map[uint64]*trace.GoroutineInfo {
+	return map[uint64]*trace.GoroutineInfo{7: {ID: 1, OriginGID: 7}}
+}
+
+func (fakeSource) OpenStacks() []trace.SessionStack {
+	return []trace.SessionStack{{GoroutineID: 1, OriginGID: 7, Frames: []trace.FrameInfo{{TraceID: 64, Name: "main.work"}}}}
+}
+
+func (fakeSource) QueueDepths() trace.QueueDepths {
+	return trace.QueueDepths{OpChan: 2}
+}
+
+func (fakeSource) GetConfig() trace.Config {
+	return trace.Config{DBType: "sqlite"}
+}
+
+func TestHandler_JSON(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(Prefix, http.StripPrefix("/debug/functrace", NewHandler(fakeSource{})))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		decoded interface{}
+	}{
+		{name: "stats", path: "stats", decoded: &[]model.FuncStats{}},
+		{name: "goroutines", path: "goroutines", decoded: &map[string]trace.GoroutineInfo{}},
+		{name: "stacks", path: "stacks", decoded: &[]trace.SessionStack{}},
+		{name: "queues", path: "queues", decoded: &trace.QueueDepths{}},
+		{name: "config", path: "config", decoded: &trace.Config{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + Prefix + tt.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(tt.decoded))
+		})
+	}
+}
+
+func TestHandler_Index(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(fakeSource{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "main.work")
+	assert.Contains(t, rec.Body.String(), "goroutine 1 (gid 7)")
+
+	rec = httptest.NewRecorder()
+	NewHandler(fakeSource{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,240 @@
+package binlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func openTestDB(t *testing.T, cfg Config) *BinlogDatabase {
+	t.Helper()
+	cfg.Prefix = "test"
+	db := NewBinlogDatabase(cfg, testLogger()).(*BinlogDatabase)
+	require.NoError(t, db.Initialize())
+	return db
+}
+
+// writeCalls 写入 n 个根调用，每个根调用带一个子调用与一个参数
+func writeCalls(t *testing.T, db *BinlogDatabase, n int) []*model.TraceData {
+	t.Helper()
+	start := time.Now()
+	g := model.NewGoroutineTrace(1, 7, start.Format(time.RFC3339Nano), 0, "main.main").WithCreator(1, "runtime.main", 0)
+	_, err := db.GetGoroutineRepository().SaveGoroutine(g)
+	require.NoError(t, err)
+
+	var want []*model.TraceData
+	traces := db.GetTraceRepository()
+	for i := 0; i < n; i++ {
+		root := model.NewTraceData(int64(2*i+1), "main.handle", 1, 0, 1, 0, start.Add(time.Duration(i)*time.Millisecond).Format(time.RFC3339Nano), "0.01")
+		child := model.NewTraceData(int64(2*i+2), fmt.Sprintf("main.step%d", i%3), 1, 1, 0, root.ID, start.Add(time.Duration(i)*time.Millisecond+time.Microsecond).Format(time.RFC3339Nano), "")
+		_, err := traces.SaveTrace(root)
+		require.NoError(t, err)
+		_, err = db.GetParamRepository().SaveParam(model.NewParamStoreData(root.ID, 0, []byte(fmt.Sprintf(`{"i":%d}`, i)), false, 0).WithID(int64(i + 1)))
+		require.NoError(t, err)
+		_, err = traces.SaveTrace(child)
+		require.NoError(t, err)
+		require.NoError(t, traces.UpdateTraceTimeCost(child.ID, "1.5µs"))
+		require.NoError(t, traces.UpdateTraceTimeCost(root.ID, "2.000123ms"))
+		child.TimeCost, child.IsFinished = "1.5µs", 1
+		root.TimeCost, root.IsFinished = "2.000123ms", 1
+		want = append(want, root, child)
+	}
+	require.NoError(t, db.GetGoroutineRepository().UpdateGoroutineTimeCost(1, "3s", 1))
+	return want
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, Config{Dir: dir})
+	want := writeCalls(t, db, 5)
+	require.NoError(t, db.Close())
+
+	files, err := ListSegments(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := ReadAll(files)
+	require.NoError(t, err)
+	require.Len(t, data.Traces, len(want))
+	for i, td := range data.Traces {
+		assert.Equal(t, *want[i], *td)
+	}
+	require.Len(t, data.Params, 5)
+	assert.Equal(t, `{"i":3}`, string(data.Params[3].Data))
+	assert.Equal(t, int64(7), data.Params[3].TraceID)
+	require.Len(t, data.Goroutines, 1)
+	assert.Equal(t, "3s", data.Goroutines[0].TimeCost)
+	assert.Equal(t, "runtime.main", data.Goroutines[0].CreatorFunc)
+
+	r, err := OpenSegment(files[0])
+	require.NoError(t, err)
+	defer r.Close()
+	footer := r.Footer()
+	require.NotNil(t, footer)
+	assert.Equal(t, uint64(10), footer.Enters)
+	assert.Equal(t, uint64(10), footer.Exits)
+	assert.Equal(t, uint64(5), footer.Params)
+	assert.Equal(t, int64(1), footer.MinTraceID)
+	assert.Equal(t, int64(10), footer.MaxTraceID)
+	assert.ElementsMatch(t, []string{"main.main", "runtime.main", "main.handle", "main.step0", "main.step1", "main.step2"}, footer.Names)
+}
+
+func TestRawValuesFallback(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, Config{Dir: dir})
+	other := time.Date(2025, 1, 1, 8, 0, 0, 0, time.FixedZone("X", 3*3600+1800)).Format(time.RFC3339Nano)
+	_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(1, "main.a", 1, 0, 0, 0, other, ""))
+	require.NoError(t, err)
+	_, err = db.GetTraceRepository().SaveTrace(model.NewTraceData(2, "main.b", 1, 0, 0, 0, "not a time", ""))
+	require.NoError(t, err)
+	require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(1, "1m0.5s"))
+	require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(2, "n/a"))
+	require.NoError(t, db.Close())
+
+	data, err := ReadAll(db.Files())
+	require.NoError(t, err)
+	require.Len(t, data.Traces, 2)
+	assert.Equal(t, other, data.Traces[0].CreatedAt)
+	assert.Equal(t, "1m0.5s", data.Traces[0].TimeCost)
+	assert.Equal(t, "not a time", data.Traces[1].CreatedAt)
+	assert.Equal(t, "n/a", data.Traces[1].TimeCost)
+}
+
+func TestRotationAndIndex(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, Config{Dir: dir, SegmentSize: 256, IndexInterval: 2})
+	want := writeCalls(t, db, 40)
+	require.NoError(t, db.Close())
+
+	files := db.Files()
+	require.Greater(t, len(files), 2)
+	data, err := ReadAll(files)
+	require.NoError(t, err)
+	require.Len(t, data.Traces, len(want))
+
+	// 每个段都有独立的字符串表与索引，可单独打开并按ID查找
+	found := 0
+	for _, path := range files {
+		r, err := OpenSegment(path)
+		require.NoError(t, err)
+		footer := r.Footer()
+		require.NotNil(t, footer)
+		assert.Greater(t, len(footer.Blocks), 0)
+		if footer.Enters > 0 {
+			td, err := r.FindTrace(footer.MaxTraceID)
+			require.NoError(t, err)
+			require.NotNil(t, td)
+			// 退出记录可能已写入下一个段
+			w := want[td.ID-1]
+			assert.Equal(t, w.Name, td.Name)
+			assert.Equal(t, w.ParentId, td.ParentId)
+			assert.Equal(t, w.CreatedAt, td.CreatedAt)
+			found++
+		}
+		td, err := r.FindTrace(10_000)
+		require.NoError(t, err)
+		assert.Nil(t, td)
+		require.NoError(t, r.Close())
+	}
+	assert.Greater(t, found, 1)
+}
+
+func TestTruncatedSegment(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, Config{Dir: dir})
+	writeCalls(t, db, 3)
+	// 模拟进程崩溃：只写出缓冲区，不写段尾
+	db.mu.Lock()
+	require.NoError(t, db.segment.flush())
+	path := db.segment.path
+	db.mu.Unlock()
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	crashed := filepath.Join(t.TempDir(), "crashed"+FileExt)
+	require.NoError(t, os.WriteFile(crashed, raw[:len(raw)-2], 0o644))
+	require.NoError(t, db.Close())
+
+	r, err := OpenSegment(crashed)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Nil(t, r.Footer())
+	enters := 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if rec.Kind == RecordEnter {
+			enters++
+		}
+	}
+	assert.Equal(t, 6, enters)
+	assert.True(t, r.Truncated())
+}
+
+func TestLoadIntoSQLite(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, Config{Dir: dir, SegmentSize: 512})
+	want := writeCalls(t, db, 10)
+	require.NoError(t, db.Close())
+
+	target, err := sqlite.Open(filepath.Join(t.TempDir(), "out.db"), testLogger())
+	require.NoError(t, err)
+	defer target.Close()
+
+	n, err := Load(db.Files(), target)
+	require.NoError(t, err)
+	assert.Equal(t, 52, n)
+
+	var got []*model.TraceData
+	require.NoError(t, target.ScanTraces(func(td *model.TraceData) error {
+		got = append(got, td)
+		return nil
+	}))
+	require.Len(t, got, len(want))
+	for i := range got {
+		assert.Equal(t, *want[i], *got[i])
+	}
+	params, err := target.GetParamRepository().FindParamsByTraceID(1)
+	require.NoError(t, err)
+	require.Len(t, params, 1)
+	assert.Equal(t, `{"i":0}`, string(params[0].Data))
+}
+
+func TestInMemoryLookups(t *testing.T) {
+	db := openTestDB(t, Config{Dir: t.TempDir()})
+	defer db.Close()
+
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(3, 42, time.Now().Format(time.RFC3339Nano), 0, "main.worker"))
+	require.NoError(t, err)
+	g, err := db.GetGoroutineRepository().FindGoroutineByID(3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), g.OriginGID)
+
+	_, err = db.GetParamRepository().SaveParamCache(model.NewParamCache("0x1", 5, []byte("{}")))
+	require.NoError(t, err)
+	cache, err := db.GetParamRepository().FindParamCacheByAddr("0x1")
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	require.NoError(t, db.GetParamRepository().DeleteParamCacheByAddr("0x1"))
+	cache, err = db.GetParamRepository().FindParamCacheByAddr("0x1")
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+}
@@ -0,0 +1,40 @@
+// Package binlog 以紧凑的二进制分段日志记录跟踪数据：varint 编码的进入/退出/参数记录，
+// 函数名写入段内字符串表，段尾附带块索引，仅在段滚动时 fsync
+package binlog
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config 二进制日志配置
+type Config struct {
+	Dir           string        // 输出目录
+	Prefix        string        // 文件名前缀，默认为可执行文件名
+	SegmentSize   int64         // 单个段文件的最大字节数，超过后滚动
+	IndexInterval int           // 每个索引块包含的函数进入记录数
+	FlushInterval time.Duration // 缓冲区定期写入操作系统的间隔（不 fsync）
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() Config {
+	return Config{
+		Dir:           DefaultDir,
+		SegmentSize:   DefaultSegmentSizeMB << 20,
+		IndexInterval: DefaultIndexInterval,
+		FlushInterval: time.Second,
+	}
+}
+
+// ConfigFromEnv 在默认配置基础上读取 FUNCTRACE_BINLOG_* 环境变量
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v := os.Getenv(EnvDir); v != "" {
+		cfg.Dir = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv(EnvSegmentSize), 10, 64); err == nil && v > 0 {
+		cfg.SegmentSize = v << 20
+	}
+	return cfg
+}
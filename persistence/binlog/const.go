@@ -0,0 +1,44 @@
+package binlog
+
+// 段文件格式
+//
+//	header  : magic "FTBL" | version(1B) | baseTime(varint, unix ns) | zoneOffset(varint, s)
+//	records : kind(1B) | payload（整数均为 uvarint，字符串/字节为 uvarint 长度 + 内容）
+//	footer  : kindFooter | 计数 | 字符串表 | 块索引
+//	trailer : footerOffset(uint64 LE) | crc32(footer) (uint32 LE) | magic "FTBE"
+//
+// 没有 trailer 的段（进程崩溃）仍可顺序读取到最后一条完整记录
+const (
+	segmentMagic  = "FTBL"
+	trailerMagic  = "FTBE"
+	formatVersion = 1
+	trailerSize   = 16
+)
+
+// 记录类型
+const (
+	kindName          byte = 1    // 字符串表条目：idx | str
+	kindEnter         byte = 2    // 函数进入：id | gid | indent | paramsCount | parentId | nameIdx | createdAt | seq
+	kindExit          byte = 3    // 函数退出：id | timeCost
+	kindParam         byte = 4    // 参数：id | traceId | position | isReceiver | baseId | data
+	kindGoroutine     byte = 5    // 协程创建：id | originGid | createTime | isFinished | initFuncIdx | creatorGid | creatorFuncIdx | parentTraceId
+	kindGoroutineExit byte = 6    // 协程结束：id | timeCost | isFinished
+	kindFooter        byte = 0xFF // 段尾索引开始
+)
+
+// 环境变量
+const (
+	EnvDir         = "FUNCTRACE_BINLOG_DIR"          // 输出目录
+	EnvSegmentSize = "FUNCTRACE_BINLOG_SEGMENT_SIZE" // 单个段文件的最大大小（MB）
+)
+
+// 默认值
+const (
+	DefaultDir           = "."
+	DefaultSegmentSizeMB = 64
+	DefaultIndexInterval = 1024 // 每个索引块包含的函数进入记录数
+
+	// 文件名格式：<可执行文件名>_<启动时间>.<序号>.ftlog
+	FileNameFormat = "%s.%06d" + FileExt
+	FileExt        = ".ftlog"
+)
@@ -0,0 +1,175 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+)
+
+// ErrQueryNotSupported 二进制日志只追加写入，查询请使用读取库或先转换为 SQLite
+var ErrQueryNotSupported = errors.New("query is not supported by the binlog backend")
+
+// 确保BinlogDatabase实现了IDatabase接口
+var _ domain.RepositoryFactory = (*BinlogDatabase)(nil)
+
+// BinlogDatabase 二进制分段日志仓储实现
+// 写入只追加记录；跟踪运行时需要回读的协程与参数缓存保留在内存索引中，参数缓存不落盘
+type BinlogDatabase struct {
+	config              Config
+	logger              *logrus.Logger
+	mu                  sync.Mutex
+	dir                 string
+	base                string
+	seq                 int
+	segment             *segmentWriter
+	files               []string
+	traceRepository     *TraceRepository
+	paramRepository     *ParamRepository
+	goroutineRepository *GoroutineRepository
+	stop                chan struct{}
+	wg                  sync.WaitGroup
+}
+
+// NewBinlogDatabase 创建新的二进制日志仓储工厂
+func NewBinlogDatabase(config Config, logger *logrus.Logger) domain.RepositoryFactory {
+	return &BinlogDatabase{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Initialize 创建输出目录并启动定期写出
+func (d *BinlogDatabase) Initialize() error {
+	d.dir = d.config.Dir
+	if d.dir == "" {
+		d.dir = DefaultDir
+	}
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return fmt.Errorf("create binlog dir error: %w", err)
+	}
+	prefix := d.config.Prefix
+	if prefix == "" {
+		prefix = defaultPrefix()
+	}
+	d.base = fmt.Sprintf("%s_%s", prefix, time.Now().Format("20060102150405"))
+	d.logger.Infof("writing binlog: %s", filepath.Join(d.dir, d.base))
+
+	d.traceRepository = &TraceRepository{db: d}
+	d.paramRepository = newParamRepository(d)
+	d.goroutineRepository = newGoroutineRepository(d)
+
+	interval := d.config.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go d.flushLoop(interval)
+	return nil
+}
+
+// flushLoop 定期将缓冲区写入操作系统，fsync 只在段滚动与关闭时进行
+func (d *BinlogDatabase) flushLoop(interval time.Duration) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.mu.Lock()
+			var err error
+			if d.segment != nil {
+				err = d.segment.flush()
+			}
+			d.mu.Unlock()
+			if err != nil {
+				d.logger.WithFields(logrus.Fields{"error": err}).Error("flush binlog failed")
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Close 停止定期写出并关闭当前段
+func (d *BinlogDatabase) Close() error {
+	if d.stop != nil {
+		close(d.stop)
+		d.wg.Wait()
+		d.stop = nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.segment == nil {
+		return nil
+	}
+	err := d.segment.close()
+	d.segment = nil
+	return err
+}
+
+// Files 返回已创建的段文件，按写入顺序
+func (d *BinlogDatabase) Files() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.files...)
+}
+
+func (d *BinlogDatabase) GetTraceRepository() domain.TraceRepository {
+	return d.traceRepository
+}
+
+func (d *BinlogDatabase) GetParamRepository() domain.ParamRepository {
+	return d.paramRepository
+}
+
+func (d *BinlogDatabase) GetGoroutineRepository() domain.GoroutineRepository {
+	return d.goroutineRepository
+}
+
+// write 在当前段上执行写入，段超过大小上限时先滚动
+func (d *BinlogDatabase) write(fn func(s *segmentWriter) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.segment == nil || (d.config.SegmentSize > 0 && d.segment.size >= d.config.SegmentSize) {
+		if err := d.rotate(); err != nil {
+			return err
+		}
+	}
+	return fn(d.segment)
+}
+
+// rotate 关闭当前段（写索引并 fsync）并创建下一个段
+func (d *BinlogDatabase) rotate() error {
+	if d.segment != nil {
+		err := d.segment.close()
+		d.segment = nil
+		if err != nil {
+			return err
+		}
+	}
+	d.seq++
+	path := filepath.Join(d.dir, fmt.Sprintf(FileNameFormat, d.base, d.seq))
+	s, err := createSegment(path, time.Now(), d.config.IndexInterval)
+	if err != nil {
+		return err
+	}
+	d.segment = s
+	d.files = append(d.files, path)
+	return nil
+}
+
+// defaultPrefix 默认文件名前缀为可执行文件名
+func defaultPrefix() string {
+	execName, err := os.Executable()
+	if err != nil {
+		return "default"
+	}
+	return filepath.Base(execName)
+}
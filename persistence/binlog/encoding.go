@@ -0,0 +1,146 @@
+package binlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// appendString 追加 uvarint 长度 + 字符串
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendBytes 追加 uvarint 长度 + 字节
+func appendBytes(b []byte, p []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(p)))
+	return append(b, p...)
+}
+
+// appendDuration 追加耗时：可无损还原的 time.Duration 字符串编码为纳秒，否则原样保存
+// 低位为 0 表示纳秒，为 1 表示随后是原始字符串
+func appendDuration(b []byte, s string) []byte {
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 && d.String() == s {
+		return binary.AppendUvarint(b, uint64(d)<<1)
+	}
+	b = binary.AppendUvarint(b, uint64(len(s))<<1|1)
+	return append(b, s...)
+}
+
+// appendTime 追加时间：与段时区一致的 RFC3339Nano 时间编码为相对上一时间戳的纳秒差，否则原样保存
+// 低位为 0 表示 zigzag 编码的差值，为 1 表示随后是原始字符串
+func appendTime(b []byte, s string, prev *int64, zoneOffset int) []byte {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		if _, off := t.Zone(); off == zoneOffset && (off != 0 || strings.HasSuffix(s, "Z")) {
+			ns := t.UnixNano()
+			delta := ns - *prev
+			*prev = ns
+			zz := uint64(delta<<1) ^ uint64(delta>>63)
+			return binary.AppendUvarint(b, zz<<1)
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(len(s))<<1|1)
+	return append(b, s...)
+}
+
+// decoder 带偏移量统计的读取器，出错后后续读取均返回零值，由调用方统一检查 err
+type decoder struct {
+	r   *bufio.Reader
+	off int64
+	err error
+}
+
+func newDecoder(r io.Reader, off int64) *decoder {
+	return &decoder{r: bufio.NewReaderSize(r, 64<<10), off: off}
+}
+
+// ReadByte 实现 io.ByteReader
+func (d *decoder) ReadByte() (byte, error) {
+	c, err := d.r.ReadByte()
+	if err == nil {
+		d.off++
+	}
+	return c, err
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	c, err := d.ReadByte()
+	if err != nil {
+		d.fail(err)
+	}
+	return c
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *decoder) raw(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > 1<<30 {
+		d.err = fmt.Errorf("field too large: %d bytes", n)
+		return nil
+	}
+	p := make([]byte, n)
+	m, err := io.ReadFull(d.r, p)
+	d.off += int64(m)
+	if err != nil {
+		d.fail(err)
+		return nil
+	}
+	return p
+}
+
+func (d *decoder) bytes() []byte {
+	return d.raw(d.uvarint())
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) duration() string {
+	v := d.uvarint()
+	if v&1 == 0 {
+		return time.Duration(v >> 1).String()
+	}
+	return string(d.raw(v >> 1))
+}
+
+func (d *decoder) time(prev *int64, zone *time.Location) string {
+	v := d.uvarint()
+	if v&1 == 1 {
+		return string(d.raw(v >> 1))
+	}
+	zz := v >> 1
+	delta := int64(zz>>1) ^ -int64(zz&1)
+	*prev += delta
+	return time.Unix(0, *prev).In(zone).Format(time.RFC3339Nano)
+}
+
+// fail 记录第一个错误，记录中途遇到 EOF 视为截断
+func (d *decoder) fail(err error) {
+	if d.err != nil {
+		return
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	d.err = err
+}
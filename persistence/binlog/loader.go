@@ -0,0 +1,53 @@
+package binlog
+
+import (
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// loadParamBatchSize 转换时参数批量写入的大小
+const loadParamBatchSize = 500
+
+// Load 按顺序将段文件中的记录写入目标仓储（如 SQLite），返回写入的记录数
+func Load(paths []string, target domain.RepositoryFactory) (int, error) {
+	traces := target.GetTraceRepository()
+	params := target.GetParamRepository()
+	goroutines := target.GetGoroutineRepository()
+
+	var pending []*model.ParamStoreData
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := params.SaveParamsBatch(pending)
+		pending = pending[:0]
+		return err
+	}
+
+	n := 0
+	err := Replay(paths, func(rec *Record) error {
+		n++
+		switch rec.Kind {
+		case RecordEnter:
+			_, err := traces.SaveTrace(rec.Trace)
+			return err
+		case RecordExit:
+			return traces.UpdateTraceTimeCost(rec.Trace.ID, rec.Trace.TimeCost)
+		case RecordParam:
+			pending = append(pending, rec.Param)
+			if len(pending) >= loadParamBatchSize {
+				return flush()
+			}
+		case RecordGoroutine:
+			_, err := goroutines.SaveGoroutine(rec.Goroutine)
+			return err
+		case RecordGoroutineExit:
+			return goroutines.UpdateGoroutineTimeCost(rec.Goroutine.ID, rec.Goroutine.TimeCost, rec.Goroutine.IsFinished)
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, flush()
+}
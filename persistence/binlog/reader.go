@@ -0,0 +1,436 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// RecordKind 读取到的记录类型
+type RecordKind int
+
+const (
+	RecordEnter         RecordKind = iota + 1 // 函数进入
+	RecordExit                                // 函数退出
+	RecordParam                               // 参数
+	RecordGoroutine                           // 协程创建
+	RecordGoroutineExit                       // 协程结束
+)
+
+// Record 解码后的一条记录
+type Record struct {
+	Kind      RecordKind
+	Trace     *model.TraceData      // RecordEnter 为完整进入数据；RecordExit 仅含 ID、TimeCost、IsFinished
+	Param     *model.ParamStoreData // RecordParam
+	Goroutine *model.GoroutineTrace // RecordGoroutine 为完整数据；RecordGoroutineExit 仅含 ID、TimeCost、IsFinished
+}
+
+// SegmentReader 顺序读取单个段文件
+type SegmentReader struct {
+	path       string
+	f          *os.File
+	zone       *time.Location
+	baseTime   int64
+	headerSize int64
+	dataEnd    int64
+	footer     *Footer
+	names      []string
+	dec        *decoder
+	lastTime   int64
+	truncated  bool
+}
+
+// OpenSegment 打开段文件并读取文件头与段尾索引（若存在）
+func OpenSegment(path string) (*SegmentReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open segment error: %w", err)
+	}
+	r := &SegmentReader{path: path, f: f}
+	if err := r.init(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open segment %s error: %w", path, err)
+	}
+	return r, nil
+}
+
+func (r *SegmentReader) init() error {
+	info, err := r.f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat segment error: %w", err)
+	}
+	size := info.Size()
+
+	d := newDecoder(io.NewSectionReader(r.f, 0, size), 0)
+	magic := d.raw(uint64(len(segmentMagic)))
+	version := d.byte()
+	if d.err != nil {
+		return fmt.Errorf("read header error: %w", d.err)
+	}
+	if string(magic) != segmentMagic {
+		return fmt.Errorf("not a functrace binlog segment")
+	}
+	if version != formatVersion {
+		return fmt.Errorf("unsupported segment version: %d", version)
+	}
+	baseTime, err := binary.ReadVarint(d)
+	if err != nil {
+		return fmt.Errorf("read header error: %w", err)
+	}
+	zoneOffset, err := binary.ReadVarint(d)
+	if err != nil {
+		return fmt.Errorf("read header error: %w", err)
+	}
+	r.baseTime = baseTime
+	r.zone = time.FixedZone("", int(zoneOffset))
+	r.headerSize = d.off
+	r.dataEnd = size
+
+	if err := r.readFooter(size); err != nil {
+		return err
+	}
+	r.reset(r.headerSize, r.baseTime)
+	return nil
+}
+
+// readFooter 校验 trailer 并解码段尾索引；未正常关闭的段没有 trailer
+func (r *SegmentReader) readFooter(size int64) error {
+	if size < r.headerSize+trailerSize {
+		return nil
+	}
+	trailer := make([]byte, trailerSize)
+	if _, err := r.f.ReadAt(trailer, size-trailerSize); err != nil {
+		return fmt.Errorf("read trailer error: %w", err)
+	}
+	if string(trailer[12:]) != trailerMagic {
+		return nil
+	}
+	footerOffset := int64(binary.LittleEndian.Uint64(trailer))
+	if footerOffset < r.headerSize || footerOffset > size-trailerSize {
+		return fmt.Errorf("invalid footer offset: %d", footerOffset)
+	}
+	raw := make([]byte, size-trailerSize-footerOffset)
+	if _, err := r.f.ReadAt(raw, footerOffset); err != nil {
+		return fmt.Errorf("read footer error: %w", err)
+	}
+	if crc32.ChecksumIEEE(raw) != binary.LittleEndian.Uint32(trailer[8:]) {
+		return fmt.Errorf("footer checksum mismatch")
+	}
+	footer, err := decodeFooter(raw)
+	if err != nil {
+		return err
+	}
+	r.footer = footer
+	r.names = append([]string(nil), footer.Names...)
+	r.dataEnd = footerOffset
+	return nil
+}
+
+// decodeFooter 解码段尾索引
+func decodeFooter(raw []byte) (*Footer, error) {
+	d := newDecoder(bytes.NewReader(raw), 0)
+	if d.byte() != kindFooter {
+		return nil, fmt.Errorf("invalid footer")
+	}
+	f := &Footer{
+		Records:    d.uvarint(),
+		Enters:     d.uvarint(),
+		Exits:      d.uvarint(),
+		Params:     d.uvarint(),
+		Goroutines: d.uvarint(),
+		MinTraceID: int64(d.uvarint()),
+		MaxTraceID: int64(d.uvarint()),
+	}
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		f.Names = append(f.Names, d.string())
+	}
+	n = d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		blk := IndexBlock{Offset: int64(d.uvarint())}
+		if d.err == nil {
+			var err error
+			if blk.PrevTime, err = binary.ReadVarint(d); err != nil {
+				d.fail(err)
+			}
+		}
+		blk.MinTraceID = int64(d.uvarint())
+		blk.MaxTraceID = int64(d.uvarint())
+		f.Blocks = append(f.Blocks, blk)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("decode footer error: %w", d.err)
+	}
+	return f, nil
+}
+
+// reset 从指定偏移开始读取
+func (r *SegmentReader) reset(offset, prevTime int64) {
+	r.dec = newDecoder(io.NewSectionReader(r.f, offset, r.dataEnd-offset), offset)
+	r.lastTime = prevTime
+}
+
+// Footer 返回段尾索引，段未正常关闭时返回 nil
+func (r *SegmentReader) Footer() *Footer {
+	return r.footer
+}
+
+// Truncated 报告段是否以不完整的记录结尾（进程在写入时退出）
+func (r *SegmentReader) Truncated() bool {
+	return r.truncated
+}
+
+// Close 关闭段文件
+func (r *SegmentReader) Close() error {
+	return r.f.Close()
+}
+
+// Next 返回下一条记录，读完时返回 io.EOF
+func (r *SegmentReader) Next() (*Record, error) {
+	for {
+		kind, err := r.dec.ReadByte()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read record error: %w", err)
+		}
+		if kind == kindFooter {
+			// 写段尾时中断：索引不完整，数据已读完
+			return nil, io.EOF
+		}
+		rec, err := r.decode(kind)
+		if err != nil {
+			if r.footer == nil && errors.Is(err, io.ErrUnexpectedEOF) {
+				r.truncated = true
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("decode record at offset %d error: %w", r.dec.off, err)
+		}
+		if rec != nil {
+			return rec, nil
+		}
+	}
+}
+
+// decode 解码一条记录的负载，字符串表条目返回 nil
+func (r *SegmentReader) decode(kind byte) (*Record, error) {
+	d := r.dec
+	var rec *Record
+	switch kind {
+	case kindName:
+		idx := d.uvarint()
+		name := d.string()
+		if d.err == nil {
+			for uint64(len(r.names)) <= idx {
+				r.names = append(r.names, "")
+			}
+			r.names[idx] = name
+		}
+	case kindEnter:
+		td := &model.TraceData{
+			ID:          int64(d.uvarint()),
+			GID:         d.uvarint(),
+			Indent:      int(d.uvarint()),
+			ParamsCount: int(d.uvarint()),
+			ParentId:    int64(d.uvarint()),
+		}
+		td.Name = r.name(d.uvarint())
+		td.CreatedAt = d.time(&r.lastTime, r.zone)
+		td.Seq = d.string()
+		rec = &Record{Kind: RecordEnter, Trace: td}
+	case kindExit:
+		td := &model.TraceData{ID: int64(d.uvarint()), IsFinished: 1}
+		td.TimeCost = d.duration()
+		rec = &Record{Kind: RecordExit, Trace: td}
+	case kindParam:
+		p := &model.ParamStoreData{
+			ID:       int64(d.uvarint()),
+			TraceID:  int64(d.uvarint()),
+			Position: int(d.uvarint()),
+		}
+		p.IsReceiver = d.byte() == 1
+		p.BaseID = int64(d.uvarint())
+		p.Data = d.bytes()
+		rec = &Record{Kind: RecordParam, Param: p}
+	case kindGoroutine:
+		g := &model.GoroutineTrace{
+			ID:        int64(d.uvarint()),
+			OriginGID: d.uvarint(),
+		}
+		g.CreateTime = d.time(&r.lastTime, r.zone)
+		g.IsFinished = int(d.uvarint())
+		g.InitFuncName = r.name(d.uvarint())
+		g.CreatorGID = d.uvarint()
+		g.CreatorFunc = r.name(d.uvarint())
+		g.ParentTraceID = int64(d.uvarint())
+		rec = &Record{Kind: RecordGoroutine, Goroutine: g}
+	case kindGoroutineExit:
+		g := &model.GoroutineTrace{ID: int64(d.uvarint())}
+		g.TimeCost = d.duration()
+		g.IsFinished = int(d.uvarint())
+		rec = &Record{Kind: RecordGoroutineExit, Goroutine: g}
+	default:
+		return nil, fmt.Errorf("unknown record kind: %d", kind)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return rec, nil
+}
+
+// name 按下标查找字符串表，下标未知时记录错误
+func (r *SegmentReader) name(idx uint64) string {
+	if r.dec.err != nil {
+		return ""
+	}
+	if idx >= uint64(len(r.names)) {
+		r.dec.err = fmt.Errorf("unknown name index: %d", idx)
+		return ""
+	}
+	return r.names[idx]
+}
+
+// FindTrace 借助块索引查找指定跟踪ID，并在本段内继续查找其退出记录
+// 段未正常关闭时退化为顺序扫描；未找到时返回 nil
+func (r *SegmentReader) FindTrace(id int64) (*model.TraceData, error) {
+	starts := []IndexBlock{{Offset: r.headerSize, PrevTime: r.baseTime}}
+	if r.footer != nil {
+		if id < r.footer.MinTraceID || id > r.footer.MaxTraceID {
+			return nil, nil
+		}
+		starts = starts[:0]
+		for _, blk := range r.footer.Blocks {
+			if blk.contains(id) {
+				starts = append(starts, blk)
+			}
+		}
+	}
+	defer r.reset(r.headerSize, r.baseTime)
+
+	for _, blk := range starts {
+		r.reset(blk.Offset, blk.PrevTime)
+		var found *model.TraceData
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case found == nil && rec.Kind == RecordEnter && rec.Trace.ID == id:
+				found = rec.Trace
+			case found != nil && rec.Kind == RecordExit && rec.Trace.ID == id:
+				found.TimeCost = rec.Trace.TimeCost
+				found.IsFinished = 1
+				return found, nil
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListSegments 返回目录下全部段文件，按文件名即写入顺序排序
+func ListSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read binlog dir error: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), FileExt) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Replay 按顺序读取多个段文件的全部记录，fn 返回错误时终止并返回该错误
+func Replay(paths []string, fn func(rec *Record) error) error {
+	for _, path := range paths {
+		if err := replaySegment(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replaySegment(path string, fn func(rec *Record) error) error {
+	r, err := OpenSegment(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s error: %w", path, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// Data 合并进入/退出记录后的完整数据，均按ID升序
+type Data struct {
+	Traces     []*model.TraceData
+	Goroutines []*model.GoroutineTrace
+	Params     []*model.ParamStoreData
+}
+
+// ReadAll 读取全部段文件，将退出记录合并到对应的 TraceData / GoroutineTrace 行
+func ReadAll(paths []string) (*Data, error) {
+	traces := make(map[int64]*model.TraceData)
+	goroutines := make(map[int64]*model.GoroutineTrace)
+	data := &Data{}
+	err := Replay(paths, func(rec *Record) error {
+		switch rec.Kind {
+		case RecordEnter:
+			traces[rec.Trace.ID] = rec.Trace
+			data.Traces = append(data.Traces, rec.Trace)
+		case RecordExit:
+			if td, ok := traces[rec.Trace.ID]; ok {
+				td.TimeCost = rec.Trace.TimeCost
+				td.IsFinished = 1
+			}
+		case RecordParam:
+			data.Params = append(data.Params, rec.Param)
+		case RecordGoroutine:
+			goroutines[rec.Goroutine.ID] = rec.Goroutine
+			data.Goroutines = append(data.Goroutines, rec.Goroutine)
+		case RecordGoroutineExit:
+			if g, ok := goroutines[rec.Goroutine.ID]; ok {
+				g.TimeCost = rec.Goroutine.TimeCost
+				g.IsFinished = rec.Goroutine.IsFinished
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(data.Traces, func(i, j int) bool { return data.Traces[i].ID < data.Traces[j].ID })
+	sort.Slice(data.Goroutines, func(i, j int) bool { return data.Goroutines[i].ID < data.Goroutines[j].ID })
+	sort.Slice(data.Params, func(i, j int) bool { return data.Params[i].ID < data.Params[j].ID })
+	return data, nil
+}
@@ -0,0 +1,165 @@
+package binlog
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+var _ domain.TraceRepository = (*TraceRepository)(nil)
+var _ domain.ParamRepository = (*ParamRepository)(nil)
+var _ domain.GoroutineRepository = (*GoroutineRepository)(nil)
+
+// TraceRepository 是二进制日志实现的跟踪数据仓储
+type TraceRepository struct {
+	db *BinlogDatabase
+}
+
+// SaveTrace 写入函数进入记录
+func (r *TraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
+	if err := r.db.write(func(s *segmentWriter) error { return s.writeEnter(trace) }); err != nil {
+		return 0, fmt.Errorf("save trace error: %w", err)
+	}
+	return trace.ID, nil
+}
+
+// UpdateTraceTimeCost 写入函数退出记录
+func (r *TraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
+	if err := r.db.write(func(s *segmentWriter) error { return s.writeExit(id, timeCost) }); err != nil {
+		return fmt.Errorf("update trace time cost error: %w", err)
+	}
+	return nil
+}
+
+// FindRootFunctionsByGID 不支持查询
+func (r *TraceRepository) FindRootFunctionsByGID(gid uint64) ([]model.TraceData, error) {
+	return nil, ErrQueryNotSupported
+}
+
+// ParamRepository 是二进制日志实现的参数数据仓储
+// 参数缓存只是运行时状态，仅保留在内存中
+type ParamRepository struct {
+	db     *BinlogDatabase
+	mu     sync.RWMutex
+	caches map[string]*model.ParamCache
+	nextID int64
+}
+
+func newParamRepository(db *BinlogDatabase) *ParamRepository {
+	return &ParamRepository{db: db, caches: make(map[string]*model.ParamCache)}
+}
+
+// SaveParam 写入参数记录
+func (r *ParamRepository) SaveParam(param *model.ParamStoreData) (int64, error) {
+	if err := r.db.write(func(s *segmentWriter) error { return s.writeParam(param) }); err != nil {
+		return 0, fmt.Errorf("save param error: %w", err)
+	}
+	return param.ID, nil
+}
+
+// SaveParamsBatch 在同一次加锁内写入多条参数记录
+func (r *ParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	err := r.db.write(func(s *segmentWriter) error {
+		for _, p := range params {
+			if err := s.writeParam(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("batch save param error: %w", err)
+	}
+	return nil
+}
+
+// FindParamsByTraceID 不支持查询
+func (r *ParamRepository) FindParamsByTraceID(traceId int64) ([]model.ParamStoreData, error) {
+	return nil, ErrQueryNotSupported
+}
+
+// SaveParamCache 保存参数缓存到内存（同地址覆盖）
+func (r *ParamRepository) SaveParamCache(cache *model.ParamCache) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	stored := *cache
+	stored.ID = r.nextID
+	r.caches[cache.Addr] = &stored
+	return stored.ID, nil
+}
+
+// FindParamCacheByAddr 根据地址查找参数缓存，未找到时返回 nil
+func (r *ParamRepository) FindParamCacheByAddr(addr string) (*model.ParamCache, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cache, ok := r.caches[addr]
+	if !ok {
+		return nil, nil
+	}
+	c := *cache
+	return &c, nil
+}
+
+// DeleteParamCacheByAddr 根据地址删除参数缓存
+func (r *ParamRepository) DeleteParamCacheByAddr(addr string) error {
+	r.mu.Lock()
+	delete(r.caches, addr)
+	r.mu.Unlock()
+	return nil
+}
+
+// GoroutineRepository 是二进制日志实现的协程数据仓储
+// 未结束的协程在内存中保留一份，结束时移除
+type GoroutineRepository struct {
+	db         *BinlogDatabase
+	mu         sync.RWMutex
+	goroutines map[int64]*model.GoroutineTrace
+}
+
+func newGoroutineRepository(db *BinlogDatabase) *GoroutineRepository {
+	return &GoroutineRepository{db: db, goroutines: make(map[int64]*model.GoroutineTrace)}
+}
+
+// SaveGoroutine 写入协程创建记录
+func (r *GoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (int64, error) {
+	g := *goroutine
+	r.mu.Lock()
+	r.goroutines[g.ID] = &g
+	r.mu.Unlock()
+
+	if err := r.db.write(func(s *segmentWriter) error { return s.writeGoroutine(&g) }); err != nil {
+		return 0, fmt.Errorf("save goroutine error: %w", err)
+	}
+	return g.ID, nil
+}
+
+// UpdateGoroutineTimeCost 写入协程结束记录
+func (r *GoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
+	r.mu.Lock()
+	if isFinished != 0 {
+		delete(r.goroutines, id)
+	} else if g, ok := r.goroutines[id]; ok {
+		g.TimeCost = timeCost
+	}
+	r.mu.Unlock()
+
+	if err := r.db.write(func(s *segmentWriter) error { return s.writeGoroutineExit(id, timeCost, isFinished) }); err != nil {
+		return fmt.Errorf("update goroutine time cost error: %w", err)
+	}
+	return nil
+}
+
+// FindGoroutineByID 从内存索引查找未结束的协程
+func (r *GoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.goroutines[id]
+	if !ok {
+		return nil, fmt.Errorf("goroutine data not found: id=%d", id)
+	}
+	c := *g
+	return &c, nil
+}
@@ -0,0 +1,269 @@
+package binlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// Footer 段尾索引
+type Footer struct {
+	Records    uint64       // 记录总数（不含字符串表条目）
+	Enters     uint64       // 函数进入记录数
+	Exits      uint64       // 函数退出记录数
+	Params     uint64       // 参数记录数
+	Goroutines uint64       // 协程创建记录数
+	MinTraceID int64        // 段内最小跟踪ID
+	MaxTraceID int64        // 段内最大跟踪ID
+	Names      []string     // 段内字符串表，下标即记录中的 nameIdx
+	Blocks     []IndexBlock // 块索引，按偏移升序
+}
+
+// IndexBlock 块索引：每 IndexInterval 条函数进入记录为一块
+type IndexBlock struct {
+	Offset     int64 // 块内第一条记录在文件中的偏移
+	PrevTime   int64 // 块开始前的时间戳基准（unix ns），从块起点解码时使用
+	MinTraceID int64 // 块内函数进入记录的最小ID
+	MaxTraceID int64 // 块内函数进入记录的最大ID
+}
+
+// contains 判断块是否可能包含指定跟踪ID
+func (b IndexBlock) contains(id int64) bool {
+	return id >= b.MinTraceID && id <= b.MaxTraceID
+}
+
+// segmentWriter 单个段文件的写入器，非并发安全
+type segmentWriter struct {
+	path          string
+	f             *os.File
+	w             *bufio.Writer
+	size          int64
+	zoneOffset    int
+	lastTime      int64
+	names         map[string]uint64
+	footer        Footer
+	indexInterval int
+	blockEnters   int
+	buf           []byte
+}
+
+// createSegment 创建段文件并写入文件头
+func createSegment(path string, now time.Time, indexInterval int) (*segmentWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("create segment error: %w", err)
+	}
+	if indexInterval <= 0 {
+		indexInterval = DefaultIndexInterval
+	}
+	_, zoneOffset := now.Zone()
+	s := &segmentWriter{
+		path:          path,
+		f:             f,
+		w:             bufio.NewWriterSize(f, 256<<10),
+		zoneOffset:    zoneOffset,
+		lastTime:      now.UnixNano(),
+		names:         make(map[string]uint64),
+		indexInterval: indexInterval,
+	}
+	header := append([]byte(segmentMagic), formatVersion)
+	header = binary.AppendVarint(header, s.lastTime)
+	header = binary.AppendVarint(header, int64(zoneOffset))
+	if err := s.write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// write 写入已编码的数据
+func (s *segmentWriter) write(p []byte) error {
+	n, err := s.w.Write(p)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write segment error: %w", err)
+	}
+	return nil
+}
+
+// intern 返回字符串在段内字符串表中的下标，首次出现时写入字符串表条目
+func (s *segmentWriter) intern(name string) (uint64, error) {
+	if idx, ok := s.names[name]; ok {
+		return idx, nil
+	}
+	idx := uint64(len(s.footer.Names))
+	s.names[name] = idx
+	s.footer.Names = append(s.footer.Names, name)
+
+	b := append(s.buf[:0], kindName)
+	b = binary.AppendUvarint(b, idx)
+	b = appendString(b, name)
+	s.buf = b
+	return idx, s.write(b)
+}
+
+// writeEnter 写入函数进入记录，并维护块索引
+func (s *segmentWriter) writeEnter(td *model.TraceData) error {
+	if s.blockEnters == 0 {
+		s.footer.Blocks = append(s.footer.Blocks, IndexBlock{
+			Offset:     s.size,
+			PrevTime:   s.lastTime,
+			MinTraceID: td.ID,
+			MaxTraceID: td.ID,
+		})
+	}
+	block := &s.footer.Blocks[len(s.footer.Blocks)-1]
+	block.MinTraceID = min(block.MinTraceID, td.ID)
+	block.MaxTraceID = max(block.MaxTraceID, td.ID)
+	if s.blockEnters++; s.blockEnters >= s.indexInterval {
+		s.blockEnters = 0
+	}
+	if s.footer.Enters == 0 {
+		s.footer.MinTraceID, s.footer.MaxTraceID = td.ID, td.ID
+	}
+	s.footer.MinTraceID = min(s.footer.MinTraceID, td.ID)
+	s.footer.MaxTraceID = max(s.footer.MaxTraceID, td.ID)
+	s.footer.Enters++
+	s.footer.Records++
+
+	nameIdx, err := s.intern(td.Name)
+	if err != nil {
+		return err
+	}
+	b := append(s.buf[:0], kindEnter)
+	b = binary.AppendUvarint(b, uint64(td.ID))
+	b = binary.AppendUvarint(b, td.GID)
+	b = binary.AppendUvarint(b, uint64(td.Indent))
+	b = binary.AppendUvarint(b, uint64(td.ParamsCount))
+	b = binary.AppendUvarint(b, uint64(td.ParentId))
+	b = binary.AppendUvarint(b, nameIdx)
+	b = appendTime(b, td.CreatedAt, &s.lastTime, s.zoneOffset)
+	b = appendString(b, td.Seq)
+	s.buf = b
+	return s.write(b)
+}
+
+// writeExit 写入函数退出记录
+func (s *segmentWriter) writeExit(id int64, timeCost string) error {
+	s.footer.Exits++
+	s.footer.Records++
+	b := append(s.buf[:0], kindExit)
+	b = binary.AppendUvarint(b, uint64(id))
+	b = appendDuration(b, timeCost)
+	s.buf = b
+	return s.write(b)
+}
+
+// writeParam 写入参数记录
+func (s *segmentWriter) writeParam(p *model.ParamStoreData) error {
+	s.footer.Params++
+	s.footer.Records++
+	b := append(s.buf[:0], kindParam)
+	b = binary.AppendUvarint(b, uint64(p.ID))
+	b = binary.AppendUvarint(b, uint64(p.TraceID))
+	b = binary.AppendUvarint(b, uint64(p.Position))
+	if p.IsReceiver {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = binary.AppendUvarint(b, uint64(p.BaseID))
+	b = appendBytes(b, p.Data)
+	s.buf = b
+	return s.write(b)
+}
+
+// writeGoroutine 写入协程创建记录
+func (s *segmentWriter) writeGoroutine(g *model.GoroutineTrace) error {
+	s.footer.Goroutines++
+	s.footer.Records++
+	initIdx, err := s.intern(g.InitFuncName)
+	if err != nil {
+		return err
+	}
+	creatorIdx, err := s.intern(g.CreatorFunc)
+	if err != nil {
+		return err
+	}
+	b := append(s.buf[:0], kindGoroutine)
+	b = binary.AppendUvarint(b, uint64(g.ID))
+	b = binary.AppendUvarint(b, g.OriginGID)
+	b = appendTime(b, g.CreateTime, &s.lastTime, s.zoneOffset)
+	b = binary.AppendUvarint(b, uint64(g.IsFinished))
+	b = binary.AppendUvarint(b, initIdx)
+	b = binary.AppendUvarint(b, g.CreatorGID)
+	b = binary.AppendUvarint(b, creatorIdx)
+	b = binary.AppendUvarint(b, uint64(g.ParentTraceID))
+	s.buf = b
+	return s.write(b)
+}
+
+// writeGoroutineExit 写入协程结束记录
+func (s *segmentWriter) writeGoroutineExit(id int64, timeCost string, isFinished int) error {
+	s.footer.Records++
+	b := append(s.buf[:0], kindGoroutineExit)
+	b = binary.AppendUvarint(b, uint64(id))
+	b = appendDuration(b, timeCost)
+	b = binary.AppendUvarint(b, uint64(isFinished))
+	s.buf = b
+	return s.write(b)
+}
+
+// flush 将缓冲区写入操作系统（不 fsync）
+func (s *segmentWriter) flush() error {
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("flush segment error: %w", err)
+	}
+	return nil
+}
+
+// close 写入段尾索引与 trailer，fsync 后关闭文件
+func (s *segmentWriter) close() error {
+	footerOffset := s.size
+	footer := encodeFooter(&s.footer)
+	trailer := binary.LittleEndian.AppendUint64(nil, uint64(footerOffset))
+	trailer = binary.LittleEndian.AppendUint32(trailer, crc32.ChecksumIEEE(footer))
+	trailer = append(trailer, trailerMagic...)
+
+	err := s.write(footer)
+	if err == nil {
+		err = s.write(trailer)
+	}
+	if err == nil {
+		err = s.flush()
+	}
+	if err == nil {
+		if serr := s.f.Sync(); serr != nil {
+			err = fmt.Errorf("sync segment error: %w", serr)
+		}
+	}
+	if cerr := s.f.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("close segment error: %w", cerr)
+	}
+	return err
+}
+
+// encodeFooter 编码段尾索引
+func encodeFooter(f *Footer) []byte {
+	b := []byte{kindFooter}
+	for _, v := range []uint64{f.Records, f.Enters, f.Exits, f.Params, f.Goroutines, uint64(f.MinTraceID), uint64(f.MaxTraceID)} {
+		b = binary.AppendUvarint(b, v)
+	}
+	b = binary.AppendUvarint(b, uint64(len(f.Names)))
+	for _, name := range f.Names {
+		b = appendString(b, name)
+	}
+	b = binary.AppendUvarint(b, uint64(len(f.Blocks)))
+	for _, blk := range f.Blocks {
+		b = binary.AppendUvarint(b, uint64(blk.Offset))
+		b = binary.AppendVarint(b, blk.PrevTime)
+		b = binary.AppendUvarint(b, uint64(blk.MinTraceID))
+		b = binary.AppendUvarint(b, uint64(blk.MaxTraceID))
+	}
+	return b
+}
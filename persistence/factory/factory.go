@@ -2,12 +2,17 @@ package factory
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/toheart/functrace/domain"
 	"github.com/toheart/functrace/persistence/memory"
 	"github.com/toheart/functrace/persistence/sqlite"
+	"github.com/toheart/functrace/persistence/tee"
 )
 
 // OpType 定义数据库操作类型
@@ -42,21 +47,124 @@ const (
 	DBTypeSQLite DatabaseType = "sqlite"
 	DBTypeMySQL  DatabaseType = "mysql"
 	DBTypeMock   DatabaseType = "mock" // 添加Mock数据库类型
-	// 可以添加更多数据库类型
+	DBTypeMemory DatabaseType = "memory"
+	DBTypeJSONL  DatabaseType = "jsonl"
+	DBTypeBinlog DatabaseType = "binlog"
+	DBTypeRemote DatabaseType = "remote"
+	// sqlite、memory 与 mock 内置；其他类型由各自的包在 init 中通过 Register 注册，需匿名导入该包
 )
 
-// CreateRepositoryFactory 创建仓储工厂
+// TeeSeparator 分隔同时写入的多个数据库类型
+const TeeSeparator = ","
+
+// Constructor 创建未初始化的仓储工厂，由 CreateRepositoryFactory 调用 Initialize
+type Constructor func(logger *logrus.Logger) domain.RepositoryFactory
+
+// ConfigConstructor 按 DatabaseConfig 创建未初始化的仓储工厂，由 CreateRepositoryFactoryWithConfig 调用
+type ConfigConstructor func(config DatabaseConfig, logger *logrus.Logger) domain.RepositoryFactory
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Constructor{}
+	configured = map[string]ConfigConstructor{}
+)
+
+func init() {
+	Register(string(DBTypeSQLite), func(logger *logrus.Logger) domain.RepositoryFactory {
+		return sqlite.NewDatabase(sqlite.ConfigFromEnv(), logger)
+	})
+	Register(string(DBTypeMock), func(logger *logrus.Logger) domain.RepositoryFactory {
+		return memory.NewMockDatabase(logger)
+	})
+	Register(string(DBTypeMemory), func(logger *logrus.Logger) domain.RepositoryFactory {
+		return memory.NewMemDatabase(memory.ConfigFromEnv(), logger)
+	})
+	RegisterConfig(string(DBTypeSQLite), func(config DatabaseConfig, logger *logrus.Logger) domain.RepositoryFactory {
+		cfg := sqlite.ConfigFromEnv()
+		if config.DSN != "" {
+			cfg.DSN = config.DSN
+		}
+		return sqlite.NewDatabase(cfg, logger)
+	})
+}
+
+// Register 注册数据库类型，之后可通过 FUNCTRACE_DB_TYPE 选择；通常在外部包的 init 中调用
+// 名称为空、包含逗号、constructor 为 nil 或重复注册时 panic
+func Register(name string, constructor Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" || strings.Contains(name, TeeSeparator) {
+		panic("factory: invalid database type name " + strconv.Quote(name))
+	}
+	if constructor == nil {
+		panic("factory: Register constructor is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("factory: Register called twice for " + name)
+	}
+	registry[name] = constructor
+}
+
+// RegisterConfig 注册数据库类型按 DatabaseConfig 创建的方式，未注册的类型在 CreateRepositoryFactoryWithConfig 中只使用 DBType
+// 名称为空、constructor 为 nil 或重复注册时 panic
+func RegisterConfig(name string, constructor ConfigConstructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" {
+		panic("factory: invalid database type name " + strconv.Quote(name))
+	}
+	if constructor == nil {
+		panic("factory: RegisterConfig constructor is nil for " + name)
+	}
+	if _, dup := configured[name]; dup {
+		panic("factory: RegisterConfig called twice for " + name)
+	}
+	configured[name] = constructor
+}
+
+// Registered 返回已注册的数据库类型，按名称排序
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newFactory 按名称创建未初始化的仓储工厂，名称以逗号分隔多个类型时创建同时写入这些后端的 tee
+func newFactory(dbType string, logger *logrus.Logger) (domain.RepositoryFactory, error) {
+	names := strings.Split(dbType, TeeSeparator)
+	constructors := make([]Constructor, 0, len(names))
+	registryMu.RLock()
+	for _, name := range names {
+		constructor, ok := registry[strings.TrimSpace(name)]
+		if !ok {
+			registryMu.RUnlock()
+			return nil, fmt.Errorf("unsupported database type: %s", name)
+		}
+		constructors = append(constructors, constructor)
+	}
+	registryMu.RUnlock()
+
+	backends := make([]domain.RepositoryFactory, 0, len(constructors))
+	for _, constructor := range constructors {
+		backends = append(backends, constructor(logger))
+	}
+	if len(backends) == 1 {
+		return backends[0], nil
+	}
+	return tee.NewTeeDatabase(logger, backends...), nil
+}
+
+// CreateRepositoryFactory 创建并初始化仓储工厂
+// dbType 为已注册的类型名，或以逗号分隔的多个类型（如 "sqlite,remote"），此时写入同时转发到全部后端，查询读第一个
 func CreateRepositoryFactory(dbType string, logger *logrus.Logger) (domain.RepositoryFactory, error) {
-	var factory domain.RepositoryFactory
-
-	// 根据数据库类型返回对应的仓储工厂
-	switch dbType {
-	case "sqlite":
-		factory = sqlite.NewSQLiteDatabase(logger)
-	case "mock":
-		factory = memory.NewMockDatabase(logger)
-	default:
-		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	factory, err := newFactory(dbType, logger)
+	if err != nil {
+		return nil, err
 	}
 
 	// 初始化数据库
@@ -67,6 +175,22 @@ func CreateRepositoryFactory(dbType string, logger *logrus.Logger) (domain.Repos
 	return factory, nil
 }
 
+// CreateRepositoryFactoryWithConfig 按配置创建仓储工厂，DSN 与连接池设置覆盖环境变量（用于 mysql 与 sqlite）
+func CreateRepositoryFactoryWithConfig(config DatabaseConfig, logger *logrus.Logger) (domain.RepositoryFactory, error) {
+	registryMu.RLock()
+	constructor, ok := configured[config.DBType]
+	registryMu.RUnlock()
+	if !ok {
+		return CreateRepositoryFactory(config.DBType, logger)
+	}
+
+	factory := constructor(config, logger)
+	if err := factory.Initialize(); err != nil {
+		return nil, fmt.Errorf("initialize database failed: %w", err)
+	}
+	return factory, nil
+}
+
 // CloseFactory 关闭指定仓储工厂并释放资源
 func CloseFactory(factory domain.RepositoryFactory) error {
 	if factory == nil {
@@ -0,0 +1,59 @@
+package factory
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/memory"
+	"github.com/toheart/functrace/persistence/tee"
+)
+
+// customBackend 由外部包注册的后端
+var customBackend = memory.NewMemDatabase(memory.Config{}, nil)
+
+func init() {
+	Register("custom-test", func(*logrus.Logger) domain.RepositoryFactory { return customBackend })
+}
+
+func TestRegister(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	assert.Contains(t, Registered(), "custom-test")
+	assert.Contains(t, Registered(), string(DBTypeSQLite))
+	// 其他内置后端需匿名导入各自的包才会注册
+	assert.NotContains(t, Registered(), string(DBTypeMySQL))
+
+	db, err := CreateRepositoryFactory("custom-test", logger)
+	require.NoError(t, err)
+	assert.Same(t, customBackend, db)
+
+	assert.Panics(t, func() { Register("custom-test", func(*logrus.Logger) domain.RepositoryFactory { return nil }) })
+	assert.Panics(t, func() { Register("a,b", func(*logrus.Logger) domain.RepositoryFactory { return nil }) })
+	assert.Panics(t, func() { Register("nil-test", nil) })
+
+	_, err = CreateRepositoryFactory("memory,unknown", logger)
+	assert.EqualError(t, err, "unsupported database type: unknown")
+}
+
+func TestCreateTee(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db, err := CreateRepositoryFactory("memory, custom-test", logger)
+	require.NoError(t, err)
+	defer db.Close()
+	td, ok := db.(*tee.TeeDatabase)
+	require.True(t, ok)
+	require.Len(t, td.Backends(), 2)
+	assert.Same(t, customBackend, td.Backends()[1])
+
+	_, err = db.GetTraceRepository().SaveTrace(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", ""))
+	require.NoError(t, err)
+	saved, err := customBackend.FindTraceByID(1)
+	require.NoError(t, err)
+	assert.NotNil(t, saved)
+}
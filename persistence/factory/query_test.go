@@ -0,0 +1,181 @@
+package factory
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/memory"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+var queryBase = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// queryBackends 返回实现了 domain.TraceQueryReader 的全部后端
+func queryBackends(t *testing.T) map[string]domain.RepositoryFactory {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "query.db"), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return map[string]domain.RepositoryFactory{
+		"sqlite": db,
+		"memory": memory.NewMemDatabase(memory.Config{}, logger),
+	}
+}
+
+// writeQueryFixture 写入两个协程的调用树，第 i 个调用在 queryBase+i 秒开始
+//
+//	gid 1: 1 main.main(10ms) -> 2 main.a(5ms), 3 main.b(未完成) -> 4 main.a(5ms) -> 5 main.c(1ms)
+//	gid 2: 6 main.a(20ms)
+func writeQueryFixture(t *testing.T, db domain.RepositoryFactory) {
+	t.Helper()
+	calls := []struct {
+		id       int64
+		name     string
+		gid      uint64
+		indent   int
+		parent   int64
+		timeCost string
+	}{
+		{1, "main.main", 1, 0, 0, "10ms"},
+		{2, "main.a", 1, 1, 1, "5ms"},
+		{3, "main.b", 1, 1, 1, ""},
+		{4, "main.a", 1, 2, 3, "5ms"},
+		{5, "main.c", 1, 3, 4, "1ms"},
+		{6, "main.a", 2, 0, 0, "20ms"},
+	}
+	for _, c := range calls {
+		created := queryBase.Add(time.Duration(c.id) * time.Second).Format(time.RFC3339Nano)
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(c.id, c.name, c.gid, c.indent, 0, c.parent, created, ""))
+		require.NoError(t, err)
+		if c.timeCost != "" {
+			require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(c.id, c.timeCost))
+		}
+	}
+
+	goroutines := []*model.GoroutineTrace{
+		model.NewGoroutineTrace(1, 100, "", 1, "main.main"),
+		model.NewGoroutineTrace(2, 101, "", 0, "main.worker"),
+		model.NewGoroutineTrace(3, 102, "", 1, "main.worker"),
+	}
+	goroutines[1].CreatorGID, goroutines[1].ParentTraceID = 100, 3
+	goroutines[2].CreatorGID = 100
+	for _, g := range goroutines {
+		_, err := db.GetGoroutineRepository().SaveGoroutine(g)
+		require.NoError(t, err)
+	}
+}
+
+func traceIDs(page *model.TracePage) []int64 {
+	ids := []int64{}
+	for _, td := range page.Traces {
+		ids = append(ids, td.ID)
+	}
+	return ids
+}
+
+// collectPages 按游标读取全部页，返回每页的ID
+func collectPages(t *testing.T, fetch func(page model.PageRequest) (*model.TracePage, error), limit int) [][]int64 {
+	t.Helper()
+	var pages [][]int64
+	req := model.PageRequest{Limit: limit}
+	for {
+		page, err := fetch(req)
+		require.NoError(t, err)
+		pages = append(pages, traceIDs(page))
+		if page.NextCursor == "" {
+			return pages
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+func TestTraceQueryReader(t *testing.T) {
+	for name, db := range queryBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			writeQueryFixture(t, db)
+			reader, ok := db.(domain.TraceQueryReader)
+			require.True(t, ok)
+
+			pages := collectPages(t, func(p model.PageRequest) (*model.TracePage, error) {
+				return reader.FindTraces(model.TraceQuery{Name: "main.a"}, p)
+			}, 2)
+			assert.Equal(t, [][]int64{{2, 4}, {6}}, pages)
+
+			page, err := reader.FindTraces(model.TraceQuery{GID: 1, MinDuration: 5 * time.Millisecond}, model.PageRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1, 2, 4}, traceIDs(page))
+
+			page, err = reader.FindTraces(model.TraceQuery{Since: queryBase.Add(2 * time.Second), Until: queryBase.Add(5 * time.Second)}, model.PageRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2, 3, 4}, traceIDs(page))
+
+			// 耗时相同时按ID升序，未完成的调用不参与
+			pages = collectPages(t, func(p model.PageRequest) (*model.TracePage, error) {
+				return reader.FindSlowestTraces(model.TraceQuery{}, p)
+			}, 2)
+			assert.Equal(t, [][]int64{{6, 1}, {2, 4}, {5}}, pages)
+
+			page, err = reader.FindSlowestTraces(model.TraceQuery{Name: "main.a", GID: 1}, model.PageRequest{Limit: 1})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2}, traceIDs(page))
+			assert.NotEmpty(t, page.NextCursor)
+
+			pages = collectPages(t, func(p model.PageRequest) (*model.TracePage, error) {
+				return reader.ListSubtree(3, p)
+			}, 2)
+			assert.Equal(t, [][]int64{{3, 4}, {5}}, pages)
+			page, err = reader.ListSubtree(99, model.PageRequest{})
+			require.NoError(t, err)
+			assert.Empty(t, page.Traces)
+
+			page, err = reader.ListTraceChildren(1, model.PageRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2, 3}, traceIDs(page))
+			assert.Empty(t, page.NextCursor)
+
+			td, err := reader.FindTraceByID(4)
+			require.NoError(t, err)
+			assert.Equal(t, "5ms", td.TimeCost)
+
+			_, err = reader.FindTraces(model.TraceQuery{}, model.PageRequest{Cursor: "not a cursor"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFindGoroutines(t *testing.T) {
+	for name, db := range queryBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			writeQueryFixture(t, db)
+			reader := db.(domain.TraceQueryReader)
+
+			page, err := reader.FindGoroutines(model.GoroutineQuery{State: model.GoroutineStateRunning}, model.PageRequest{})
+			require.NoError(t, err)
+			require.Len(t, page.Goroutines, 1)
+			assert.Equal(t, int64(2), page.Goroutines[0].ID)
+
+			page, err = reader.FindGoroutines(model.GoroutineQuery{InitFuncName: "main.worker", CreatorGID: 100}, model.PageRequest{Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, page.Goroutines, 1)
+			assert.Equal(t, int64(2), page.Goroutines[0].ID)
+			page, err = reader.FindGoroutines(model.GoroutineQuery{InitFuncName: "main.worker", CreatorGID: 100}, model.PageRequest{Limit: 1, Cursor: page.NextCursor})
+			require.NoError(t, err)
+			require.Len(t, page.Goroutines, 1)
+			assert.Equal(t, int64(3), page.Goroutines[0].ID)
+			assert.Empty(t, page.NextCursor)
+
+			page, err = reader.FindGoroutines(model.GoroutineQuery{State: model.GoroutineStateFinished, OriginGID: 100}, model.PageRequest{})
+			require.NoError(t, err)
+			require.Len(t, page.Goroutines, 1)
+			assert.Equal(t, "main.main", page.Goroutines[0].InitFuncName)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Package jsonl 将跟踪数据以 JSON Lines 事件流的形式追加写入滚动文件，便于接入日志管道
+package jsonl
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config JSONL 后端配置
+type Config struct {
+	Dir           string        // 输出目录
+	Prefix        string        // 文件名前缀，默认为可执行文件名
+	MaxSize       int64         // 单个文件的最大字节数（压缩前），超过后滚动到新文件
+	MaxFiles      int           // 最多保留的文件数，0 表示全部保留
+	Compress      bool          // 使用 zstd 压缩，文件名追加 .zst
+	FlushInterval time.Duration // 缓冲区定期刷盘间隔
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() Config {
+	return Config{
+		Dir:           DefaultDir,
+		MaxSize:       DefaultMaxSizeMB << 20,
+		FlushInterval: DefaultFlushInterval,
+	}
+}
+
+// ConfigFromEnv 在默认配置基础上读取 FUNCTRACE_JSONL_* 环境变量
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v := os.Getenv(EnvDir); v != "" {
+		cfg.Dir = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv(EnvMaxSize), 10, 64); err == nil && v > 0 {
+		cfg.MaxSize = v << 20
+	}
+	if v, err := strconv.Atoi(os.Getenv(EnvMaxFiles)); err == nil && v >= 0 {
+		cfg.MaxFiles = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv(EnvCompress)); err == nil {
+		cfg.Compress = v
+	}
+	return cfg
+}
@@ -0,0 +1,23 @@
+package jsonl
+
+import "time"
+
+// 环境变量
+const (
+	EnvDir      = "FUNCTRACE_JSONL_DIR"       // 输出目录
+	EnvMaxSize  = "FUNCTRACE_JSONL_MAX_SIZE"  // 单个文件的最大大小（MB，压缩前）
+	EnvMaxFiles = "FUNCTRACE_JSONL_MAX_FILES" // 最多保留的文件数
+	EnvCompress = "FUNCTRACE_JSONL_COMPRESS"  // 是否使用 zstd 压缩
+)
+
+// 默认值
+const (
+	DefaultDir           = "."
+	DefaultMaxSizeMB     = 100
+	DefaultFlushInterval = time.Second
+
+	// 文件名格式：<可执行文件名>_<启动时间>.<序号>.jsonl[.zst]
+	FileNameFormat = "%s.%06d.jsonl"
+	FileExt        = ".jsonl"
+	CompressExt    = ".zst"
+)
@@ -0,0 +1,170 @@
+package jsonl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/domain/record"
+)
+
+// ErrQueryNotSupported JSONL 后端只追加写入，不支持历史数据查询
+var ErrQueryNotSupported = errors.New("query is not supported by the jsonl backend")
+
+// 确保JSONLDatabase实现了IDatabase接口
+var _ domain.RepositoryFactory = (*JSONLDatabase)(nil)
+
+// LineWriter JSONL 行的输出目标，默认为按大小滚动的本地文件
+type LineWriter interface {
+	// WriteLine 写入一行（不含换行符），实现需复制 line
+	WriteLine(line []byte) error
+	// Flush 将缓冲的行尽快写出
+	Flush() error
+	// Close 写出剩余数据并释放资源
+	Close() error
+}
+
+// JSONLDatabase JSON Lines 仓储实现
+// 写入只追加事件；跟踪运行时需要回读的协程与参数缓存保留在内存索引中
+type JSONLDatabase struct {
+	config              Config
+	logger              *logrus.Logger
+	writer              LineWriter
+	traceRepository     *TraceRepository
+	paramRepository     *ParamRepository
+	goroutineRepository *GoroutineRepository
+	stop                chan struct{}
+	wg                  sync.WaitGroup
+}
+
+// NewJSONLDatabase 创建新的JSONL仓储工厂
+func NewJSONLDatabase(config Config, logger *logrus.Logger) domain.RepositoryFactory {
+	return &JSONLDatabase{
+		config: config,
+		logger: logger,
+	}
+}
+
+// NewStreamDatabase 使用自定义输出目标创建仓储工厂（如网络发送），Config 中的文件相关配置不生效
+func NewStreamDatabase(w LineWriter, config Config, logger *logrus.Logger) *JSONLDatabase {
+	return &JSONLDatabase{
+		config: config,
+		logger: logger,
+		writer: w,
+	}
+}
+
+// Initialize 创建输出目录并启动定期刷盘
+func (d *JSONLDatabase) Initialize() error {
+	if d.writer == nil {
+		if err := d.openFiles(); err != nil {
+			return err
+		}
+	}
+
+	d.traceRepository = &TraceRepository{db: d}
+	d.paramRepository = &ParamRepository{db: d, caches: make(map[string]*model.ParamCache)}
+	d.goroutineRepository = &GoroutineRepository{db: d, goroutines: make(map[int64]*model.GoroutineTrace)}
+
+	interval := d.config.FlushInterval
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go d.flushLoop(interval)
+	return nil
+}
+
+// openFiles 创建输出目录与滚动文件写入器
+func (d *JSONLDatabase) openFiles() error {
+	dir := d.config.Dir
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create jsonl dir error: %w", err)
+	}
+	prefix := d.config.Prefix
+	if prefix == "" {
+		prefix = defaultPrefix()
+	}
+	base := fmt.Sprintf("%s_%s", prefix, time.Now().Format("20060102150405"))
+	d.writer = newRotatingWriter(dir, base, d.config.MaxSize, d.config.MaxFiles, d.config.Compress)
+	d.logger.Infof("writing jsonl: %s", filepath.Join(dir, base))
+	return nil
+}
+
+// flushLoop 定期将缓冲区写入文件
+func (d *JSONLDatabase) flushLoop(interval time.Duration) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.writer.Flush(); err != nil {
+				d.logger.WithFields(logrus.Fields{"error": err}).Error("flush jsonl failed")
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Close 停止刷盘并关闭当前文件
+func (d *JSONLDatabase) Close() error {
+	if d.writer == nil {
+		return nil
+	}
+	if d.stop != nil {
+		close(d.stop)
+		d.wg.Wait()
+		d.stop = nil
+	}
+	return d.writer.Close()
+}
+
+// Files 返回已写入且仍保留的文件路径，按写入顺序
+func (d *JSONLDatabase) Files() []string {
+	if w, ok := d.writer.(*rotatingWriter); ok {
+		return w.Files()
+	}
+	return nil
+}
+
+func (d *JSONLDatabase) GetTraceRepository() domain.TraceRepository {
+	return d.traceRepository
+}
+
+func (d *JSONLDatabase) GetParamRepository() domain.ParamRepository {
+	return d.paramRepository
+}
+
+func (d *JSONLDatabase) GetGoroutineRepository() domain.GoroutineRepository {
+	return d.goroutineRepository
+}
+
+// append 编码并追加一条事件
+func (d *JSONLDatabase) append(op, table string, data interface{}) error {
+	line, err := record.Encode(op, table, data)
+	if err != nil {
+		return err
+	}
+	return d.writer.WriteLine(line)
+}
+
+// defaultPrefix 默认文件名前缀为可执行文件名
+func defaultPrefix() string {
+	execName, err := os.Executable()
+	if err != nil {
+		return "default"
+	}
+	return filepath.Base(execName)
+}
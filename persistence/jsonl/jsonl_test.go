@@ -0,0 +1,198 @@
+package jsonl
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/domain/record"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func openTestDB(t *testing.T, cfg Config) *JSONLDatabase {
+	t.Helper()
+	cfg.Prefix = "test"
+	db := NewJSONLDatabase(cfg, testLogger()).(*JSONLDatabase)
+	require.NoError(t, db.Initialize())
+	return db
+}
+
+// writeRun 模拟一次跟踪运行写入的事件
+func writeRun(t *testing.T, db *JSONLDatabase) {
+	t.Helper()
+	g := model.NewGoroutineTrace(1, 17, "2025-01-01T00:00:00Z", 0, "main.main")
+	_, err := db.GetGoroutineRepository().SaveGoroutine(g)
+	require.NoError(t, err)
+
+	traces := db.GetTraceRepository()
+	_, err = traces.SaveTrace(model.NewTraceData(1, "main.main", 1, 0, 1, 0, "2025-01-01T00:00:00Z", ""))
+	require.NoError(t, err)
+	_, err = traces.SaveTrace(model.NewTraceData(2, "main.work", 1, 1, 0, 1, "2025-01-01T00:00:01Z", ""))
+	require.NoError(t, err)
+
+	params := db.GetParamRepository()
+	_, err = params.SaveParam(model.NewParamStoreData(1, 0, []byte(`{"a":1}`), false, 0).WithID(10))
+	require.NoError(t, err)
+	_, err = params.SaveParamCache(model.NewParamCache("0xc000", 10, []byte(`{"a":1}`)))
+	require.NoError(t, err)
+	require.NoError(t, params.DeleteParamCacheByAddr("0xc000"))
+
+	require.NoError(t, traces.UpdateTraceTimeCost(2, "1s"))
+	require.NoError(t, traces.UpdateTraceTimeCost(1, "2s"))
+	require.NoError(t, db.GetGoroutineRepository().UpdateGoroutineTimeCost(1, "2s", 1))
+}
+
+func TestInMemoryLookups(t *testing.T) {
+	db := openTestDB(t, Config{Dir: t.TempDir()})
+	defer db.Close()
+
+	goroutines := db.GetGoroutineRepository()
+	_, err := goroutines.SaveGoroutine(model.NewGoroutineTrace(3, 42, "2025-01-01T00:00:00Z", 0, "main.worker"))
+	require.NoError(t, err)
+	g, err := goroutines.FindGoroutineByID(3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), g.OriginGID)
+
+	require.NoError(t, goroutines.UpdateGoroutineTimeCost(3, "1s", 1))
+	_, err = goroutines.FindGoroutineByID(3)
+	assert.Error(t, err)
+
+	params := db.GetParamRepository()
+	_, err = params.SaveParamCache(model.NewParamCache("0x1", 5, []byte("{}")))
+	require.NoError(t, err)
+	cache, err := params.FindParamCacheByAddr("0x1")
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	assert.Equal(t, int64(5), cache.BaseID)
+
+	require.NoError(t, params.DeleteParamCacheByAddr("0x1"))
+	cache, err = params.FindParamCacheByAddr("0x1")
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+
+	_, err = db.GetTraceRepository().FindRootFunctionsByGID(1)
+	assert.ErrorIs(t, err, ErrQueryNotSupported)
+}
+
+func TestRotationAndMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, Config{Dir: dir, MaxSize: 200, MaxFiles: 2})
+	for i := int64(1); i <= 20; i++ {
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(i, "main.loop", 1, 0, 0, 0, "2025-01-01T00:00:00Z", ""))
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+
+	files, err := ListFiles(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, db.Files(), files)
+	assert.True(t, strings.HasSuffix(files[1], ".jsonl"))
+}
+
+func TestLoadIntoSQLite(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "zstd"}[compress], func(t *testing.T) {
+			dir := t.TempDir()
+			db := openTestDB(t, Config{Dir: dir, MaxSize: 300, Compress: compress})
+			writeRun(t, db)
+			require.NoError(t, db.Close())
+
+			files, err := ListFiles(dir)
+			require.NoError(t, err)
+			require.Greater(t, len(files), 1, "expected rotation")
+			if compress {
+				assert.True(t, strings.HasSuffix(files[0], ".jsonl.zst"))
+			}
+
+			target, err := sqlite.Open(filepath.Join(t.TempDir(), "out.db"), testLogger())
+			require.NoError(t, err)
+			defer target.Close()
+
+			n, err := LoadFiles(files, target)
+			require.NoError(t, err)
+			assert.Equal(t, 9, n)
+
+			var traces []*model.TraceData
+			require.NoError(t, target.ScanTraces(func(td *model.TraceData) error {
+				traces = append(traces, td)
+				return nil
+			}))
+			require.Len(t, traces, 2)
+			assert.Equal(t, "main.work", traces[1].Name)
+			assert.Equal(t, int64(1), traces[1].ParentId)
+			assert.Equal(t, "1s", traces[1].TimeCost)
+			assert.Equal(t, 1, traces[0].IsFinished)
+
+			var goroutines []*model.GoroutineTrace
+			require.NoError(t, target.ScanGoroutines(func(g *model.GoroutineTrace) error {
+				goroutines = append(goroutines, g)
+				return nil
+			}))
+			require.Len(t, goroutines, 1)
+			assert.Equal(t, "2s", goroutines[0].TimeCost)
+			assert.Equal(t, 1, goroutines[0].IsFinished)
+
+			params, err := target.GetParamRepository().FindParamsByTraceID(1)
+			require.NoError(t, err)
+			require.Len(t, params, 1)
+			assert.Equal(t, `{"a":1}`, string(params[0].Data))
+
+			cache, err := target.GetParamRepository().FindParamCacheByAddr("0xc000")
+			require.NoError(t, err)
+			assert.Nil(t, cache)
+		})
+	}
+}
+
+func TestLoadRejectsUnknownRecord(t *testing.T) {
+	target, err := sqlite.Open(filepath.Join(t.TempDir(), "out.db"), testLogger())
+	require.NoError(t, err)
+	defer target.Close()
+
+	_, err = Load(strings.NewReader("{\"op\":\"insert\",\"table\":\"trace\",\"data\":{\"id\":1}}\n{\"op\":\"drop\",\"table\":\"trace\"}\n"), target)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestLoadUpsertOverwritesOpenRow(t *testing.T) {
+	target, err := sqlite.Open(filepath.Join(t.TempDir(), "out.db"), testLogger())
+	require.NoError(t, err)
+	defer target.Close()
+
+	open := model.NewTraceData(1, "main.main", 1, 0, 0, 0, "2025-01-01T00:00:00Z", "0.00")
+	finished := *open
+	finished.TimeCost, finished.IsFinished = "3s", 1
+	var input strings.Builder
+	for _, r := range []struct {
+		op   string
+		data interface{}
+	}{{record.OpInsert, open}, {record.OpUpsert, &finished}} {
+		line, err := record.Encode(r.op, record.TableTrace, r.data)
+		require.NoError(t, err)
+		input.Write(line)
+		input.WriteByte('\n')
+	}
+
+	n, err := Load(strings.NewReader(input.String()), target)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	got, err := target.FindTraceByID(1)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "main.main", got.Name)
+	assert.Equal(t, "3s", got.TimeCost)
+	assert.Equal(t, 1, got.IsFinished)
+}
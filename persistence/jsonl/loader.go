@@ -0,0 +1,95 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/record"
+)
+
+// ListFiles 返回目录下全部 JSONL 文件（含 .zst），按文件名即写入顺序排序
+func ListFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read jsonl dir error: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasSuffix(name, FileExt) || strings.HasSuffix(name, FileExt+CompressExt)) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadFiles 按顺序将多个 JSONL 文件导入目标仓储，.zst 文件自动解压，返回应用的事件数
+func LoadFiles(paths []string, target domain.RepositoryFactory) (int, error) {
+	total := 0
+	for _, path := range paths {
+		n, err := loadFile(path, target)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("load %s error: %w", path, err)
+		}
+	}
+	return total, nil
+}
+
+func loadFile(path string, target domain.RepositoryFactory) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open jsonl file error: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, CompressExt) {
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return 0, fmt.Errorf("create zstd reader error: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	return Load(r, target)
+}
+
+// Load 将 JSONL 事件流依次应用到目标仓储（如 SQLite），返回应用的事件数
+func Load(r io.Reader, target domain.RepositoryFactory) (int, error) {
+	a := record.NewApplier(target)
+	br := bufio.NewReaderSize(r, 64<<10)
+	lineNo, applied := 0, 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			lineNo++
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				if aerr := a.Apply(line); aerr != nil {
+					return applied, fmt.Errorf("line %d: %w", lineNo, aerr)
+				}
+				applied++
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return applied, fmt.Errorf("read jsonl error: %w", err)
+		}
+	}
+	if err := a.Flush(); err != nil {
+		return applied, err
+	}
+	return applied, nil
+}
@@ -0,0 +1,14 @@
+package jsonl
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/persistence/factory"
+)
+
+// 匿名导入本包即可通过 FUNCTRACE_DB_TYPE=jsonl 选择该后端
+func init() {
+	factory.Register(string(factory.DBTypeJSONL), func(logger *logrus.Logger) domain.RepositoryFactory {
+		return NewJSONLDatabase(ConfigFromEnv(), logger)
+	})
+}
@@ -0,0 +1,160 @@
+package jsonl
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/domain/record"
+)
+
+var _ domain.TraceRepository = (*TraceRepository)(nil)
+var _ domain.ParamRepository = (*ParamRepository)(nil)
+var _ domain.GoroutineRepository = (*GoroutineRepository)(nil)
+
+// TraceRepository 是JSONL实现的跟踪数据仓储
+type TraceRepository struct {
+	db *JSONLDatabase
+}
+
+// SaveTrace 追加跟踪数据插入事件
+func (r *TraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
+	if err := r.db.append(record.OpInsert, record.TableTrace, trace); err != nil {
+		return 0, fmt.Errorf("save trace error: %w", err)
+	}
+	return trace.ID, nil
+}
+
+// UpdateTraceTimeCost 追加跟踪数据完成事件
+func (r *TraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
+	if err := r.db.append(record.OpUpdate, record.TableTrace, record.TraceUpdate{ID: id, TimeCost: timeCost, IsFinished: 1}); err != nil {
+		return fmt.Errorf("update trace time cost error: %w", err)
+	}
+	return nil
+}
+
+// FindRootFunctionsByGID 不支持查询
+func (r *TraceRepository) FindRootFunctionsByGID(gid uint64) ([]model.TraceData, error) {
+	return nil, ErrQueryNotSupported
+}
+
+// ParamRepository 是JSONL实现的参数数据仓储
+// 参数缓存在内存中保留一份，供指针接收者增量存储时回读
+type ParamRepository struct {
+	db     *JSONLDatabase
+	mu     sync.RWMutex
+	caches map[string]*model.ParamCache
+	nextID int64
+}
+
+// SaveParam 追加参数插入事件
+func (r *ParamRepository) SaveParam(param *model.ParamStoreData) (int64, error) {
+	if err := r.db.append(record.OpInsert, record.TableParam, param); err != nil {
+		return 0, fmt.Errorf("save param error: %w", err)
+	}
+	return param.ID, nil
+}
+
+// SaveParamsBatch 依次追加参数插入事件
+func (r *ParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	for _, p := range params {
+		if err := r.db.append(record.OpInsert, record.TableParam, p); err != nil {
+			return fmt.Errorf("batch save param error: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindParamsByTraceID 不支持查询
+func (r *ParamRepository) FindParamsByTraceID(traceId int64) ([]model.ParamStoreData, error) {
+	return nil, ErrQueryNotSupported
+}
+
+// SaveParamCache 追加参数缓存事件并更新内存索引（同地址覆盖）
+func (r *ParamRepository) SaveParamCache(cache *model.ParamCache) (int64, error) {
+	r.mu.Lock()
+	r.nextID++
+	stored := *cache
+	stored.ID = r.nextID
+	r.caches[cache.Addr] = &stored
+	r.mu.Unlock()
+
+	if err := r.db.append(record.OpInsert, record.TableParamCache, &stored); err != nil {
+		return 0, fmt.Errorf("save param cache error: %w", err)
+	}
+	return stored.ID, nil
+}
+
+// FindParamCacheByAddr 从内存索引查找参数缓存，未找到时返回 nil
+func (r *ParamRepository) FindParamCacheByAddr(addr string) (*model.ParamCache, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cache, ok := r.caches[addr]
+	if !ok {
+		return nil, nil
+	}
+	c := *cache
+	return &c, nil
+}
+
+// DeleteParamCacheByAddr 追加参数缓存删除事件并移出内存索引
+func (r *ParamRepository) DeleteParamCacheByAddr(addr string) error {
+	r.mu.Lock()
+	delete(r.caches, addr)
+	r.mu.Unlock()
+
+	if err := r.db.append(record.OpDelete, record.TableParamCache, record.ParamCacheDelete{Addr: addr}); err != nil {
+		return fmt.Errorf("delete param cache by addr error: %w", err)
+	}
+	return nil
+}
+
+// GoroutineRepository 是JSONL实现的协程数据仓储
+// 未结束的协程在内存中保留一份，结束时移除
+type GoroutineRepository struct {
+	db         *JSONLDatabase
+	mu         sync.RWMutex
+	goroutines map[int64]*model.GoroutineTrace
+}
+
+// SaveGoroutine 追加协程插入事件
+func (r *GoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (int64, error) {
+	g := *goroutine
+	r.mu.Lock()
+	r.goroutines[g.ID] = &g
+	r.mu.Unlock()
+
+	if err := r.db.append(record.OpInsert, record.TableGoroutine, &g); err != nil {
+		return 0, fmt.Errorf("save goroutine error: %w", err)
+	}
+	return g.ID, nil
+}
+
+// UpdateGoroutineTimeCost 追加协程更新事件
+func (r *GoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
+	r.mu.Lock()
+	if isFinished != 0 {
+		delete(r.goroutines, id)
+	} else if g, ok := r.goroutines[id]; ok {
+		g.TimeCost = timeCost
+	}
+	r.mu.Unlock()
+
+	if err := r.db.append(record.OpUpdate, record.TableGoroutine, record.GoroutineUpdate{ID: id, TimeCost: timeCost, IsFinished: isFinished}); err != nil {
+		return fmt.Errorf("update goroutine time cost error: %w", err)
+	}
+	return nil
+}
+
+// FindGoroutineByID 从内存索引查找未结束的协程
+func (r *GoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.goroutines[id]
+	if !ok {
+		return nil, fmt.Errorf("goroutine data not found: id=%d", id)
+	}
+	c := *g
+	return &c, nil
+}
@@ -0,0 +1,159 @@
+package jsonl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// rotatingWriter 按大小滚动的行写入器，可选 zstd 压缩
+type rotatingWriter struct {
+	mu       sync.Mutex
+	dir      string
+	base     string // 不含序号的文件名前缀，如 app_20250101120000
+	maxSize  int64
+	maxFiles int
+	compress bool
+
+	seq   int
+	size  int64
+	file  *os.File
+	zw    *zstd.Encoder
+	buf   *bufio.Writer
+	files []string // 已创建的文件，按创建顺序
+}
+
+// newRotatingWriter 创建写入器，首个文件在第一次写入时创建
+func newRotatingWriter(dir, base string, maxSize int64, maxFiles int, compress bool) *rotatingWriter {
+	return &rotatingWriter{
+		dir:      dir,
+		base:     base,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		compress: compress,
+	}
+}
+
+// WriteLine 写入一行（line 不含换行符），必要时先滚动到新文件
+func (w *rotatingWriter) WriteLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := int64(len(line) + 1)
+	if w.file == nil || (w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.buf.Write(line); err != nil {
+		return fmt.Errorf("write jsonl error: %w", err)
+	}
+	if err := w.buf.WriteByte('\n'); err != nil {
+		return fmt.Errorf("write jsonl error: %w", err)
+	}
+	w.size += n
+	return nil
+}
+
+// Flush 将缓冲区写入文件（压缩模式下结束当前 zstd 块）
+func (w *rotatingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Close 刷盘并关闭当前文件
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+// Files 返回已创建且仍保留的文件路径
+func (w *rotatingWriter) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.files...)
+}
+
+func (w *rotatingWriter) flush() error {
+	if w.buf == nil {
+		return nil
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("flush jsonl error: %w", err)
+	}
+	if w.zw != nil {
+		if err := w.zw.Flush(); err != nil {
+			return fmt.Errorf("flush zstd error: %w", err)
+		}
+	}
+	return nil
+}
+
+// rotate 关闭当前文件并创建下一个文件，超过保留数量时删除最旧的文件
+func (w *rotatingWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	w.seq++
+	name := fmt.Sprintf(FileNameFormat, w.base, w.seq)
+	if w.compress {
+		name += CompressExt
+	}
+	path := filepath.Join(w.dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create jsonl file error: %w", err)
+	}
+	var out io.Writer = f
+	if w.compress {
+		zw, err := zstd.NewWriter(f, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("create zstd writer error: %w", err)
+		}
+		w.zw = zw
+		out = zw
+	}
+	w.file = f
+	w.buf = bufio.NewWriterSize(out, 64<<10)
+	w.size = 0
+	w.files = append(w.files, path)
+
+	for w.maxFiles > 0 && len(w.files) > w.maxFiles {
+		if err := os.Remove(w.files[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove old jsonl file error: %w", err)
+		}
+		w.files = w.files[1:]
+	}
+	return nil
+}
+
+// closeFile 刷盘、同步并关闭当前文件
+func (w *rotatingWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.flush()
+	if w.zw != nil {
+		if cerr := w.zw.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("close zstd writer error: %w", cerr)
+		}
+		w.zw = nil
+	}
+	if serr := w.file.Sync(); serr != nil && err == nil {
+		err = fmt.Errorf("sync jsonl file error: %w", serr)
+	}
+	if cerr := w.file.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("close jsonl file error: %w", cerr)
+	}
+	w.file = nil
+	w.buf = nil
+	return err
+}
@@ -0,0 +1,26 @@
+// Package memory 将跟踪数据保存在进程内存中，适合单元测试与短时运行的场景
+package memory
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	// EnvMaxTraces 内存后端最多保留的跟踪数据条数
+	EnvMaxTraces = "FUNCTRACE_MEMORY_MAX_TRACES"
+)
+
+// Config 内存后端配置
+type Config struct {
+	MaxTraces int // 最多保留的跟踪数据条数，超过后按写入顺序淘汰最早的记录及其参数与事件；0 表示不限制
+}
+
+// ConfigFromEnv 读取 FUNCTRACE_MEMORY_* 环境变量
+func ConfigFromEnv() Config {
+	var cfg Config
+	if v, err := strconv.Atoi(os.Getenv(EnvMaxTraces)); err == nil && v > 0 {
+		cfg.MaxTraces = v
+	}
+	return cfg
+}
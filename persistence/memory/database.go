@@ -1,55 +1,146 @@
 package memory
 
 import (
+	"sort"
+	"sync"
+
 	"github.com/sirupsen/logrus"
 	"github.com/toheart/functrace/domain"
 	"github.com/toheart/functrace/domain/model"
 )
 
-// 确保MockDatabase实现了IDatabase接口
+// 确保MemDatabase实现了IDatabase接口及可选的查询能力
 var _ domain.RepositoryFactory = (*MemDatabase)(nil)
+var _ domain.TraceScanner = (*MemDatabase)(nil)
+var _ domain.TraceTreeReader = (*MemDatabase)(nil)
+var _ domain.TraceQueryReader = (*MemDatabase)(nil)
+var _ domain.StatsRepositoryProvider = (*MemDatabase)(nil)
+var _ domain.EventRepositoryProvider = (*MemDatabase)(nil)
+var _ domain.LeakRepositoryProvider = (*MemDatabase)(nil)
+var _ domain.RunRepositoryProvider = (*MemDatabase)(nil)
+
+// idSet ID集合，查询时按升序输出
+type idSet map[int64]struct{}
+
+// sorted 返回升序排列的ID
+func (s idSet) sorted() []int64 {
+	ids := make([]int64, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
 
-// MemDatabase 模拟数据库实现
+// MemStats 内存后端的记录数量
+type MemStats struct {
+	Traces     int    // 当前保留的跟踪数据条数
+	Params     int    // 当前保留的参数条数
+	Goroutines int    // 协程数
+	Events     int    // 当前保留的事件数
+	Evicted    uint64 // 因超出 MaxTraces 被淘汰的跟踪数据条数
+}
+
+// MemDatabase 内存数据库实现
+// 全部数据由一把读写锁保护，写入与读出时都会复制，调用方可以继续复用自己的对象
 type MemDatabase struct {
-	logger              *logrus.Logger
-	traceData           map[int64]*model.TraceData
-	params              map[int64]*model.ParamStoreData
-	goroutines          map[int64]*model.GoroutineTrace
+	config Config
+	logger *logrus.Logger
+
+	mu         sync.RWMutex
+	traces     map[int64]*model.TraceData
+	order      []int64 // 跟踪数据的写入顺序，用于淘汰
+	head       int     // order 中下一个待淘汰的位置
+	byGID      map[uint64]idSet
+	children   map[int64]idSet // parentId -> 子调用
+	params     map[int64][]*model.ParamStoreData
+	paramCount int
+	paramSeq   int64
+	caches     map[string]*model.ParamCache
+	cacheSeq   int64
+	goroutines map[int64]*model.GoroutineTrace
+	spawned    map[int64]idSet // parentTraceId -> 协程
+	events     map[int64][]*model.TraceEvent
+	eventCount int
+	eventSeq   int64
+	funcStats  map[string]*model.FuncStats
+	leaks      []*model.LeakReport
+	runs       []*model.Run
+	evicted    uint64
+	maxEvicted int64 // 已淘汰的最大跟踪ID
+
 	traceRepository     *MemTraceRepository
 	paramRepository     *MemParamRepository
 	goroutineRepository *MemGoroutineRepository
-	nextID              int64
 }
 
-// NewMockDatabase 创建新的模拟数据库
+// NewMemDatabase 创建新的内存数据库
+func NewMemDatabase(config Config, logger *logrus.Logger) *MemDatabase {
+	m := &MemDatabase{config: config, logger: logger}
+	m.reset()
+	m.traceRepository = &MemTraceRepository{db: m}
+	m.paramRepository = &MemParamRepository{db: m}
+	m.goroutineRepository = &MemGoroutineRepository{db: m}
+	return m
+}
+
+// NewMockDatabase 按环境变量创建内存数据库，保留给 mock 类型使用
 func NewMockDatabase(logger *logrus.Logger) domain.RepositoryFactory {
-	return &MemDatabase{
-		logger:              logger,
-		traceData:           make(map[int64]*model.TraceData),
-		params:              make(map[int64]*model.ParamStoreData),
-		goroutines:          make(map[int64]*model.GoroutineTrace),
-		traceRepository:     NewMockTraceRepository(logger),
-		paramRepository:     NewMockParamRepository(logger),
-		goroutineRepository: NewMockGoroutineRepository(logger),
-		nextID:              1,
-	}
+	return NewMemDatabase(ConfigFromEnv(), logger)
+}
+
+// reset 清空全部数据
+func (m *MemDatabase) reset() {
+	m.traces = make(map[int64]*model.TraceData)
+	m.order = nil
+	m.head = 0
+	m.byGID = make(map[uint64]idSet)
+	m.children = make(map[int64]idSet)
+	m.params = make(map[int64][]*model.ParamStoreData)
+	m.paramCount = 0
+	m.paramSeq = 0
+	m.caches = make(map[string]*model.ParamCache)
+	m.cacheSeq = 0
+	m.goroutines = make(map[int64]*model.GoroutineTrace)
+	m.spawned = make(map[int64]idSet)
+	m.events = make(map[int64][]*model.TraceEvent)
+	m.eventCount = 0
+	m.eventSeq = 0
+	m.funcStats = make(map[string]*model.FuncStats)
+	m.leaks = nil
+	m.runs = nil
+	m.evicted = 0
+	m.maxEvicted = 0
 }
 
 // Initialize 初始化数据库
 func (m *MemDatabase) Initialize() error {
-	return nil // 模拟实现无需初始化
+	return nil // 内存实现无需初始化
 }
 
-// Close 关闭数据库连接
+// Close 关闭数据库，保留已记录的数据供调用方在关闭后读取
 func (m *MemDatabase) Close() error {
-	return nil // 模拟实现无需关闭
+	return nil
+}
+
+// Reset 清空全部数据，便于在多个测试间复用
+func (m *MemDatabase) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset()
 }
 
-// getNextID 获取下一个ID
-func (m *MemDatabase) getNextID() int64 {
-	id := m.nextID
-	m.nextID++
-	return id
+// Stats 返回当前的记录数量
+func (m *MemDatabase) Stats() MemStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return MemStats{
+		Traces:     len(m.traces),
+		Params:     m.paramCount,
+		Goroutines: len(m.goroutines),
+		Events:     m.eventCount,
+		Evicted:    m.evicted,
+	}
 }
 
 func (m *MemDatabase) GetGoroutineRepository() domain.GoroutineRepository {
@@ -63,3 +154,87 @@ func (m *MemDatabase) GetTraceRepository() domain.TraceRepository {
 func (m *MemDatabase) GetParamRepository() domain.ParamRepository {
 	return m.paramRepository
 }
+
+func (m *MemDatabase) GetStatsRepository() domain.StatsRepository {
+	return &MemStatsRepository{db: m}
+}
+
+func (m *MemDatabase) GetEventRepository() domain.EventRepository {
+	return &MemEventRepository{db: m}
+}
+
+func (m *MemDatabase) GetLeakRepository() domain.LeakRepository {
+	return &MemLeakRepository{db: m}
+}
+
+func (m *MemDatabase) GetRunRepository() domain.RunRepository {
+	return &MemRunRepository{db: m}
+}
+
+// addTrace 写入跟踪数据并维护索引，超出上限时淘汰最早的记录；调用方需持有写锁
+func (m *MemDatabase) addTrace(trace *model.TraceData) {
+	m.traces[trace.ID] = trace
+	m.order = append(m.order, trace.ID)
+	ids := m.byGID[trace.GID]
+	if ids == nil {
+		ids = make(idSet)
+		m.byGID[trace.GID] = ids
+	}
+	ids[trace.ID] = struct{}{}
+	if trace.ParentId != 0 {
+		children := m.children[trace.ParentId]
+		if children == nil {
+			children = make(idSet)
+			m.children[trace.ParentId] = children
+		}
+		children[trace.ID] = struct{}{}
+	}
+
+	for m.config.MaxTraces > 0 && len(m.traces) > m.config.MaxTraces {
+		m.evictOldest()
+	}
+}
+
+// evictOldest 淘汰最早写入的跟踪数据及其参数与事件；调用方需持有写锁
+func (m *MemDatabase) evictOldest() {
+	id := m.order[m.head]
+	m.head++
+	// 已消费的部分超过一半时压缩队列
+	if m.head > len(m.order)/2 {
+		m.order = append(m.order[:0], m.order[m.head:]...)
+		m.head = 0
+	}
+
+	trace, ok := m.traces[id]
+	if !ok {
+		return
+	}
+	delete(m.traces, id)
+	if ids := m.byGID[trace.GID]; ids != nil {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(m.byGID, trace.GID)
+		}
+	}
+	if ids := m.children[trace.ParentId]; ids != nil {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(m.children, trace.ParentId)
+		}
+	}
+	m.paramCount -= len(m.params[id])
+	delete(m.params, id)
+	m.eventCount -= len(m.events[id])
+	delete(m.events, id)
+
+	m.evicted++
+	if id > m.maxEvicted {
+		m.maxEvicted = id
+	}
+}
+
+// isEvicted 判断缺失的跟踪ID是否可能已被淘汰；调用方需持有锁
+// 各协程的ID交错写入，因此不大于已淘汰最大ID的缺失记录都按已淘汰处理
+func (m *MemDatabase) isEvicted(id int64) bool {
+	return m.evicted > 0 && id <= m.maxEvicted
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+func newTestDB(maxTraces int) *MemDatabase {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewMemDatabase(Config{MaxTraces: maxTraces}, logger)
+}
+
+// writeTree 写入一个协程：根调用 1，子调用 2、3，孙调用 4；调用 2 中创建协程 2
+func writeTree(t *testing.T, db *MemDatabase) {
+	t.Helper()
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(1, 10, "t0", 0, "main.main"))
+	require.NoError(t, err)
+	for _, td := range []*model.TraceData{
+		model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", ""),
+		model.NewTraceData(2, "main.a", 1, 1, 1, 1, "t2", ""),
+		model.NewTraceData(3, "main.b", 1, 1, 0, 1, "t3", ""),
+		model.NewTraceData(4, "main.c", 1, 2, 0, 3, "t4", ""),
+	} {
+		_, err := db.GetTraceRepository().SaveTrace(td)
+		require.NoError(t, err)
+	}
+	spawned := model.NewGoroutineTrace(2, 11, "t2", 0, "main.worker")
+	spawned.ParentTraceID = 2
+	_, err = db.GetGoroutineRepository().SaveGoroutine(spawned)
+	require.NoError(t, err)
+}
+
+func TestTraceRepository(t *testing.T) {
+	db := newTestDB(0)
+	writeTree(t, db)
+	repo := db.GetTraceRepository()
+
+	_, err := repo.SaveTrace(model.NewTraceData(1, "dup", 1, 0, 0, 0, "", ""))
+	assert.Error(t, err)
+	require.NoError(t, repo.UpdateTraceTimeCost(1, "5ms"))
+	assert.Error(t, repo.UpdateTraceTimeCost(99, "1ms"))
+
+	roots, err := repo.FindRootFunctionsByGID(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Equal(t, "main.main", roots[0].Name)
+	assert.Equal(t, "5ms", roots[0].TimeCost)
+	assert.Equal(t, 1, roots[0].IsFinished)
+
+	roots, err = repo.FindRootFunctionsByGID(2)
+	require.NoError(t, err)
+	assert.Empty(t, roots)
+}
+
+func TestTreeReader(t *testing.T) {
+	db := newTestDB(0)
+	writeTree(t, db)
+
+	td, err := db.FindTraceByID(3)
+	require.NoError(t, err)
+	assert.Equal(t, "main.b", td.Name)
+	_, err = db.FindTraceByID(99)
+	assert.Error(t, err)
+
+	children, err := db.FindTraceChildren(1)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, []int64{2, 3}, []int64{children[0].ID, children[1].ID})
+
+	roots, err := db.FindRootTracesByGID(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Equal(t, int64(1), roots[0].ID)
+
+	spawned, err := db.FindGoroutinesSpawnedBy(2)
+	require.NoError(t, err)
+	require.Len(t, spawned, 1)
+	assert.Equal(t, "main.worker", spawned[0].InitFuncName)
+
+	var ids []int64
+	require.NoError(t, db.ScanTraces(func(td *model.TraceData) error {
+		ids = append(ids, td.ID)
+		return nil
+	}))
+	assert.Equal(t, []int64{1, 2, 3, 4}, ids)
+	stop := fmt.Errorf("stop")
+	assert.Equal(t, stop, db.ScanGoroutines(func(*model.GoroutineTrace) error { return stop }))
+}
+
+func TestParamRepository(t *testing.T) {
+	db := newTestDB(0)
+	writeTree(t, db)
+	repo := db.GetParamRepository()
+
+	data := []byte(`{"a":1}`)
+	id, err := repo.SaveParam(model.NewParamStoreData(2, 0, data, false, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	require.NoError(t, repo.SaveParamsBatch([]*model.ParamStoreData{
+		model.NewParamStoreData(2, 1, []byte(`"x"`), false, 0).WithID(7),
+		model.NewParamStoreData(3, 0, []byte(`1`), false, 0),
+	}))
+	data[2] = 'b' // 调用方复用缓冲区不影响已保存的数据
+
+	params, err := repo.FindParamsByTraceID(2)
+	require.NoError(t, err)
+	require.Len(t, params, 2)
+	assert.Equal(t, `{"a":1}`, string(params[0].Data))
+	assert.Equal(t, int64(7), params[1].ID)
+	params, err = repo.FindParamsByTraceID(3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), params[0].ID)
+
+	cache, err := repo.FindParamCacheByAddr("0xc0")
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+	_, err = repo.SaveParamCache(model.NewParamCache("0xc0", 1, []byte("a")))
+	require.NoError(t, err)
+	_, err = repo.SaveParamCache(model.NewParamCache("0xc0", 7, []byte("b")))
+	require.NoError(t, err)
+	cache, err = repo.FindParamCacheByAddr("0xc0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), cache.BaseID)
+	assert.Equal(t, "b", string(cache.Data))
+	require.NoError(t, repo.DeleteParamCacheByAddr("0xc0"))
+	cache, err = repo.FindParamCacheByAddr("0xc0")
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+}
+
+func TestGoroutineRepository(t *testing.T) {
+	db := newTestDB(0)
+	writeTree(t, db)
+	repo := db.GetGoroutineRepository()
+
+	require.NoError(t, repo.UpdateGoroutineTimeCost(1, "1s", 1))
+	g, err := repo.FindGoroutineByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), g.OriginGID)
+	assert.Equal(t, "1s", g.TimeCost)
+	assert.Equal(t, 1, g.IsFinished)
+	_, err = repo.FindGoroutineByID(3)
+	assert.Error(t, err)
+	_, err = repo.SaveGoroutine(model.NewGoroutineTrace(1, 10, "", 0, ""))
+	assert.Error(t, err)
+}
+
+func TestEviction(t *testing.T) {
+	db := newTestDB(3)
+	repo := db.GetTraceRepository()
+	for i := int64(1); i <= 10; i++ {
+		_, err := repo.SaveTrace(model.NewTraceData(i, "main.f", 1, 0, 0, 0, "", ""))
+		require.NoError(t, err)
+		_, err = db.GetParamRepository().SaveParam(model.NewParamStoreData(i, 0, []byte(`1`), false, 0))
+		require.NoError(t, err)
+		_, err = db.GetEventRepository().SaveEvent(model.NewTraceEvent(i, 1, model.EventKindSlowCall, "slow", ""))
+		require.NoError(t, err)
+	}
+
+	stats := db.Stats()
+	assert.Equal(t, MemStats{Traces: 3, Params: 3, Goroutines: 0, Events: 3, Evicted: 7}, stats)
+	roots, err := repo.FindRootFunctionsByGID(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 3)
+	assert.Equal(t, int64(8), roots[0].ID)
+
+	// 已淘汰调用的延迟更新与参数被忽略
+	assert.NoError(t, repo.UpdateTraceTimeCost(2, "1ms"))
+	_, err = db.GetParamRepository().SaveParam(model.NewParamStoreData(2, 0, []byte(`1`), false, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.Stats().Params)
+	assert.Error(t, repo.UpdateTraceTimeCost(11, "1ms"))
+
+	db.Reset()
+	assert.Equal(t, MemStats{}, db.Stats())
+}
+
+func TestStatsAndLeaks(t *testing.T) {
+	db := newTestDB(0)
+	require.NoError(t, db.GetStatsRepository().SaveFuncStats([]*model.FuncStats{
+		{Name: "main.a", TotalTime: 10},
+		{Name: "main.b", TotalTime: 30},
+	}))
+	require.NoError(t, db.GetStatsRepository().SaveFuncStats([]*model.FuncStats{{Name: "main.a", TotalTime: 50}}))
+	stats, err := db.GetStatsRepository().FindAllFuncStats()
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "main.a", stats[0].Name)
+
+	require.NoError(t, db.GetLeakRepository().SaveLeakReports([]*model.LeakReport{{GoroutineID: 4}, {GoroutineID: 5}}))
+	leaks, err := db.GetLeakRepository().FindAllLeakReports()
+	require.NoError(t, err)
+	require.Len(t, leaks, 2)
+	assert.Equal(t, int64(2), leaks[1].ID)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	db := newTestDB(0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				id := int64(g*500 + i + 1)
+				_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(id, "main.f", uint64(g), 0, 0, 0, "", ""))
+				assert.NoError(t, err)
+				_, err = db.GetParamRepository().SaveParam(model.NewParamStoreData(id, 0, []byte(`1`), false, 0))
+				assert.NoError(t, err)
+				assert.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(id, "1ms"))
+				_, err = db.GetTraceRepository().FindRootFunctionsByGID(uint64(g))
+				assert.NoError(t, err)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, 4000, db.Stats().Traces)
+	assert.Equal(t, 4000, db.Stats().Params)
+}
@@ -0,0 +1,316 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// ScanTraces 按ID升序遍历全部跟踪数据
+// 遍历的是调用时的快照，fn 中可以继续读写本数据库
+func (m *MemDatabase) ScanTraces(fn func(trace *model.TraceData) error) error {
+	m.mu.RLock()
+	list := make([]*model.TraceData, 0, len(m.traces))
+	for _, trace := range m.traces {
+		list = append(list, copyTrace(trace))
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	for _, trace := range list {
+		if err := fn(trace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanGoroutines 按ID升序遍历全部协程数据
+func (m *MemDatabase) ScanGoroutines(fn func(goroutine *model.GoroutineTrace) error) error {
+	m.mu.RLock()
+	list := make([]*model.GoroutineTrace, 0, len(m.goroutines))
+	for _, g := range m.goroutines {
+		c := *g
+		list = append(list, &c)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	for _, g := range list {
+		if err := fn(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindTraceByID 根据ID查找跟踪数据
+func (m *MemDatabase) FindTraceByID(id int64) (*model.TraceData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	trace, ok := m.traces[id]
+	if !ok {
+		return nil, fmt.Errorf("trace data not found: id=%d", id)
+	}
+	return copyTrace(trace), nil
+}
+
+// FindTraceChildren 查找直接子调用，按ID升序
+func (m *MemDatabase) FindTraceChildren(parentId int64) ([]model.TraceData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []model.TraceData
+	for _, id := range m.children[parentId].sorted() {
+		result = append(result, *copyTrace(m.traces[id]))
+	}
+	return result, nil
+}
+
+// FindRootTracesByGID 查找协程的全部根调用（parentId 为 0），按ID升序
+func (m *MemDatabase) FindRootTracesByGID(gid uint64) ([]model.TraceData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []model.TraceData
+	for _, id := range m.byGID[gid].sorted() {
+		if trace := m.traces[id]; trace.ParentId == 0 {
+			result = append(result, *copyTrace(trace))
+		}
+	}
+	return result, nil
+}
+
+// FindGoroutinesSpawnedBy 查找在指定调用执行期间创建的协程
+func (m *MemDatabase) FindGoroutinesSpawnedBy(traceId int64) ([]model.GoroutineTrace, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []model.GoroutineTrace
+	for _, id := range m.spawned[traceId].sorted() {
+		result = append(result, *m.goroutines[id])
+	}
+	return result, nil
+}
+
+// ListTraceChildren 分页查询直接子调用，按ID升序
+func (m *MemDatabase) ListTraceChildren(parentId int64, page model.PageRequest) (*model.TracePage, error) {
+	after, err := cursorID(page)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*model.TraceData
+	for id := range m.children[parentId] {
+		if id > after {
+			list = append(list, m.traces[id])
+		}
+	}
+	return pageByID(list, page.PageLimit()), nil
+}
+
+// ListSubtree 分页查询以 rootId 为根的完整调用子树（含根），按ID升序
+func (m *MemDatabase) ListSubtree(rootId int64, page model.PageRequest) (*model.TracePage, error) {
+	after, err := cursorID(page)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*model.TraceData
+	if _, ok := m.traces[rootId]; ok {
+		queue := []int64{rootId}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if id > after {
+				list = append(list, m.traces[id])
+			}
+			for child := range m.children[id] {
+				queue = append(queue, child)
+			}
+		}
+	}
+	return pageByID(list, page.PageLimit()), nil
+}
+
+// FindTraces 按条件分页查询跟踪数据，按ID升序
+func (m *MemDatabase) FindTraces(query model.TraceQuery, page model.PageRequest) (*model.TracePage, error) {
+	after, err := cursorID(page)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*model.TraceData
+	m.eachTrace(query, func(td *model.TraceData) {
+		if td.ID > after && matchTrace(td, query) {
+			list = append(list, td)
+		}
+	})
+	return pageByID(list, page.PageLimit()), nil
+}
+
+// FindSlowestTraces 按条件分页查询已完成的调用，按耗时降序，耗时相同时按ID升序
+func (m *MemDatabase) FindSlowestTraces(query model.TraceQuery, page model.PageRequest) (*model.TracePage, error) {
+	var cursor []int64
+	if page.Cursor != "" {
+		keys, err := model.DecodeCursor(page.Cursor, 2)
+		if err != nil {
+			return nil, err
+		}
+		cursor = keys
+	}
+
+	type timed struct {
+		trace    *model.TraceData
+		duration int64
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []timed
+	m.eachTrace(query, func(td *model.TraceData) {
+		d, ok := model.ParseTimeCost(td.TimeCost)
+		if !ok || !matchTrace(td, query) {
+			return
+		}
+		if cursor != nil && !(int64(d) < cursor[0] || (int64(d) == cursor[0] && td.ID > cursor[1])) {
+			return
+		}
+		list = append(list, timed{td, int64(d)})
+	})
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].duration != list[j].duration {
+			return list[i].duration > list[j].duration
+		}
+		return list[i].trace.ID < list[j].trace.ID
+	})
+
+	limit := page.PageLimit()
+	result := &model.TracePage{}
+	for i, t := range list {
+		if i == limit {
+			last := list[limit-1]
+			result.NextCursor = model.EncodeCursor(last.duration, last.trace.ID)
+			break
+		}
+		result.Traces = append(result.Traces, *copyTrace(t.trace))
+	}
+	return result, nil
+}
+
+// FindGoroutines 按条件分页查询协程，按ID升序
+func (m *MemDatabase) FindGoroutines(query model.GoroutineQuery, page model.PageRequest) (*model.GoroutinePage, error) {
+	after, err := cursorID(page)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*model.GoroutineTrace
+	for id, g := range m.goroutines {
+		if id > after && matchGoroutine(g, query) {
+			list = append(list, g)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	limit := page.PageLimit()
+	result := &model.GoroutinePage{}
+	for i, g := range list {
+		if i == limit {
+			result.NextCursor = model.EncodeCursor(list[limit-1].ID)
+			break
+		}
+		result.Goroutines = append(result.Goroutines, *g)
+	}
+	return result, nil
+}
+
+// eachTrace 遍历可能满足条件的跟踪数据，指定 GID 时只遍历该协程；调用方需持有锁
+func (m *MemDatabase) eachTrace(query model.TraceQuery, fn func(td *model.TraceData)) {
+	if query.GID != 0 {
+		for id := range m.byGID[query.GID] {
+			fn(m.traces[id])
+		}
+		return
+	}
+	for _, td := range m.traces {
+		fn(td)
+	}
+}
+
+// matchTrace 判断跟踪数据是否满足查询条件
+func matchTrace(td *model.TraceData, q model.TraceQuery) bool {
+	if q.Name != "" && td.Name != q.Name {
+		return false
+	}
+	if q.GID != 0 && td.GID != q.GID {
+		return false
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		created, err := time.Parse(time.RFC3339Nano, td.CreatedAt)
+		if err != nil {
+			return false
+		}
+		if !q.Since.IsZero() && created.Before(q.Since) {
+			return false
+		}
+		if !q.Until.IsZero() && !created.Before(q.Until) {
+			return false
+		}
+	}
+	if q.MinDuration > 0 {
+		d, ok := model.ParseTimeCost(td.TimeCost)
+		if !ok || d < q.MinDuration {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGoroutine 判断协程是否满足查询条件
+func matchGoroutine(g *model.GoroutineTrace, q model.GoroutineQuery) bool {
+	if q.InitFuncName != "" && g.InitFuncName != q.InitFuncName {
+		return false
+	}
+	if q.OriginGID != 0 && g.OriginGID != q.OriginGID {
+		return false
+	}
+	if q.CreatorGID != 0 && g.CreatorGID != q.CreatorGID {
+		return false
+	}
+	switch q.State {
+	case model.GoroutineStateRunning:
+		return g.IsFinished == 0
+	case model.GoroutineStateFinished:
+		return g.IsFinished != 0
+	}
+	return true
+}
+
+// cursorID 解码按ID排序的游标，第一页返回 0
+func cursorID(page model.PageRequest) (int64, error) {
+	if page.Cursor == "" {
+		return 0, nil
+	}
+	keys, err := model.DecodeCursor(page.Cursor, 1)
+	if err != nil {
+		return 0, err
+	}
+	return keys[0], nil
+}
+
+// pageByID 按ID升序截取一页数据并复制
+func pageByID(list []*model.TraceData, limit int) *model.TracePage {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	result := &model.TracePage{}
+	for i, td := range list {
+		if i == limit {
+			result.NextCursor = model.EncodeCursor(list[limit-1].ID)
+			break
+		}
+		result.Traces = append(result.Traces, *copyTrace(td))
+	}
+	return result
+}
@@ -1,146 +1,383 @@
 package memory
 
 import (
-	"github.com/sirupsen/logrus"
+	"fmt"
+	"sort"
+
 	"github.com/toheart/functrace/domain"
 	"github.com/toheart/functrace/domain/model"
 )
 
-// MemTraceRepository 实现跟踪仓储的Mock
+var _ domain.TraceRepository = (*MemTraceRepository)(nil)
+var _ domain.TraceBatchWriter = (*MemTraceRepository)(nil)
+
+// MemTraceRepository 内存实现的跟踪数据仓储
 type MemTraceRepository struct {
-	logger *logrus.Logger
+	db *MemDatabase
 }
 
-// NewMockTraceRepository 创建新的Mock跟踪仓储
-func NewMockTraceRepository(logger *logrus.Logger) *MemTraceRepository {
-	return &MemTraceRepository{
-		logger: logger,
+// SaveTrace 保存跟踪数据，ID 重复时返回错误
+func (r *MemTraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
+	stored := copyTrace(trace)
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if _, ok := r.db.traces[trace.ID]; ok {
+		return 0, fmt.Errorf("save trace error: duplicate id %d", trace.ID)
 	}
+	r.db.addTrace(stored)
+	return trace.ID, nil
 }
 
-// SaveTrace 保存跟踪数据
-func (r *MemTraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
-	r.logger.WithField("trace", trace).Info("Mock保存跟踪数据")
-	return 1, nil
+// SaveTracesBatch 批量写入完整的跟踪数据，ID 已存在时整行覆盖
+func (r *MemTraceRepository) SaveTracesBatch(traces []*model.TraceData) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, trace := range traces {
+		if existing, ok := r.db.traces[trace.ID]; ok {
+			// 同一调用的 GID 与父调用不变，索引无需调整
+			*existing = *copyTrace(trace)
+			continue
+		}
+		r.db.addTrace(copyTrace(trace))
+	}
+	return nil
 }
 
-// UpdateTraceTimeCost 更新跟踪时间成本
+// UpdateTraceTimeCost 更新跟踪时间成本，已被淘汰的记录忽略
 func (r *MemTraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
-	r.logger.WithFields(logrus.Fields{
-		"id":       id,
-		"timeCost": timeCost,
-	}).Info("Mock更新跟踪时间成本")
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	trace, ok := r.db.traces[id]
+	if !ok {
+		if r.db.isEvicted(id) {
+			return nil
+		}
+		return fmt.Errorf("update trace time cost failed, no rows affected")
+	}
+	trace.TimeCost = timeCost
+	trace.IsFinished = 1
 	return nil
 }
 
-// FindRootFunctionsByGID 查找指定GID的根函数
+// FindRootFunctionsByGID 根据GID查找根函数（indent 为 0），按ID升序
 func (r *MemTraceRepository) FindRootFunctionsByGID(gid uint64) ([]model.TraceData, error) {
-	r.logger.WithField("gid", gid).Info("Mock查找根函数")
-	return []model.TraceData{
-		*model.NewTraceData(1, "MockRootFunction", gid, 0, 1, 0, "2023-01-01T00:00:00Z", "seq001"),
-	}, nil
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var result []model.TraceData
+	for _, id := range r.db.byGID[gid].sorted() {
+		if trace := r.db.traces[id]; trace.Indent == 0 {
+			result = append(result, *copyTrace(trace))
+		}
+	}
+	return result, nil
 }
 
 var _ domain.ParamRepository = (*MemParamRepository)(nil)
 
-// MemParamRepository 实现参数仓储的Mock
+// MemParamRepository 内存实现的参数仓储
 type MemParamRepository struct {
-	logger *logrus.Logger
-}
-
-// FindParamsByTraceID implements domain.ParamRepository.
-func (r *MemParamRepository) FindParamsByTraceID(traceId int64) ([]model.ParamStoreData, error) {
-	r.logger.WithField("traceId", traceId).Info("Mock查找参数")
-	return []model.ParamStoreData{
-		*model.NewParamStoreData(1, 0, []byte("MockParam"), false, 0),
-	}, nil
+	db *MemDatabase
 }
 
-// NewMockParamRepository 创建新的Mock参数仓储
-func NewMockParamRepository(logger *logrus.Logger) *MemParamRepository {
-	return &MemParamRepository{
-		logger: logger,
-	}
-}
-
-// SaveParam 保存参数数据
+// SaveParam 保存参数数据，ID 为 0 时自动分配
 func (r *MemParamRepository) SaveParam(param *model.ParamStoreData) (int64, error) {
-	r.logger.WithField("param", param).Info("Mock保存参数数据")
-	return 1, nil
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.db.addParam(param), nil
 }
 
-// SaveParamsBatch 批量保存参数数据（内存实现仅日志输出）
+// SaveParamsBatch 批量保存参数数据
 func (r *MemParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
-	r.logger.WithField("count", len(params)).Info("Mock批量保存参数数据")
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, p := range params {
+		r.db.addParam(p)
+	}
 	return nil
 }
 
-// SaveParamCache 保存参数缓存
+// FindParamsByTraceID 根据跟踪ID查找参数，按写入顺序
+func (r *MemParamRepository) FindParamsByTraceID(traceId int64) ([]model.ParamStoreData, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var result []model.ParamStoreData
+	for _, p := range r.db.params[traceId] {
+		result = append(result, *copyParam(p))
+	}
+	return result, nil
+}
+
+// SaveParamCache 保存参数缓存，同一地址覆盖旧值
 func (r *MemParamRepository) SaveParamCache(cache *model.ParamCache) (int64, error) {
-	r.logger.WithField("cache", cache).Info("Mock保存参数缓存")
-	return 1, nil
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.cacheSeq++
+	stored := *cache
+	stored.ID = r.db.cacheSeq
+	stored.Data = copyBytes(cache.Data)
+	r.db.caches[cache.Addr] = &stored
+	return stored.ID, nil
 }
 
-// FindParamCacheByAddr 根据地址查找参数缓存
+// FindParamCacheByAddr 根据地址查找参数缓存，未找到时返回 nil
 func (r *MemParamRepository) FindParamCacheByAddr(addr string) (*model.ParamCache, error) {
-	r.logger.WithField("addr", addr).Info("Mock查找参数缓存")
-	return &model.ParamCache{
-		ID:     1,
-		Addr:   addr,
-		BaseID: 1,
-		Data:   []byte("MockCacheData"),
-	}, nil
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	cache, ok := r.db.caches[addr]
+	if !ok {
+		return nil, nil
+	}
+	found := *cache
+	found.Data = copyBytes(cache.Data)
+	return &found, nil
 }
 
 // DeleteParamCacheByAddr 根据地址删除参数缓存
 func (r *MemParamRepository) DeleteParamCacheByAddr(addr string) error {
-	r.logger.WithField("addr", addr).Info("Mock删除参数缓存")
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.caches, addr)
 	return nil
 }
 
-// UpdateParamCache 更新参数缓存
-func (r *MemParamRepository) UpdateParamCache(cache *model.ParamCache) error {
-	r.logger.WithField("cache", cache).Info("Mock更新参数缓存")
-	return nil
+// addParam 写入一条参数；所属调用已被淘汰时丢弃。调用方需持有写锁
+func (m *MemDatabase) addParam(param *model.ParamStoreData) int64 {
+	stored := copyParam(param)
+	if stored.ID == 0 {
+		stored.ID = m.paramSeq + 1
+	}
+	if stored.ID > m.paramSeq {
+		m.paramSeq = stored.ID
+	}
+	if _, ok := m.traces[stored.TraceID]; !ok && m.isEvicted(stored.TraceID) {
+		return stored.ID
+	}
+	m.params[stored.TraceID] = append(m.params[stored.TraceID], stored)
+	m.paramCount++
+	return stored.ID
 }
 
-// MemGoroutineRepository 实现协程仓储的Mock
+var _ domain.GoroutineRepository = (*MemGoroutineRepository)(nil)
+
+// MemGoroutineRepository 内存实现的协程仓储
 type MemGoroutineRepository struct {
-	logger *logrus.Logger
+	db *MemDatabase
 }
 
-// FindGoroutineByID implements domain.GoroutineRepository.
+// SaveGoroutine 保存协程数据，ID 重复时返回错误
+func (r *MemGoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (int64, error) {
+	stored := *goroutine
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if _, ok := r.db.goroutines[stored.ID]; ok {
+		return 0, fmt.Errorf("save goroutine error: duplicate id %d", stored.ID)
+	}
+	r.db.goroutines[stored.ID] = &stored
+	if stored.ParentTraceID != 0 {
+		ids := r.db.spawned[stored.ParentTraceID]
+		if ids == nil {
+			ids = make(idSet)
+			r.db.spawned[stored.ParentTraceID] = ids
+		}
+		ids[stored.ID] = struct{}{}
+	}
+	return stored.ID, nil
+}
+
+// UpdateGoroutineTimeCost 更新协程时间成本
+func (r *MemGoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if g, ok := r.db.goroutines[id]; ok {
+		g.TimeCost = timeCost
+		g.IsFinished = isFinished
+	}
+	return nil
+}
+
+// FindGoroutineByID 根据ID查找协程
 func (r *MemGoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
-	r.logger.WithField("id", id).Info("Mock查找协程")
-	return &model.GoroutineTrace{
-		ID:           id,
-		OriginGID:    1,
-		TimeCost:     "100ms",
-		CreateTime:   "2023-01-01T00:00:00Z",
-		IsFinished:   0,
-		InitFuncName: "MockInitFunction",
-	}, nil
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	g, ok := r.db.goroutines[id]
+	if !ok {
+		return nil, fmt.Errorf("goroutine data not found: id=%d", id)
+	}
+	found := *g
+	return &found, nil
 }
 
-// NewMockGoroutineRepository 创建新的Mock协程仓储
-func NewMockGoroutineRepository(logger *logrus.Logger) *MemGoroutineRepository {
-	return &MemGoroutineRepository{
-		logger: logger,
+var _ domain.StatsRepository = (*MemStatsRepository)(nil)
+
+// MemStatsRepository 内存实现的函数统计仓储
+type MemStatsRepository struct {
+	db *MemDatabase
+}
+
+// SaveFuncStats 批量保存函数统计快照，同名函数覆盖
+func (r *MemStatsRepository) SaveFuncStats(stats []*model.FuncStats) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, s := range stats {
+		c := *s
+		c.Sketch = copyBytes(s.Sketch)
+		r.db.funcStats[s.Name] = &c
 	}
+	return nil
 }
 
-// SaveGoroutine 保存协程数据
-func (r *MemGoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (int64, error) {
-	r.logger.WithField("goroutine", goroutine).Info("Mock保存协程数据")
-	return 1, nil
+// FindAllFuncStats 查询全部函数统计快照，按总耗时降序
+func (r *MemStatsRepository) FindAllFuncStats() ([]model.FuncStats, error) {
+	r.db.mu.RLock()
+	result := make([]model.FuncStats, 0, len(r.db.funcStats))
+	for _, s := range r.db.funcStats {
+		result = append(result, *s)
+	}
+	r.db.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalTime != result[j].TotalTime {
+			return result[i].TotalTime > result[j].TotalTime
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
 }
 
-// UpdateGoroutineTimeCost 更新协程时间成本
-func (r *MemGoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
-	r.logger.WithFields(logrus.Fields{
-		"id":         id,
-		"timeCost":   timeCost,
-		"isFinished": isFinished,
-	}).Info("Mock更新协程时间成本")
+var _ domain.EventRepository = (*MemEventRepository)(nil)
+
+// MemEventRepository 内存实现的跟踪事件仓储
+type MemEventRepository struct {
+	db *MemDatabase
+}
+
+// SaveEvent 保存跟踪事件，所属调用已被淘汰时丢弃
+func (r *MemEventRepository) SaveEvent(event *model.TraceEvent) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.eventSeq++
+	stored := *event
+	stored.ID = r.db.eventSeq
+	if _, ok := r.db.traces[stored.TraceID]; !ok && r.db.isEvicted(stored.TraceID) {
+		return stored.ID, nil
+	}
+	r.db.events[stored.TraceID] = append(r.db.events[stored.TraceID], &stored)
+	r.db.eventCount++
+	return stored.ID, nil
+}
+
+// FindEventsByTraceID 根据跟踪ID查找事件，按ID升序
+func (r *MemEventRepository) FindEventsByTraceID(traceId int64) ([]model.TraceEvent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var result []model.TraceEvent
+	for _, e := range r.db.events[traceId] {
+		result = append(result, *e)
+	}
+	return result, nil
+}
+
+var _ domain.LeakRepository = (*MemLeakRepository)(nil)
+
+// MemLeakRepository 内存实现的泄漏报告仓储
+type MemLeakRepository struct {
+	db *MemDatabase
+}
+
+// SaveLeakReports 批量保存泄漏报告
+func (r *MemLeakRepository) SaveLeakReports(reports []*model.LeakReport) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, report := range reports {
+		stored := *report
+		stored.ID = int64(len(r.db.leaks) + 1)
+		r.db.leaks = append(r.db.leaks, &stored)
+	}
+	return nil
+}
+
+// FindAllLeakReports 查询全部泄漏报告
+func (r *MemLeakRepository) FindAllLeakReports() ([]model.LeakReport, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var result []model.LeakReport
+	for _, report := range r.db.leaks {
+		result = append(result, *report)
+	}
+	return result, nil
+}
+
+var _ domain.RunRepository = (*MemRunRepository)(nil)
+
+// MemRunRepository 内存实现的运行记录仓储
+type MemRunRepository struct {
+	db *MemDatabase
+}
+
+// StartRun 登记一次运行，run.ID 为0时按登记顺序分配ID
+func (r *MemRunRepository) StartRun(run *model.Run) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	stored := *run
+	if stored.ID == 0 {
+		stored.ID = int64(len(r.db.runs) + 1)
+	}
+	r.db.runs = append(r.db.runs, &stored)
+	return stored.ID, nil
+}
+
+// EndRun 记录运行的结束时间与状态
+func (r *MemRunRepository) EndRun(id int64, endedAt string, status string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, run := range r.db.runs {
+		if run.ID == id {
+			run.EndedAt, run.Status = endedAt, status
+		}
+	}
 	return nil
 }
+
+// FindRunByID 根据ID查找运行记录，未找到时返回 nil
+func (r *MemRunRepository) FindRunByID(id int64) (*model.Run, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	for _, run := range r.db.runs {
+		if run.ID == id {
+			found := *run
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindAllRuns 查询全部运行记录，按登记顺序
+func (r *MemRunRepository) FindAllRuns() ([]model.Run, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var result []model.Run
+	for _, run := range r.db.runs {
+		result = append(result, *run)
+	}
+	return result, nil
+}
+
+// copyTrace 复制跟踪数据，不保留原始参数
+func copyTrace(trace *model.TraceData) *model.TraceData {
+	c := *trace
+	c.Params = nil
+	return &c
+}
+
+// copyParam 复制参数数据
+func copyParam(param *model.ParamStoreData) *model.ParamStoreData {
+	c := *param
+	c.Data = copyBytes(param.Data)
+	return &c
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
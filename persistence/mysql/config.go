@@ -0,0 +1,69 @@
+// Package mysql 提供 MySQL 仓储实现，多个进程可以写入同一个库
+package mysql
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// Config MySQL 仓储配置
+type Config struct {
+	DSN           string        // 数据源，如 user:pass@tcp(127.0.0.1:3306)/functrace
+	MaxOpenConn   int           // 最大打开连接数
+	MaxIdleConn   int           // 最大空闲连接数
+	MaxIdleTime   time.Duration // 连接最大空闲时间
+	BatchSize     int           // 每条批量插入语句的最大行数
+	FlushInterval time.Duration // 跟踪数据缓冲的最长停留时间
+}
+
+// ConfigFromEnv 读取 FUNCTRACE_MYSQL_* 环境变量构造配置
+func ConfigFromEnv() Config {
+	cfg := Config{DSN: os.Getenv(EnvDSN)}
+	if v, err := strconv.Atoi(os.Getenv(EnvMaxOpenConn)); err == nil {
+		cfg.MaxOpenConn = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(EnvMaxIdleConn)); err == nil {
+		cfg.MaxIdleConn = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(EnvBatchSize)); err == nil {
+		cfg.BatchSize = v
+	}
+	return cfg
+}
+
+// withDefaults 为未设置的字段填充默认值
+func (c Config) withDefaults() Config {
+	if c.MaxOpenConn <= 0 {
+		c.MaxOpenConn = DefaultMaxOpenConn
+	}
+	if c.MaxIdleConn <= 0 {
+		c.MaxIdleConn = DefaultMaxIdleConn
+	}
+	if c.MaxIdleTime <= 0 {
+		c.MaxIdleTime = DefaultMaxIdleTime
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	return c
+}
+
+// driverDSN 校验数据源并开启 clientFoundRows，使更新为相同值时也返回匹配行数
+func (c Config) driverDSN() (string, error) {
+	if c.DSN == "" {
+		return "", fmt.Errorf("%s is required for the mysql backend", EnvDSN)
+	}
+	dc, err := driver.ParseDSN(c.DSN)
+	if err != nil {
+		return "", fmt.Errorf("parse mysql dsn error: %w", err)
+	}
+	dc.ClientFoundRows = true
+	return dc.FormatDSN(), nil
+}
@@ -0,0 +1,141 @@
+package mysql
+
+import "time"
+
+// 环境变量
+const (
+	EnvDSN         = "FUNCTRACE_MYSQL_DSN"           // 数据源，如 user:pass@tcp(127.0.0.1:3306)/functrace
+	EnvMaxOpenConn = "FUNCTRACE_MYSQL_MAX_OPEN_CONN" // 最大打开连接数
+	EnvMaxIdleConn = "FUNCTRACE_MYSQL_MAX_IDLE_CONN" // 最大空闲连接数
+	EnvBatchSize   = "FUNCTRACE_MYSQL_BATCH_SIZE"    // 每条批量插入语句的最大行数
+)
+
+// 默认值
+const (
+	DefaultMaxOpenConn   = 16
+	DefaultMaxIdleConn   = 4
+	DefaultMaxIdleTime   = 30 * time.Second
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 200 * time.Millisecond
+)
+
+// 建表语句
+const (
+	SQLCreateTraceTable = `CREATE TABLE IF NOT EXISTS TraceData (
+		id BIGINT PRIMARY KEY, 
+		name VARCHAR(1024), 
+		gid BIGINT, 
+		indent INT, 
+		paramsCount INT, 
+		timeCost VARCHAR(64), 
+		parentId BIGINT, 
+		isFinished TINYINT, 
+		createdAt VARCHAR(64), 
+		seq VARCHAR(64), 
+		INDEX idx_gid (gid), 
+		INDEX idx_parent (parentId)
+	)`
+
+	SQLCreateGoroutineTable = `CREATE TABLE IF NOT EXISTS GoroutineTrace (
+		id BIGINT PRIMARY KEY AUTO_INCREMENT, 
+		originGid BIGINT, 
+		timeCost VARCHAR(64), 
+		createTime VARCHAR(64), 
+		isFinished TINYINT, 
+		initFuncName VARCHAR(1024), 
+		creatorGid BIGINT, 
+		creatorFunc VARCHAR(1024), 
+		parentTraceId BIGINT, 
+		INDEX idx_goroutine_parent_trace (parentTraceId)
+	)`
+
+	// position 与内置函数同名，需加反引号
+	SQLCreateParamTable = "CREATE TABLE IF NOT EXISTS ParamStore (" +
+		"id BIGINT PRIMARY KEY AUTO_INCREMENT, " +
+		"traceId BIGINT, " +
+		"`position` INT, " +
+		"data LONGBLOB, " +
+		"isReceiver BOOLEAN, " +
+		"baseId BIGINT, " +
+		"INDEX idx_param_trace (traceId), " +
+		"INDEX idx_param_base (baseId))"
+
+	// 参数缓存按来源隔离，不同进程的地址可能相同
+	SQLCreateParamCacheTable = `CREATE TABLE IF NOT EXISTS ParamCache (
+		id BIGINT PRIMARY KEY AUTO_INCREMENT, 
+		sourceId BIGINT NOT NULL, 
+		addr VARCHAR(255) NOT NULL, 
+		baseId BIGINT, 
+		data LONGBLOB, 
+		UNIQUE KEY idx_param_cache_addr (sourceId, addr)
+	)`
+
+	SQLCreateSourceTable = `CREATE TABLE IF NOT EXISTS Source (
+		id BIGINT PRIMARY KEY AUTO_INCREMENT, 
+		runId VARCHAR(64) NOT NULL, 
+		process VARCHAR(255), 
+		host VARCHAR(255), 
+		pid INT, 
+		remoteAddr VARCHAR(255), 
+		connectedAt VARCHAR(64), 
+		lastSeenAt VARCHAR(64), 
+		lastSeq BIGINT, 
+		records BIGINT, 
+		UNIQUE KEY idx_source_run (runId)
+	)`
+
+	// 运行的自增ID决定其数据的ID命名空间，与 SQLite 相同
+	SQLCreateRunTable = `CREATE TABLE IF NOT EXISTS Run (
+		id BIGINT PRIMARY KEY AUTO_INCREMENT, 
+		uuid VARCHAR(64) NOT NULL, 
+		executable VARCHAR(1024), 
+		args TEXT, 
+		hostname VARCHAR(255), 
+		pid INT, 
+		goVersion VARCHAR(64), 
+		modulePath VARCHAR(1024), 
+		moduleVersion VARCHAR(255), 
+		vcsRevision VARCHAR(255), 
+		vcsTime VARCHAR(64), 
+		vcsModified BOOLEAN, 
+		buildInfo TEXT, 
+		config TEXT, 
+		startedAt VARCHAR(64), 
+		endedAt VARCHAR(64), 
+		status VARCHAR(32), 
+		origin VARCHAR(1024)
+	)`
+)
+
+// 操作语句
+const (
+	// 批量插入时按行数重复 SQLTraceValues
+	SQLInsertTracePrefix = "INSERT INTO TraceData (id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq) VALUES "
+	SQLTraceValues       = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLUpdateTimeCost    = "UPDATE TraceData SET timeCost = ?, isFinished = ? WHERE id = ?"
+
+	SQLQueryRootFunctions = "SELECT id, timeCost FROM TraceData WHERE gid = ? AND indent = 0"
+
+	SQLInsertParamPrefix     = "INSERT INTO ParamStore (id, traceId, `position`, data, isReceiver, baseId) VALUES "
+	SQLParamValues           = "(?, ?, ?, ?, ?, ?)"
+	SQLSelectParamsByTrace   = "SELECT id, traceId, `position`, data, isReceiver, baseId FROM ParamStore WHERE traceId = ? ORDER BY id"
+	SQLUpsertParamCache      = "INSERT INTO ParamCache (sourceId, addr, baseId, data) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE baseId = VALUES(baseId), data = VALUES(data)"
+	SQLSelectParamCache      = "SELECT id, addr, baseId, data FROM ParamCache WHERE sourceId = ? AND addr = ? LIMIT 1"
+	SQLDeleteParamCache      = "DELETE FROM ParamCache WHERE sourceId = ? AND addr = ?"
+	SQLDeleteParamCacheBySrc = "DELETE FROM ParamCache WHERE sourceId = ?"
+
+	SQLInsertGoroutine         = "INSERT INTO GoroutineTrace (id, originGid, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLSelectGoroutineByID     = "SELECT id, originGid, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId FROM GoroutineTrace WHERE id = ?"
+	SQLUpdateGoroutineTimeCost = "UPDATE GoroutineTrace SET timeCost = ?, isFinished = ? WHERE id = ?"
+
+	SQLInsertSource         = "INSERT INTO Source (runId, process, host, pid, remoteAddr, connectedAt, lastSeenAt, lastSeq, records) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLUpdateSourceProgress = "UPDATE Source SET lastSeq = ?, records = ?, lastSeenAt = ? WHERE id = ?"
+	SQLSelectSourceByRunID  = "SELECT id, runId, process, host, pid, remoteAddr, connectedAt, lastSeenAt, lastSeq, records FROM Source WHERE runId = ?"
+	SQLSelectAllSources     = "SELECT id, runId, process, host, pid, remoteAddr, connectedAt, lastSeenAt, lastSeq, records FROM Source ORDER BY id"
+
+	SQLInsertRun       = "INSERT INTO Run (uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLInsertRunWithID = "INSERT INTO Run (id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLUpdateRunEnd    = "UPDATE Run SET endedAt = ?, status = ? WHERE id = ?"
+	SQLSelectRunByID   = "SELECT id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin FROM Run WHERE id = ?"
+	SQLSelectAllRuns   = "SELECT id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin FROM Run ORDER BY id"
+)
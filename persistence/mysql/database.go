@@ -0,0 +1,210 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// 确保MySQLDatabase实现了IDatabase接口
+var _ domain.RepositoryFactory = (*MySQLDatabase)(nil)
+var _ domain.SourceRepositoryProvider = (*MySQLDatabase)(nil)
+var _ domain.RunRepositoryProvider = (*MySQLDatabase)(nil)
+
+// MySQLDatabase MySQL数据库实现
+// 多个进程共用一个库时，各进程在 Run 表登记运行，并按 Run.IDBase() 生成ID，避免主键冲突；
+// Initialize 另为当前进程登记一个 Source，记录写入进度并隔离参数缓存
+type MySQLDatabase struct {
+	config              Config
+	logger              *logrus.Logger
+	db                  *sql.DB
+	source              *model.Source // Open 打开时为 nil
+	traceRepository     *TraceRepository
+	paramRepository     *ParamRepository
+	goroutineRepository *GoroutineRepository
+	sourceRepository    *SourceRepository
+	runRepository       *RunRepository
+	stop                chan struct{}
+	wg                  sync.WaitGroup
+}
+
+// NewMySQLDatabase 创建新的MySQL仓储工厂
+func NewMySQLDatabase(config Config, logger *logrus.Logger) domain.RepositoryFactory {
+	return &MySQLDatabase{
+		config: config.withDefaults(),
+		logger: logger,
+	}
+}
+
+// Open 打开已有的库（如收集器或离线工具），不登记来源
+func Open(config Config, logger *logrus.Logger) (*MySQLDatabase, error) {
+	d := &MySQLDatabase{config: config.withDefaults(), logger: logger}
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	d.initRepositories(0)
+	return d, nil
+}
+
+// Initialize 连接数据库、创建表并登记当前进程
+func (d *MySQLDatabase) Initialize() error {
+	if err := d.open(); err != nil {
+		return err
+	}
+
+	src, err := d.registerSource()
+	if err != nil {
+		d.db.Close()
+		return err
+	}
+	d.source = src
+	d.initRepositories(src.ID)
+	d.logger.Infof("writing mysql as source %d", src.ID)
+	return nil
+}
+
+// open 打开连接池并创建表
+func (d *MySQLDatabase) open() error {
+	dsn, err := d.config.driverDSN()
+	if err != nil {
+		return err
+	}
+	d.db, err = sql.Open("mysql", dsn)
+	if err != nil {
+		return fmt.Errorf("can't open db: %w", err)
+	}
+	d.db.SetMaxOpenConns(d.config.MaxOpenConn)
+	d.db.SetMaxIdleConns(d.config.MaxIdleConn)
+	d.db.SetConnMaxIdleTime(d.config.MaxIdleTime)
+
+	if err := d.db.Ping(); err != nil {
+		d.db.Close()
+		return fmt.Errorf("can't ping db: %w", err)
+	}
+	for _, table := range []string{
+		SQLCreateTraceTable,
+		SQLCreateGoroutineTable,
+		SQLCreateParamTable,
+		SQLCreateParamCacheTable,
+		SQLCreateSourceTable,
+		SQLCreateRunTable,
+	} {
+		if _, err := d.db.Exec(table); err != nil {
+			d.db.Close()
+			return fmt.Errorf("can't exec sql: %s, %w", table, err)
+		}
+	}
+	return nil
+}
+
+// initRepositories 创建仓储并启动跟踪数据的定期刷写
+func (d *MySQLDatabase) initRepositories(sourceID int64) {
+	d.traceRepository = &TraceRepository{db: d.db, batchSize: d.config.BatchSize, index: make(map[int64]int)}
+	d.paramRepository = &ParamRepository{db: d.db, sourceID: sourceID, batchSize: d.config.BatchSize}
+	d.goroutineRepository = &GoroutineRepository{db: d.db}
+	d.sourceRepository = &SourceRepository{db: d.db}
+	d.runRepository = &RunRepository{db: d.db}
+
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go d.flushLoop()
+}
+
+// registerSource 登记当前进程
+func (d *MySQLDatabase) registerSource() (*model.Source, error) {
+	host, _ := os.Hostname()
+	process := "default"
+	if exe, err := os.Executable(); err == nil {
+		process = filepath.Base(exe)
+	}
+	now := time.Now().Format(time.RFC3339Nano)
+	src := &model.Source{
+		RunID:       uuid.NewString(),
+		Process:     process,
+		Host:        host,
+		PID:         os.Getpid(),
+		ConnectedAt: now,
+		LastSeenAt:  now,
+	}
+	id, err := (&SourceRepository{db: d.db}).SaveSource(src)
+	if err != nil {
+		return nil, err
+	}
+	src.ID = id
+	return src, nil
+}
+
+// flushLoop 定期写出缓冲的跟踪数据
+func (d *MySQLDatabase) flushLoop() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.traceRepository.Flush(); err != nil {
+				d.logger.WithFields(logrus.Fields{"error": err}).Error("flush traces failed")
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Close 写出缓冲数据，清理当前进程的参数缓存并关闭连接池
+func (d *MySQLDatabase) Close() error {
+	if d.db == nil {
+		return nil
+	}
+	close(d.stop)
+	d.wg.Wait()
+	err := d.traceRepository.Flush()
+	if d.source != nil {
+		// 参数缓存只是运行时状态
+		if _, cerr := d.db.Exec(SQLDeleteParamCacheBySrc, d.source.ID); cerr != nil && err == nil {
+			err = fmt.Errorf("clear param cache error: %w", cerr)
+		}
+		records := d.traceRepository.inserted.Load()
+		if uerr := d.sourceRepository.UpdateSourceProgress(d.source.ID, 0, records, time.Now().Format(time.RFC3339Nano)); uerr != nil && err == nil {
+			err = uerr
+		}
+	}
+	if cerr := d.db.Close(); err == nil {
+		err = cerr
+	}
+	d.db = nil
+	return err
+}
+
+// Source 返回 Initialize 登记的来源，Open 打开时为 nil
+func (d *MySQLDatabase) Source() *model.Source {
+	return d.source
+}
+
+func (d *MySQLDatabase) GetTraceRepository() domain.TraceRepository {
+	return d.traceRepository
+}
+
+func (d *MySQLDatabase) GetParamRepository() domain.ParamRepository {
+	return d.paramRepository
+}
+
+func (d *MySQLDatabase) GetGoroutineRepository() domain.GoroutineRepository {
+	return d.goroutineRepository
+}
+
+func (d *MySQLDatabase) GetSourceRepository() domain.SourceRepository {
+	return d.sourceRepository
+}
+
+func (d *MySQLDatabase) GetRunRepository() domain.RunRepository {
+	return d.runRepository
+}
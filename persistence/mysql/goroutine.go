@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+var _ domain.GoroutineRepository = (*GoroutineRepository)(nil)
+
+// GoroutineRepository 是MySQL实现的协程数据仓储
+type GoroutineRepository struct {
+	db *sql.DB
+}
+
+// SaveGoroutine 保存协程数据
+func (r *GoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (int64, error) {
+	result, err := r.db.Exec(
+		SQLInsertGoroutine,
+		goroutine.ID,
+		goroutine.OriginGID,
+		goroutine.CreateTime,
+		goroutine.IsFinished,
+		goroutine.InitFuncName,
+		goroutine.CreatorGID,
+		goroutine.CreatorFunc,
+		goroutine.ParentTraceID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("save goroutine error: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateGoroutineTimeCost 更新协程时间成本
+func (r *GoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
+	if _, err := r.db.Exec(SQLUpdateGoroutineTimeCost, timeCost, isFinished, id); err != nil {
+		return fmt.Errorf("update goroutine time cost error: %w", err)
+	}
+	return nil
+}
+
+// FindGoroutineByID 根据ID查找协程
+func (r *GoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
+	var (
+		goroutine     model.GoroutineTrace
+		creatorGid    sql.NullInt64
+		creatorFunc   sql.NullString
+		parentTraceId sql.NullInt64
+	)
+	err := r.db.QueryRow(SQLSelectGoroutineByID, id).Scan(&goroutine.ID, &goroutine.OriginGID, &goroutine.CreateTime,
+		&goroutine.IsFinished, &goroutine.InitFuncName, &creatorGid, &creatorFunc, &parentTraceId)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("goroutine data not found: id=%d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find goroutine by id error: %w", err)
+	}
+	goroutine.ID = id
+	goroutine.CreatorGID = uint64(creatorGid.Int64)
+	goroutine.CreatorFunc = creatorFunc.String
+	goroutine.ParentTraceID = parentTraceId.Int64
+	return &goroutine, nil
+}
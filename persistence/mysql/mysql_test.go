@@ -0,0 +1,249 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	sqle "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/memory"
+	"github.com/dolthub/go-mysql-server/server"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/factory"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// startServer 启动进程内的 MySQL 兼容服务，返回 DSN
+func startServer(t *testing.T) string {
+	t.Helper()
+	logrus.SetOutput(io.Discard) // go-mysql-server 使用全局日志
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	db := memory.NewDatabase("functrace")
+	db.BaseDatabase.EnablePrimaryKeyIndexes()
+	pro := memory.NewDBProvider(db)
+	s, err := server.NewServer(server.Config{Protocol: "tcp", Address: addr}, sqle.NewDefault(pro), memory.NewSessionBuilder(pro), nil)
+	require.NoError(t, err)
+	go s.Start()
+	t.Cleanup(func() { s.Close() })
+	return fmt.Sprintf("root@tcp(%s)/functrace", addr)
+}
+
+func openTestDB(t *testing.T, dsn string, batchSize int) *MySQLDatabase {
+	t.Helper()
+	db := NewMySQLDatabase(Config{DSN: dsn, BatchSize: batchSize, FlushInterval: time.Hour}, testLogger()).(*MySQLDatabase)
+	require.NoError(t, db.Initialize())
+	return db
+}
+
+// writeCalls 写入一个协程及 n 个带参数的调用，ID 从 base+1 开始
+func writeCalls(t *testing.T, db *MySQLDatabase, base int64, name string, n int) {
+	t.Helper()
+	now := time.Now().Format(time.RFC3339Nano)
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(base+1, 9, now, 0, name))
+	require.NoError(t, err)
+	var params []*model.ParamStoreData
+	for i := base + 1; i <= base+int64(n); i++ {
+		var parent int64
+		if i > base+1 {
+			parent = base + 1
+		}
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(i, name, uint64(base+1), int(min(i-base-1, 1)), 1, parent, now, ""))
+		require.NoError(t, err)
+		params = append(params, model.NewParamStoreData(i, 0, []byte(fmt.Sprintf(`{"i":%d}`, i-base)), false, 0).WithID(i))
+		if (i-base)%2 == 0 {
+			require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(i, "2ms"))
+		}
+	}
+	require.NoError(t, db.GetParamRepository().SaveParamsBatch(params))
+}
+
+// startRun 登记一次运行，返回其ID偏移
+func startRun(t *testing.T, db *MySQLDatabase) int64 {
+	t.Helper()
+	run := &model.Run{UUID: uuid.NewString(), Status: model.RunStatusRunning}
+	id, err := db.GetRunRepository().StartRun(run)
+	require.NoError(t, err)
+	run.ID = id
+	return run.IDBase()
+}
+
+func countRows(t *testing.T, dsn, query string, args ...interface{}) int {
+	t.Helper()
+	conn, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer conn.Close()
+	var n int
+	require.NoError(t, conn.QueryRow(query, args...).Scan(&n))
+	return n
+}
+
+func TestRepositories(t *testing.T) {
+	dsn := startServer(t)
+	db := openTestDB(t, dsn, 4)
+	writeCalls(t, db, 0, "main.run", 10)
+
+	// 已写出的行通过 UPDATE 更新，缓冲中的行直接合并
+	require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(1, "10ms"))
+	require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(9, "1ms"))
+	assert.Error(t, db.GetTraceRepository().UpdateTraceTimeCost(99, "1ms"))
+
+	roots, err := db.GetTraceRepository().FindRootFunctionsByGID(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Equal(t, int64(1), roots[0].ID)
+	assert.Equal(t, "10ms", roots[0].TimeCost)
+
+	params, err := db.GetParamRepository().FindParamsByTraceID(7)
+	require.NoError(t, err)
+	require.Len(t, params, 1)
+	assert.Equal(t, int64(7), params[0].ID)
+	assert.Equal(t, `{"i":7}`, string(params[0].Data))
+
+	g, err := db.GetGoroutineRepository().FindGoroutineByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), g.OriginGID)
+	require.NoError(t, db.GetGoroutineRepository().UpdateGoroutineTimeCost(1, "1s", 1))
+	_, err = db.GetGoroutineRepository().FindGoroutineByID(2)
+	assert.Error(t, err)
+
+	_, err = db.GetParamRepository().SaveParamCache(model.NewParamCache("0xc000", 3, []byte("a")))
+	require.NoError(t, err)
+	_, err = db.GetParamRepository().SaveParamCache(model.NewParamCache("0xc000", 5, []byte("b")))
+	require.NoError(t, err)
+	cache, err := db.GetParamRepository().FindParamCacheByAddr("0xc000")
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	assert.Equal(t, int64(5), cache.BaseID)
+	assert.Equal(t, "b", string(cache.Data))
+	require.NoError(t, db.GetParamRepository().DeleteParamCacheByAddr("0xc000"))
+	cache, err = db.GetParamRepository().FindParamCacheByAddr("0xc000")
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+
+	require.NoError(t, db.Close())
+	assert.Equal(t, 10, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData WHERE id >= 1 AND id <= 10"))
+	assert.Equal(t, 7, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData WHERE isFinished = 1"))
+}
+
+func TestFailedFlushKeepsRows(t *testing.T) {
+	dsn := startServer(t)
+	db := openTestDB(t, dsn, 2)
+	repo := db.traceRepository
+	conn, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 库中已有同ID的行，写出失败
+	_, err = conn.Exec("INSERT INTO TraceData (id, name) VALUES (?, 'main.conflict')", 2)
+	require.NoError(t, err)
+	now := time.Now().Format(time.RFC3339Nano)
+	for i := int64(1); i <= 2; i++ {
+		_, err := repo.SaveTrace(model.NewTraceData(i, "main.run", 1, 0, 0, 0, now, ""))
+		require.NoError(t, err)
+	}
+	require.Error(t, repo.Flush())
+
+	// 失败的行留在缓冲区，仍可合并耗时
+	require.NoError(t, repo.UpdateTraceTimeCost(1, "1ms"))
+	assert.Len(t, repo.pending, 2)
+
+	// 积压超过上限后新的写入返回错误，交给调用方处理
+	for i := int64(3); i <= 2*maxPendingBatches; i++ {
+		_, err := repo.SaveTrace(model.NewTraceData(i, "main.run", 1, 0, 0, 0, now, ""))
+		require.NoError(t, err)
+	}
+	_, err = repo.SaveTrace(model.NewTraceData(99, "main.run", 1, 0, 0, 0, now, ""))
+	assert.Error(t, err)
+
+	// 冲突消除后缓冲的行全部写出
+	_, err = conn.Exec("DELETE FROM TraceData WHERE name = 'main.conflict'")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	assert.Equal(t, 2*maxPendingBatches, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData"))
+	assert.Equal(t, 1, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData WHERE isFinished = 1"))
+}
+
+func TestSharedDatabase(t *testing.T) {
+	dsn := startServer(t)
+	a := openTestDB(t, dsn, 100)
+	b := openTestDB(t, dsn, 100)
+	// 两个进程各自登记运行，ID 按运行的命名空间生成
+	baseA, baseB := startRun(t, a), startRun(t, b)
+	writeCalls(t, a, baseA, "main.a", 5)
+	writeCalls(t, b, baseB, "main.b", 7)
+	_, err := a.GetParamRepository().SaveParamCache(model.NewParamCache("0x1", 1, []byte("a")))
+	require.NoError(t, err)
+	_, err = b.GetParamRepository().SaveParamCache(model.NewParamCache("0x1", 2, []byte("b")))
+	require.NoError(t, err)
+	cache, err := a.GetParamRepository().FindParamCacheByAddr("0x1")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(cache.Data))
+
+	require.NoError(t, a.Close())
+	require.NoError(t, b.Close())
+
+	assert.NotEqual(t, a.Source().ID, b.Source().ID)
+	assert.Zero(t, baseA)
+	assert.Equal(t, int64(1)<<model.RunIDShift, baseB)
+	assert.Equal(t, 12, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData"))
+	assert.Equal(t, 7, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData WHERE id > ? AND id <= ?", baseB, baseB+7))
+	assert.Equal(t, 6, countRows(t, dsn, "SELECT COUNT(*) FROM TraceData WHERE parentId = ?", baseB+1))
+	assert.Equal(t, 2, countRows(t, dsn, "SELECT COUNT(*) FROM GoroutineTrace"))
+	assert.Equal(t, 0, countRows(t, dsn, "SELECT COUNT(*) FROM ParamCache"))
+
+	reader, err := Open(Config{DSN: dsn}, testLogger())
+	require.NoError(t, err)
+	defer reader.Close()
+	sources, err := reader.GetSourceRepository().FindAllSources()
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, int64(7), sources[1].Records)
+	runs, err := reader.GetRunRepository().FindAllRuns()
+	require.NoError(t, err)
+	assert.Len(t, runs, 2)
+	params, err := reader.GetParamRepository().FindParamsByTraceID(baseA + 3)
+	require.NoError(t, err)
+	require.Len(t, params, 1)
+	assert.Equal(t, `{"i":3}`, string(params[0].Data))
+}
+
+func TestConfig(t *testing.T) {
+	_, err := Config{}.driverDSN()
+	assert.Error(t, err)
+	dsn, err := Config{DSN: "u:p@tcp(db:3306)/functrace"}.driverDSN()
+	require.NoError(t, err)
+	assert.Contains(t, dsn, "clientFoundRows=true")
+
+	t.Setenv(EnvDSN, "u@tcp(db)/x")
+	t.Setenv(EnvMaxOpenConn, "8")
+	cfg := ConfigFromEnv().withDefaults()
+	assert.Equal(t, "u@tcp(db)/x", cfg.DSN)
+	assert.Equal(t, 8, cfg.MaxOpenConn)
+	assert.Equal(t, DefaultBatchSize, cfg.BatchSize)
+}
+
+func TestRegisteredInFactory(t *testing.T) {
+	assert.Contains(t, factory.Registered(), string(factory.DBTypeMySQL))
+
+	dsn := startServer(t)
+	db, err := factory.CreateRepositoryFactoryWithConfig(factory.DatabaseConfig{DBType: string(factory.DBTypeMySQL), DSN: dsn, MaxOpenConn: 2}, testLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, 2, db.(*MySQLDatabase).config.MaxOpenConn)
+}
@@ -0,0 +1,106 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+var _ domain.ParamRepository = (*ParamRepository)(nil)
+
+// ParamRepository 是MySQL实现的参数数据仓储
+type ParamRepository struct {
+	db        *sql.DB
+	sourceID  int64
+	batchSize int
+}
+
+// SaveParam 保存参数数据
+func (r *ParamRepository) SaveParam(param *model.ParamStoreData) (int64, error) {
+	result, err := r.db.Exec(SQLInsertParamPrefix+SQLParamValues,
+		param.ID, param.TraceID, param.Position, param.Data, param.IsReceiver, param.BaseID)
+	if err != nil {
+		return 0, fmt.Errorf("save param error: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// SaveParamsBatch 以多行插入批量保存参数数据（单事务）
+func (r *ParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	if len(params) == 0 {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx error: %w", err)
+	}
+	for start := 0; start < len(params); start += r.batchSize {
+		chunk := params[start:min(start+r.batchSize, len(params))]
+		args := make([]interface{}, 0, len(chunk)*6)
+		for _, p := range chunk {
+			args = append(args, p.ID, p.TraceID, p.Position, p.Data, p.IsReceiver, p.BaseID)
+		}
+		if _, err := tx.Exec(SQLInsertParamPrefix+repeatValues(SQLParamValues, len(chunk)), args...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("batch save param error: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// FindParamsByTraceID 根据跟踪ID查找参数
+func (r *ParamRepository) FindParamsByTraceID(traceId int64) ([]model.ParamStoreData, error) {
+	rows, err := r.db.Query(SQLSelectParamsByTrace, traceId)
+	if err != nil {
+		return nil, fmt.Errorf("find params by trace id error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.ParamStoreData
+	for rows.Next() {
+		var param model.ParamStoreData
+		if err := rows.Scan(&param.ID, &param.TraceID, &param.Position, &param.Data, &param.IsReceiver, &param.BaseID); err != nil {
+			return nil, fmt.Errorf("scan param data error: %w", err)
+		}
+		result = append(result, param)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate param data result error: %w", err)
+	}
+	return result, nil
+}
+
+// SaveParamCache 保存参数缓存，同一来源的相同地址会被覆盖
+func (r *ParamRepository) SaveParamCache(cache *model.ParamCache) (int64, error) {
+	result, err := r.db.Exec(SQLUpsertParamCache, r.sourceID, cache.Addr, cache.BaseID, cache.Data)
+	if err != nil {
+		return 0, fmt.Errorf("save param cache error: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// FindParamCacheByAddr 根据地址查找参数缓存
+func (r *ParamRepository) FindParamCacheByAddr(addr string) (*model.ParamCache, error) {
+	var cache model.ParamCache
+	err := r.db.QueryRow(SQLSelectParamCache, r.sourceID, addr).Scan(&cache.ID, &cache.Addr, &cache.BaseID, &cache.Data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // 没有找到缓存，返回 nil
+		}
+		return nil, fmt.Errorf("find param cache by addr error: %w", err)
+	}
+	return &cache, nil
+}
+
+// DeleteParamCacheByAddr 根据地址删除参数缓存
+func (r *ParamRepository) DeleteParamCacheByAddr(addr string) error {
+	if _, err := r.db.Exec(SQLDeleteParamCache, r.sourceID, addr); err != nil {
+		return fmt.Errorf("delete param cache by addr error: %w", err)
+	}
+	return nil
+}
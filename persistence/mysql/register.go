@@ -0,0 +1,30 @@
+package mysql
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/persistence/factory"
+)
+
+// 匿名导入本包即可通过 FUNCTRACE_DB_TYPE=mysql 选择该后端
+func init() {
+	factory.Register(string(factory.DBTypeMySQL), func(logger *logrus.Logger) domain.RepositoryFactory {
+		return NewMySQLDatabase(ConfigFromEnv(), logger)
+	})
+	factory.RegisterConfig(string(factory.DBTypeMySQL), func(config factory.DatabaseConfig, logger *logrus.Logger) domain.RepositoryFactory {
+		cfg := ConfigFromEnv()
+		if config.DSN != "" {
+			cfg.DSN = config.DSN
+		}
+		if config.MaxOpenConn > 0 {
+			cfg.MaxOpenConn = config.MaxOpenConn
+		}
+		if config.MaxIdleConn > 0 {
+			cfg.MaxIdleConn = config.MaxIdleConn
+		}
+		if config.MaxIdleTime > 0 {
+			cfg.MaxIdleTime = config.MaxIdleTime
+		}
+		return NewMySQLDatabase(cfg, logger)
+	})
+}
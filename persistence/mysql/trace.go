@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+var _ domain.TraceRepository = (*TraceRepository)(nil)
+
+// maxPendingBatches 写出失败时缓冲区最多积压的批次数，超出后拒绝新的写入
+const maxPendingBatches = 4
+
+// TraceRepository 是MySQL实现的跟踪数据仓储
+// 插入先进入缓冲区，按批次写出；调用在写出前返回时，耗时直接合并进待插入的行，省去一次 UPDATE。
+// 写出失败的行留在缓冲区中由下一次写出重试；积压超过 maxPendingBatches 个批次后 SaveTrace 返回写出错误，
+// 由调用方计数、重试或溢出
+type TraceRepository struct {
+	db        *sql.DB
+	batchSize int
+	inserted  atomic.Int64
+
+	mu      sync.Mutex
+	pending []*model.TraceData
+	index   map[int64]int // 跟踪ID -> pending 下标
+
+	// flushMu 写出期间持有写锁；更新在缓冲区中找不到行时先等待进行中的写出完成
+	flushMu sync.RWMutex
+}
+
+// SaveTrace 缓冲跟踪数据，缓冲区满时同步写出
+func (r *TraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
+	r.mu.Lock()
+	backlog := len(r.pending) >= r.batchSize*maxPendingBatches
+	r.mu.Unlock()
+	if backlog {
+		if err := r.Flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	td := *trace
+	r.mu.Lock()
+	r.index[td.ID] = len(r.pending)
+	r.pending = append(r.pending, &td)
+	full := len(r.pending) >= r.batchSize
+	r.mu.Unlock()
+
+	if full {
+		// 本行已进入缓冲区，写出失败时由之后的写出重试，不在此返回错误
+		_ = r.Flush()
+	}
+	return trace.ID, nil
+}
+
+// UpdateTraceTimeCost 更新跟踪时间成本
+func (r *TraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
+	if r.updatePending(id, timeCost) {
+		return nil
+	}
+
+	r.flushMu.RLock()
+	defer r.flushMu.RUnlock()
+	// 进行中的写出失败时，行会回到缓冲区
+	if r.updatePending(id, timeCost) {
+		return nil
+	}
+	result, err := r.db.Exec(SQLUpdateTimeCost, timeCost, 1, id)
+	if err != nil {
+		return fmt.Errorf("update trace time cost error: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected error: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("update trace time cost failed, no rows affected")
+	}
+	return nil
+}
+
+// updatePending 将耗时合并进缓冲中的行，行不在缓冲区时返回 false
+func (r *TraceRepository) updatePending(id int64, timeCost string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.index[id]
+	if ok {
+		r.pending[i].TimeCost = timeCost
+		r.pending[i].IsFinished = 1
+	}
+	return ok
+}
+
+// Flush 以多行插入写出缓冲的跟踪数据，失败的批次及其后的行放回缓冲区
+func (r *TraceRepository) Flush() error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	rows := r.pending
+	r.pending = nil
+	r.index = make(map[int64]int, len(rows))
+	r.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(rows); start += r.batchSize {
+		end := min(start+r.batchSize, len(rows))
+		chunk := rows[start:end]
+		args := make([]interface{}, 0, len(chunk)*10)
+		for _, t := range chunk {
+			args = append(args, t.ID, t.Name, t.GID, t.Indent, t.ParamsCount,
+				t.TimeCost, t.ParentId, t.IsFinished, t.CreatedAt, t.Seq)
+		}
+		if _, err := r.db.Exec(SQLInsertTracePrefix+repeatValues(SQLTraceValues, len(chunk)), args...); err != nil {
+			r.requeue(rows[start:])
+			return fmt.Errorf("save %d traces error: %w", len(rows)-start, err)
+		}
+		r.inserted.Add(int64(len(chunk)))
+	}
+	return nil
+}
+
+// requeue 将未写出的行放回缓冲区头部，保持写入顺序
+func (r *TraceRepository) requeue(rows []*model.TraceData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(rows[:len(rows):len(rows)], r.pending...)
+	r.index = make(map[int64]int, len(r.pending))
+	for i, td := range r.pending {
+		r.index[td.ID] = i
+	}
+}
+
+// FindRootFunctionsByGID 根据GID查找根函数
+func (r *TraceRepository) FindRootFunctionsByGID(gid uint64) ([]model.TraceData, error) {
+	if err := r.Flush(); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(SQLQueryRootFunctions, gid)
+	if err != nil {
+		return nil, fmt.Errorf("find root functions by gid error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.TraceData
+	for rows.Next() {
+		var (
+			trace    model.TraceData
+			timeCost sql.NullString
+		)
+		if err := rows.Scan(&trace.ID, &timeCost); err != nil {
+			return nil, fmt.Errorf("scan root functions data error: %w", err)
+		}
+		trace.GID = gid
+		trace.TimeCost = timeCost.String
+		result = append(result, trace)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate root functions result error: %w", err)
+	}
+	return result, nil
+}
+
+// repeatValues 生成 n 组以逗号分隔的占位符
+func repeatValues(values string, n int) string {
+	var b strings.Builder
+	b.Grow(n * (len(values) + 2))
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(values)
+	}
+	return b.String()
+}
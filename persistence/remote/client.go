@@ -0,0 +1,367 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/persistence/jsonl"
+)
+
+// ErrClientClosed 客户端已关闭
+var ErrClientClosed = errors.New("remote client is closed")
+
+// 确保Client实现了jsonl.LineWriter接口
+var _ jsonl.LineWriter = (*Client)(nil)
+
+// Stats 客户端发送统计
+type Stats struct {
+	Queued     int   // 队列中待打包的记录数
+	Pending    int   // 已打包但未确认的记录数
+	Sent       int64 // 已写入连接的记录数（含重发）
+	Acked      int64 // 已被收集器确认的记录数
+	Dropped    int64 // 队列满或关闭超时丢弃的记录数
+	Reconnects int64 // 重连次数
+	Connected  bool  // 当前是否已连接
+}
+
+// batch 已打包的一批记录
+type batch struct {
+	seq   int64
+	count int
+	frame []byte
+}
+
+// conn 一次连接及其确认读取协程
+type conn struct {
+	nc   net.Conn
+	acks chan int64
+	errs chan error
+}
+
+// Client 将 JSONL 记录按批次发送到收集器
+// 记录先进入有界队列，由后台协程打包发送；连接断开后按序重发未确认的批次
+type Client struct {
+	cfg     Config
+	network string
+	address string
+	hello   []byte
+	logger  *logrus.Logger
+
+	queue   chan []byte
+	flushCh chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	pending    atomic.Int64
+	sent       atomic.Int64
+	acked      atomic.Int64
+	dropped    atomic.Int64
+	reconnects atomic.Int64
+	connected  atomic.Bool
+}
+
+// NewClient 创建客户端并启动后台发送协程，连接在后台建立
+func NewClient(cfg Config, logger *logrus.Logger) (*Client, error) {
+	cfg = cfg.withDefaults()
+	network, address, err := ParseAddress(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	hello, err := json.Marshal(Hello{
+		Version: protocolVersion,
+		RunID:   uuid.NewString(),
+		Process: cfg.Process,
+		Host:    host,
+		PID:     os.Getpid(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal hello error: %w", err)
+	}
+
+	c := &Client{
+		cfg:     cfg,
+		network: network,
+		address: address,
+		hello:   encodeFrame(frameHello, hello),
+		logger:  logger,
+		queue:   make(chan []byte, cfg.QueueSize),
+		flushCh: make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+// WriteLine 将一行放入发送队列；队列满时按 BlockTimeout 等待，超时后丢弃并计数
+func (c *Client) WriteLine(line []byte) error {
+	select {
+	case <-c.closing:
+		c.dropped.Add(1)
+		return ErrClientClosed
+	default:
+	}
+
+	buf := append([]byte(nil), line...)
+	select {
+	case c.queue <- buf:
+		return nil
+	default:
+	}
+
+	switch {
+	case c.cfg.BlockTimeout == 0:
+	case c.cfg.BlockTimeout < 0:
+		select {
+		case c.queue <- buf:
+			return nil
+		case <-c.closing:
+		}
+	default:
+		timer := time.NewTimer(c.cfg.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case c.queue <- buf:
+			return nil
+		case <-timer.C:
+		case <-c.closing:
+		}
+	}
+	// 丢弃而非返回错误，避免上层重试加剧拥塞
+	c.dropped.Add(1)
+	return nil
+}
+
+// Flush 请求立即发送当前未满的批次
+func (c *Client) Flush() error {
+	select {
+	case c.flushCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close 发送队列中剩余的记录并等待确认，最长等待 ShutdownTimeout
+func (c *Client) Close() error {
+	c.once.Do(func() { close(c.closing) })
+	<-c.done
+	return nil
+}
+
+// Stats 返回发送统计
+func (c *Client) Stats() Stats {
+	return Stats{
+		Queued:     len(c.queue),
+		Pending:    int(c.pending.Load()),
+		Sent:       c.sent.Load(),
+		Acked:      c.acked.Load(),
+		Dropped:    c.dropped.Load(),
+		Reconnects: c.reconnects.Load(),
+		Connected:  c.connected.Load(),
+	}
+}
+
+// run 后台发送循环：打包、发送、处理确认与重连
+func (c *Client) run() {
+	defer close(c.done)
+
+	var (
+		cn       *conn
+		seq      int64
+		unacked  []*batch
+		lines    [][]byte
+		delay    = c.cfg.ReconnectDelay
+		retry    = time.NewTimer(0)
+		draining bool
+		deadline <-chan time.Time
+	)
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	defer retry.Stop()
+
+	disconnect := func(err error) {
+		if cn == nil {
+			return
+		}
+		c.logger.WithFields(logrus.Fields{"error": err, "addr": c.cfg.Address}).Warn("remote connection lost")
+		cn.nc.Close()
+		cn = nil
+		c.connected.Store(false)
+		retry.Reset(delay)
+	}
+	send := func(b *batch) {
+		if cn == nil {
+			return
+		}
+		cn.nc.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+		if _, err := cn.nc.Write(b.frame); err != nil {
+			disconnect(err)
+			return
+		}
+		c.sent.Add(int64(b.count))
+	}
+	seal := func() {
+		if len(lines) == 0 {
+			return
+		}
+		seq++
+		b := &batch{seq: seq, count: len(lines), frame: encodeSeqFrame(frameBatch, seq, bytes.Join(lines, []byte{'\n'}))}
+		lines = lines[:0]
+		unacked = append(unacked, b)
+		c.pending.Add(int64(b.count))
+		send(b)
+	}
+	add := func(line []byte) {
+		lines = append(lines, line)
+		if len(lines) >= c.cfg.BatchSize {
+			seal()
+		}
+	}
+
+	for {
+		if draining && len(unacked) == 0 && len(lines) == 0 {
+			if cn != nil {
+				cn.nc.Close()
+			}
+			return
+		}
+
+		// 未确认批次达到上限时暂停读取队列，队列满后写入方等待或丢弃
+		var in <-chan []byte
+		if !draining && len(unacked) < c.cfg.MaxInFlight {
+			in = c.queue
+		}
+		var acks <-chan int64
+		var errs <-chan error
+		if cn != nil {
+			acks, errs = cn.acks, cn.errs
+		}
+
+		select {
+		case line := <-in:
+			add(line)
+		case <-ticker.C:
+			seal()
+		case <-c.flushCh:
+			seal()
+		case ack := <-acks:
+			n := 0
+			for n < len(unacked) && unacked[n].seq <= ack {
+				c.acked.Add(int64(unacked[n].count))
+				c.pending.Add(-int64(unacked[n].count))
+				n++
+			}
+			unacked = unacked[n:]
+			delay = c.cfg.ReconnectDelay
+		case err := <-errs:
+			disconnect(err)
+		case <-retry.C:
+			if cn != nil {
+				continue
+			}
+			nc, err := c.dial()
+			if err != nil {
+				c.logger.WithFields(logrus.Fields{"error": err, "addr": c.cfg.Address}).Debug("connect collector failed")
+				retry.Reset(delay)
+				if delay *= 2; delay > maxReconnectDelay {
+					delay = maxReconnectDelay
+				}
+				continue
+			}
+			if seq > 0 || len(unacked) > 0 {
+				c.reconnects.Add(1)
+			}
+			cn = nc
+			c.connected.Store(true)
+			for _, b := range unacked {
+				send(b)
+			}
+		case <-c.closing:
+			if draining {
+				continue
+			}
+			draining = true
+			deadline = time.After(c.cfg.ShutdownTimeout)
+			// 队列在关闭后不再写入，一次性取空
+			for {
+				select {
+				case line := <-c.queue:
+					add(line)
+					continue
+				default:
+				}
+				break
+			}
+			seal()
+		case <-deadline:
+			lost := len(lines)
+			for _, b := range unacked {
+				lost += b.count
+			}
+			c.dropped.Add(int64(lost))
+			c.pending.Store(0)
+			c.logger.WithFields(logrus.Fields{"lost": lost, "addr": c.cfg.Address}).Warn("remote shutdown timeout, records dropped")
+			if cn != nil {
+				cn.nc.Close()
+			}
+			return
+		}
+	}
+}
+
+// dial 建立连接、发送 Hello 并启动确认读取协程
+func (c *Client) dial() (*conn, error) {
+	nc, err := net.DialTimeout(c.network, c.address, c.cfg.DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial collector error: %w", err)
+	}
+	nc.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+	if _, err := nc.Write(c.hello); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("send hello error: %w", err)
+	}
+	cn := &conn{nc: nc, acks: make(chan int64, c.cfg.MaxInFlight+1), errs: make(chan error, 1)}
+	go cn.readAcks()
+	return cn, nil
+}
+
+// readAcks 读取确认帧直到连接关闭
+func (cn *conn) readAcks() {
+	r := bufio.NewReader(cn.nc)
+	for {
+		kind, payload, err := readFrame(r)
+		if err == nil && kind != frameAck {
+			err = fmt.Errorf("unexpected frame type: %d", kind)
+		}
+		var seq int64
+		if err == nil {
+			seq, _, err = splitSeq(payload)
+		}
+		if err != nil {
+			cn.errs <- err
+			return
+		}
+		select {
+		case cn.acks <- seq:
+		default:
+			// 确认是累计的，通道满时丢弃旧值、保留最新值
+			select {
+			case <-cn.acks:
+			default:
+			}
+			cn.acks <- seq
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package remote 将跟踪记录经 TCP 或 Unix socket 流式发送到独立的收集器，
+// 由收集器把多个进程的数据写入同一个库
+package remote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config 客户端配置
+type Config struct {
+	Address         string        // 收集器地址：tcp://host:port、unix:///path 或 host:port
+	Process         string        // 上报的进程名，默认为可执行文件名
+	BatchSize       int           // 每批最多记录数
+	QueueSize       int           // 待发送记录队列长度
+	MaxInFlight     int           // 最多未确认的批次数，达到后暂停读取队列形成背压
+	FlushInterval   time.Duration // 批次未满时的最长等待时间
+	BlockTimeout    time.Duration // 队列满时写入的最长等待时间，0 表示立即丢弃，负数表示一直等待
+	DialTimeout     time.Duration // 建立连接超时
+	WriteTimeout    time.Duration // 单次写入超时
+	ReconnectDelay  time.Duration // 首次重连间隔，之后指数增长
+	ShutdownTimeout time.Duration // 关闭时等待剩余数据确认的最长时间
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() Config {
+	return Config{
+		BatchSize:       DefaultBatchSize,
+		QueueSize:       DefaultQueueSize,
+		MaxInFlight:     DefaultMaxInFlight,
+		FlushInterval:   DefaultFlushInterval,
+		BlockTimeout:    DefaultBlockTimeout,
+		DialTimeout:     DefaultDialTimeout,
+		WriteTimeout:    DefaultWriteTimeout,
+		ReconnectDelay:  DefaultReconnectDelay,
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// ConfigFromEnv 在默认配置基础上读取 FUNCTRACE_REMOTE_* 环境变量
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	cfg.Address = os.Getenv(EnvAddr)
+	cfg.Process = os.Getenv(EnvProcess)
+	if v, err := strconv.Atoi(os.Getenv(EnvQueueSize)); err == nil && v > 0 {
+		cfg.QueueSize = v
+	}
+	if v, err := time.ParseDuration(os.Getenv(EnvBlockTimeout)); err == nil {
+		cfg.BlockTimeout = v
+	}
+	return cfg
+}
+
+// withDefaults 为未设置的字段填充默认值
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c.BatchSize <= 0 {
+		c.BatchSize = d.BatchSize
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = d.QueueSize
+	}
+	if c.MaxInFlight <= 0 {
+		c.MaxInFlight = d.MaxInFlight
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = d.FlushInterval
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = d.DialTimeout
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = d.WriteTimeout
+	}
+	if c.ReconnectDelay <= 0 {
+		c.ReconnectDelay = d.ReconnectDelay
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = d.ShutdownTimeout
+	}
+	if c.Process == "" {
+		if exe, err := os.Executable(); err == nil {
+			c.Process = filepath.Base(exe)
+		}
+	}
+	return c
+}
+
+// ParseAddress 解析地址为 net.Dial 使用的网络类型与地址
+func ParseAddress(addr string) (network, address string, err error) {
+	switch {
+	case addr == "":
+		return "", "", fmt.Errorf("remote address is empty")
+	case strings.HasPrefix(addr, "unix://"):
+		return "unix", strings.TrimPrefix(addr, "unix://"), nil
+	case strings.HasPrefix(addr, "tcp://"):
+		return "tcp", strings.TrimPrefix(addr, "tcp://"), nil
+	case strings.Contains(addr, "://"):
+		return "", "", fmt.Errorf("unsupported remote address: %s", addr)
+	default:
+		return "tcp", addr, nil
+	}
+}
@@ -0,0 +1,37 @@
+package remote
+
+import "time"
+
+// 帧格式：长度(uint32 BE，不含自身) | 类型(1B) | 负载
+const (
+	frameHello byte = 1 // 客户端 → 服务端：Hello JSON
+	frameBatch byte = 2 // 客户端 → 服务端：批次序号(uint64 BE) | 以换行分隔的 JSONL 记录
+	frameAck   byte = 3 // 服务端 → 客户端：已写入的最大批次序号(uint64 BE)
+
+	protocolVersion = 1
+	maxFrameSize    = 64 << 20
+)
+
+// 环境变量
+const (
+	EnvAddr         = "FUNCTRACE_REMOTE_ADDR"          // 收集器地址，如 tcp://127.0.0.1:7070、unix:///tmp/functrace.sock
+	EnvProcess      = "FUNCTRACE_REMOTE_PROCESS"       // 上报的进程名，默认为可执行文件名
+	EnvQueueSize    = "FUNCTRACE_REMOTE_QUEUE_SIZE"    // 待发送记录队列长度
+	EnvBlockTimeout = "FUNCTRACE_REMOTE_BLOCK_TIMEOUT" // 队列满时写入的最长等待时间
+)
+
+// 默认值
+const (
+	DefaultBatchSize       = 256
+	DefaultQueueSize       = 65536
+	DefaultMaxInFlight     = 8
+	DefaultFlushInterval   = 200 * time.Millisecond
+	DefaultBlockTimeout    = 50 * time.Millisecond
+	DefaultDialTimeout     = 5 * time.Second
+	DefaultWriteTimeout    = 10 * time.Second
+	DefaultReconnectDelay  = 100 * time.Millisecond
+	DefaultShutdownTimeout = 5 * time.Second
+
+	maxReconnectDelay = 5 * time.Second
+	helloTimeout      = 10 * time.Second
+)
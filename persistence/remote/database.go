@@ -0,0 +1,79 @@
+package remote
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/persistence/jsonl"
+)
+
+// 确保RemoteDatabase实现了IDatabase接口
+var _ domain.RepositoryFactory = (*RemoteDatabase)(nil)
+
+// RemoteDatabase 网络发送仓储实现
+// 记录格式与 jsonl 后端相同，只是输出目标换成了收集器连接
+type RemoteDatabase struct {
+	config Config
+	logger *logrus.Logger
+	client *Client
+	stream *jsonl.JSONLDatabase
+}
+
+// NewRemoteDatabase 创建新的网络发送仓储工厂
+func NewRemoteDatabase(config Config, logger *logrus.Logger) domain.RepositoryFactory {
+	return &RemoteDatabase{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Initialize 启动客户端，连接在后台建立，收集器暂不可用时记录先排队
+func (d *RemoteDatabase) Initialize() error {
+	if d.config.Address == "" {
+		return fmt.Errorf("%s is required for the remote backend", EnvAddr)
+	}
+	client, err := NewClient(d.config, d.logger)
+	if err != nil {
+		return fmt.Errorf("create remote client error: %w", err)
+	}
+	d.client = client
+	d.stream = jsonl.NewStreamDatabase(client, jsonl.Config{FlushInterval: d.client.cfg.FlushInterval}, d.logger)
+	if err := d.stream.Initialize(); err != nil {
+		client.Close()
+		return err
+	}
+	d.logger.Infof("streaming traces to %s", d.config.Address)
+	return nil
+}
+
+// Close 发送剩余记录并关闭连接
+func (d *RemoteDatabase) Close() error {
+	if d.stream == nil {
+		return nil
+	}
+	err := d.stream.Close()
+	stats := d.client.Stats()
+	d.logger.WithFields(logrus.Fields{"acked": stats.Acked, "dropped": stats.Dropped, "reconnects": stats.Reconnects}).Info("remote stream closed")
+	return err
+}
+
+// Stats 返回客户端发送统计
+func (d *RemoteDatabase) Stats() Stats {
+	if d.client == nil {
+		return Stats{}
+	}
+	return d.client.Stats()
+}
+
+func (d *RemoteDatabase) GetTraceRepository() domain.TraceRepository {
+	return d.stream.GetTraceRepository()
+}
+
+func (d *RemoteDatabase) GetParamRepository() domain.ParamRepository {
+	return d.stream.GetParamRepository()
+}
+
+func (d *RemoteDatabase) GetGoroutineRepository() domain.GoroutineRepository {
+	return d.stream.GetGoroutineRepository()
+}
@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Hello 客户端连接后发送的第一帧，标识数据来源
+type Hello struct {
+	Version int    `json:"version"`
+	RunID   string `json:"runId"`   // 运行ID，同一进程重连时保持不变
+	Process string `json:"process"` // 进程名
+	Host    string `json:"host"`    // 主机名
+	PID     int    `json:"pid"`     // 进程ID
+}
+
+// appendFrameHeader 追加帧头，n 为类型之后负载的长度
+func appendFrameHeader(b []byte, kind byte, n int) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(n+1))
+	return append(b, kind)
+}
+
+// encodeFrame 编码一个完整的帧
+func encodeFrame(kind byte, payload []byte) []byte {
+	b := appendFrameHeader(make([]byte, 0, len(payload)+5), kind, len(payload))
+	return append(b, payload...)
+}
+
+// encodeSeqFrame 编码以序号开头的帧（批次与确认）
+func encodeSeqFrame(kind byte, seq int64, body []byte) []byte {
+	b := appendFrameHeader(make([]byte, 0, len(body)+13), kind, len(body)+8)
+	b = binary.BigEndian.AppendUint64(b, uint64(seq))
+	return append(b, body...)
+}
+
+// readFrame 读取一帧，返回类型与负载
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n == 0 || n > maxFrameSize {
+		return 0, nil, fmt.Errorf("invalid frame size: %d", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return buf[0], buf[1:], nil
+}
+
+// splitSeq 拆分以序号开头的负载
+func splitSeq(payload []byte) (int64, []byte, error) {
+	if len(payload) < 8 {
+		return 0, nil, fmt.Errorf("short frame: %d bytes", len(payload))
+	}
+	return int64(binary.BigEndian.Uint64(payload)), payload[8:], nil
+}
@@ -0,0 +1,337 @@
+package remote
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func openStore(t *testing.T) *sqlite.SQLiteDatabase {
+	t.Helper()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "collected.db"), testLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// startServer 启动写入 store 的收集器，返回监听地址
+func startServer(t *testing.T, store domain.RepositoryFactory, addr string) (*Server, string) {
+	t.Helper()
+	l, err := Listen(addr)
+	require.NoError(t, err)
+	server := NewServer(store, testLogger())
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	if l.Addr().Network() == "unix" {
+		return server, "unix://" + l.Addr().String()
+	}
+	return server, "tcp://" + l.Addr().String()
+}
+
+func openRemote(t *testing.T, cfg Config) *RemoteDatabase {
+	t.Helper()
+	cfg.FlushInterval = 10 * time.Millisecond
+	cfg.ReconnectDelay = 10 * time.Millisecond
+	db := NewRemoteDatabase(cfg, testLogger()).(*RemoteDatabase)
+	require.NoError(t, db.Initialize())
+	return db
+}
+
+// writeCalls 写入 n 个带参数的已完成调用，跟踪ID从 1 开始
+func writeCalls(t *testing.T, db domain.RepositoryFactory, name string, n int) {
+	t.Helper()
+	now := time.Now().Format(time.RFC3339Nano)
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(1, 1, now, 0, name))
+	require.NoError(t, err)
+	for i := 1; i <= n; i++ {
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(int64(i), name, 1, 0, 0, 0, now, ""))
+		require.NoError(t, err)
+		_, err = db.GetParamRepository().SaveParam(model.NewParamStoreData(int64(i), 0, []byte(`{}`), false, 0).WithID(int64(i)))
+		require.NoError(t, err)
+		require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(int64(i), "1ms"))
+	}
+}
+
+func scanTraces(t *testing.T, db *sqlite.SQLiteDatabase) []*model.TraceData {
+	t.Helper()
+	var got []*model.TraceData
+	require.NoError(t, db.ScanTraces(func(td *model.TraceData) error {
+		got = append(got, td)
+		return nil
+	}))
+	return got
+}
+
+func TestParseAddress(t *testing.T) {
+	for addr, want := range map[string][2]string{
+		"tcp://127.0.0.1:7070":   {"tcp", "127.0.0.1:7070"},
+		"localhost:7070":         {"tcp", "localhost:7070"},
+		"unix:///tmp/trace.sock": {"unix", "/tmp/trace.sock"},
+	} {
+		network, address, err := ParseAddress(addr)
+		require.NoError(t, err)
+		assert.Equal(t, want, [2]string{network, address})
+	}
+	_, _, err := ParseAddress("udp://127.0.0.1:1")
+	assert.Error(t, err)
+}
+
+func TestTwoSourcesIntoOneStore(t *testing.T) {
+	store := openStore(t)
+	_, addr := startServer(t, store, "tcp://127.0.0.1:0")
+
+	a := openRemote(t, Config{Address: addr, Process: "svc-a"})
+	b := openRemote(t, Config{Address: addr, Process: "svc-b"})
+	writeCalls(t, a, "main.a", 20)
+	writeCalls(t, b, "main.b", 30)
+	require.NoError(t, a.Close())
+	require.NoError(t, b.Close())
+	assert.Equal(t, int64(0), a.Stats().Dropped)
+	assert.Equal(t, int64(20*3+1), a.Stats().Acked)
+
+	sources, err := store.GetSourceRepository().FindAllSources()
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	byProcess := map[string]model.Source{}
+	for _, s := range sources {
+		byProcess[s.Process] = s
+	}
+	assert.Equal(t, int64(20*3+1), byProcess["svc-a"].Records)
+	assert.Equal(t, int64(30*3+1), byProcess["svc-b"].Records)
+
+	// 两个进程的ID都从 1 开始，每个来源登记一次运行，写入后按运行区分命名空间
+	runs, err := store.GetRunRepository().FindAllRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	byUUID := map[string]model.Run{}
+	for _, r := range runs {
+		byUUID[r.UUID] = r
+	}
+	traces := scanTraces(t, store)
+	require.Len(t, traces, 50)
+	for _, td := range traces {
+		run := byUUID[byProcess["svc-a"].RunID]
+		if td.Name == "main.b" {
+			run = byUUID[byProcess["svc-b"].RunID]
+		}
+		assert.Equal(t, run.ID, model.RunIDOf(td.ID))
+		assert.Equal(t, uint64(run.IDBase()+1), td.GID)
+		assert.Equal(t, "1ms", td.TimeCost)
+	}
+	runB := byUUID[byProcess["svc-b"].RunID]
+	assert.Equal(t, "svc-b", runB.Executable)
+	params, err := store.GetParamRepository().FindParamsByTraceID(runB.IDBase() + 30)
+	require.NoError(t, err)
+	assert.Len(t, params, 1)
+}
+
+func TestReconnectAfterServerRestart(t *testing.T) {
+	store := openStore(t)
+	sock := "unix://" + filepath.Join(t.TempDir(), "collector.sock")
+	server, addr := startServer(t, store, sock)
+
+	db := openRemote(t, Config{Address: addr})
+	writeCalls(t, db, "main.first", 10)
+	require.Eventually(t, func() bool { return db.Stats().Acked == 10*3+1 }, 5*time.Second, 10*time.Millisecond)
+
+	// 收集器重启期间的记录先排队，重连后继续发送且不重复写入
+	require.NoError(t, server.Close())
+	for i := 11; i <= 20; i++ {
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(int64(i), "main.second", 1, 0, 0, 0, time.Now().Format(time.RFC3339Nano), ""))
+		require.NoError(t, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	startServer(t, store, sock)
+	require.NoError(t, db.Close())
+
+	stats := db.Stats()
+	assert.Equal(t, int64(0), stats.Dropped)
+	assert.GreaterOrEqual(t, stats.Reconnects, int64(1))
+	assert.Len(t, scanTraces(t, store), 20)
+
+	sources, err := store.GetSourceRepository().FindAllSources()
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, int64(10*3+1+10), sources[0].Records)
+
+	// 重启后的收集器沿用该来源已登记的运行
+	runs, err := store.GetRunRepository().FindAllRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, sources[0].RunID, runs[0].UUID)
+}
+
+func TestDuplicateBatchIsAcked(t *testing.T) {
+	store := openStore(t)
+	_, addr := startServer(t, store, "tcp://127.0.0.1:0")
+	_, address, _ := ParseAddress(addr)
+
+	nc, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer nc.Close()
+	_, err = nc.Write(encodeFrame(frameHello, []byte(`{"version":1,"runId":"run-1","process":"p"}`)))
+	require.NoError(t, err)
+
+	line := []byte(`{"op":"insert","table":"trace","data":{"id":1,"name":"main.x","gid":1}}`)
+	r := bufio.NewReader(nc)
+	for i := 0; i < 2; i++ {
+		_, err = nc.Write(encodeSeqFrame(frameBatch, 1, line))
+		require.NoError(t, err)
+		kind, payload, err := readFrame(r)
+		require.NoError(t, err)
+		assert.Equal(t, frameAck, kind)
+		seq, _, err := splitSeq(payload)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), seq)
+	}
+	assert.Len(t, scanTraces(t, store), 1)
+}
+
+// flakyStore 第一次更新调用耗时时失败的仓储
+type flakyStore struct {
+	*sqlite.SQLiteDatabase
+	failed bool
+}
+
+type flakyTraceRepository struct {
+	domain.TraceRepository
+	store *flakyStore
+}
+
+func (r flakyTraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
+	if !r.store.failed {
+		r.store.failed = true
+		return errors.New("disk full")
+	}
+	return r.TraceRepository.UpdateTraceTimeCost(id, timeCost)
+}
+
+func (f *flakyStore) GetTraceRepository() domain.TraceRepository {
+	return flakyTraceRepository{TraceRepository: f.SQLiteDatabase.GetTraceRepository(), store: f}
+}
+
+func TestFailedBatchIsNotAcked(t *testing.T) {
+	store := &flakyStore{SQLiteDatabase: openStore(t)}
+	_, addr := startServer(t, store, "tcp://127.0.0.1:0")
+	_, address, _ := ParseAddress(addr)
+
+	body := []byte(`{"op":"insert","table":"trace","data":{"id":1,"name":"main.x","gid":1}}` + "\n" +
+		`{"op":"update","table":"trace","data":{"id":1,"timeCost":"1ms"}}`)
+	send := func() (byte, error) {
+		nc, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		defer nc.Close()
+		_, err = nc.Write(encodeFrame(frameHello, []byte(`{"version":1,"runId":"run-1","process":"p"}`)))
+		require.NoError(t, err)
+		_, err = nc.Write(encodeSeqFrame(frameBatch, 1, body))
+		require.NoError(t, err)
+		kind, _, err := readFrame(bufio.NewReader(nc))
+		return kind, err
+	}
+
+	// 写入失败的批次不确认，连接被关闭
+	_, err := send()
+	require.Error(t, err)
+
+	// 重发后从失败的记录继续写入，已写入的记录不重复
+	kind, err := send()
+	require.NoError(t, err)
+	assert.Equal(t, frameAck, kind)
+	traces := scanTraces(t, store.SQLiteDatabase)
+	require.Len(t, traces, 1)
+	assert.Equal(t, "1ms", traces[0].TimeCost)
+
+	sources, err := store.GetSourceRepository().FindAllSources()
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, int64(1), sources[0].LastSeq)
+	assert.Equal(t, int64(2), sources[0].Records)
+}
+
+func TestDropWhenQueueFull(t *testing.T) {
+	// 收集器不可用、队列满时不阻塞调用方，丢弃并计数
+	db := openRemote(t, Config{Address: "tcp://127.0.0.1:1", QueueSize: 4, BlockTimeout: 0, ShutdownTimeout: 50 * time.Millisecond})
+	writeCalls(t, db, "main.lost", 10)
+	require.NoError(t, db.Close())
+
+	stats := db.Stats()
+	assert.Equal(t, int64(10*3+1), stats.Dropped)
+	assert.Equal(t, int64(0), stats.Acked)
+	assert.False(t, stats.Connected)
+}
+
+func TestMissingAddress(t *testing.T) {
+	db := NewRemoteDatabase(Config{}, testLogger())
+	assert.Error(t, db.Initialize())
+}
+
+func TestMergeCollectedDatabase(t *testing.T) {
+	dir := t.TempDir()
+	collected := filepath.Join(dir, "collected.db")
+	store, err := sqlite.Open(collected, testLogger())
+	require.NoError(t, err)
+	server, addr := startServer(t, store, "tcp://127.0.0.1:0")
+	a := openRemote(t, Config{Address: addr, Process: "svc-a"})
+	b := openRemote(t, Config{Address: addr, Process: "svc-b"})
+	writeCalls(t, a, "main.a", 20)
+	writeCalls(t, b, "main.b", 30)
+	require.NoError(t, a.Close())
+	require.NoError(t, b.Close())
+	require.NoError(t, server.Close())
+	require.NoError(t, store.Close())
+
+	// 目标库已有一次运行，合并后的来源运行需要平移到新的命名空间
+	target, err := sqlite.Open(filepath.Join(dir, "merged.db"), testLogger())
+	require.NoError(t, err)
+	defer target.Close()
+	_, err = target.GetRunRepository().StartRun(&model.Run{UUID: "local", Status: model.RunStatusClean})
+	require.NoError(t, err)
+
+	merged, err := target.Merge(collected)
+	require.NoError(t, err)
+	require.Len(t, merged, 2)
+	byProcess := map[string]*model.Run{}
+	for _, m := range merged {
+		run, err := target.GetRunRepository().FindRunByID(m.RunID)
+		require.NoError(t, err)
+		require.NotNil(t, run)
+		assert.Equal(t, collected, run.Origin)
+		byProcess[run.Executable] = run
+		assert.Equal(t, int64(1), m.Goroutines)
+		assert.Equal(t, m.Traces, m.Params)
+	}
+	require.Contains(t, byProcess, "svc-a")
+	require.Contains(t, byProcess, "svc-b")
+
+	// 每个来源的数据落在其运行的命名空间中，引用同步平移
+	traces := scanTraces(t, target)
+	require.Len(t, traces, 50)
+	for _, td := range traces {
+		run := byProcess["svc-a"]
+		if td.Name == "main.b" {
+			run = byProcess["svc-b"]
+		}
+		assert.Equal(t, run.ID, model.RunIDOf(td.ID))
+		assert.Equal(t, uint64(run.IDBase()+1), td.GID)
+	}
+	params, err := target.GetParamRepository().FindParamsByTraceID(byProcess["svc-b"].IDBase() + 30)
+	require.NoError(t, err)
+	assert.Len(t, params, 1)
+}
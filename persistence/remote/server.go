@@ -0,0 +1,316 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/domain/record"
+)
+
+// source 一个数据来源的写入状态，同一来源的重连共享该状态
+type source struct {
+	mu      sync.Mutex
+	model   model.Source
+	run     *model.Run
+	applier *record.Applier
+
+	// 写入中途失败的批次及其已写入的行数，客户端重发该批次时从失败处继续
+	partialSeq   int64
+	partialLines int
+}
+
+// Server 收集器服务端，将多个客户端发送的记录写入同一个仓储
+// 每个来源登记为一次运行，ID加上 Run.IDBase() 偏移，与本地直接写入的运行共用一个命名空间
+type Server struct {
+	store   domain.RepositoryFactory
+	sources domain.SourceRepository // 仓储不支持来源记录时为 nil
+	runs    domain.RunRepository    // 仓储不支持运行记录时为 nil
+	logger  *logrus.Logger
+
+	mu        sync.Mutex
+	byRunID   map[string]*source
+	nextID    int64 // 仓储不支持运行记录时在进程内分配运行ID
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer 创建写入 store 的收集器服务端
+func NewServer(store domain.RepositoryFactory, logger *logrus.Logger) *Server {
+	s := &Server{
+		store:     store,
+		logger:    logger,
+		byRunID:   make(map[string]*source),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+	if p, ok := store.(domain.SourceRepositoryProvider); ok {
+		s.sources = p.GetSourceRepository()
+	}
+	if p, ok := store.(domain.RunRepositoryProvider); ok {
+		s.runs = p.GetRunRepository()
+	}
+	return s
+}
+
+// Listen 按地址（tcp://、unix:// 或 host:port）创建监听，Unix socket 的残留文件会被删除
+func Listen(addr string) (net.Listener, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		os.Remove(address)
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s error: %w", addr, err)
+	}
+	return l, nil
+}
+
+// Serve 接受连接直到监听关闭，Close 后返回 nil
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("accept error: %w", err)
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			nc.Close()
+			continue
+		}
+		s.conns[nc] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handle(nc)
+	}
+}
+
+// Close 关闭全部监听与连接，并等待正在写入的批次完成
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for nc := range s.conns {
+		nc.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// handle 处理一个连接：读取 Hello，随后逐批写入并确认
+func (s *Server) handle(nc net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		nc.Close()
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+	}()
+	log := s.logger.WithFields(logrus.Fields{"remote": nc.RemoteAddr().String()})
+
+	r := bufio.NewReaderSize(nc, 64<<10)
+	nc.SetReadDeadline(time.Now().Add(helloTimeout))
+	kind, payload, err := readFrame(r)
+	if err != nil || kind != frameHello {
+		log.WithFields(logrus.Fields{"error": err, "type": kind}).Warn("invalid hello")
+		return
+	}
+	nc.SetReadDeadline(time.Time{})
+	var hello Hello
+	if err := json.Unmarshal(payload, &hello); err != nil || hello.RunID == "" {
+		log.WithFields(logrus.Fields{"error": err}).Warn("invalid hello")
+		return
+	}
+	src, err := s.source(&hello, nc.RemoteAddr().String())
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Error("register source failed")
+		return
+	}
+	log = log.WithFields(logrus.Fields{"source": src.model.ID, "run": src.run.ID, "process": hello.Process, "pid": hello.PID})
+	log.Info("source connected")
+
+	for {
+		kind, payload, err := readFrame(r)
+		if err != nil {
+			log.WithFields(logrus.Fields{"error": err}).Info("source disconnected")
+			return
+		}
+		if kind != frameBatch {
+			log.WithFields(logrus.Fields{"type": kind}).Warn("unexpected frame")
+			return
+		}
+		seq, body, err := splitSeq(payload)
+		if err != nil {
+			log.WithFields(logrus.Fields{"error": err}).Warn("invalid batch")
+			return
+		}
+		if err := s.apply(src, seq, body, log); err != nil {
+			log.WithFields(logrus.Fields{"error": err, "seq": seq}).Error("write batch failed")
+			return
+		}
+		if _, err := nc.Write(encodeSeqFrame(frameAck, seq, nil)); err != nil {
+			log.WithFields(logrus.Fields{"error": err}).Info("source disconnected")
+			return
+		}
+	}
+}
+
+// source 按运行ID查找或登记数据来源及其运行，客户端重连或收集器重启后沿用原来的ID与进度
+func (s *Server) source(hello *Hello, remoteAddr string) (*source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if src, ok := s.byRunID[hello.RunID]; ok {
+		return src, nil
+	}
+
+	now := time.Now().Format(time.RFC3339Nano)
+	m := model.Source{
+		RunID:       hello.RunID,
+		Process:     hello.Process,
+		Host:        hello.Host,
+		PID:         hello.PID,
+		RemoteAddr:  remoteAddr,
+		ConnectedAt: now,
+		LastSeenAt:  now,
+	}
+	var existing *model.Source
+	if s.sources != nil {
+		var err error
+		if existing, err = s.sources.FindSourceByRunID(hello.RunID); err != nil {
+			return nil, err
+		}
+	}
+	run, err := s.registerRun(hello, existing != nil)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		m = *existing
+	} else if s.sources != nil {
+		if m.ID, err = s.sources.SaveSource(&m); err != nil {
+			return nil, err
+		}
+	} else {
+		m.ID = run.ID
+	}
+
+	applier := record.NewApplier(s.store)
+	applier.IDOffset = run.IDBase()
+	applier.SkipParamCache = true
+	src := &source{model: m, run: run, applier: applier}
+	s.byRunID[hello.RunID] = src
+	return src, nil
+}
+
+// registerRun 为来源登记一次运行；收集器重启后（来源已存在）按运行UUID沿用库中已有的运行
+func (s *Server) registerRun(hello *Hello, known bool) (*model.Run, error) {
+	if s.runs == nil {
+		s.nextID++
+		return &model.Run{ID: s.nextID, UUID: hello.RunID}, nil
+	}
+	if known {
+		runs, err := s.runs.FindAllRuns()
+		if err != nil {
+			return nil, err
+		}
+		for i := range runs {
+			if runs[i].UUID == hello.RunID {
+				return &runs[i], nil
+			}
+		}
+	}
+	run := &model.Run{
+		UUID:       hello.RunID,
+		Executable: hello.Process,
+		Hostname:   hello.Host,
+		PID:        hello.PID,
+		StartedAt:  time.Now().Format(time.RFC3339Nano),
+		Status:     model.RunStatusRunning,
+	}
+	id, err := s.runs.StartRun(run)
+	if err != nil {
+		return nil, err
+	}
+	run.ID = id
+	return run, nil
+}
+
+// apply 写入一个批次；已写入过的序号（重连后的重发）直接确认
+// 写入失败时返回错误，该批次不被确认，客户端重连后重发，并从失败的记录继续写入；
+// 无法解码的记录重发也不会成功，记录日志后跳过
+func (s *Server) apply(src *source, seq int64, body []byte, log *logrus.Entry) error {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if seq <= src.model.LastSeq {
+		return nil
+	}
+
+	var lines [][]byte
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	start := 0
+	if seq == src.partialSeq {
+		start = src.partialLines
+	}
+	for i := start; i < len(lines); i++ {
+		err := src.applier.Apply(lines[i])
+		if errors.Is(err, record.ErrInvalid) {
+			log.WithFields(logrus.Fields{"error": err, "seq": seq}).Warn("skip invalid record")
+			continue
+		}
+		if err != nil {
+			src.partialSeq, src.partialLines = seq, i
+			return fmt.Errorf("apply record error: %w", err)
+		}
+		src.model.Records++
+	}
+	if err := src.applier.Flush(); err != nil {
+		src.partialSeq, src.partialLines = seq, len(lines)
+		return fmt.Errorf("flush params error: %w", err)
+	}
+
+	src.partialSeq, src.partialLines = 0, 0
+	src.model.LastSeq = seq
+	src.model.LastSeenAt = time.Now().Format(time.RFC3339Nano)
+	if s.sources != nil {
+		return s.sources.UpdateSourceProgress(src.model.ID, src.model.LastSeq, src.model.Records, src.model.LastSeenAt)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config SQLite 后端配置
+type Config struct {
+	Dir           string        // 数据库目录，默认为当前目录
+	NameTemplate  string        // 文件名模板，支持 {exe} {pid} {time} {seq}，默认 DefaultNameTemplate
+	DSN           string        // 直接指定数据库文件路径或 file: URI，设置后忽略 Dir、NameTemplate 与滚动配置
+	MaxSize       int64         // 当前文件的数据量超过该字节数后滚动到新文件，0 表示不按大小滚动
+	MaxAge        time.Duration // 当前文件创建超过该时长后滚动到新文件，0 表示不按时间滚动
+	MaxTotalSize  int64         // 本次运行全部文件的总大小上限（字节），0 表示不限制
+	Retention     string        // 超出总大小时的清理方式：RetentionFiles 或 RetentionTrees
+	CheckInterval time.Duration // 检查大小、时间与总大小的间隔
+}
+
+// DefaultConfig 返回默认配置：当前目录下的 <可执行文件名>_<启动时间>.db，不滚动
+func DefaultConfig() Config {
+	return Config{
+		Dir:           DefaultDir,
+		NameTemplate:  DefaultNameTemplate,
+		Retention:     RetentionFiles,
+		CheckInterval: DefaultCheckInterval,
+	}
+}
+
+// ConfigFromEnv 在默认配置基础上读取 FUNCTRACE_SQLITE_* 环境变量
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v := os.Getenv(EnvDir); v != "" {
+		cfg.Dir = v
+	}
+	if v := os.Getenv(EnvNameTemplate); v != "" {
+		cfg.NameTemplate = v
+	}
+	cfg.DSN = os.Getenv(EnvDSN)
+	if v, err := strconv.ParseInt(os.Getenv(EnvMaxSize), 10, 64); err == nil && v > 0 {
+		cfg.MaxSize = v << 20
+	}
+	if v, err := time.ParseDuration(os.Getenv(EnvMaxAge)); err == nil && v > 0 {
+		cfg.MaxAge = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv(EnvMaxTotalSize), 10, 64); err == nil && v > 0 {
+		cfg.MaxTotalSize = v << 20
+	}
+	if v := os.Getenv(EnvRetention); v == RetentionFiles || v == RetentionTrees {
+		cfg.Retention = v
+	}
+	return cfg
+}
+
+// withDefaults 补全未设置的字段
+func (c Config) withDefaults() Config {
+	if c.Dir == "" {
+		c.Dir = DefaultDir
+	}
+	if c.NameTemplate == "" {
+		c.NameTemplate = DefaultNameTemplate
+	}
+	if c.Retention == "" {
+		c.Retention = RetentionFiles
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = DefaultCheckInterval
+	}
+	return c
+}
+
+// rotates 是否需要滚动或总大小控制
+func (c Config) rotates() bool {
+	return c.DSN == "" && (c.MaxSize > 0 || c.MaxAge > 0 || c.MaxTotalSize > 0)
+}
+
+// fileName 展开文件名模板；滚动时模板中没有 {seq} 则在扩展名前插入序号
+func (c Config) fileName(start time.Time, seq int, rotating bool) string {
+	tmpl := c.NameTemplate
+	if rotating && !strings.Contains(tmpl, "{seq}") {
+		ext := filepath.Ext(tmpl)
+		tmpl = strings.TrimSuffix(tmpl, ext) + ".{seq}" + ext
+	}
+	return strings.NewReplacer(
+		"{exe}", executableName(),
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{time}", start.Format(TimeLayout),
+		"{seq}", fmt.Sprintf("%04d", seq),
+	).Replace(tmpl)
+}
+
+// executableName 返回可执行文件名，获取失败时为 default
+func executableName() string {
+	execName, err := os.Executable()
+	if err != nil {
+		return "default"
+	}
+	return filepath.Base(execName)
+}
+
+// dataSource 将文件路径转换为驱动使用的 DSN，file: URI 原样使用
+func dataSource(path string) string {
+	if strings.HasPrefix(path, "file:") {
+		return path
+	}
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+}
@@ -1,10 +1,45 @@
 package sqlite
 
-// 数据库相关常量
+import "time"
+
+// 环境变量
+const (
+	EnvDir          = "FUNCTRACE_SQLITE_DIR"            // 数据库目录
+	EnvNameTemplate = "FUNCTRACE_SQLITE_NAME"           // 文件名模板
+	EnvDSN          = "FUNCTRACE_SQLITE_DSN"            // 数据库文件路径或 file: URI
+	EnvMaxSize      = "FUNCTRACE_SQLITE_MAX_SIZE"       // 单个文件的最大数据量（MB）
+	EnvMaxAge       = "FUNCTRACE_SQLITE_MAX_AGE"        // 单个文件的最长使用时间，如 1h
+	EnvMaxTotalSize = "FUNCTRACE_SQLITE_MAX_TOTAL_SIZE" // 全部文件的总大小上限（MB）
+	EnvRetention    = "FUNCTRACE_SQLITE_RETENTION"      // 超出总大小时的清理方式
+)
+
+// 总大小超限时的清理方式
+const (
+	RetentionFiles = "files" // 删除最旧的已滚动文件
+	RetentionTrees = "trees" // 从最旧的文件中删除最旧的已完成根调用树
+)
+
+// 默认值
 const (
-	// 数据库文件名格式
-	DBFileNameFormat = "./%s_%s.db"
+	DefaultDir           = "."
+	DefaultNameTemplate  = "{exe}_{time}.db"
+	DefaultCheckInterval = 10 * time.Second
+	TimeLayout           = "20060102150405"
 
+	// 清单文件名格式：<可执行文件名>_<启动时间>.manifest.json
+	ManifestNameFormat = "%s_%s.manifest.json"
+	// 每轮删除的根调用树数量
+	treeBatchSize = 64
+)
+
+// ReadOnlyDSNFormat 只读打开已有文件的 DSN
+const ReadOnlyDSNFormat = "file:%s?mode=ro&_pragma=busy_timeout(5000)"
+
+// CurrentSchemaVersion 当前代码使用的结构版本，等于 migrations 中最后一步的版本号
+const CurrentSchemaVersion = 9
+
+// 数据库相关常量
+const (
 	// SQL语句
 	SQLCreateTraceTable = `CREATE TABLE IF NOT EXISTS TraceData (
 		id INTEGER PRIMARY KEY, 
@@ -46,14 +81,149 @@ const (
 		data BLOB
 	)`
 
-	SQLCreateGIDIndex            = "CREATE INDEX IF NOT EXISTS idx_gid ON TraceData (gid)"
-	SQLCreateParentIndex         = "CREATE INDEX IF NOT EXISTS idx_parent ON TraceData (parentId)"
-	SQLCreateParamTraceIndex     = "CREATE INDEX IF NOT EXISTS idx_param_trace ON ParamStore (traceId)"
-	SQLCreateParamBaseIndex      = "CREATE INDEX IF NOT EXISTS idx_param_base ON ParamStore (baseId)"
-	SQLCreateParamCacheAddrIndex = "CREATE INDEX IF NOT EXISTS idx_param_cache_addr ON ParamCache (addr)"
+	// 函数统计快照表创建语句
+	SQLCreateFuncStatsTable = `CREATE TABLE IF NOT EXISTS FuncStats (
+		name TEXT PRIMARY KEY, 
+		count INTEGER, 
+		errorCount INTEGER, 
+		totalTime INTEGER, 
+		selfTime INTEGER, 
+		maxTime INTEGER, 
+		p50 INTEGER, 
+		p95 INTEGER, 
+		p99 INTEGER, 
+		sketch BLOB, 
+		updatedAt TEXT
+	)`
+
+	// 跟踪事件表创建语句
+	SQLCreateTraceEventTable = `CREATE TABLE IF NOT EXISTS TraceEvent (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, 
+		traceId INTEGER, 
+		gid INTEGER, 
+		kind TEXT, 
+		message TEXT, 
+		stack TEXT, 
+		createdAt TEXT
+	)`
+
+	// 泄漏报告表创建语句
+	SQLCreateLeakReportTable = `CREATE TABLE IF NOT EXISTS LeakReport (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, 
+		goroutineId INTEGER, 
+		originGid INTEGER, 
+		initFuncName TEXT, 
+		creatorGid INTEGER, 
+		creatorFunc TEXT, 
+		createTime TEXT, 
+		age TEXT, 
+		lastTraceId INTEGER, 
+		lastFuncName TEXT, 
+		lastFinished INTEGER, 
+		stack TEXT, 
+		reportedAt TEXT
+	)`
+
+	// 数据来源表创建语句
+	SQLCreateSourceTable = `CREATE TABLE IF NOT EXISTS Source (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, 
+		runId TEXT UNIQUE, 
+		process TEXT, 
+		host TEXT, 
+		pid INTEGER, 
+		remoteAddr TEXT, 
+		connectedAt TEXT, 
+		lastSeenAt TEXT, 
+		lastSeq INTEGER, 
+		records INTEGER
+	)`
+
+	// 运行记录表创建语句
+	SQLCreateRunTable = `CREATE TABLE IF NOT EXISTS Run (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, 
+		uuid TEXT, 
+		executable TEXT, 
+		args TEXT, 
+		hostname TEXT, 
+		pid INTEGER, 
+		goVersion TEXT, 
+		modulePath TEXT, 
+		moduleVersion TEXT, 
+		vcsRevision TEXT, 
+		vcsTime TEXT, 
+		vcsModified INTEGER, 
+		buildInfo TEXT, 
+		config TEXT, 
+		startedAt TEXT, 
+		endedAt TEXT, 
+		status TEXT
+	)`
+
+	// 按运行区分的函数统计快照表，迁移时替换原 FuncStats 表
+	SQLCreateRunFuncStatsTable = `CREATE TABLE IF NOT EXISTS FuncStatsByRun (
+		runId INTEGER NOT NULL, 
+		name TEXT NOT NULL, 
+		count INTEGER, 
+		errorCount INTEGER, 
+		totalTime INTEGER, 
+		selfTime INTEGER, 
+		maxTime INTEGER, 
+		p50 INTEGER, 
+		p95 INTEGER, 
+		p99 INTEGER, 
+		sketch BLOB, 
+		updatedAt TEXT, 
+		PRIMARY KEY (runId, name)
+	)`
+	SQLCopyFuncStatsToRun = "INSERT INTO FuncStatsByRun (runId, name, count, errorCount, totalTime, selfTime, maxTime, p50, p95, p99, sketch, updatedAt) SELECT 1, name, count, errorCount, totalTime, selfTime, maxTime, p50, p95, p99, sketch, updatedAt FROM FuncStats"
+	SQLDropFuncStatsTable = "DROP TABLE FuncStats"
+	SQLRenameRunFuncStats = "ALTER TABLE FuncStatsByRun RENAME TO FuncStats"
+	// 升级前已有数据的文件登记一个占位运行，使之后的运行使用新的ID命名空间
+	SQLInsertLegacyRun = "INSERT INTO Run (id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status) SELECT 1, '', '', '', '', 0, '', '', '', '', '', 0, '', '', '', '', 'unknown' WHERE NOT EXISTS (SELECT 1 FROM Run) AND (EXISTS (SELECT 1 FROM TraceData) OR EXISTS (SELECT 1 FROM GoroutineTrace))"
+
+	// 其他表通过ID命名空间关联运行，runId 为虚拟生成列，不占用存储也不增加写入开销
+	SQLAddTraceRunColumn     = "ALTER TABLE TraceData ADD COLUMN runId INTEGER GENERATED ALWAYS AS ((id >> 40) + 1) VIRTUAL"
+	SQLAddGoroutineRunColumn = "ALTER TABLE GoroutineTrace ADD COLUMN runId INTEGER GENERATED ALWAYS AS ((id >> 40) + 1) VIRTUAL"
+	SQLAddParamRunColumn     = "ALTER TABLE ParamStore ADD COLUMN runId INTEGER GENERATED ALWAYS AS ((id >> 40) + 1) VIRTUAL"
+	SQLAddEventRunColumn     = "ALTER TABLE TraceEvent ADD COLUMN runId INTEGER GENERATED ALWAYS AS ((traceId >> 40) + 1) VIRTUAL"
+	SQLAddLeakRunColumn      = "ALTER TABLE LeakReport ADD COLUMN runId INTEGER GENERATED ALWAYS AS ((goroutineId >> 40) + 1) VIRTUAL"
+	SQLAddRunOriginColumn    = "ALTER TABLE Run ADD COLUMN origin TEXT NOT NULL DEFAULT ''"
+
+	SQLCreateGIDIndex             = "CREATE INDEX IF NOT EXISTS idx_gid ON TraceData (gid)"
+	SQLCreateParentIndex          = "CREATE INDEX IF NOT EXISTS idx_parent ON TraceData (parentId)"
+	SQLCreateParamTraceIndex      = "CREATE INDEX IF NOT EXISTS idx_param_trace ON ParamStore (traceId)"
+	SQLCreateParamBaseIndex       = "CREATE INDEX IF NOT EXISTS idx_param_base ON ParamStore (baseId)"
+	SQLCreateParamCacheAddrIndex  = "CREATE INDEX IF NOT EXISTS idx_param_cache_addr ON ParamCache (addr)"
+	SQLCreateEventTraceIndex      = "CREATE INDEX IF NOT EXISTS idx_event_trace ON TraceEvent (traceId)"
+	SQLCreateGoroutineParentIndex = "CREATE INDEX IF NOT EXISTS idx_goroutine_parent_trace ON GoroutineTrace (parentTraceId)"
+	SQLCreateNameIndex            = "CREATE INDEX IF NOT EXISTS idx_trace_name ON TraceData (name)"
+	SQLCreateDurationIndex        = "CREATE INDEX IF NOT EXISTS idx_trace_duration ON TraceData (duration)"
+	SQLCreateTraceRunIndex        = "CREATE INDEX IF NOT EXISTS idx_trace_run ON TraceData (runId)"
+	SQLCreateGoroutineRunIndex    = "CREATE INDEX IF NOT EXISTS idx_goroutine_run ON GoroutineTrace (runId)"
+
+	// 结构版本与迁移
+	SQLCreateSchemaMigrationTable = `CREATE TABLE IF NOT EXISTS SchemaMigration (
+		version INTEGER PRIMARY KEY, 
+		name TEXT, 
+		appliedAt TEXT
+	)`
+	SQLSelectSchemaTableExists = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'SchemaMigration'"
+	SQLSelectSchemaVersion     = "SELECT COALESCE(MAX(version), 0) FROM SchemaMigration"
+	SQLInsertSchemaMigration   = "INSERT OR IGNORE INTO SchemaMigration (version, name, appliedAt) VALUES (?, ?, ?)"
+	SQLSelectColumns           = "SELECT name FROM pragma_table_xinfo(?)" // 包含生成列
+	SQLVacuumInto              = "VACUUM INTO ?"
+
+	// 迁移中补充的列
+	SQLAddCreatorGidColumn    = "ALTER TABLE GoroutineTrace ADD COLUMN creatorGid INTEGER"
+	SQLAddCreatorFuncColumn   = "ALTER TABLE GoroutineTrace ADD COLUMN creatorFunc TEXT"
+	SQLAddParentTraceIDColumn = "ALTER TABLE GoroutineTrace ADD COLUMN parentTraceId INTEGER"
+	SQLAddDurationColumn      = "ALTER TABLE TraceData ADD COLUMN duration INTEGER"
+	SQLSelectMissingDurations = "SELECT id, timeCost FROM TraceData WHERE duration IS NULL AND timeCost IS NOT NULL AND timeCost != ''"
+	SQLUpdateDuration         = "UPDATE TraceData SET duration = ? WHERE id = ?"
 
 	SQLInsertTrace    = "INSERT INTO TraceData (id, name, gid, indent, paramsCount, parentId, createdAt, seq) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
-	SQLUpdateTimeCost = "UPDATE TraceData SET timeCost = ?, isFinished = ? WHERE id = ?"
+	SQLUpsertTrace    = "INSERT OR REPLACE INTO TraceData (id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq, duration) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLUpdateTimeCost = "UPDATE TraceData SET timeCost = ?, isFinished = ?, duration = ? WHERE id = ?"
 
 	// 参数表操作语句
 	SQLInsertParam = "INSERT INTO ParamStore (id, traceId, position, data, isReceiver, baseId) VALUES (?, ?, ?, ?, ?, ?)"
@@ -64,9 +234,185 @@ const (
 	SQLDeleteParamCacheByAddr = "DELETE FROM ParamCache WHERE addr = ?"
 
 	// Goroutine表操作语句
-	SQLInsertGoroutine         = "INSERT INTO GoroutineTrace (id, originGid, createTime, isFinished, initFuncName) VALUES (?, ?, ?, ?, ?)"
+	SQLInsertGoroutine         = "INSERT INTO GoroutineTrace (id, originGid, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLSelectGoroutineByID     = "SELECT id, originGid, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId FROM GoroutineTrace WHERE id = ?"
 	SQLUpdateGoroutineTimeCost = "UPDATE GoroutineTrace SET timeCost = ?, isFinished = ? WHERE id = ?"
 
+	// 函数统计表操作语句
+	SQLUpsertFuncStats    = "INSERT OR REPLACE INTO FuncStats (runId, name, count, errorCount, totalTime, selfTime, maxTime, p50, p95, p99, sketch, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLSelectAllFuncStats = "SELECT runId, name, count, errorCount, totalTime, selfTime, maxTime, p50, p95, p99, sketch, updatedAt FROM FuncStats ORDER BY totalTime DESC"
+
+	// 运行记录表操作语句
+	SQLInsertRun       = "INSERT INTO Run (uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLInsertRunWithID = "INSERT INTO Run (id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLUpdateRunEnd    = "UPDATE Run SET endedAt = ?, status = ? WHERE id = ?"
+	SQLSelectRunByID   = "SELECT id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin FROM Run WHERE id = ?"
+	SQLSelectAllRuns   = "SELECT id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin FROM Run ORDER BY id"
+
+	// 跟踪事件表操作语句
+	SQLInsertTraceEvent      = "INSERT INTO TraceEvent (traceId, gid, kind, message, stack, createdAt) VALUES (?, ?, ?, ?, ?, ?)"
+	SQLSelectEventsByTraceID = "SELECT id, traceId, gid, kind, message, stack, createdAt FROM TraceEvent WHERE traceId = ? ORDER BY id"
+
+	// 泄漏报告表操作语句
+	SQLInsertLeakReport     = "INSERT INTO LeakReport (goroutineId, originGid, initFuncName, creatorGid, creatorFunc, createTime, age, lastTraceId, lastFuncName, lastFinished, stack, reportedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLSelectAllLeakReports = "SELECT id, goroutineId, originGid, initFuncName, creatorGid, creatorFunc, createTime, age, lastTraceId, lastFuncName, lastFinished, stack, reportedAt FROM LeakReport ORDER BY id"
+
+	// 数据来源表操作语句
+	SQLInsertSource         = "INSERT INTO Source (runId, process, host, pid, remoteAddr, connectedAt, lastSeenAt, lastSeq, records) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLUpdateSourceProgress = "UPDATE Source SET lastSeq = ?, records = ?, lastSeenAt = ? WHERE id = ?"
+	SQLSelectSourceByRunID  = "SELECT id, runId, process, host, pid, remoteAddr, connectedAt, lastSeenAt, lastSeq, records FROM Source WHERE runId = ?"
+	SQLSelectAllSources     = "SELECT id, runId, process, host, pid, remoteAddr, connectedAt, lastSeenAt, lastSeq, records FROM Source ORDER BY id"
+
+	// 全表遍历语句（离线导出）
+	SQLScanTraces     = "SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq FROM TraceData ORDER BY id"
+	SQLScanGoroutines = "SELECT id, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId FROM GoroutineTrace ORDER BY id"
+
+	// 调用树查询语句
+	SQLSelectTraceByID           = "SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq FROM TraceData WHERE id = ?"
+	SQLSelectTraceChildren       = "SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq FROM TraceData WHERE parentId = ? ORDER BY id"
+	SQLSelectRootTracesByGID     = "SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq FROM TraceData WHERE gid = ? AND parentId = 0 ORDER BY id"
+	SQLSelectGoroutinesSpawnedBy = "SELECT id, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId FROM GoroutineTrace WHERE parentTraceId = ? ORDER BY id"
+
+	// 条件查询语句，WHERE 子句按查询条件拼接
+	SQLSelectTraces     = "SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq FROM TraceData"
+	SQLSelectGoroutines = "SELECT id, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId FROM GoroutineTrace"
+	SQLSelectSubtree    = `WITH RECURSIVE subtree(id) AS (
+		SELECT id FROM TraceData WHERE id = ?
+		UNION ALL
+		SELECT t.id FROM TraceData t JOIN subtree s ON t.parentId = s.id
+	)
+	SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq FROM TraceData
+	WHERE id IN (SELECT id FROM subtree) AND id > ? ORDER BY id LIMIT ?`
+
 	// 查询特定goroutine的根函数调用
 	SQLQueryRootFunctions = "SELECT id, timeCost FROM TraceData WHERE gid = ? AND indent = 0"
+
+	// 滚动时将仍在执行的调用与协程复制到新文件
+	SQLAttachPrevious        = "ATTACH DATABASE ? AS prev"
+	SQLDetachPrevious        = "DETACH DATABASE prev"
+	SQLCopyOpenTraces        = "INSERT OR IGNORE INTO main.TraceData (id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq, duration) SELECT id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq, duration FROM prev.TraceData WHERE isFinished IS NULL OR isFinished = 0"
+	SQLCopyRuns              = "INSERT OR IGNORE INTO main.Run (id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin) SELECT id, uuid, executable, args, hostname, pid, goVersion, modulePath, moduleVersion, vcsRevision, vcsTime, vcsModified, buildInfo, config, startedAt, endedAt, status, origin FROM prev.Run"
+	SQLCopyRunningGoroutines = "INSERT OR IGNORE INTO main.GoroutineTrace (id, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId) SELECT id, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId FROM prev.GoroutineTrace WHERE isFinished IS NULL OR isFinished = 0"
+
+	// 数据量与按根调用树清理
+	SQLPageCount         = "PRAGMA page_count"
+	SQLFreelistCount     = "PRAGMA freelist_count"
+	SQLPageSize          = "PRAGMA page_size"
+	SQLVacuum            = "VACUUM"
+	SQLSelectOldestRoots = "SELECT id FROM TraceData WHERE parentId = 0 AND isFinished = 1 ORDER BY id LIMIT ?"
+	SQLCountTraces       = "SELECT COUNT(*) FROM TraceData"
+	sqlSubtreeIDs        = "WITH RECURSIVE subtree(id) AS (SELECT ? UNION ALL SELECT t.id FROM TraceData t JOIN subtree s ON t.parentId = s.id) "
+	// 仍被其他参数作为增量基准引用的参数保留
+	SQLDeleteTreeParams = sqlSubtreeIDs + "DELETE FROM ParamStore WHERE traceId IN (SELECT id FROM subtree) AND id NOT IN (SELECT baseId FROM ParamStore WHERE baseId > 0)"
+	SQLDeleteTreeEvents = sqlSubtreeIDs + "DELETE FROM TraceEvent WHERE traceId IN (SELECT id FROM subtree)"
+	SQLDeleteTree       = sqlSubtreeIDs + "DELETE FROM TraceData WHERE id IN (SELECT id FROM subtree)"
+
+	// 合并其他库：?1 为ID平移量（目标运行与来源运行的ID偏移之差），?2 为来源运行ID；0 表示无引用，保持不变
+	SQLAttachSource     = "ATTACH DATABASE ? AS src"
+	SQLDetachSource     = "DETACH DATABASE src"
+	SQLSelectSourceRuns = "SELECT DISTINCT runId FROM TraceData UNION SELECT DISTINCT runId FROM GoroutineTrace UNION SELECT DISTINCT runId FROM ParamStore"
+	SQLMergeTraces      = "INSERT INTO main.TraceData (id, name, gid, indent, paramsCount, timeCost, parentId, isFinished, createdAt, seq, duration) SELECT id + ?1, name, gid + ?1, indent, paramsCount, timeCost, CASE WHEN parentId = 0 THEN 0 ELSE parentId + ?1 END, isFinished, createdAt, seq, duration FROM src.TraceData WHERE runId = ?2"
+	SQLMergeGoroutines  = "INSERT INTO main.GoroutineTrace (id, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, parentTraceId) SELECT id + ?1, originGid, timeCost, createTime, isFinished, initFuncName, creatorGid, creatorFunc, CASE WHEN parentTraceId = 0 THEN 0 ELSE parentTraceId + ?1 END FROM src.GoroutineTrace WHERE runId = ?2"
+	SQLMergeParams      = "INSERT INTO main.ParamStore (id, traceId, position, data, isReceiver, baseId) SELECT id + ?1, traceId + ?1, position, data, isReceiver, CASE WHEN baseId = 0 THEN 0 ELSE baseId + ?1 END FROM src.ParamStore WHERE runId = ?2"
+	SQLMergeEvents      = "INSERT INTO main.TraceEvent (traceId, gid, kind, message, stack, createdAt) SELECT traceId + ?1, gid + ?1, kind, message, stack, createdAt FROM src.TraceEvent WHERE runId = ?2 ORDER BY id"
+	SQLMergeLeaks       = "INSERT INTO main.LeakReport (goroutineId, originGid, initFuncName, creatorGid, creatorFunc, createTime, age, lastTraceId, lastFuncName, lastFinished, stack, reportedAt) SELECT goroutineId + ?1, originGid, initFuncName, creatorGid, creatorFunc, createTime, age, CASE WHEN lastTraceId = 0 THEN 0 ELSE lastTraceId + ?1 END, lastFuncName, lastFinished, stack, reportedAt FROM src.LeakReport WHERE runId = ?2 ORDER BY id"
+	// 函数统计：?1 为目标运行ID
+	SQLMergeFuncStats = "INSERT OR REPLACE INTO main.FuncStats (runId, name, count, errorCount, totalTime, selfTime, maxTime, p50, p95, p99, sketch, updatedAt) SELECT ?1, name, count, errorCount, totalTime, selfTime, maxTime, p50, p95, p99, sketch, updatedAt FROM src.FuncStats WHERE runId = ?2"
+
+	// 分析汇总表：由 Summarize 从 TraceData 重新计算，时间单位均为纳秒
+	SQLCreateFuncSummaryTable = `CREATE TABLE IF NOT EXISTS FuncSummary (
+		runId INTEGER NOT NULL, 
+		name TEXT NOT NULL, 
+		calls INTEGER, 
+		unfinished INTEGER, 
+		totalTime INTEGER, 
+		selfTime INTEGER, 
+		minTime INTEGER, 
+		maxTime INTEGER, 
+		p50 INTEGER, 
+		p90 INTEGER, 
+		p99 INTEGER, 
+		PRIMARY KEY (runId, name)
+	)`
+	SQLCreateCallEdgeTable = `CREATE TABLE IF NOT EXISTS CallEdge (
+		runId INTEGER NOT NULL, 
+		caller TEXT NOT NULL, 
+		callee TEXT NOT NULL, 
+		calls INTEGER, 
+		totalTime INTEGER, 
+		maxTime INTEGER, 
+		PRIMARY KEY (runId, caller, callee)
+	)`
+	SQLCreateGoroutineSummaryTable = `CREATE TABLE IF NOT EXISTS GoroutineSummary (
+		gid INTEGER PRIMARY KEY, 
+		runId INTEGER, 
+		originGid INTEGER, 
+		initFuncName TEXT, 
+		calls INTEGER, 
+		rootCalls INTEGER, 
+		rootTime INTEGER, 
+		maxDepth INTEGER, 
+		firstCallAt TEXT, 
+		lastCallAt TEXT
+	)`
+	SQLCreateRootCallSummaryTable = `CREATE TABLE IF NOT EXISTS RootCallSummary (
+		traceId INTEGER PRIMARY KEY, 
+		runId INTEGER, 
+		gid INTEGER, 
+		name TEXT, 
+		duration INTEGER, 
+		calls INTEGER, 
+		unfinished INTEGER, 
+		maxDepth INTEGER, 
+		createdAt TEXT
+	)`
+	SQLCreateFuncSummaryTotalIndex  = "CREATE INDEX IF NOT EXISTS idx_func_summary_total ON FuncSummary (runId, totalTime)"
+	SQLCreateFuncSummarySelfIndex   = "CREATE INDEX IF NOT EXISTS idx_func_summary_self ON FuncSummary (runId, selfTime)"
+	SQLCreateCallEdgeCalleeIndex    = "CREATE INDEX IF NOT EXISTS idx_call_edge_callee ON CallEdge (runId, callee)"
+	SQLCreateGoroutineSummaryIndex  = "CREATE INDEX IF NOT EXISTS idx_goroutine_summary_run ON GoroutineSummary (runId)"
+	SQLCreateRootCallDurationIndex  = "CREATE INDEX IF NOT EXISTS idx_root_call_duration ON RootCallSummary (runId, duration)"
+	SQLCreateRootCallNameIndex      = "CREATE INDEX IF NOT EXISTS idx_root_call_name ON RootCallSummary (name)"
+	SQLCreateRootCallGoroutineIndex = "CREATE INDEX IF NOT EXISTS idx_root_call_gid ON RootCallSummary (gid)"
+
+	SQLClearFuncSummary      = "DELETE FROM FuncSummary"
+	SQLClearCallEdge         = "DELETE FROM CallEdge"
+	SQLClearGoroutineSummary = "DELETE FROM GoroutineSummary"
+	SQLClearRootCallSummary  = "DELETE FROM RootCallSummary"
+	// 按运行清除与计算：? 为运行ID
+	SQLClearRunFuncSummary      = "DELETE FROM FuncSummary WHERE runId = ?"
+	SQLClearRunCallEdge         = "DELETE FROM CallEdge WHERE runId = ?"
+	SQLClearRunGoroutineSummary = "DELETE FROM GoroutineSummary WHERE runId = ?"
+	SQLClearRunRootCallSummary  = "DELETE FROM RootCallSummary WHERE runId = ?"
+	SQLSelectTraceRuns          = "SELECT DISTINCT runId FROM TraceData"
+	// 自身耗时为调用耗时减去已完成的直接子调用耗时之和；分位数按最近秩计算，未完成的调用排在最后不参与
+	SQLSummarizeFuncs = `WITH child AS (
+		SELECT parentId, SUM(duration) AS childTime FROM TraceData WHERE runId = ?1 AND parentId != 0 AND duration IS NOT NULL GROUP BY parentId
+	), ranked AS (
+		SELECT t.runId, t.name, t.isFinished, t.duration, t.duration - COALESCE(c.childTime, 0) AS self, 
+			ROW_NUMBER() OVER (PARTITION BY t.runId, t.name ORDER BY t.duration IS NULL, t.duration) AS rn, 
+			COUNT(t.duration) OVER (PARTITION BY t.runId, t.name) AS n 
+		FROM TraceData t LEFT JOIN child c ON c.parentId = t.id WHERE t.runId = ?1
+	)
+	INSERT INTO FuncSummary (runId, name, calls, unfinished, totalTime, selfTime, minTime, maxTime, p50, p90, p99) 
+	SELECT runId, name, COUNT(*), SUM(CASE WHEN isFinished = 1 THEN 0 ELSE 1 END), COALESCE(SUM(duration), 0), COALESCE(SUM(self), 0), MIN(duration), MAX(duration), 
+		MAX(CASE WHEN rn = (n * 50 + 99) / 100 THEN duration END), 
+		MAX(CASE WHEN rn = (n * 90 + 99) / 100 THEN duration END), 
+		MAX(CASE WHEN rn = (n * 99 + 99) / 100 THEN duration END) 
+	FROM ranked GROUP BY runId, name`
+	SQLSummarizeEdges = `INSERT INTO CallEdge (runId, caller, callee, calls, totalTime, maxTime) 
+	SELECT c.runId, p.name, c.name, COUNT(*), COALESCE(SUM(c.duration), 0), MAX(c.duration) 
+	FROM TraceData c JOIN TraceData p ON p.id = c.parentId WHERE c.runId = ? GROUP BY c.runId, p.name, c.name`
+	SQLSummarizeGoroutines = `INSERT INTO GoroutineSummary (gid, runId, originGid, initFuncName, calls, rootCalls, rootTime, maxDepth, firstCallAt, lastCallAt) 
+	SELECT t.gid, MIN(t.runId), g.originGid, g.initFuncName, COUNT(*), SUM(CASE WHEN t.parentId = 0 THEN 1 ELSE 0 END), 
+		COALESCE(SUM(CASE WHEN t.parentId = 0 THEN t.duration END), 0), MAX(t.indent) + 1, MIN(t.createdAt), MAX(t.createdAt) 
+	FROM TraceData t LEFT JOIN GoroutineTrace g ON g.id = t.gid WHERE t.runId = ? GROUP BY t.gid`
+	// 同一协程内的ID单调递增，根调用的子树即该协程中从它到下一个根调用之前的全部调用
+	SQLSummarizeRootCalls = `WITH roots AS (
+		SELECT id, runId, gid, name, indent, duration, createdAt, LEAD(id) OVER (PARTITION BY gid ORDER BY id) AS nextId 
+		FROM TraceData WHERE runId = ? AND parentId = 0
+	)
+	INSERT INTO RootCallSummary (traceId, runId, gid, name, duration, calls, unfinished, maxDepth, createdAt) 
+	SELECT r.id, r.runId, r.gid, r.name, r.duration, COUNT(*), SUM(CASE WHEN t.isFinished = 1 THEN 0 ELSE 1 END), MAX(t.indent) - r.indent + 1, r.createdAt 
+	FROM roots r JOIN TraceData t ON t.gid = r.gid AND t.id >= r.id AND (r.nextId IS NULL OR t.id < r.nextId) 
+	GROUP BY r.id`
 )
@@ -14,33 +14,80 @@ import (
 
 // 确保SQLiteDatabase实现了IDatabase接口
 var _ domain.RepositoryFactory = (*SQLiteDatabase)(nil)
+var _ domain.StatsRepositoryProvider = (*SQLiteDatabase)(nil)
+var _ domain.EventRepositoryProvider = (*SQLiteDatabase)(nil)
+var _ domain.LeakRepositoryProvider = (*SQLiteDatabase)(nil)
+var _ domain.TraceScanner = (*SQLiteDatabase)(nil)
+var _ domain.TraceTreeReader = (*SQLiteDatabase)(nil)
+var _ domain.TraceQueryReader = (*SQLiteDatabase)(nil)
+var _ domain.SourceRepositoryProvider = (*SQLiteDatabase)(nil)
+var _ domain.RunRepositoryProvider = (*SQLiteDatabase)(nil)
 
 // SQLiteDatabase SQLite数据库实现
 type SQLiteDatabase struct {
 	goroutineRepository domain.GoroutineRepository
 	traceRepository     domain.TraceRepository
 	paramRepository     domain.ParamRepository
+	statsRepository     domain.StatsRepository
+	eventRepository     domain.EventRepository
+	leakRepository      domain.LeakRepository
+	sourceRepository    domain.SourceRepository
+	runRepository       domain.RunRepository
 	db                  *sql.DB
+	path                string
+	tempDir             string // OpenReadOnly 迁移副本所在的临时目录
+	config              Config
 	logger              *logrus.Logger
 }
 
-// NewSQLiteDatabase 创建新的SQLite数据库
+// NewSQLiteDatabase 创建新的SQLite数据库，文件位置读取 FUNCTRACE_SQLITE_* 环境变量，不滚动
 func NewSQLiteDatabase(logger *logrus.Logger) domain.RepositoryFactory {
 	s := &SQLiteDatabase{
 		db:     nil,
+		config: ConfigFromEnv(),
 		logger: logger,
 	}
 
 	return s
 }
 
-// Initialize 初始化数据库
+// NewDatabase 按配置创建SQLite仓储工厂，配置了滚动或总大小上限时返回 RotatingDatabase
+func NewDatabase(config Config, logger *logrus.Logger) domain.RepositoryFactory {
+	config = config.withDefaults()
+	if config.rotates() {
+		return NewRotatingDatabase(config, logger)
+	}
+	return &SQLiteDatabase{config: config, logger: logger}
+}
+
+// Open 打开指定路径的数据库文件，缺失的表会被创建，旧版本的表结构会被升级
+func Open(dbPath string, logger *logrus.Logger) (*SQLiteDatabase, error) {
+	s := &SQLiteDatabase{logger: logger}
+	if err := s.open(dbPath); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Initialize 按配置创建目录并打开数据库文件
 func (s *SQLiteDatabase) Initialize() error {
+	config := s.config.withDefaults()
+	if config.DSN != "" {
+		return s.open(config.DSN)
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return fmt.Errorf("create db dir error: %w", err)
+	}
+	return s.open(filepath.Join(config.Dir, config.fileName(time.Now(), 1, false)))
+}
+
+// open 打开数据库连接并创建表
+func (s *SQLiteDatabase) open(dbPath string) error {
 	// 创建数据库连接
-	dbPath := findAvailableDBName()
 	var err error
 	s.logger.Infof("opening db: %s", dbPath)
-	s.db, err = sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath))
+	s.path = dbPath
+	s.db, err = sql.Open("sqlite", dataSource(dbPath))
 	if err != nil {
 		return fmt.Errorf("can't open db: %w", err)
 	}
@@ -55,48 +102,42 @@ func (s *SQLiteDatabase) Initialize() error {
 		return fmt.Errorf("can't ping db: %w", err)
 	}
 
-	// 创建表
-	if err := s.createTablesAndIndexes(); err != nil {
-		return fmt.Errorf("can't create tables and indexes: %w", err)
+	// 创建表或升级旧版本的表结构
+	if err := s.migrate(); err != nil {
+		return fmt.Errorf("can't migrate schema: %w", err)
 	}
+	s.initRepositories()
 
 	return nil
 }
 
-// createTables 创建数据表
-func (s *SQLiteDatabase) createTablesAndIndexes() error {
-	tables := []string{
-		SQLCreateTraceTable,
-		SQLCreateGoroutineTable,
-		SQLCreateParamTable,
-		SQLCreateParamCacheTable,
-
-		// 创建索引
-		SQLCreateGIDIndex,
-		SQLCreateParentIndex,
-		SQLCreateParamTraceIndex,
-		SQLCreateParamBaseIndex,
-		SQLCreateParamCacheAddrIndex,
-	}
-
-	for _, table := range tables {
-		if _, err := s.db.Exec(table); err != nil {
-			return fmt.Errorf("can't exec sql: %s, %w", table, err)
-		}
-	}
+// initRepositories 创建各仓储
+func (s *SQLiteDatabase) initRepositories() {
 	s.goroutineRepository = NewGoroutineRepository(s.db)
 	s.traceRepository = NewTraceRepository(s.db)
 	s.paramRepository = NewParamRepository(s.db)
+	s.statsRepository = NewStatsRepository(s.db)
+	s.eventRepository = NewEventRepository(s.db)
+	s.leakRepository = NewLeakRepository(s.db)
+	s.sourceRepository = NewSourceRepository(s.db)
+	s.runRepository = NewRunRepository(s.db)
+}
 
-	return nil
+// Path 返回数据库文件路径
+func (s *SQLiteDatabase) Path() string {
+	return s.path
 }
 
-// Close 关闭数据库连接
+// Close 关闭数据库连接，并删除只读打开时创建的迁移副本
 func (s *SQLiteDatabase) Close() error {
+	var err error
 	if s.db != nil {
-		return s.db.Close()
+		err = s.db.Close()
 	}
-	return nil
+	if s.tempDir != "" {
+		os.RemoveAll(s.tempDir)
+	}
+	return err
 }
 
 func (s *SQLiteDatabase) GetGoroutineRepository() domain.GoroutineRepository {
@@ -111,13 +152,22 @@ func (s *SQLiteDatabase) GetParamRepository() domain.ParamRepository {
 	return s.paramRepository
 }
 
-// findAvailableDBName 查找可用的数据库文件名
-func findAvailableDBName() string {
-	execName, err := os.Executable()
-	if err != nil {
-		execName = "default"
-	}
-	execName = filepath.Base(execName)
-	currentTime := time.Now().Format("20060102150405")
-	return fmt.Sprintf(DBFileNameFormat, execName, currentTime)
+func (s *SQLiteDatabase) GetStatsRepository() domain.StatsRepository {
+	return s.statsRepository
+}
+
+func (s *SQLiteDatabase) GetEventRepository() domain.EventRepository {
+	return s.eventRepository
+}
+
+func (s *SQLiteDatabase) GetLeakRepository() domain.LeakRepository {
+	return s.leakRepository
+}
+
+func (s *SQLiteDatabase) GetSourceRepository() domain.SourceRepository {
+	return s.sourceRepository
+}
+
+func (s *SQLiteDatabase) GetRunRepository() domain.RunRepository {
+	return s.runRepository
 }
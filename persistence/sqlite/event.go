@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// EventRepository 是SQLite实现的跟踪事件仓储
+type EventRepository struct {
+	db *sql.DB
+}
+
+// NewEventRepository 创建一个新的SQLite跟踪事件仓储
+func NewEventRepository(db *sql.DB) domain.EventRepository {
+	return &EventRepository{
+		db: db,
+	}
+}
+
+// SaveEvent 保存跟踪事件
+func (r *EventRepository) SaveEvent(event *model.TraceEvent) (int64, error) {
+	result, err := r.db.Exec(
+		SQLInsertTraceEvent,
+		event.TraceID,
+		event.GID,
+		event.Kind,
+		event.Message,
+		event.Stack,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("save trace event error: %w", err)
+	}
+
+	return result.LastInsertId()
+}
+
+// FindEventsByTraceID 根据跟踪ID查找事件
+func (r *EventRepository) FindEventsByTraceID(traceId int64) ([]model.TraceEvent, error) {
+	rows, err := r.db.Query(SQLSelectEventsByTraceID, traceId)
+	if err != nil {
+		return nil, fmt.Errorf("find events by trace id error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.TraceEvent
+	for rows.Next() {
+		var event model.TraceEvent
+		if err := rows.Scan(&event.ID, &event.TraceID, &event.GID, &event.Kind, &event.Message, &event.Stack, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan trace event error: %w", err)
+		}
+		result = append(result, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trace event result error: %w", err)
+	}
+
+	return result, nil
+}
@@ -29,6 +29,9 @@ func (r *GoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (in
 		goroutine.CreateTime,
 		goroutine.IsFinished,
 		goroutine.InitFuncName,
+		goroutine.CreatorGID,
+		goroutine.CreatorFunc,
+		goroutine.ParentTraceID,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("save goroutine error: %w", err)
@@ -48,7 +51,7 @@ func (r *GoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string,
 
 // FindGoroutineByID 根据ID查找协程
 func (r *GoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
-	rows, err := r.db.Query("SELECT id, originGid, createTime, isFinished, initFuncName FROM GoroutineTrace WHERE id = ?", id)
+	rows, err := r.db.Query(SQLSelectGoroutineByID, id)
 	if err != nil {
 		return nil, fmt.Errorf("find goroutine by id error: %w", err)
 	}
@@ -60,10 +63,19 @@ func (r *GoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace
 
 	var goroutine model.GoroutineTrace
 
-	if err := rows.Scan(&goroutine.ID, &goroutine.OriginGID, &goroutine.CreateTime, &goroutine.IsFinished, &goroutine.InitFuncName); err != nil {
+	var (
+		creatorGid    sql.NullInt64
+		creatorFunc   sql.NullString
+		parentTraceId sql.NullInt64
+	)
+	if err := rows.Scan(&goroutine.ID, &goroutine.OriginGID, &goroutine.CreateTime, &goroutine.IsFinished, &goroutine.InitFuncName, &creatorGid, &creatorFunc, &parentTraceId); err != nil {
 		return nil, fmt.Errorf("scan goroutine data error: %w", err)
 	}
 
+	goroutine.CreatorGID = uint64(creatorGid.Int64)
+	goroutine.CreatorFunc = creatorFunc.String
+	goroutine.ParentTraceID = parentTraceId.Int64
+
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("process goroutine data error: %w", err)
 	}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// LeakRepository 是SQLite实现的泄漏报告仓储
+type LeakRepository struct {
+	db *sql.DB
+}
+
+// NewLeakRepository 创建一个新的SQLite泄漏报告仓储
+func NewLeakRepository(db *sql.DB) domain.LeakRepository {
+	return &LeakRepository{
+		db: db,
+	}
+}
+
+// SaveLeakReports 批量保存泄漏报告（单事务）
+func (r *LeakRepository) SaveLeakReports(reports []*model.LeakReport) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx error: %w", err)
+	}
+	stmt, err := tx.Prepare(SQLInsertLeakReport)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("prepare insert leak report error: %w", err)
+	}
+	defer stmt.Close()
+	for _, l := range reports {
+		if _, err := stmt.Exec(l.GoroutineID, l.OriginGID, l.InitFuncName, l.CreatorGID, l.CreatorFunc, l.CreateTime, l.Age, l.LastTraceID, l.LastFuncName, l.LastFinished, l.Stack, l.ReportedAt); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("save leak report error: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// FindAllLeakReports 查询全部泄漏报告
+func (r *LeakRepository) FindAllLeakReports() ([]model.LeakReport, error) {
+	rows, err := r.db.Query(SQLSelectAllLeakReports)
+	if err != nil {
+		return nil, fmt.Errorf("find leak reports error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.LeakReport
+	for rows.Next() {
+		var l model.LeakReport
+		if err := rows.Scan(&l.ID, &l.GoroutineID, &l.OriginGID, &l.InitFuncName, &l.CreatorGID, &l.CreatorFunc, &l.CreateTime, &l.Age, &l.LastTraceID, &l.LastFuncName, &l.LastFinished, &l.Stack, &l.ReportedAt); err != nil {
+			return nil, fmt.Errorf("scan leak report error: %w", err)
+		}
+		result = append(result, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate leak report result error: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Manifest 一次运行产生的全部数据库文件，按创建顺序排列
+type Manifest struct {
+	Process   string         `json:"process"`   // 可执行文件名
+	PID       int            `json:"pid"`       // 进程ID
+	StartedAt string         `json:"startedAt"` // 运行开始时间
+	UpdatedAt string         `json:"updatedAt"` // 清单最后更新时间
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile 清单中的一个数据库文件
+type ManifestFile struct {
+	Name         string `json:"name"`                   // 相对清单所在目录的文件名
+	Seq          int    `json:"seq"`                    // 滚动序号，从 1 开始
+	CreatedAt    string `json:"createdAt"`              // 创建时间
+	ClosedAt     string `json:"closedAt,omitempty"`     // 滚动或关闭时间，空表示仍在写入
+	Size         int64  `json:"size"`                   // 最近一次检查时的数据量（字节）
+	DeletedTrees int64  `json:"deletedTrees,omitempty"` // 按保留策略删除的根调用树数量
+	Deleted      bool   `json:"deleted,omitempty"`      // 文件已按保留策略删除
+}
+
+// ReadManifest 读取清单文件
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest error: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decode manifest error: %w", err)
+	}
+	return &m, nil
+}
+
+// write 先写临时文件再重命名，读取方不会看到写了一半的清单
+func (m *Manifest) write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest error: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write manifest error: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename manifest error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// MergedRun 合并进目标库的一次运行
+type MergedRun struct {
+	Source      string `json:"source"`      // 来源文件
+	SourceRunID int64  `json:"sourceRunId"` // 来源文件中的运行ID
+	RunID       int64  `json:"runId"`       // 目标库中的运行ID
+	Traces      int64  `json:"traces"`      // 导入的跟踪数据条数
+	Goroutines  int64  `json:"goroutines"`  // 导入的协程数
+	Params      int64  `json:"params"`      // 导入的参数条数
+}
+
+// Merge 将 srcPath 中的全部运行导入当前库，每个来源文件在单个事务中导入
+// 每次运行在当前库中登记为新的 Run（Origin 为来源文件），全部ID平移到新运行的命名空间，
+// ParentId、BaseID、parentTraceId 等引用同步平移；来源文件不会被修改，旧版本文件在临时副本上升级后读取
+func (s *SQLiteDatabase) Merge(srcPath string) ([]MergedRun, error) {
+	src, err := OpenReadOnly(srcPath, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	runs, err := src.mergeRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	// ATTACH 只对单个连接生效，且不能在事务中执行
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get db conn error: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, SQLAttachSource, src.path); err != nil {
+		return nil, fmt.Errorf("attach source db error: %w", err)
+	}
+	defer conn.ExecContext(ctx, SQLDetachSource)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx error: %w", err)
+	}
+	var merged []MergedRun
+	for _, run := range runs {
+		m, err := mergeRun(tx, srcPath, run)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("merge run %d of %s error: %w", run.ID, srcPath, err)
+		}
+		merged = append(merged, m)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx error: %w", err)
+	}
+	return merged, nil
+}
+
+// mergeRuns 返回来源库中需要导入的运行，数据所在命名空间没有对应 Run 记录时补充占位运行
+func (s *SQLiteDatabase) mergeRuns() ([]model.Run, error) {
+	runs, err := s.runRepository.FindAllRuns()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]bool, len(runs))
+	for _, r := range runs {
+		known[r.ID] = true
+	}
+	rows, err := s.db.Query(SQLSelectSourceRuns)
+	if err != nil {
+		return nil, fmt.Errorf("query source runs error: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id sql.NullInt64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan source run error: %w", err)
+		}
+		if id.Valid && !known[id.Int64] {
+			known[id.Int64] = true
+			runs = append(runs, model.Run{ID: id.Int64, Status: model.RunStatusUnknown})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate source runs error: %w", err)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	return runs, nil
+}
+
+// mergeRun 在目标库登记运行并导入该运行的全部数据
+func mergeRun(tx *sql.Tx, srcPath string, run model.Run) (MergedRun, error) {
+	m := MergedRun{Source: srcPath, SourceRunID: run.ID}
+	oldBase := run.IDBase()
+
+	target := run
+	target.ID, target.Origin = 0, srcPath
+	result, err := tx.Exec(SQLInsertRun, target.UUID, target.Executable, target.Args, target.Hostname, target.PID, target.GoVersion, target.ModulePath, target.ModuleVersion,
+		target.VCSRevision, target.VCSTime, target.VCSModified, target.BuildInfo, target.Config, target.StartedAt, target.EndedAt, target.Status, target.Origin)
+	if err != nil {
+		return m, fmt.Errorf("save run error: %w", err)
+	}
+	if target.ID, err = result.LastInsertId(); err != nil {
+		return m, err
+	}
+	m.RunID = target.ID
+	delta := target.IDBase() - oldBase
+
+	for _, step := range []struct {
+		stmt  string
+		arg   int64
+		count *int64
+	}{
+		{SQLMergeTraces, delta, &m.Traces},
+		{SQLMergeGoroutines, delta, &m.Goroutines},
+		{SQLMergeParams, delta, &m.Params},
+		{SQLMergeEvents, delta, nil},
+		{SQLMergeLeaks, delta, nil},
+		{SQLMergeFuncStats, target.ID, nil},
+	} {
+		result, err := tx.Exec(step.stmt, step.arg, run.ID)
+		if err != nil {
+			return m, fmt.Errorf("can't exec sql: %s, %w", step.stmt, err)
+		}
+		if step.count != nil {
+			*step.count, _ = result.RowsAffected()
+		}
+	}
+	return m, nil
+}
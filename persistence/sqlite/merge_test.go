@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// writeRunDB 写入一次运行的数据，不同文件使用相同的进程内ID
+func writeRunDB(t *testing.T, path, uuid string) {
+	t.Helper()
+	db, err := Open(path, quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.GetRunRepository().StartRun(&model.Run{UUID: uuid, Status: model.RunStatusClean})
+	require.NoError(t, err)
+
+	traces := db.GetTraceRepository().(*TraceRepository)
+	require.NoError(t, traces.SaveTracesBatch([]*model.TraceData{
+		{ID: 1, Name: "main.main", GID: 1, TimeCost: "3ms", IsFinished: 1},
+		{ID: 65, Name: "main.work", GID: 1, Indent: 1, ParentId: 1, TimeCost: "2ms", IsFinished: 1},
+	}))
+	_, err = db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(1, 10, "", 1, "main.main"))
+	require.NoError(t, err)
+	_, err = db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(2, 11, "", 1, "main.worker").WithCreator(10, "main.main", 65))
+	require.NoError(t, err)
+	require.NoError(t, db.GetParamRepository().SaveParamsBatch([]*model.ParamStoreData{
+		{ID: 1, TraceID: 1, Data: []byte(`{"a":1}`)},
+		{ID: 65, TraceID: 65, Data: []byte(`{"a":2}`), BaseID: 1},
+	}))
+	_, err = db.GetEventRepository().SaveEvent(model.NewTraceEvent(65, 1, model.EventKindSlowCall, "slow", ""))
+	require.NoError(t, err)
+	require.NoError(t, db.GetStatsRepository().SaveFuncStats([]*model.FuncStats{{RunID: 1, Name: "main.work", Count: 1}}))
+}
+
+func TestMergeRemapsIDs(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")
+	writeRunDB(t, a, "run-a")
+	writeRunDB(t, b, "run-b")
+
+	target, err := Open(filepath.Join(dir, "merged.db"), quietLogger())
+	require.NoError(t, err)
+	defer target.Close()
+	for _, src := range []string{a, b} {
+		merged, err := target.Merge(src)
+		require.NoError(t, err)
+		require.Len(t, merged, 1)
+		assert.Equal(t, int64(2), merged[0].Traces)
+		assert.Equal(t, int64(2), merged[0].Goroutines)
+		assert.Equal(t, int64(2), merged[0].Params)
+	}
+
+	runs, err := target.GetRunRepository().FindAllRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run-b", runs[1].UUID)
+	assert.Equal(t, b, runs[1].Origin)
+	assert.Equal(t, model.RunStatusClean, runs[1].Status)
+
+	// 第二个文件的数据平移到运行2的命名空间，引用保持一致
+	base := runs[1].IDBase()
+	child, err := target.FindTraceByID(base + 65)
+	require.NoError(t, err)
+	require.NotNil(t, child)
+	assert.Equal(t, base+1, child.ParentId)
+	assert.Equal(t, uint64(base+1), child.GID)
+	root, err := target.FindTraceByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "main.main", root.Name)
+
+	g, err := target.GetGoroutineRepository().FindGoroutineByID(base + 2)
+	require.NoError(t, err)
+	require.NotNil(t, g)
+	assert.Equal(t, base+65, g.ParentTraceID)
+	assert.Equal(t, uint64(10), g.CreatorGID)
+
+	params, err := target.GetParamRepository().FindParamsByTraceID(base + 65)
+	require.NoError(t, err)
+	require.Len(t, params, 1)
+	baseParams, err := target.GetParamRepository().FindParamsByTraceID(base + 1)
+	require.NoError(t, err)
+	require.Len(t, baseParams, 1)
+	assert.Equal(t, baseParams[0].ID, params[0].BaseID)
+	assert.Equal(t, runs[1].ID, model.RunIDOf(params[0].ID))
+
+	events, err := target.GetEventRepository().FindEventsByTraceID(base + 65)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(base+1), events[0].GID)
+
+	stats, err := target.GetStatsRepository().FindAllFuncStats()
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+}
+
+func TestMergeLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "baseline.db")
+	writeBaselineDB(t, old)
+	before := fileHash(t, old)
+
+	target, err := Open(filepath.Join(dir, "merged.db"), quietLogger())
+	require.NoError(t, err)
+	defer target.Close()
+	_, err = target.GetRunRepository().StartRun(&model.Run{Status: model.RunStatusClean})
+	require.NoError(t, err)
+
+	merged, err := target.Merge(old)
+	require.NoError(t, err)
+	require.Len(t, merged, 1)
+	assert.Equal(t, int64(2), merged[0].RunID)
+	assert.Equal(t, int64(1), merged[0].Traces)
+	assert.Equal(t, before, fileHash(t, old))
+
+	td, err := target.FindTraceByID(1<<model.RunIDShift + 1)
+	require.NoError(t, err)
+	require.NotNil(t, td)
+	assert.Equal(t, "main.main", td.Name)
+}
@@ -0,0 +1,278 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// migration 有序的结构变更步骤，每步在单独事务中执行并记录到 SchemaMigration 表
+// 步骤必须可重复执行：没有 SchemaMigration 表的旧文件会从第一步开始全部执行一遍
+type migration struct {
+	version int
+	name    string
+	apply   func(tx *sql.Tx) error
+}
+
+// migrations 只能在末尾追加，已发布的步骤不能修改
+var migrations = []migration{
+	{1, "create trace, goroutine and param tables", execAll(
+		SQLCreateTraceTable,
+		SQLCreateGoroutineTable,
+		SQLCreateParamTable,
+		SQLCreateParamCacheTable,
+		SQLCreateGIDIndex,
+		SQLCreateParentIndex,
+		SQLCreateParamTraceIndex,
+		SQLCreateParamBaseIndex,
+		SQLCreateParamCacheAddrIndex,
+	)},
+	{2, "create function stats table", execAll(SQLCreateFuncStatsTable)},
+	{3, "create trace event table", execAll(SQLCreateTraceEventTable, SQLCreateEventTraceIndex)},
+	{4, "add goroutine creator columns and leak report table", func(tx *sql.Tx) error {
+		for _, c := range []struct{ name, stmt string }{
+			{"creatorGid", SQLAddCreatorGidColumn},
+			{"creatorFunc", SQLAddCreatorFuncColumn},
+			{"parentTraceId", SQLAddParentTraceIDColumn},
+		} {
+			if err := addColumn(tx, "GoroutineTrace", c.name, c.stmt); err != nil {
+				return err
+			}
+		}
+		return execAll(SQLCreateGoroutineParentIndex, SQLCreateLeakReportTable)(tx)
+	}},
+	{5, "create source table", execAll(SQLCreateSourceTable)},
+	{6, "add trace duration column", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "TraceData", "duration", SQLAddDurationColumn); err != nil {
+			return err
+		}
+		if err := backfillDurations(tx); err != nil {
+			return err
+		}
+		return execAll(SQLCreateNameIndex, SQLCreateDurationIndex)(tx)
+	}},
+	{7, "create run table and reference runs from other tables", func(tx *sql.Tx) error {
+		if err := execAll(SQLCreateRunTable, SQLInsertLegacyRun)(tx); err != nil {
+			return err
+		}
+		for _, c := range []struct{ table, stmt string }{
+			{"TraceData", SQLAddTraceRunColumn},
+			{"GoroutineTrace", SQLAddGoroutineRunColumn},
+			{"ParamStore", SQLAddParamRunColumn},
+			{"TraceEvent", SQLAddEventRunColumn},
+			{"LeakReport", SQLAddLeakRunColumn},
+		} {
+			if err := addColumn(tx, c.table, "runId", c.stmt); err != nil {
+				return err
+			}
+		}
+		// FuncStats 的主键改为 (runId, name)，SQLite 只能重建表
+		found, err := hasColumn(tx, "FuncStats", "runId")
+		if err != nil {
+			return err
+		}
+		if !found {
+			if err := execAll(SQLCreateRunFuncStatsTable, SQLCopyFuncStatsToRun, SQLDropFuncStatsTable, SQLRenameRunFuncStats)(tx); err != nil {
+				return err
+			}
+		}
+		return execAll(SQLCreateTraceRunIndex, SQLCreateGoroutineRunIndex)(tx)
+	}},
+	{8, "add run origin column", func(tx *sql.Tx) error {
+		return addColumn(tx, "Run", "origin", SQLAddRunOriginColumn)
+	}},
+	{9, "create summary tables", execAll(
+		SQLCreateFuncSummaryTable,
+		SQLCreateCallEdgeTable,
+		SQLCreateGoroutineSummaryTable,
+		SQLCreateRootCallSummaryTable,
+		SQLCreateFuncSummaryTotalIndex,
+		SQLCreateFuncSummarySelfIndex,
+		SQLCreateCallEdgeCalleeIndex,
+		SQLCreateGoroutineSummaryIndex,
+		SQLCreateRootCallDurationIndex,
+		SQLCreateRootCallNameIndex,
+		SQLCreateRootCallGoroutineIndex,
+	)},
+}
+
+// execAll 依次执行语句
+func execAll(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("can't exec sql: %s, %w", stmt, err)
+			}
+		}
+		return nil
+	}
+}
+
+// hasColumn 检查表中是否存在指定列（含生成列）
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(SQLSelectColumns, table)
+	if err != nil {
+		return false, fmt.Errorf("query %s columns error: %w", table, err)
+	}
+	defer rows.Close()
+	found := false
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, fmt.Errorf("scan %s column error: %w", table, err)
+		}
+		found = found || name == column
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("iterate %s columns error: %w", table, err)
+	}
+	return found, nil
+}
+
+// addColumn 列不存在时执行 ALTER TABLE 语句
+func addColumn(tx *sql.Tx, table, column, stmt string) error {
+	found, err := hasColumn(tx, table, column)
+	if err != nil || found {
+		return err
+	}
+	if _, err := tx.Exec(stmt); err != nil {
+		return fmt.Errorf("add %s.%s column error: %w", table, column, err)
+	}
+	return nil
+}
+
+// backfillDurations 解析已完成调用的 timeCost 写入 duration 列
+func backfillDurations(tx *sql.Tx) error {
+	rows, err := tx.Query(SQLSelectMissingDurations)
+	if err != nil {
+		return fmt.Errorf("query missing durations error: %w", err)
+	}
+	durations := make(map[int64]int64)
+	for rows.Next() {
+		var (
+			id       int64
+			timeCost string
+		)
+		if err := rows.Scan(&id, &timeCost); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan time cost error: %w", err)
+		}
+		if d, ok := model.ParseTimeCost(timeCost); ok {
+			durations[id] = int64(d)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate time cost error: %w", err)
+	}
+
+	for id, d := range durations {
+		if _, err := tx.Exec(SQLUpdateDuration, d, id); err != nil {
+			return fmt.Errorf("backfill duration error: %w", err)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion 返回数据库的结构版本，没有 SchemaMigration 表的旧文件返回 0
+func (s *SQLiteDatabase) SchemaVersion() (int, error) {
+	var exists int
+	if err := s.db.QueryRow(SQLSelectSchemaTableExists).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("query schema table error: %w", err)
+	}
+	if exists == 0 {
+		return 0, nil
+	}
+	var version int
+	if err := s.db.QueryRow(SQLSelectSchemaVersion).Scan(&version); err != nil {
+		return 0, fmt.Errorf("query schema version error: %w", err)
+	}
+	return version, nil
+}
+
+// migrate 依次执行高于当前版本的迁移步骤；文件版本高于代码版本时不做修改
+func (s *SQLiteDatabase) migrate() error {
+	if _, err := s.db.Exec(SQLCreateSchemaMigrationTable); err != nil {
+		return fmt.Errorf("create schema table error: %w", err)
+	}
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		s.logger.Warnf("db schema version %d is newer than supported version %d", version, CurrentSchemaVersion)
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin tx error: %w", err)
+		}
+		if err := m.apply(tx); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("apply schema migration %d error: %w", m.version, err)
+		}
+		if _, err := tx.Exec(SQLInsertSchemaMigration, m.version, m.name, time.Now().Format(time.RFC3339Nano)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("record schema migration %d error: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit tx error: %w", err)
+		}
+		s.logger.Debugf("applied schema migration %d: %s", m.version, m.name)
+	}
+	return nil
+}
+
+// OpenReadOnly 以只读方式打开已有的数据库文件，文件本身不会被修改
+// 结构版本低于 CurrentSchemaVersion 时先复制到临时目录，在副本上执行迁移后读取，关闭时删除副本
+func OpenReadOnly(dbPath string, logger *logrus.Logger) (*SQLiteDatabase, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("stat db error: %w", err)
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf(ReadOnlyDSNFormat, dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("can't open db: %w", err)
+	}
+	s := &SQLiteDatabase{db: db, path: dbPath, logger: logger}
+	version, err := s.SchemaVersion()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if version >= CurrentSchemaVersion {
+		s.initRepositories()
+		return s, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "functrace-db-")
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create temp dir error: %w", err)
+	}
+	copyPath := filepath.Join(tmpDir, filepath.Base(dbPath))
+	// VACUUM INTO 可在只读连接上执行，并包含 WAL 中尚未写回的数据
+	_, err = db.Exec(SQLVacuumInto, copyPath)
+	db.Close()
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("copy db error: %w", err)
+	}
+	logger.Infof("db schema version %d is older than %d, reading a migrated copy", version, CurrentSchemaVersion)
+	c, err := Open(copyPath, logger)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	c.tempDir = tmpDir
+	return c, nil
+}
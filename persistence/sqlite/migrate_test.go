@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// writeBaselineDB 写入最早版本的数据库：没有 SchemaMigration 表，GoroutineTrace 没有创建者列
+func writeBaselineDB(t *testing.T, path string) {
+	t.Helper()
+	old, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer old.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE TraceData (id INTEGER PRIMARY KEY, name TEXT, gid INTEGER, indent INTEGER, paramsCount INTEGER, timeCost TEXT, parentId INTEGER, isFinished INTEGER, createdAt TEXT, seq TEXT)`,
+		`CREATE TABLE GoroutineTrace (id INTEGER PRIMARY KEY AUTOINCREMENT, originGid INTEGER, timeCost TEXT, createTime TEXT, isFinished INTEGER, initFuncName TEXT)`,
+		`CREATE TABLE ParamStore (id INTEGER PRIMARY KEY AUTOINCREMENT, traceId INTEGER, position INTEGER, data BLOB, isReceiver BOOLEAN, baseId INTEGER)`,
+		`INSERT INTO TraceData VALUES (1, 'main.main', 1, 0, 0, '2ms', 0, 1, '', '')`,
+		`INSERT INTO GoroutineTrace VALUES (1, 10, '2ms', '', 1, 'main.main')`,
+	} {
+		_, err = old.Exec(stmt)
+		require.NoError(t, err)
+	}
+}
+
+func fileHash(t *testing.T, path string) [32]byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return sha256.Sum256(data)
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, m.name)
+	}
+	assert.Equal(t, CurrentSchemaVersion, migrations[len(migrations)-1].version)
+}
+
+func TestOpenMigratesBaselineFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	writeBaselineDB(t, path)
+
+	db, err := Open(path, quietLogger())
+	require.NoError(t, err)
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, version)
+
+	g, err := db.GetGoroutineRepository().FindGoroutineByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "main.main", g.InitFuncName)
+	spawned := model.NewGoroutineTrace(2, 11, "", 0, "main.worker")
+	spawned.CreatorGID, spawned.ParentTraceID = 10, 1
+	_, err = db.GetGoroutineRepository().SaveGoroutine(spawned)
+	require.NoError(t, err)
+	require.NoError(t, db.GetStatsRepository().SaveFuncStats(nil))
+	require.NoError(t, db.Close())
+
+	// 再次打开不会重复执行迁移
+	db, err = Open(path, quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	var applied int
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM SchemaMigration").Scan(&applied))
+	assert.Equal(t, CurrentSchemaVersion, applied)
+	children, err := db.FindGoroutinesSpawnedBy(1)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, int64(2), children[0].ID)
+}
+
+func TestOpenReadOnlyOldFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.db")
+	writeBaselineDB(t, path)
+	before := fileHash(t, path)
+
+	db, err := OpenReadOnly(path, quietLogger())
+	require.NoError(t, err)
+	page, err := db.FindSlowestTraces(model.TraceQuery{}, model.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Traces, 1)
+	assert.Equal(t, "main.main", page.Traces[0].Name)
+	tempDir := db.tempDir
+	require.DirExists(t, tempDir)
+	require.NoError(t, db.Close())
+
+	// 归档文件保持不变，迁移副本已删除
+	assert.Equal(t, before, fileHash(t, path))
+	assert.NoDirExists(t, tempDir)
+}
+
+func TestOpenReadOnlyCurrentFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "current.db")
+	db, err := Open(path, quietLogger())
+	require.NoError(t, err)
+	_, err = db.GetTraceRepository().SaveTrace(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "", ""))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = OpenReadOnly(path, quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Empty(t, db.tempDir)
+	td, err := db.FindTraceByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "main.main", td.Name)
+	_, err = db.GetTraceRepository().SaveTrace(model.NewTraceData(2, "main.a", 1, 0, 0, 0, "", ""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// whereClause 按查询条件拼接的 WHERE 子句
+type whereClause struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *whereClause) add(cond string, args ...interface{}) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *whereClause) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// traceWhere 将跟踪数据查询条件转换为 WHERE 子句；开始时间按毫秒精度比较
+func traceWhere(q model.TraceQuery) *whereClause {
+	w := &whereClause{}
+	if q.Name != "" {
+		w.add("name = ?", q.Name)
+	}
+	if q.GID != 0 {
+		w.add("gid = ?", q.GID)
+	}
+	if !q.Since.IsZero() {
+		w.add("julianday(createdAt) >= julianday(?)", q.Since.Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() {
+		w.add("julianday(createdAt) < julianday(?)", q.Until.Format(time.RFC3339Nano))
+	}
+	if q.MinDuration > 0 {
+		w.add("duration >= ?", int64(q.MinDuration))
+	}
+	return w
+}
+
+// ListTraceChildren 分页查询直接子调用，按ID升序
+func (s *SQLiteDatabase) ListTraceChildren(parentId int64, page model.PageRequest) (*model.TracePage, error) {
+	w := &whereClause{}
+	w.add("parentId = ?", parentId)
+	return s.pageTracesByID(w, page)
+}
+
+// ListSubtree 分页查询以 rootId 为根的完整调用子树（含根），按ID升序
+func (s *SQLiteDatabase) ListSubtree(rootId int64, page model.PageRequest) (*model.TracePage, error) {
+	var after int64
+	if page.Cursor != "" {
+		keys, err := model.DecodeCursor(page.Cursor, 1)
+		if err != nil {
+			return nil, err
+		}
+		after = keys[0]
+	}
+	limit := page.PageLimit()
+	traces, err := s.queryTraces(SQLSelectSubtree, rootId, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return newTracePage(traces, limit, traceIDKey), nil
+}
+
+// FindTraces 按条件分页查询跟踪数据，按ID升序
+func (s *SQLiteDatabase) FindTraces(query model.TraceQuery, page model.PageRequest) (*model.TracePage, error) {
+	return s.pageTracesByID(traceWhere(query), page)
+}
+
+// FindSlowestTraces 按条件分页查询已完成的调用，按耗时降序，耗时相同时按ID升序
+func (s *SQLiteDatabase) FindSlowestTraces(query model.TraceQuery, page model.PageRequest) (*model.TracePage, error) {
+	w := traceWhere(query)
+	w.add("duration IS NOT NULL")
+	if page.Cursor != "" {
+		keys, err := model.DecodeCursor(page.Cursor, 2)
+		if err != nil {
+			return nil, err
+		}
+		w.add("(duration < ? OR (duration = ? AND id > ?))", keys[0], keys[0], keys[1])
+	}
+	limit := page.PageLimit()
+	traces, err := s.queryTraces(SQLSelectTraces+w.String()+" ORDER BY duration DESC, id LIMIT ?", append(w.args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	return newTracePage(traces, limit, traceDurationKey), nil
+}
+
+// FindGoroutines 按条件分页查询协程，按ID升序
+func (s *SQLiteDatabase) FindGoroutines(query model.GoroutineQuery, page model.PageRequest) (*model.GoroutinePage, error) {
+	w := &whereClause{}
+	if query.InitFuncName != "" {
+		w.add("initFuncName = ?", query.InitFuncName)
+	}
+	if query.OriginGID != 0 {
+		w.add("originGid = ?", query.OriginGID)
+	}
+	if query.CreatorGID != 0 {
+		w.add("creatorGid = ?", query.CreatorGID)
+	}
+	switch query.State {
+	case model.GoroutineStateRunning:
+		w.add("(isFinished IS NULL OR isFinished = 0)")
+	case model.GoroutineStateFinished:
+		w.add("isFinished != 0")
+	}
+	if page.Cursor != "" {
+		keys, err := model.DecodeCursor(page.Cursor, 1)
+		if err != nil {
+			return nil, err
+		}
+		w.add("id > ?", keys[0])
+	}
+
+	limit := page.PageLimit()
+	rows, err := s.db.Query(SQLSelectGoroutines+w.String()+" ORDER BY id LIMIT ?", append(w.args, limit+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("find goroutines error: %w", err)
+	}
+	defer rows.Close()
+
+	result := &model.GoroutinePage{}
+	for rows.Next() {
+		g, err := scanGoroutineRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Goroutines = append(result.Goroutines, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate goroutines error: %w", err)
+	}
+	if len(result.Goroutines) > limit {
+		result.Goroutines = result.Goroutines[:limit]
+		result.NextCursor = model.EncodeCursor(result.Goroutines[limit-1].ID)
+	}
+	return result, nil
+}
+
+// pageTracesByID 按ID升序分页查询满足 w 的跟踪数据
+func (s *SQLiteDatabase) pageTracesByID(w *whereClause, page model.PageRequest) (*model.TracePage, error) {
+	if page.Cursor != "" {
+		keys, err := model.DecodeCursor(page.Cursor, 1)
+		if err != nil {
+			return nil, err
+		}
+		w.add("id > ?", keys[0])
+	}
+	limit := page.PageLimit()
+	traces, err := s.queryTraces(SQLSelectTraces+w.String()+" ORDER BY id LIMIT ?", append(w.args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	return newTracePage(traces, limit, traceIDKey), nil
+}
+
+// newTracePage 截取一页数据；多查询的一行表示还有下一页，游标取本页最后一行的排序键
+func newTracePage(traces []model.TraceData, limit int, key func(td *model.TraceData) []int64) *model.TracePage {
+	if len(traces) <= limit {
+		return &model.TracePage{Traces: traces}
+	}
+	traces = traces[:limit]
+	return &model.TracePage{Traces: traces, NextCursor: model.EncodeCursor(key(&traces[limit-1])...)}
+}
+
+// traceIDKey 按ID排序时的游标键
+func traceIDKey(td *model.TraceData) []int64 {
+	return []int64{td.ID}
+}
+
+// traceDurationKey 按耗时排序时的游标键，与 duration 列一致由 timeCost 解析
+func traceDurationKey(td *model.TraceData) []int64 {
+	d, _ := model.ParseTimeCost(td.TimeCost)
+	return []int64{int64(d), td.ID}
+}
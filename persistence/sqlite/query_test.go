@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"database/sql"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+func TestOpenAddsDurationColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	old, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	// 旧版本的 TraceData 没有 duration 列
+	_, err = old.Exec(`CREATE TABLE TraceData (id INTEGER PRIMARY KEY, name TEXT, gid INTEGER, indent INTEGER, paramsCount INTEGER, timeCost TEXT, parentId INTEGER, isFinished INTEGER, createdAt TEXT, seq TEXT)`)
+	require.NoError(t, err)
+	_, err = old.Exec(`INSERT INTO TraceData VALUES (1, 'main.a', 1, 0, 0, '3ms', 0, 1, '', ''), (2, 'main.b', 1, 0, 0, '1.5s', 0, 1, '', ''), (3, 'main.c', 1, 0, 0, NULL, 0, 0, '', '')`)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db, err := Open(path, logger)
+	require.NoError(t, err)
+	defer db.Close()
+
+	page, err := db.FindSlowestTraces(model.TraceQuery{}, model.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Traces, 2)
+	assert.Equal(t, "main.b", page.Traces[0].Name)
+	assert.Equal(t, "main.a", page.Traces[1].Name)
+
+	// 新写入的耗时同时更新 duration
+	require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(3, "2s"))
+	page, err = db.FindSlowestTraces(model.TraceQuery{}, model.PageRequest{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "main.c", page.Traces[0].Name)
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+)
+
+// usedBytes 返回数据库中已使用页的字节数，删除数据后释放的空闲页不计入
+func (s *SQLiteDatabase) usedBytes() (int64, error) {
+	var pages, free, size int64
+	for _, q := range []struct {
+		sql string
+		dst *int64
+	}{{SQLPageCount, &pages}, {SQLFreelistCount, &free}, {SQLPageSize, &size}} {
+		if err := s.db.QueryRow(q.sql).Scan(q.dst); err != nil {
+			return 0, fmt.Errorf("query db size error: %w", err)
+		}
+	}
+	return (pages - free) * size, nil
+}
+
+// deleteOldestTrees 删除最旧的 n 棵已完成根调用树及其参数与事件，返回删除的树数量
+func (s *SQLiteDatabase) deleteOldestTrees(n int) (int, error) {
+	rows, err := s.db.Query(SQLSelectOldestRoots, n)
+	if err != nil {
+		return 0, fmt.Errorf("query oldest roots error: %w", err)
+	}
+	var roots []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan root id error: %w", err)
+		}
+		roots = append(roots, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterate roots error: %w", err)
+	}
+	if len(roots) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin tx error: %w", err)
+	}
+	for _, id := range roots {
+		for _, stmt := range []string{SQLDeleteTreeParams, SQLDeleteTreeEvents, SQLDeleteTree} {
+			if _, err := tx.Exec(stmt, id); err != nil {
+				_ = tx.Rollback()
+				return 0, fmt.Errorf("delete trace tree error: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx error: %w", err)
+	}
+	return len(roots), nil
+}
+
+// countTraces 返回跟踪数据条数
+func (s *SQLiteDatabase) countTraces() (int64, error) {
+	var n int64
+	if err := s.db.QueryRow(SQLCountTraces).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count traces error: %w", err)
+	}
+	return n, nil
+}
+
+// fileSize 返回数据库文件与 WAL 文件的总大小
+func fileSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if fi, err := os.Stat(p); err == nil {
+			size += fi.Size()
+		}
+	}
+	return size
+}
+
+// removeDBFile 删除数据库文件及其 WAL 与共享内存文件
+func removeDBFile(path string) error {
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove db file error: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,617 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+var _ domain.RepositoryFactory = (*RotatingDatabase)(nil)
+var _ domain.StatsRepositoryProvider = (*RotatingDatabase)(nil)
+var _ domain.EventRepositoryProvider = (*RotatingDatabase)(nil)
+var _ domain.LeakRepositoryProvider = (*RotatingDatabase)(nil)
+var _ domain.RunRepositoryProvider = (*RotatingDatabase)(nil)
+
+// RotatingDatabase 按大小或时间滚动到新文件的SQLite数据库
+// 每次滚动时把运行记录、未完成的调用与运行中的协程复制到新文件，之后的耗时更新仍能命中；
+// 参数缓存不复制，滚动后的首次参数记录会重新保存完整值。
+// 全部文件记录在清单中，超出总大小上限时按 Retention 删除最旧的文件或最旧的根调用树
+type RotatingDatabase struct {
+	config    Config
+	logger    *logrus.Logger
+	startedAt time.Time
+
+	// mu 保护 current：仓储操作持读锁，切换文件持写锁
+	mu      sync.RWMutex
+	current *SQLiteDatabase
+	created time.Time
+
+	// maintainMu 串行化滚动、清理与关闭，同时保护清单
+	maintainMu   sync.Mutex
+	seq          int
+	runID        int64 // 当前进程登记的运行，滚动时在旧文件中标记为已滚动
+	closed       bool
+	manifest     Manifest
+	manifestPath string
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRotatingDatabase 创建滚动数据库，Initialize 时打开第一个文件
+func NewRotatingDatabase(config Config, logger *logrus.Logger) *RotatingDatabase {
+	return &RotatingDatabase{
+		config: config.withDefaults(),
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Initialize 创建目录、打开第一个文件并启动定时检查
+func (r *RotatingDatabase) Initialize() error {
+	if err := os.MkdirAll(r.config.Dir, 0o755); err != nil {
+		return fmt.Errorf("create db dir error: %w", err)
+	}
+	r.startedAt = time.Now()
+	exe := executableName()
+	r.manifestPath = filepath.Join(r.config.Dir, fmt.Sprintf(ManifestNameFormat, exe, r.startedAt.Format(TimeLayout)))
+	r.manifest = Manifest{
+		Process:   exe,
+		PID:       os.Getpid(),
+		StartedAt: r.startedAt.Format(time.RFC3339Nano),
+	}
+
+	r.maintainMu.Lock()
+	err := r.rotate()
+	r.maintainMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go r.checkLoop()
+	return nil
+}
+
+// checkLoop 按 CheckInterval 定时检查
+func (r *RotatingDatabase) checkLoop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Check(); err != nil {
+				r.logger.WithError(err).Warn("check db rotation failed")
+			}
+		}
+	}
+}
+
+// Check 立即检查是否需要滚动，并按总大小上限执行清理
+func (r *RotatingDatabase) Check() error {
+	r.maintainMu.Lock()
+	defer r.maintainMu.Unlock()
+	if r.closed {
+		return nil
+	}
+
+	used, err := r.current.usedBytes()
+	if err != nil {
+		return err
+	}
+	if (r.config.MaxAge > 0 && time.Since(r.created) >= r.config.MaxAge) ||
+		(r.config.MaxSize > 0 && used >= r.config.MaxSize) {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	if r.config.MaxTotalSize > 0 {
+		if err := r.enforceRetention(); err != nil {
+			return err
+		}
+	}
+	return r.writeManifest()
+}
+
+// rotate 打开下一个文件并复制未完成的数据，调用方持有 maintainMu
+func (r *RotatingDatabase) rotate() error {
+	seq := r.seq + 1
+	name := r.config.fileName(r.startedAt, seq, true)
+	path := filepath.Join(r.config.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create db dir error: %w", err)
+	}
+	next, err := Open(path, r.logger)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	prev := r.current
+	if prev != nil {
+		if err := copyOpenFrames(next, prev.path); err != nil {
+			r.mu.Unlock()
+			next.Close()
+			return err
+		}
+	}
+	now := time.Now()
+	r.current, r.created, r.seq = next, now, seq
+	r.mu.Unlock()
+
+	if prev != nil {
+		if r.runID != 0 {
+			if err := prev.runRepository.EndRun(r.runID, now.Format(time.RFC3339Nano), model.RunStatusRotated); err != nil {
+				r.logger.WithError(err).Warn("mark rotated run failed")
+			}
+		}
+		if err := prev.Close(); err != nil {
+			r.logger.WithError(err).Warn("close rotated db failed")
+		}
+		r.closeEntry(len(r.manifest.Files)-1, prev.path, now)
+		r.logger.Infof("rotated db: %s -> %s", prev.path, path)
+	}
+	r.manifest.Files = append(r.manifest.Files, ManifestFile{
+		Name:      name,
+		Seq:       seq,
+		CreatedAt: now.Format(time.RFC3339Nano),
+	})
+	return r.writeManifest()
+}
+
+// copyOpenFrames 把上一个文件中的运行记录、未完成的调用与运行中的协程复制到新文件
+func copyOpenFrames(next *SQLiteDatabase, prevPath string) error {
+	ctx := context.Background()
+	// ATTACH 只对单个连接生效，必须固定同一个连接
+	conn, err := next.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get db conn error: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, SQLAttachPrevious, prevPath); err != nil {
+		return fmt.Errorf("attach previous db error: %w", err)
+	}
+	defer conn.ExecContext(ctx, SQLDetachPrevious)
+	for _, stmt := range []string{SQLCopyRuns, SQLCopyOpenTraces, SQLCopyRunningGoroutines} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("copy open frames error: %w", err)
+		}
+	}
+	return nil
+}
+
+// enforceRetention 总大小超过上限时删除最旧的数据，调用方持有 maintainMu
+func (r *RotatingDatabase) enforceRetention() error {
+	for {
+		total, err := r.totalSize()
+		if err != nil {
+			return err
+		}
+		if total <= r.config.MaxTotalSize {
+			return nil
+		}
+		oldest := r.oldestFile()
+		if oldest < 0 {
+			return nil
+		}
+
+		var freed bool
+		if r.config.Retention == RetentionTrees {
+			freed, err = r.deleteTrees(oldest)
+		} else {
+			freed, err = r.deleteFile(oldest)
+		}
+		if err != nil {
+			return err
+		}
+		if !freed {
+			r.logger.Warnf("db total size %d exceeds limit %d, nothing left to delete", total, r.config.MaxTotalSize)
+			return nil
+		}
+	}
+}
+
+// totalSize 返回未删除文件的总大小，当前文件按已使用页计算
+func (r *RotatingDatabase) totalSize() (int64, error) {
+	var total int64
+	last := len(r.manifest.Files) - 1
+	for i := range r.manifest.Files {
+		f := &r.manifest.Files[i]
+		if f.Deleted {
+			continue
+		}
+		if i == last {
+			used, err := r.current.usedBytes()
+			if err != nil {
+				return 0, err
+			}
+			f.Size = used
+		}
+		total += f.Size
+	}
+	return total, nil
+}
+
+// oldestFile 返回最旧的未删除文件下标；按文件清理时跳过当前文件
+func (r *RotatingDatabase) oldestFile() int {
+	last := len(r.manifest.Files) - 1
+	for i, f := range r.manifest.Files {
+		if f.Deleted {
+			continue
+		}
+		if i == last && r.config.Retention != RetentionTrees {
+			return -1
+		}
+		return i
+	}
+	return -1
+}
+
+// deleteFile 删除一个已关闭的文件
+func (r *RotatingDatabase) deleteFile(i int) (bool, error) {
+	f := &r.manifest.Files[i]
+	if err := removeDBFile(filepath.Join(r.config.Dir, f.Name)); err != nil {
+		return false, err
+	}
+	f.Deleted, f.Size = true, 0
+	r.logger.Infof("retention removed db: %s", f.Name)
+	return true, nil
+}
+
+// deleteTrees 从文件中删除一批最旧的根调用树；已关闭的文件删空后整体删除，否则压缩
+func (r *RotatingDatabase) deleteTrees(i int) (bool, error) {
+	f := &r.manifest.Files[i]
+	if i == len(r.manifest.Files)-1 {
+		n, err := r.current.deleteOldestTrees(treeBatchSize)
+		f.DeletedTrees += int64(n)
+		return n > 0, err
+	}
+
+	path := filepath.Join(r.config.Dir, f.Name)
+	db, err := Open(path, r.logger)
+	if err != nil {
+		return false, err
+	}
+	n, err := db.deleteOldestTrees(treeBatchSize)
+	if err != nil {
+		db.Close()
+		return false, err
+	}
+	f.DeletedTrees += int64(n)
+	left, err := db.countTraces()
+	if err != nil {
+		db.Close()
+		return false, err
+	}
+	// 只剩未完成的调用时它们已被复制到后续文件，整个文件可以删除
+	if n == 0 || left == 0 {
+		db.Close()
+		return r.deleteFile(i)
+	}
+	if _, err := db.db.Exec(SQLVacuum); err != nil {
+		db.Close()
+		return false, fmt.Errorf("vacuum db error: %w", err)
+	}
+	if err := db.Close(); err != nil {
+		return false, err
+	}
+	f.Size = fileSize(path)
+	return true, nil
+}
+
+// closeEntry 记录文件的关闭时间与最终大小
+func (r *RotatingDatabase) closeEntry(i int, path string, at time.Time) {
+	if i < 0 || i >= len(r.manifest.Files) {
+		return
+	}
+	f := &r.manifest.Files[i]
+	f.ClosedAt = at.Format(time.RFC3339Nano)
+	f.Size = fileSize(path)
+}
+
+// writeManifest 写入清单，调用方持有 maintainMu
+func (r *RotatingDatabase) writeManifest() error {
+	r.manifest.UpdatedAt = time.Now().Format(time.RFC3339Nano)
+	return r.manifest.write(r.manifestPath)
+}
+
+// Path 返回当前写入的文件路径
+func (r *RotatingDatabase) Path() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.path
+}
+
+// ManifestPath 返回清单文件路径
+func (r *RotatingDatabase) ManifestPath() string {
+	return r.manifestPath
+}
+
+// Files 返回未被删除的数据库文件路径，按创建顺序排列
+func (r *RotatingDatabase) Files() []string {
+	r.maintainMu.Lock()
+	defer r.maintainMu.Unlock()
+	var files []string
+	for _, f := range r.manifest.Files {
+		if !f.Deleted {
+			files = append(files, filepath.Join(r.config.Dir, f.Name))
+		}
+	}
+	return files
+}
+
+// Close 停止定时检查，关闭当前文件并更新清单
+func (r *RotatingDatabase) Close() error {
+	r.maintainMu.Lock()
+	if r.closed {
+		r.maintainMu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.maintainMu.Unlock()
+	close(r.stop)
+	r.wg.Wait()
+
+	r.maintainMu.Lock()
+	defer r.maintainMu.Unlock()
+	r.mu.Lock()
+	err := r.current.Close()
+	r.mu.Unlock()
+	r.closeEntry(len(r.manifest.Files)-1, r.current.path, time.Now())
+	if werr := r.writeManifest(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// GetTraceRepository 获取跟踪数据仓储
+func (r *RotatingDatabase) GetTraceRepository() domain.TraceRepository {
+	return rotatingTraceRepository{r}
+}
+
+// GetParamRepository 获取参数数据仓储
+func (r *RotatingDatabase) GetParamRepository() domain.ParamRepository {
+	return rotatingParamRepository{r}
+}
+
+// GetGoroutineRepository 获取协程数据仓储
+func (r *RotatingDatabase) GetGoroutineRepository() domain.GoroutineRepository {
+	return rotatingGoroutineRepository{r}
+}
+
+// GetStatsRepository 获取函数统计仓储
+func (r *RotatingDatabase) GetStatsRepository() domain.StatsRepository {
+	return rotatingStatsRepository{r}
+}
+
+// GetEventRepository 获取跟踪事件仓储
+func (r *RotatingDatabase) GetEventRepository() domain.EventRepository {
+	return rotatingEventRepository{r}
+}
+
+// GetLeakRepository 获取泄漏报告仓储
+func (r *RotatingDatabase) GetLeakRepository() domain.LeakRepository {
+	return rotatingLeakRepository{r}
+}
+
+// GetRunRepository 获取运行记录仓储
+func (r *RotatingDatabase) GetRunRepository() domain.RunRepository {
+	return rotatingRunRepository{r}
+}
+
+// with 持读锁在当前文件上执行操作
+func (r *RotatingDatabase) with(fn func(db *SQLiteDatabase) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return fn(r.current)
+}
+
+var _ domain.TraceBatchWriter = rotatingTraceRepository{}
+
+// rotatingTraceRepository 始终写入当前文件的跟踪数据仓储
+type rotatingTraceRepository struct{ r *RotatingDatabase }
+
+func (t rotatingTraceRepository) SaveTrace(trace *model.TraceData) (id int64, err error) {
+	err = t.r.with(func(db *SQLiteDatabase) error {
+		id, err = db.traceRepository.SaveTrace(trace)
+		return err
+	})
+	return id, err
+}
+
+func (t rotatingTraceRepository) SaveTracesBatch(traces []*model.TraceData) error {
+	return t.r.with(func(db *SQLiteDatabase) error {
+		if w, ok := db.traceRepository.(domain.TraceBatchWriter); ok {
+			return w.SaveTracesBatch(traces)
+		}
+		return fmt.Errorf("trace repository does not support batch writes")
+	})
+}
+
+func (t rotatingTraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
+	return t.r.with(func(db *SQLiteDatabase) error {
+		return db.traceRepository.UpdateTraceTimeCost(id, timeCost)
+	})
+}
+
+func (t rotatingTraceRepository) FindRootFunctionsByGID(gid uint64) (traces []model.TraceData, err error) {
+	err = t.r.with(func(db *SQLiteDatabase) error {
+		traces, err = db.traceRepository.FindRootFunctionsByGID(gid)
+		return err
+	})
+	return traces, err
+}
+
+// rotatingParamRepository 始终写入当前文件的参数数据仓储
+type rotatingParamRepository struct{ r *RotatingDatabase }
+
+func (p rotatingParamRepository) SaveParam(param *model.ParamStoreData) (id int64, err error) {
+	err = p.r.with(func(db *SQLiteDatabase) error {
+		id, err = db.paramRepository.SaveParam(param)
+		return err
+	})
+	return id, err
+}
+
+func (p rotatingParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	return p.r.with(func(db *SQLiteDatabase) error {
+		return db.paramRepository.SaveParamsBatch(params)
+	})
+}
+
+func (p rotatingParamRepository) FindParamsByTraceID(traceId int64) (params []model.ParamStoreData, err error) {
+	err = p.r.with(func(db *SQLiteDatabase) error {
+		params, err = db.paramRepository.FindParamsByTraceID(traceId)
+		return err
+	})
+	return params, err
+}
+
+func (p rotatingParamRepository) SaveParamCache(cache *model.ParamCache) (id int64, err error) {
+	err = p.r.with(func(db *SQLiteDatabase) error {
+		id, err = db.paramRepository.SaveParamCache(cache)
+		return err
+	})
+	return id, err
+}
+
+func (p rotatingParamRepository) FindParamCacheByAddr(addr string) (cache *model.ParamCache, err error) {
+	err = p.r.with(func(db *SQLiteDatabase) error {
+		cache, err = db.paramRepository.FindParamCacheByAddr(addr)
+		return err
+	})
+	return cache, err
+}
+
+func (p rotatingParamRepository) DeleteParamCacheByAddr(addr string) error {
+	return p.r.with(func(db *SQLiteDatabase) error {
+		return db.paramRepository.DeleteParamCacheByAddr(addr)
+	})
+}
+
+// rotatingGoroutineRepository 始终写入当前文件的协程数据仓储
+type rotatingGoroutineRepository struct{ r *RotatingDatabase }
+
+func (g rotatingGoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (id int64, err error) {
+	err = g.r.with(func(db *SQLiteDatabase) error {
+		id, err = db.goroutineRepository.SaveGoroutine(goroutine)
+		return err
+	})
+	return id, err
+}
+
+func (g rotatingGoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
+	return g.r.with(func(db *SQLiteDatabase) error {
+		return db.goroutineRepository.UpdateGoroutineTimeCost(id, timeCost, isFinished)
+	})
+}
+
+func (g rotatingGoroutineRepository) FindGoroutineByID(id int64) (goroutine *model.GoroutineTrace, err error) {
+	err = g.r.with(func(db *SQLiteDatabase) error {
+		goroutine, err = db.goroutineRepository.FindGoroutineByID(id)
+		return err
+	})
+	return goroutine, err
+}
+
+// rotatingStatsRepository 函数统计快照写入当前文件
+type rotatingStatsRepository struct{ r *RotatingDatabase }
+
+func (s rotatingStatsRepository) SaveFuncStats(stats []*model.FuncStats) error {
+	return s.r.with(func(db *SQLiteDatabase) error {
+		return db.statsRepository.SaveFuncStats(stats)
+	})
+}
+
+func (s rotatingStatsRepository) FindAllFuncStats() (stats []model.FuncStats, err error) {
+	err = s.r.with(func(db *SQLiteDatabase) error {
+		stats, err = db.statsRepository.FindAllFuncStats()
+		return err
+	})
+	return stats, err
+}
+
+// rotatingEventRepository 跟踪事件写入当前文件
+type rotatingEventRepository struct{ r *RotatingDatabase }
+
+func (e rotatingEventRepository) SaveEvent(event *model.TraceEvent) (id int64, err error) {
+	err = e.r.with(func(db *SQLiteDatabase) error {
+		id, err = db.eventRepository.SaveEvent(event)
+		return err
+	})
+	return id, err
+}
+
+func (e rotatingEventRepository) FindEventsByTraceID(traceId int64) (events []model.TraceEvent, err error) {
+	err = e.r.with(func(db *SQLiteDatabase) error {
+		events, err = db.eventRepository.FindEventsByTraceID(traceId)
+		return err
+	})
+	return events, err
+}
+
+// rotatingLeakRepository 泄漏报告写入当前文件
+type rotatingLeakRepository struct{ r *RotatingDatabase }
+
+func (l rotatingLeakRepository) SaveLeakReports(reports []*model.LeakReport) error {
+	return l.r.with(func(db *SQLiteDatabase) error {
+		return db.leakRepository.SaveLeakReports(reports)
+	})
+}
+
+func (l rotatingLeakRepository) FindAllLeakReports() (reports []model.LeakReport, err error) {
+	err = l.r.with(func(db *SQLiteDatabase) error {
+		reports, err = db.leakRepository.FindAllLeakReports()
+		return err
+	})
+	return reports, err
+}
+
+// rotatingRunRepository 运行记录写入当前文件，滚动时随文件复制
+type rotatingRunRepository struct{ r *RotatingDatabase }
+
+func (u rotatingRunRepository) StartRun(run *model.Run) (id int64, err error) {
+	u.r.maintainMu.Lock()
+	defer u.r.maintainMu.Unlock()
+	err = u.r.with(func(db *SQLiteDatabase) error {
+		id, err = db.runRepository.StartRun(run)
+		return err
+	})
+	if err == nil {
+		u.r.runID = id
+	}
+	return id, err
+}
+
+func (u rotatingRunRepository) EndRun(id int64, endedAt string, status string) error {
+	return u.r.with(func(db *SQLiteDatabase) error {
+		return db.runRepository.EndRun(id, endedAt, status)
+	})
+}
+
+func (u rotatingRunRepository) FindRunByID(id int64) (run *model.Run, err error) {
+	err = u.r.with(func(db *SQLiteDatabase) error {
+		run, err = db.runRepository.FindRunByID(id)
+		return err
+	})
+	return run, err
+}
+
+func (u rotatingRunRepository) FindAllRuns() (runs []model.Run, err error) {
+	err = u.r.with(func(db *SQLiteDatabase) error {
+		runs, err = db.runRepository.FindAllRuns()
+		return err
+	})
+	return runs, err
+}
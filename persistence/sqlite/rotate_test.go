@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestConfigFileName(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	cfg := Config{NameTemplate: "trace_{pid}_{time}.db"}
+	pid := strconv.Itoa(os.Getpid())
+	assert.Equal(t, "trace_"+pid+"_20250102030405.db", cfg.fileName(start, 1, false))
+	assert.Equal(t, "trace_"+pid+"_20250102030405.0002.db", cfg.fileName(start, 2, true))
+
+	cfg.NameTemplate = "run/{seq}.db"
+	assert.Equal(t, "run/0003.db", cfg.fileName(start, 3, true))
+}
+
+func TestNewDatabaseDSN(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixed.db")
+	// 设置 DSN 后忽略滚动配置
+	db := NewDatabase(Config{DSN: path, MaxSize: 1}, quietLogger())
+	require.IsType(t, &SQLiteDatabase{}, db)
+	require.NoError(t, db.Initialize())
+	defer db.Close()
+	assert.Equal(t, path, db.(*SQLiteDatabase).Path())
+}
+
+// openRotating 创建只手动检查的滚动数据库
+func openRotating(t *testing.T, cfg Config) *RotatingDatabase {
+	t.Helper()
+	cfg.Dir = t.TempDir()
+	cfg.NameTemplate = "test_{time}.db"
+	cfg.CheckInterval = time.Hour
+	db, ok := NewDatabase(cfg, quietLogger()).(*RotatingDatabase)
+	require.True(t, ok)
+	require.NoError(t, db.Initialize())
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func saveRoot(t *testing.T, db *RotatingDatabase, id int64, finished bool) {
+	t.Helper()
+	_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(id, "main.f", 1, 0, 0, 0, time.Now().Format(time.RFC3339Nano), ""))
+	require.NoError(t, err)
+	if finished {
+		require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(id, "1ms"))
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	db := openRotating(t, Config{MaxSize: 1})
+	first := db.Path()
+	saveRoot(t, db, 1, false)
+	saveRoot(t, db, 2, true)
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(1, 10, "", 0, "main.main"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Check())
+	assert.NotEqual(t, first, db.Path())
+
+	// 未完成的调用与协程已复制到新文件，耗时更新仍然成功
+	require.NoError(t, db.GetTraceRepository().UpdateTraceTimeCost(1, "5ms"))
+	require.NoError(t, db.GetGoroutineRepository().UpdateGoroutineTimeCost(1, "5ms", 1))
+	saveRoot(t, db, 3, true)
+
+	current, err := Open(db.Path(), quietLogger())
+	require.NoError(t, err)
+	defer current.Close()
+	n, err := current.countTraces()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	manifest, err := ReadManifest(db.ManifestPath())
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, filepath.Base(first), manifest.Files[0].Name)
+	assert.NotEmpty(t, manifest.Files[0].ClosedAt)
+	assert.Positive(t, manifest.Files[0].Size)
+	assert.Empty(t, manifest.Files[1].ClosedAt)
+	assert.Equal(t, 2, manifest.Files[1].Seq)
+
+	require.NoError(t, db.Close())
+	manifest, err = ReadManifest(db.ManifestPath())
+	require.NoError(t, err)
+	assert.NotEmpty(t, manifest.Files[1].ClosedAt)
+}
+
+func TestRetentionFiles(t *testing.T) {
+	db := openRotating(t, Config{MaxSize: 1, MaxTotalSize: 1})
+	first := db.Path()
+	saveRoot(t, db, 1, true)
+	require.NoError(t, db.Check())
+	saveRoot(t, db, 2, true)
+	require.NoError(t, db.Check())
+
+	// 当前文件永远保留，之前的文件全部删除
+	assert.Equal(t, []string{db.Path()}, db.Files())
+	assert.NoFileExists(t, first)
+	manifest, err := ReadManifest(db.ManifestPath())
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 3)
+	assert.True(t, manifest.Files[0].Deleted)
+	assert.True(t, manifest.Files[1].Deleted)
+	assert.False(t, manifest.Files[2].Deleted)
+}
+
+func TestRetentionTrees(t *testing.T) {
+	db := openRotating(t, Config{MaxTotalSize: 1, Retention: RetentionTrees})
+	saveRoot(t, db, 1, false)
+	for id := int64(2); id <= 100; id++ {
+		saveRoot(t, db, id, true)
+	}
+	_, err := db.GetParamRepository().SaveParam(model.NewParamStoreData(2, 0, []byte(`{"a":1}`), false, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Check())
+
+	// 已完成的根调用树全部删除，未完成的调用保留
+	current, err := Open(db.Path(), quietLogger())
+	require.NoError(t, err)
+	defer current.Close()
+	n, err := current.countTraces()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	params, err := current.GetParamRepository().FindParamsByTraceID(2)
+	require.NoError(t, err)
+	assert.Empty(t, params)
+
+	manifest, err := ReadManifest(db.ManifestPath())
+	require.NoError(t, err)
+	assert.Equal(t, int64(99), manifest.Files[0].DeletedTrees)
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// RunRepository 是SQLite实现的运行记录仓储
+type RunRepository struct {
+	db *sql.DB
+}
+
+// NewRunRepository 创建一个新的SQLite运行记录仓储
+func NewRunRepository(db *sql.DB) domain.RunRepository {
+	return &RunRepository{
+		db: db,
+	}
+}
+
+// StartRun 登记一次运行，run.ID 非0时按指定ID写入
+func (r *RunRepository) StartRun(run *model.Run) (int64, error) {
+	args := []interface{}{run.UUID, run.Executable, run.Args, run.Hostname, run.PID, run.GoVersion, run.ModulePath, run.ModuleVersion,
+		run.VCSRevision, run.VCSTime, run.VCSModified, run.BuildInfo, run.Config, run.StartedAt, run.EndedAt, run.Status, run.Origin}
+	if run.ID != 0 {
+		if _, err := r.db.Exec(SQLInsertRunWithID, append([]interface{}{run.ID}, args...)...); err != nil {
+			return 0, fmt.Errorf("save run error: %w", err)
+		}
+		return run.ID, nil
+	}
+	result, err := r.db.Exec(SQLInsertRun, args...)
+	if err != nil {
+		return 0, fmt.Errorf("save run error: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// EndRun 记录运行的结束时间与状态
+func (r *RunRepository) EndRun(id int64, endedAt string, status string) error {
+	if _, err := r.db.Exec(SQLUpdateRunEnd, endedAt, status, id); err != nil {
+		return fmt.Errorf("end run error: %w", err)
+	}
+	return nil
+}
+
+// FindRunByID 根据ID查找运行记录，未找到时返回 nil
+func (r *RunRepository) FindRunByID(id int64) (*model.Run, error) {
+	rows, err := r.db.Query(SQLSelectRunByID, id)
+	if err != nil {
+		return nil, fmt.Errorf("find run by id error: %w", err)
+	}
+	runs, err := scanRuns(rows)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// FindAllRuns 查询全部运行记录，按ID升序
+func (r *RunRepository) FindAllRuns() ([]model.Run, error) {
+	rows, err := r.db.Query(SQLSelectAllRuns)
+	if err != nil {
+		return nil, fmt.Errorf("find runs error: %w", err)
+	}
+	return scanRuns(rows)
+}
+
+func scanRuns(rows *sql.Rows) ([]model.Run, error) {
+	defer rows.Close()
+	var result []model.Run
+	for rows.Next() {
+		var r model.Run
+		if err := rows.Scan(&r.ID, &r.UUID, &r.Executable, &r.Args, &r.Hostname, &r.PID, &r.GoVersion, &r.ModulePath, &r.ModuleVersion,
+			&r.VCSRevision, &r.VCSTime, &r.VCSModified, &r.BuildInfo, &r.Config, &r.StartedAt, &r.EndedAt, &r.Status, &r.Origin); err != nil {
+			return nil, fmt.Errorf("scan run error: %w", err)
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate run result error: %w", err)
+	}
+	return result, nil
+}
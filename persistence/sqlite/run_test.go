@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+func TestRunsShareDatabase(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "shared.db"), quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	runs := db.GetRunRepository()
+
+	first := &model.Run{UUID: "a", Status: model.RunStatusRunning}
+	first.ID, err = runs.StartRun(first)
+	require.NoError(t, err)
+	second := &model.Run{UUID: "b", Status: model.RunStatusRunning}
+	second.ID, err = runs.StartRun(second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.ID)
+	assert.Equal(t, int64(2), second.ID)
+	assert.Zero(t, first.IDBase())
+
+	// 两次运行的进程内ID相同，写入后落在各自的命名空间
+	for _, run := range []*model.Run{first, second} {
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(run.IDBase()+1, "main.main", uint64(run.IDBase()+1), 0, 0, 0, "", ""))
+		require.NoError(t, err)
+		require.NoError(t, db.GetStatsRepository().SaveFuncStats([]*model.FuncStats{{RunID: run.ID, Name: "main.main", Count: run.ID, TotalTime: run.ID}}))
+	}
+	var runID int64
+	require.NoError(t, db.db.QueryRow("SELECT runId FROM TraceData WHERE id = ?", second.IDBase()+1).Scan(&runID))
+	assert.Equal(t, second.ID, runID)
+	assert.Equal(t, second.ID, model.RunIDOf(second.IDBase()+1))
+
+	stats, err := db.GetStatsRepository().FindAllFuncStats()
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, second.ID, stats[0].RunID)
+
+	require.NoError(t, runs.EndRun(first.ID, "t1", model.RunStatusClean))
+	all, err := runs.FindAllRuns()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, model.RunStatusClean, all[0].Status)
+	assert.Equal(t, "t1", all[0].EndedAt)
+	assert.Equal(t, model.RunStatusRunning, all[1].Status)
+
+	missing, err := runs.FindRunByID(3)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestMigrationRegistersLegacyRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	writeBaselineDB(t, path)
+
+	db, err := Open(path, quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	runs, err := db.GetRunRepository().FindAllRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, model.RunStatusUnknown, runs[0].Status)
+
+	// 新的运行不会与升级前的数据冲突
+	id, err := db.GetRunRepository().StartRun(&model.Run{Status: model.RunStatusRunning})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+}
+
+func TestRotateCopiesRun(t *testing.T) {
+	db := openRotating(t, Config{MaxSize: 1})
+	first := db.Path()
+	id, err := db.GetRunRepository().StartRun(&model.Run{UUID: "r", Status: model.RunStatusRunning, StartedAt: time.Now().Format(time.RFC3339Nano)})
+	require.NoError(t, err)
+	saveRoot(t, db, 1, true)
+	require.NoError(t, db.Check())
+	require.NotEqual(t, first, db.Path())
+
+	run, err := db.GetRunRepository().FindRunByID(id)
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, "r", run.UUID)
+	assert.Equal(t, model.RunStatusRunning, run.Status)
+
+	prev, err := OpenReadOnly(first, quietLogger())
+	require.NoError(t, err)
+	defer prev.Close()
+	run, err = prev.GetRunRepository().FindRunByID(id)
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, model.RunStatusRotated, run.Status)
+	assert.NotEmpty(t, run.EndedAt)
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// ScanTraces 按ID升序遍历全部跟踪数据
+func (s *SQLiteDatabase) ScanTraces(fn func(trace *model.TraceData) error) error {
+	rows, err := s.db.Query(SQLScanTraces)
+	if err != nil {
+		return fmt.Errorf("scan traces error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		trace, err := scanTraceRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(trace); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate traces error: %w", err)
+	}
+	return nil
+}
+
+// ScanGoroutines 按ID升序遍历全部协程数据
+func (s *SQLiteDatabase) ScanGoroutines(fn func(goroutine *model.GoroutineTrace) error) error {
+	rows, err := s.db.Query(SQLScanGoroutines)
+	if err != nil {
+		return fmt.Errorf("scan goroutines error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		goroutine, err := scanGoroutineRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(goroutine); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate goroutines error: %w", err)
+	}
+	return nil
+}
+
+// scanTraceRow 扫描一行完整的跟踪数据，未完成调用的可空列按零值处理
+func scanTraceRow(rows *sql.Rows) (*model.TraceData, error) {
+	var (
+		trace      model.TraceData
+		timeCost   sql.NullString
+		isFinished sql.NullInt64
+		createdAt  sql.NullString
+		seq        sql.NullString
+	)
+	if err := rows.Scan(&trace.ID, &trace.Name, &trace.GID, &trace.Indent, &trace.ParamsCount, &timeCost, &trace.ParentId, &isFinished, &createdAt, &seq); err != nil {
+		return nil, fmt.Errorf("scan trace data error: %w", err)
+	}
+	trace.TimeCost = timeCost.String
+	trace.IsFinished = int(isFinished.Int64)
+	trace.CreatedAt = createdAt.String
+	trace.Seq = seq.String
+	return &trace, nil
+}
+
+// scanGoroutineRow 扫描一行完整的协程数据
+func scanGoroutineRow(rows *sql.Rows) (*model.GoroutineTrace, error) {
+	var (
+		goroutine     model.GoroutineTrace
+		timeCost      sql.NullString
+		isFinished    sql.NullInt64
+		initFuncName  sql.NullString
+		creatorGid    sql.NullInt64
+		creatorFunc   sql.NullString
+		parentTraceId sql.NullInt64
+	)
+	if err := rows.Scan(&goroutine.ID, &goroutine.OriginGID, &timeCost, &goroutine.CreateTime, &isFinished, &initFuncName, &creatorGid, &creatorFunc, &parentTraceId); err != nil {
+		return nil, fmt.Errorf("scan goroutine data error: %w", err)
+	}
+	goroutine.TimeCost = timeCost.String
+	goroutine.IsFinished = int(isFinished.Int64)
+	goroutine.InitFuncName = initFuncName.String
+	goroutine.CreatorGID = uint64(creatorGid.Int64)
+	goroutine.CreatorFunc = creatorFunc.String
+	goroutine.ParentTraceID = parentTraceId.Int64
+	return &goroutine, nil
+}
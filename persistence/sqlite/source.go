@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// SourceRepository 是SQLite实现的数据来源仓储
+type SourceRepository struct {
+	db *sql.DB
+}
+
+// NewSourceRepository 创建一个新的SQLite数据来源仓储
+func NewSourceRepository(db *sql.DB) domain.SourceRepository {
+	return &SourceRepository{
+		db: db,
+	}
+}
+
+// SaveSource 保存数据来源
+func (r *SourceRepository) SaveSource(source *model.Source) (int64, error) {
+	result, err := r.db.Exec(SQLInsertSource, source.RunID, source.Process, source.Host, source.PID, source.RemoteAddr, source.ConnectedAt, source.LastSeenAt, source.LastSeq, source.Records)
+	if err != nil {
+		return 0, fmt.Errorf("save source error: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateSourceProgress 更新已写入的批次序号、记录数与最后活动时间
+func (r *SourceRepository) UpdateSourceProgress(id int64, lastSeq int64, records int64, lastSeenAt string) error {
+	if _, err := r.db.Exec(SQLUpdateSourceProgress, lastSeq, records, lastSeenAt, id); err != nil {
+		return fmt.Errorf("update source progress error: %w", err)
+	}
+	return nil
+}
+
+// FindSourceByRunID 根据运行ID查找数据来源，未找到时返回 nil
+func (r *SourceRepository) FindSourceByRunID(runId string) (*model.Source, error) {
+	rows, err := r.db.Query(SQLSelectSourceByRunID, runId)
+	if err != nil {
+		return nil, fmt.Errorf("find source by run id error: %w", err)
+	}
+	sources, err := scanSources(rows)
+	if err != nil || len(sources) == 0 {
+		return nil, err
+	}
+	return &sources[0], nil
+}
+
+// FindAllSources 查询全部数据来源
+func (r *SourceRepository) FindAllSources() ([]model.Source, error) {
+	rows, err := r.db.Query(SQLSelectAllSources)
+	if err != nil {
+		return nil, fmt.Errorf("find sources error: %w", err)
+	}
+	return scanSources(rows)
+}
+
+// scanSources 读取数据来源查询结果并关闭 rows
+func scanSources(rows *sql.Rows) ([]model.Source, error) {
+	defer rows.Close()
+	var result []model.Source
+	for rows.Next() {
+		var s model.Source
+		if err := rows.Scan(&s.ID, &s.RunID, &s.Process, &s.Host, &s.PID, &s.RemoteAddr, &s.ConnectedAt, &s.LastSeenAt, &s.LastSeq, &s.Records); err != nil {
+			return nil, fmt.Errorf("scan source error: %w", err)
+		}
+		result = append(result, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate source result error: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// StatsRepository 是SQLite实现的函数统计仓储
+type StatsRepository struct {
+	db *sql.DB
+}
+
+// NewStatsRepository 创建一个新的SQLite函数统计仓储
+func NewStatsRepository(db *sql.DB) domain.StatsRepository {
+	return &StatsRepository{
+		db: db,
+	}
+}
+
+// SaveFuncStats 批量保存函数统计快照（单事务）
+func (r *StatsRepository) SaveFuncStats(stats []*model.FuncStats) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx error: %w", err)
+	}
+	stmt, err := tx.Prepare(SQLUpsertFuncStats)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("prepare upsert func stats error: %w", err)
+	}
+	defer stmt.Close()
+	for _, s := range stats {
+		if _, err := stmt.Exec(s.RunID, s.Name, s.Count, s.ErrorCount, s.TotalTime, s.SelfTime, s.MaxTime, s.P50, s.P95, s.P99, s.Sketch, s.UpdatedAt); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("save func stats error: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// FindAllFuncStats 查询全部函数统计快照
+func (r *StatsRepository) FindAllFuncStats() ([]model.FuncStats, error) {
+	rows, err := r.db.Query(SQLSelectAllFuncStats)
+	if err != nil {
+		return nil, fmt.Errorf("find func stats error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.FuncStats
+	for rows.Next() {
+		var s model.FuncStats
+		if err := rows.Scan(&s.RunID, &s.Name, &s.Count, &s.ErrorCount, &s.TotalTime, &s.SelfTime, &s.MaxTime, &s.P50, &s.P95, &s.P99, &s.Sketch, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan func stats error: %w", err)
+		}
+		result = append(result, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate func stats result error: %w", err)
+	}
+
+	return result, nil
+}
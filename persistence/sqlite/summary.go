@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/toheart/functrace/domain"
+)
+
+var _ domain.Summarizer = (*SQLiteDatabase)(nil)
+var _ domain.Summarizer = (*RotatingDatabase)(nil)
+
+// summarizeStatements 按运行计算汇总表的语句，均以运行ID为参数
+var summarizeStatements = []string{
+	SQLSummarizeFuncs,
+	SQLSummarizeEdges,
+	SQLSummarizeGoroutines,
+	SQLSummarizeRootCalls,
+}
+
+// Summarize 从 TraceData 重新计算 FuncSummary、CallEdge、GoroutineSummary 与 RootCallSummary（单事务）
+// 全部运行逐个计算，已有的汇总结果被替换
+func (s *SQLiteDatabase) Summarize() error {
+	return s.summarizeTx(func(tx *sql.Tx) error {
+		if err := execAll(SQLClearFuncSummary, SQLClearCallEdge, SQLClearGoroutineSummary, SQLClearRootCallSummary)(tx); err != nil {
+			return err
+		}
+		runs, err := traceRuns(tx)
+		if err != nil {
+			return err
+		}
+		for _, runID := range runs {
+			if err := summarizeRun(tx, runID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SummarizeRun 只重新计算指定运行的汇总表（单事务），其他运行的汇总结果保持不变
+func (s *SQLiteDatabase) SummarizeRun(runID int64) error {
+	return s.summarizeTx(func(tx *sql.Tx) error {
+		for _, stmt := range []string{SQLClearRunFuncSummary, SQLClearRunCallEdge, SQLClearRunGoroutineSummary, SQLClearRunRootCallSummary} {
+			if _, err := tx.Exec(stmt, runID); err != nil {
+				return fmt.Errorf("can't exec sql: %s, %w", stmt, err)
+			}
+		}
+		return summarizeRun(tx, runID)
+	})
+}
+
+// summarizeTx 在单个事务中执行 fn
+func (s *SQLiteDatabase) summarizeTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx error: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// summarizeRun 写入一个运行的汇总结果，调用方需先清除该运行已有的结果
+func summarizeRun(tx *sql.Tx, runID int64) error {
+	for _, stmt := range summarizeStatements {
+		if _, err := tx.Exec(stmt, runID); err != nil {
+			return fmt.Errorf("can't exec sql: %s, %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// traceRuns 返回 TraceData 中出现的全部运行ID
+func traceRuns(tx *sql.Tx) ([]int64, error) {
+	rows, err := tx.Query(SQLSelectTraceRuns)
+	if err != nil {
+		return nil, fmt.Errorf("query trace runs error: %w", err)
+	}
+	defer rows.Close()
+	var runs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan trace run error: %w", err)
+		}
+		runs = append(runs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trace runs error: %w", err)
+	}
+	return runs, nil
+}
+
+// Summarize 在当前文件上重新计算汇总表，已滚动的文件不受影响
+func (r *RotatingDatabase) Summarize() error {
+	return r.with(func(db *SQLiteDatabase) error {
+		return db.Summarize()
+	})
+}
+
+// SummarizeRun 在当前文件上重新计算指定运行的汇总表
+func (r *RotatingDatabase) SummarizeRun(runID int64) error {
+	return r.with(func(db *SQLiteDatabase) error {
+		return db.SummarizeRun(runID)
+	})
+}
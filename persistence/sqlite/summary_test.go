@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// writeSummaryFixture 写入两个协程的调用
+//
+//	gid 1: 1 main.main(10ms) -> 2 main.a(3ms) -> 3 main.c(1ms), 4 main.a(5ms)
+//	       5 main.main(未完成) -> 6 main.a(2ms)
+//	gid 2: 7 main.a(4ms)
+func writeSummaryFixture(t *testing.T, db *SQLiteDatabase) {
+	t.Helper()
+	traces := db.GetTraceRepository().(*TraceRepository)
+	require.NoError(t, traces.SaveTracesBatch([]*model.TraceData{
+		{ID: 1, Name: "main.main", GID: 1, TimeCost: "10ms", IsFinished: 1, CreatedAt: "t1"},
+		{ID: 2, Name: "main.a", GID: 1, Indent: 1, ParentId: 1, TimeCost: "3ms", IsFinished: 1, CreatedAt: "t2"},
+		{ID: 3, Name: "main.c", GID: 1, Indent: 2, ParentId: 2, TimeCost: "1ms", IsFinished: 1, CreatedAt: "t3"},
+		{ID: 4, Name: "main.a", GID: 1, Indent: 1, ParentId: 1, TimeCost: "5ms", IsFinished: 1, CreatedAt: "t4"},
+		{ID: 5, Name: "main.main", GID: 1, CreatedAt: "t5"},
+		{ID: 6, Name: "main.a", GID: 1, Indent: 1, ParentId: 5, TimeCost: "2ms", IsFinished: 1, CreatedAt: "t6"},
+		{ID: 7, Name: "main.a", GID: 2, TimeCost: "4ms", IsFinished: 1, CreatedAt: "t7"},
+	}))
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(1, 10, "t0", 0, "main.main"))
+	require.NoError(t, err)
+	_, err = db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(2, 11, "t0", 1, "main.worker"))
+	require.NoError(t, err)
+}
+
+func TestSummarize(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "summary.db"), quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	writeSummaryFixture(t, db)
+	ms := int64(time.Millisecond)
+
+	// 重复计算时替换已有结果
+	require.NoError(t, db.Summarize())
+	require.NoError(t, db.Summarize())
+
+	var calls, unfinished, total, self, minTime, maxTime, p50, p90, p99 int64
+	require.NoError(t, db.db.QueryRow("SELECT calls, unfinished, totalTime, selfTime, minTime, maxTime, p50, p90, p99 FROM FuncSummary WHERE runId = 1 AND name = 'main.a'").
+		Scan(&calls, &unfinished, &total, &self, &minTime, &maxTime, &p50, &p90, &p99))
+	assert.Equal(t, []int64{4, 0, 14 * ms, 13 * ms, 2 * ms, 5 * ms, 3 * ms, 5 * ms, 5 * ms}, []int64{calls, unfinished, total, self, minTime, maxTime, p50, p90, p99})
+	require.NoError(t, db.db.QueryRow("SELECT calls, unfinished, totalTime, selfTime, p50 FROM FuncSummary WHERE name = 'main.main'").
+		Scan(&calls, &unfinished, &total, &self, &p50))
+	assert.Equal(t, []int64{2, 1, 10 * ms, 2 * ms, 10 * ms}, []int64{calls, unfinished, total, self, p50})
+
+	require.NoError(t, db.db.QueryRow("SELECT calls, totalTime, maxTime FROM CallEdge WHERE caller = 'main.main' AND callee = 'main.a'").Scan(&calls, &total, &maxTime))
+	assert.Equal(t, []int64{3, 10 * ms, 5 * ms}, []int64{calls, total, maxTime})
+	var edges int
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM CallEdge").Scan(&edges))
+	assert.Equal(t, 2, edges)
+
+	var originGid, rootCalls, rootTime, depth int64
+	var initFunc, first, last string
+	require.NoError(t, db.db.QueryRow("SELECT originGid, initFuncName, calls, rootCalls, rootTime, maxDepth, firstCallAt, lastCallAt FROM GoroutineSummary WHERE gid = 1").
+		Scan(&originGid, &initFunc, &calls, &rootCalls, &rootTime, &depth, &first, &last))
+	assert.Equal(t, []int64{10, 6, 2, 10 * ms, 3}, []int64{originGid, calls, rootCalls, rootTime, depth})
+	assert.Equal(t, []string{"main.main", "t1", "t6"}, []string{initFunc, first, last})
+
+	rows, err := db.db.Query("SELECT traceId, gid, calls, unfinished, maxDepth FROM RootCallSummary ORDER BY traceId")
+	require.NoError(t, err)
+	defer rows.Close()
+	var roots [][]int64
+	for rows.Next() {
+		var id, gid int64
+		require.NoError(t, rows.Scan(&id, &gid, &calls, &unfinished, &depth))
+		roots = append(roots, []int64{id, gid, calls, unfinished, depth})
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, [][]int64{{1, 1, 4, 0, 3}, {5, 1, 2, 1, 2}, {7, 2, 1, 0, 1}}, roots)
+}
+
+func TestSummarizeRun(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "summary.db"), quietLogger())
+	require.NoError(t, err)
+	defer db.Close()
+	writeSummaryFixture(t, db)
+	base := (&model.Run{ID: 2}).IDBase()
+	traces := db.GetTraceRepository().(*TraceRepository)
+	require.NoError(t, traces.SaveTracesBatch([]*model.TraceData{
+		{ID: base + 1, Name: "main.main", GID: uint64(base + 1), TimeCost: "6ms", IsFinished: 1},
+		{ID: base + 2, Name: "main.b", GID: uint64(base + 1), Indent: 1, ParentId: base + 1, TimeCost: "4ms", IsFinished: 1},
+	}))
+	count := func(table string, runID int64) int {
+		var n int
+		require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE runId = ?", runID).Scan(&n))
+		return n
+	}
+
+	// 只计算指定运行，其他运行的汇总不被计算也不被清除
+	require.NoError(t, db.SummarizeRun(2))
+	assert.Equal(t, []int{0, 2, 1, 1, 1}, []int{count("FuncSummary", 1), count("FuncSummary", 2), count("CallEdge", 2), count("GoroutineSummary", 2), count("RootCallSummary", 2)})
+	require.NoError(t, db.Summarize())
+	assert.Equal(t, 3, count("FuncSummary", 1))
+	require.NoError(t, db.SummarizeRun(2))
+	assert.Equal(t, []int{3, 2, 2}, []int{count("FuncSummary", 1), count("FuncSummary", 2), count("CallEdge", 1)})
+
+	var self int64
+	require.NoError(t, db.db.QueryRow("SELECT selfTime FROM FuncSummary WHERE runId = 2 AND name = 'main.main'").Scan(&self))
+	assert.Equal(t, 2*int64(time.Millisecond), self)
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/toheart/functrace/domain/model"
 )
 
+var _ domain.TraceBatchWriter = (*TraceRepository)(nil)
+
 // TraceRepository 是SQLite实现的跟踪数据仓储
 type TraceRepository struct {
 	db *sql.DB
@@ -40,9 +42,41 @@ func (r *TraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
 	return result.LastInsertId()
 }
 
+// SaveTracesBatch 批量写入完整的跟踪数据（单事务），ID 已存在时整行覆盖
+func (r *TraceRepository) SaveTracesBatch(traces []*model.TraceData) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx error: %w", err)
+	}
+	stmt, err := tx.Prepare(SQLUpsertTrace)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("prepare upsert trace error: %w", err)
+	}
+	defer stmt.Close()
+	for _, t := range traces {
+		var duration sql.NullInt64
+		if d, ok := model.ParseTimeCost(t.TimeCost); ok {
+			duration = sql.NullInt64{Int64: int64(d), Valid: true}
+		}
+		if _, err := stmt.Exec(t.ID, t.Name, t.GID, t.Indent, t.ParamsCount, t.TimeCost, t.ParentId, t.IsFinished, t.CreatedAt, t.Seq, duration); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("batch save trace error: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
 // UpdateTraceTimeCost 更新跟踪时间成本
 func (r *TraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
-	result, err := r.db.Exec(SQLUpdateTimeCost, timeCost, 1, id)
+	var duration sql.NullInt64
+	if d, ok := model.ParseTimeCost(timeCost); ok {
+		duration = sql.NullInt64{Int64: int64(d), Valid: true}
+	}
+	result, err := r.db.Exec(SQLUpdateTimeCost, timeCost, 1, duration, id)
 	if err != nil {
 		return fmt.Errorf("update trace time cost error: %w", err)
 	}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/toheart/functrace/domain/model"
+)
+
+// FindTraceByID 根据ID查找跟踪数据
+func (s *SQLiteDatabase) FindTraceByID(id int64) (*model.TraceData, error) {
+	rows, err := s.db.Query(SQLSelectTraceByID, id)
+	if err != nil {
+		return nil, fmt.Errorf("find trace by id error: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("find trace by id error: %w", err)
+		}
+		return nil, fmt.Errorf("trace data not found: id=%d", id)
+	}
+	return scanTraceRow(rows)
+}
+
+// FindTraceChildren 查找直接子调用，按ID升序
+func (s *SQLiteDatabase) FindTraceChildren(parentId int64) ([]model.TraceData, error) {
+	return s.queryTraces(SQLSelectTraceChildren, parentId)
+}
+
+// FindRootTracesByGID 查找协程的全部根调用，按ID升序
+func (s *SQLiteDatabase) FindRootTracesByGID(gid uint64) ([]model.TraceData, error) {
+	return s.queryTraces(SQLSelectRootTracesByGID, gid)
+}
+
+// FindGoroutinesSpawnedBy 查找在指定调用执行期间创建的协程
+func (s *SQLiteDatabase) FindGoroutinesSpawnedBy(traceId int64) ([]model.GoroutineTrace, error) {
+	rows, err := s.db.Query(SQLSelectGoroutinesSpawnedBy, traceId)
+	if err != nil {
+		return nil, fmt.Errorf("find goroutines spawned by trace error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.GoroutineTrace
+	for rows.Next() {
+		g, err := scanGoroutineRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate goroutines error: %w", err)
+	}
+	return result, nil
+}
+
+// queryTraces 执行返回完整跟踪数据行的查询
+func (s *SQLiteDatabase) queryTraces(query string, args ...interface{}) ([]model.TraceData, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query traces error: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.TraceData
+	for rows.Next() {
+		td, err := scanTraceRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *td)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate traces error: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,210 @@
+// Package tee 将每次写入同时转发到多个后端（如 SQLite 加流式上报），查询只读第一个后端
+package tee
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+)
+
+// ErrRunsNotSupported 没有任何后端支持运行记录
+var ErrRunsNotSupported = errors.New("no tee backend records runs")
+
+// 确保TeeDatabase实现了IDatabase接口及可选的写入能力
+var _ domain.RepositoryFactory = (*TeeDatabase)(nil)
+var _ domain.StatsRepositoryProvider = (*TeeDatabase)(nil)
+var _ domain.EventRepositoryProvider = (*TeeDatabase)(nil)
+var _ domain.LeakRepositoryProvider = (*TeeDatabase)(nil)
+var _ domain.RunRepositoryProvider = (*TeeDatabase)(nil)
+var _ domain.Summarizer = (*TeeDatabase)(nil)
+
+// TeeDatabase 同时写入多个后端的仓储工厂
+// 第一个后端为主后端：写入错误与返回的ID以主后端为准，查询只读主后端；
+// 其余后端写入失败时只计数，并在每个后端首次失败时记录日志，不影响跟踪流程。
+// 可选能力（统计、事件、泄漏报告、运行记录、汇总表）只转发给支持该能力的后端，其中第一个视为主后端
+type TeeDatabase struct {
+	logger   *logrus.Logger
+	backends []domain.RepositoryFactory
+	failures []atomic.Uint64 // 各后端的写入失败次数，下标与 backends 对应
+
+	traceRepository     domain.TraceRepository
+	paramRepository     *teeParamRepository
+	goroutineRepository *teeGoroutineRepository
+	statsRepository     *teeStatsRepository
+	eventRepository     *teeEventRepository
+	leakRepository      *teeLeakRepository
+	runRepository       *teeRunRepository
+}
+
+// NewTeeDatabase 创建写入 backends 的仓储工厂，backends 由 Initialize 统一初始化
+func NewTeeDatabase(logger *logrus.Logger, backends ...domain.RepositoryFactory) *TeeDatabase {
+	return &TeeDatabase{
+		logger:   logger,
+		backends: backends,
+		failures: make([]atomic.Uint64, len(backends)),
+	}
+}
+
+// Initialize 依次初始化全部后端，任一失败时关闭已初始化的后端
+func (t *TeeDatabase) Initialize() error {
+	if len(t.backends) == 0 {
+		return fmt.Errorf("tee needs at least one backend")
+	}
+	for i, b := range t.backends {
+		if err := b.Initialize(); err != nil {
+			for _, opened := range t.backends[:i] {
+				_ = opened.Close()
+			}
+			return fmt.Errorf("initialize tee backend %d error: %w", i, err)
+		}
+	}
+	t.buildRepositories()
+	return nil
+}
+
+// buildRepositories 收集各后端的仓储，需在后端初始化之后调用
+func (t *TeeDatabase) buildRepositories() {
+	all := make([]int, len(t.backends))
+	trace := &teeTraceRepository{members: members{t: t, idx: all}}
+	param := &teeParamRepository{members: members{t: t, idx: all}}
+	goroutine := &teeGoroutineRepository{members: members{t: t, idx: all}}
+	batch := true
+	for i, b := range t.backends {
+		all[i] = i
+		tr := b.GetTraceRepository()
+		if _, ok := tr.(domain.TraceBatchWriter); !ok {
+			batch = false
+		}
+		trace.repos = append(trace.repos, tr)
+		param.repos = append(param.repos, b.GetParamRepository())
+		goroutine.repos = append(goroutine.repos, b.GetGoroutineRepository())
+	}
+	// 全部后端都支持整行写入时才提供该能力，否则跟踪流程按插入加更新的方式写入
+	if batch {
+		t.traceRepository = &teeBatchTraceRepository{trace}
+	} else {
+		t.traceRepository = trace
+	}
+	t.paramRepository = param
+	t.goroutineRepository = goroutine
+
+	t.statsRepository = &teeStatsRepository{members: members{t: t}}
+	t.eventRepository = &teeEventRepository{members: members{t: t}}
+	t.leakRepository = &teeLeakRepository{members: members{t: t}}
+	t.runRepository = &teeRunRepository{members: members{t: t}}
+	for i, b := range t.backends {
+		if p, ok := b.(domain.StatsRepositoryProvider); ok {
+			t.statsRepository.add(i)
+			t.statsRepository.repos = append(t.statsRepository.repos, p.GetStatsRepository())
+		}
+		if p, ok := b.(domain.EventRepositoryProvider); ok {
+			t.eventRepository.add(i)
+			t.eventRepository.repos = append(t.eventRepository.repos, p.GetEventRepository())
+		}
+		if p, ok := b.(domain.LeakRepositoryProvider); ok {
+			t.leakRepository.add(i)
+			t.leakRepository.repos = append(t.leakRepository.repos, p.GetLeakRepository())
+		}
+		if p, ok := b.(domain.RunRepositoryProvider); ok {
+			t.runRepository.add(i)
+			t.runRepository.repos = append(t.runRepository.repos, p.GetRunRepository())
+		}
+	}
+}
+
+// GetTraceRepository 获取跟踪数据仓储
+func (t *TeeDatabase) GetTraceRepository() domain.TraceRepository {
+	return t.traceRepository
+}
+
+// GetParamRepository 获取参数数据仓储
+func (t *TeeDatabase) GetParamRepository() domain.ParamRepository {
+	return t.paramRepository
+}
+
+// GetGoroutineRepository 获取协程数据仓储
+func (t *TeeDatabase) GetGoroutineRepository() domain.GoroutineRepository {
+	return t.goroutineRepository
+}
+
+// GetStatsRepository 获取函数统计仓储，没有后端支持时写入被忽略
+func (t *TeeDatabase) GetStatsRepository() domain.StatsRepository {
+	return t.statsRepository
+}
+
+// GetEventRepository 获取跟踪事件仓储，没有后端支持时写入被忽略
+func (t *TeeDatabase) GetEventRepository() domain.EventRepository {
+	return t.eventRepository
+}
+
+// GetLeakRepository 获取泄漏报告仓储，没有后端支持时写入被忽略
+func (t *TeeDatabase) GetLeakRepository() domain.LeakRepository {
+	return t.leakRepository
+}
+
+// GetRunRepository 获取运行记录仓储，没有后端支持时登记运行返回 ErrRunsNotSupported
+func (t *TeeDatabase) GetRunRepository() domain.RunRepository {
+	return t.runRepository
+}
+
+// Backends 返回全部后端，第一个为主后端
+func (t *TeeDatabase) Backends() []domain.RepositoryFactory {
+	return t.backends
+}
+
+// Failures 返回各后端的写入失败次数，下标与 Backends 对应；主后端的失败由调用方处理，不计入
+func (t *TeeDatabase) Failures() []uint64 {
+	n := make([]uint64, len(t.failures))
+	for i := range t.failures {
+		n[i] = t.failures[i].Load()
+	}
+	return n
+}
+
+// Summarize 在支持汇总表的后端上重新计算，返回其中第一个后端的错误
+func (t *TeeDatabase) Summarize() error {
+	return t.summarize("summarize", domain.Summarizer.Summarize)
+}
+
+// SummarizeRun 在支持汇总表的后端上重新计算指定运行，返回其中第一个后端的错误
+func (t *TeeDatabase) SummarizeRun(runID int64) error {
+	return t.summarize("summarize run", func(s domain.Summarizer) error {
+		return s.SummarizeRun(runID)
+	})
+}
+
+// summarize 在支持汇总表的后端上执行 fn
+func (t *TeeDatabase) summarize(op string, fn func(domain.Summarizer) error) error {
+	m := members{t: t}
+	var summarizers []domain.Summarizer
+	for i, b := range t.backends {
+		if s, ok := b.(domain.Summarizer); ok {
+			m.add(i)
+			summarizers = append(summarizers, s)
+		}
+	}
+	return m.write(op, func(n int) error {
+		return fn(summarizers[n])
+	})
+}
+
+// Close 关闭全部后端，返回合并后的错误
+func (t *TeeDatabase) Close() error {
+	var errs []error
+	for i, b := range t.backends {
+		if err := b.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close tee backend %d error: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fail 记录非主后端的一次写入失败，每个后端只在首次失败时记录日志
+func (t *TeeDatabase) fail(backend int, op string, err error) {
+	if t.failures[backend].Add(1) == 1 && t.logger != nil {
+		t.logger.WithFields(logrus.Fields{"backend": backend, "op": op, "error": err}).Warn("tee backend write failed, further failures are only counted")
+	}
+}
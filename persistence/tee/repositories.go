@@ -0,0 +1,258 @@
+package tee
+
+import (
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// members 一个仓储所转发到的后端下标，idx[0] 为主后端
+type members struct {
+	t   *TeeDatabase
+	idx []int
+}
+
+func (m *members) add(backend int) {
+	m.idx = append(m.idx, backend)
+}
+
+// write 依次对每个后端执行写入，返回主后端的错误，其余后端的错误只计数
+func (m *members) write(op string, fn func(n int) error) error {
+	var primary error
+	for n, backend := range m.idx {
+		err := fn(n)
+		if err == nil {
+			continue
+		}
+		if n == 0 {
+			primary = err
+			continue
+		}
+		m.t.fail(backend, op, err)
+	}
+	return primary
+}
+
+// teeTraceRepository 跟踪数据仓储
+type teeTraceRepository struct {
+	members
+	repos []domain.TraceRepository
+}
+
+func (r *teeTraceRepository) SaveTrace(trace *model.TraceData) (int64, error) {
+	var id int64
+	err := r.write("save trace", func(n int) error {
+		got, err := r.repos[n].SaveTrace(trace)
+		if n == 0 {
+			id = got
+		}
+		return err
+	})
+	return id, err
+}
+
+func (r *teeTraceRepository) UpdateTraceTimeCost(id int64, timeCost string) error {
+	return r.write("update trace", func(n int) error {
+		return r.repos[n].UpdateTraceTimeCost(id, timeCost)
+	})
+}
+
+func (r *teeTraceRepository) FindRootFunctionsByGID(gid uint64) ([]model.TraceData, error) {
+	return r.repos[0].FindRootFunctionsByGID(gid)
+}
+
+// teeBatchTraceRepository 全部后端都支持整行写入时使用的跟踪数据仓储
+type teeBatchTraceRepository struct {
+	*teeTraceRepository
+}
+
+func (r *teeBatchTraceRepository) SaveTracesBatch(traces []*model.TraceData) error {
+	return r.write("save traces", func(n int) error {
+		return r.repos[n].(domain.TraceBatchWriter).SaveTracesBatch(traces)
+	})
+}
+
+// teeParamRepository 参数数据仓储
+type teeParamRepository struct {
+	members
+	repos []domain.ParamRepository
+}
+
+func (r *teeParamRepository) SaveParam(param *model.ParamStoreData) (int64, error) {
+	var id int64
+	err := r.write("save param", func(n int) error {
+		got, err := r.repos[n].SaveParam(param)
+		if n == 0 {
+			id = got
+		}
+		return err
+	})
+	return id, err
+}
+
+func (r *teeParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	return r.write("save params", func(n int) error {
+		return r.repos[n].SaveParamsBatch(params)
+	})
+}
+
+func (r *teeParamRepository) FindParamsByTraceID(traceId int64) ([]model.ParamStoreData, error) {
+	return r.repos[0].FindParamsByTraceID(traceId)
+}
+
+func (r *teeParamRepository) SaveParamCache(cache *model.ParamCache) (int64, error) {
+	var id int64
+	err := r.write("save param cache", func(n int) error {
+		got, err := r.repos[n].SaveParamCache(cache)
+		if n == 0 {
+			id = got
+		}
+		return err
+	})
+	return id, err
+}
+
+func (r *teeParamRepository) FindParamCacheByAddr(addr string) (*model.ParamCache, error) {
+	return r.repos[0].FindParamCacheByAddr(addr)
+}
+
+func (r *teeParamRepository) DeleteParamCacheByAddr(addr string) error {
+	return r.write("delete param cache", func(n int) error {
+		return r.repos[n].DeleteParamCacheByAddr(addr)
+	})
+}
+
+// teeGoroutineRepository 协程数据仓储
+type teeGoroutineRepository struct {
+	members
+	repos []domain.GoroutineRepository
+}
+
+func (r *teeGoroutineRepository) SaveGoroutine(goroutine *model.GoroutineTrace) (int64, error) {
+	var id int64
+	err := r.write("save goroutine", func(n int) error {
+		got, err := r.repos[n].SaveGoroutine(goroutine)
+		if n == 0 {
+			id = got
+		}
+		return err
+	})
+	return id, err
+}
+
+func (r *teeGoroutineRepository) UpdateGoroutineTimeCost(id int64, timeCost string, isFinished int) error {
+	return r.write("update goroutine", func(n int) error {
+		return r.repos[n].UpdateGoroutineTimeCost(id, timeCost, isFinished)
+	})
+}
+
+func (r *teeGoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
+	return r.repos[0].FindGoroutineByID(id)
+}
+
+// teeStatsRepository 函数统计仓储
+type teeStatsRepository struct {
+	members
+	repos []domain.StatsRepository
+}
+
+func (r *teeStatsRepository) SaveFuncStats(stats []*model.FuncStats) error {
+	return r.write("save func stats", func(n int) error {
+		return r.repos[n].SaveFuncStats(stats)
+	})
+}
+
+func (r *teeStatsRepository) FindAllFuncStats() ([]model.FuncStats, error) {
+	if len(r.repos) == 0 {
+		return nil, nil
+	}
+	return r.repos[0].FindAllFuncStats()
+}
+
+// teeEventRepository 跟踪事件仓储
+type teeEventRepository struct {
+	members
+	repos []domain.EventRepository
+}
+
+func (r *teeEventRepository) SaveEvent(event *model.TraceEvent) (int64, error) {
+	var id int64
+	err := r.write("save event", func(n int) error {
+		got, err := r.repos[n].SaveEvent(event)
+		if n == 0 {
+			id = got
+		}
+		return err
+	})
+	return id, err
+}
+
+func (r *teeEventRepository) FindEventsByTraceID(traceId int64) ([]model.TraceEvent, error) {
+	if len(r.repos) == 0 {
+		return nil, nil
+	}
+	return r.repos[0].FindEventsByTraceID(traceId)
+}
+
+// teeLeakRepository 泄漏报告仓储
+type teeLeakRepository struct {
+	members
+	repos []domain.LeakRepository
+}
+
+func (r *teeLeakRepository) SaveLeakReports(reports []*model.LeakReport) error {
+	return r.write("save leak reports", func(n int) error {
+		return r.repos[n].SaveLeakReports(reports)
+	})
+}
+
+func (r *teeLeakRepository) FindAllLeakReports() ([]model.LeakReport, error) {
+	if len(r.repos) == 0 {
+		return nil, nil
+	}
+	return r.repos[0].FindAllLeakReports()
+}
+
+// teeRunRepository 运行记录仓储
+type teeRunRepository struct {
+	members
+	repos []domain.RunRepository
+}
+
+// StartRun 先在主后端登记运行，其余后端按主后端分配的ID写入，保持各后端的ID命名空间一致
+func (r *teeRunRepository) StartRun(run *model.Run) (int64, error) {
+	if len(r.repos) == 0 {
+		return 0, ErrRunsNotSupported
+	}
+	id, err := r.repos[0].StartRun(run)
+	if err != nil {
+		return 0, err
+	}
+	same := *run
+	same.ID = id
+	for n := 1; n < len(r.repos); n++ {
+		if _, err := r.repos[n].StartRun(&same); err != nil {
+			r.t.fail(r.idx[n], "start run", err)
+		}
+	}
+	return id, nil
+}
+
+func (r *teeRunRepository) EndRun(id int64, endedAt string, status string) error {
+	return r.write("end run", func(n int) error {
+		return r.repos[n].EndRun(id, endedAt, status)
+	})
+}
+
+func (r *teeRunRepository) FindRunByID(id int64) (*model.Run, error) {
+	if len(r.repos) == 0 {
+		return nil, nil
+	}
+	return r.repos[0].FindRunByID(id)
+}
+
+func (r *teeRunRepository) FindAllRuns() ([]model.Run, error) {
+	if len(r.repos) == 0 {
+		return nil, nil
+	}
+	return r.repos[0].FindAllRuns()
+}
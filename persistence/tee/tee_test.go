@@ -0,0 +1,142 @@
+package tee
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/memory"
+)
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// brokenDatabase 跟踪数据写入总是失败的后端
+type brokenDatabase struct {
+	*memory.MemDatabase
+}
+
+type brokenTraceRepository struct {
+	domain.TraceRepository
+}
+
+func (brokenTraceRepository) SaveTrace(*model.TraceData) (int64, error) {
+	return 0, errors.New("broken")
+}
+
+func (b brokenDatabase) GetTraceRepository() domain.TraceRepository {
+	return brokenTraceRepository{b.MemDatabase.GetTraceRepository()}
+}
+
+// plainDatabase 只提供基本仓储的后端，不支持整行写入、运行记录等可选能力
+type plainDatabase struct {
+	domain.RepositoryFactory
+}
+
+func (p plainDatabase) GetTraceRepository() domain.TraceRepository {
+	return struct{ domain.TraceRepository }{p.RepositoryFactory.GetTraceRepository()}
+}
+
+func newPlainDatabase() plainDatabase {
+	return plainDatabase{memory.NewMemDatabase(memory.Config{}, quietLogger())}
+}
+
+func TestTeeWritesAllBackends(t *testing.T) {
+	primary := memory.NewMemDatabase(memory.Config{}, quietLogger())
+	mirror := memory.NewMemDatabase(memory.Config{}, quietLogger())
+	db := NewTeeDatabase(quietLogger(), primary, mirror)
+	require.NoError(t, db.Initialize())
+	defer db.Close()
+
+	_, ok := db.GetTraceRepository().(domain.TraceBatchWriter)
+	assert.True(t, ok)
+	require.NoError(t, db.GetTraceRepository().(domain.TraceBatchWriter).SaveTracesBatch([]*model.TraceData{
+		{ID: 1, Name: "main.main", GID: 1, TimeCost: "1ms", IsFinished: 1},
+	}))
+	require.NoError(t, db.GetParamRepository().SaveParamsBatch([]*model.ParamStoreData{{ID: 1, TraceID: 1, Data: []byte("{}")}}))
+	_, err := db.GetGoroutineRepository().SaveGoroutine(model.NewGoroutineTrace(1, 10, "t0", 0, "main.main"))
+	require.NoError(t, err)
+	require.NoError(t, db.GetStatsRepository().SaveFuncStats([]*model.FuncStats{{Name: "main.main", Count: 1}}))
+
+	for _, m := range []*memory.MemDatabase{primary, mirror} {
+		td, err := m.FindTraceByID(1)
+		require.NoError(t, err)
+		require.NotNil(t, td)
+		assert.Equal(t, "1ms", td.TimeCost)
+		params, err := m.GetParamRepository().FindParamsByTraceID(1)
+		require.NoError(t, err)
+		assert.Len(t, params, 1)
+		g, err := m.GetGoroutineRepository().FindGoroutineByID(1)
+		require.NoError(t, err)
+		assert.NotNil(t, g)
+		stats, err := m.GetStatsRepository().FindAllFuncStats()
+		require.NoError(t, err)
+		assert.Len(t, stats, 1)
+	}
+
+	// 其余后端按主后端分配的运行ID登记
+	_, err = primary.GetRunRepository().StartRun(&model.Run{Status: model.RunStatusClean})
+	require.NoError(t, err)
+	id, err := db.GetRunRepository().StartRun(&model.Run{UUID: "r", Status: model.RunStatusRunning})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+	run, err := mirror.GetRunRepository().FindRunByID(2)
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, "r", run.UUID)
+	assert.Equal(t, []uint64{0, 0}, db.Failures())
+}
+
+func TestTeeFailures(t *testing.T) {
+	primary := memory.NewMemDatabase(memory.Config{}, quietLogger())
+	broken := brokenDatabase{memory.NewMemDatabase(memory.Config{}, quietLogger())}
+	db := NewTeeDatabase(quietLogger(), primary, broken)
+	require.NoError(t, db.Initialize())
+
+	// 非主后端失败不影响写入结果，只计数
+	for id := int64(1); id <= 3; id++ {
+		_, err := db.GetTraceRepository().SaveTrace(model.NewTraceData(id, "main.main", 1, 0, 0, 0, "t1", ""))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []uint64{0, 3}, db.Failures())
+	roots, err := db.GetTraceRepository().FindRootFunctionsByGID(1)
+	require.NoError(t, err)
+	assert.Len(t, roots, 3)
+
+	// 主后端失败时返回其错误
+	reversed := NewTeeDatabase(quietLogger(), brokenDatabase{memory.NewMemDatabase(memory.Config{}, quietLogger())}, primary)
+	require.NoError(t, reversed.Initialize())
+	_, err = reversed.GetTraceRepository().SaveTrace(model.NewTraceData(4, "main.main", 1, 0, 0, 0, "t1", ""))
+	assert.EqualError(t, err, "broken")
+	assert.Equal(t, []uint64{0, 0}, reversed.Failures())
+}
+
+func TestTeeOptionalCapabilities(t *testing.T) {
+	db := NewTeeDatabase(quietLogger(), newPlainDatabase(), memory.NewMemDatabase(memory.Config{}, quietLogger()))
+	require.NoError(t, db.Initialize())
+	defer db.Close()
+
+	// 有后端不支持整行写入时不提供该能力
+	_, ok := db.GetTraceRepository().(domain.TraceBatchWriter)
+	assert.False(t, ok)
+
+	// 运行记录只写入支持的后端，其中第一个视为主后端
+	id, err := db.GetRunRepository().StartRun(&model.Run{Status: model.RunStatusRunning})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	alone := NewTeeDatabase(quietLogger(), newPlainDatabase())
+	require.NoError(t, alone.Initialize())
+	defer alone.Close()
+	_, err = alone.GetRunRepository().StartRun(&model.Run{})
+	assert.ErrorIs(t, err, ErrRunsNotSupported)
+	require.NoError(t, alone.GetStatsRepository().SaveFuncStats([]*model.FuncStats{{Name: "main.main"}}))
+}
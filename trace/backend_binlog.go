@@ -0,0 +1,6 @@
+//go:build functrace_binlog
+
+package trace
+
+// 使用 -tags functrace_binlog 构建时注册二进制日志后端，无需修改代码即可通过 FUNCTRACE_DB_TYPE=binlog 选择
+import _ "github.com/toheart/functrace/persistence/binlog"
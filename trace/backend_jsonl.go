@@ -0,0 +1,6 @@
+//go:build functrace_jsonl
+
+package trace
+
+// 使用 -tags functrace_jsonl 构建时注册 JSON Lines 后端，无需修改代码即可通过 FUNCTRACE_DB_TYPE=jsonl 选择
+import _ "github.com/toheart/functrace/persistence/jsonl"
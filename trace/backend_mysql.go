@@ -0,0 +1,6 @@
+//go:build functrace_mysql
+
+package trace
+
+// 使用 -tags functrace_mysql 构建时注册 MySQL 后端，无需修改代码即可通过 FUNCTRACE_DB_TYPE=mysql 选择
+import _ "github.com/toheart/functrace/persistence/mysql"
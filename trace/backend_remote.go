@@ -0,0 +1,6 @@
+//go:build functrace_remote
+
+package trace
+
+// 使用 -tags functrace_remote 构建时注册收集器后端，无需修改代码即可通过 FUNCTRACE_DB_TYPE=remote 选择
+import _ "github.com/toheart/functrace/persistence/remote"
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	objDump "github.com/toheart/functrace/objectdump"
 )
@@ -35,6 +36,24 @@ type Config struct {
 
 	// Trace 分片写入配置
 	TraceShardNum int // Trace 分片数量（用于按 traceId 分片写入）
+
+	// 统计配置
+	StatsEnabled bool // 是否在进程内维护按函数聚合的延迟统计
+
+	// 在途调用配置
+	InFlightSignal string // 收到该信号时将在途调用写入日志（如 SIGQUIT/SIGUSR1），为空则不监听
+
+	// 慢调用看门狗配置
+	SlowCallThreshold time.Duration            // 全局截止时间，调用运行超过该时长即触发，0 表示不启用
+	SlowCallDeadlines map[string]time.Duration // 按函数名指定的截止时间，优先于全局配置
+	WatchdogInterval  time.Duration            // 看门狗扫描间隔
+
+	// 写入失败溢出配置
+	SpillDir    string // 重试后仍写入失败的记录写入该目录下的溢出文件，"off" 表示不写
+	SpillReplay bool   // 启动时将溢出目录中遗留的溢出文件导入当前仓储
+
+	// 分析汇总配置
+	SummarizeOnClose bool // 关闭时在支持的仓储中重新计算本次运行的分析汇总表
 }
 
 // configField 配置字段定义
@@ -139,6 +158,58 @@ var configFields = map[string]configField{
 			return false
 		},
 	},
+	"StatsEnabled": {
+		envKey:       EnvStatsEnabled,
+		defaultValue: true,
+		validator: func(v string) bool {
+			_, err := strconv.ParseBool(v)
+			return err == nil
+		},
+	},
+	"InFlightSignal": {
+		envKey:       EnvInFlightSignal,
+		defaultValue: "",
+	},
+	"SlowCallThreshold": {
+		envKey:       EnvSlowCallThreshold,
+		defaultValue: time.Duration(0),
+		validator: func(v string) bool {
+			d, err := time.ParseDuration(v)
+			return err == nil && d >= 0
+		},
+	},
+	"SlowCallDeadlines": {
+		envKey:       EnvSlowCallDeadlines,
+		defaultValue: "",
+	},
+	"WatchdogInterval": {
+		envKey:       EnvWatchdogInterval,
+		defaultValue: DefaultWatchdogInterval,
+		validator: func(v string) bool {
+			d, err := time.ParseDuration(v)
+			return err == nil && d > 0
+		},
+	},
+	"SpillDir": {
+		envKey:       EnvSpillDir,
+		defaultValue: DefaultSpillDir,
+	},
+	"SpillReplay": {
+		envKey:       EnvSpillReplay,
+		defaultValue: false,
+		validator: func(v string) bool {
+			_, err := strconv.ParseBool(v)
+			return err == nil
+		},
+	},
+	"SummarizeOnClose": {
+		envKey:       EnvSummarizeOnClose,
+		defaultValue: false,
+		validator: func(v string) bool {
+			_, err := strconv.ParseBool(v)
+			return err == nil
+		},
+	},
 }
 
 // NewConfig 创建新的配置实例
@@ -188,6 +259,24 @@ func (c *Config) loadFromEnv() {
 
 	// Trace 分片数量
 	c.TraceShardNum = c.getIntEnv("TraceShardNum")
+
+	// 函数统计开关
+	c.StatsEnabled = c.getBoolEnv("StatsEnabled")
+
+	// 在途调用信号
+	c.InFlightSignal = c.getStringEnv("InFlightSignal")
+
+	// 慢调用看门狗
+	c.SlowCallThreshold = c.getDurationEnv("SlowCallThreshold")
+	c.SlowCallDeadlines = parseDeadlines(c.getStringEnv("SlowCallDeadlines"))
+	c.WatchdogInterval = c.getDurationEnv("WatchdogInterval")
+
+	// 写入失败溢出
+	c.SpillDir = c.getStringEnv("SpillDir")
+	c.SpillReplay = c.getBoolEnv("SpillReplay")
+
+	// 分析汇总
+	c.SummarizeOnClose = c.getBoolEnv("SummarizeOnClose")
 }
 
 // getStringEnv 获取字符串环境变量
@@ -271,6 +360,56 @@ func (c *Config) getBoolEnv(fieldName string) bool {
 	return field.defaultValue.(bool)
 }
 
+// getDurationEnv 获取时长环境变量（time.ParseDuration 格式）
+func (c *Config) getDurationEnv(fieldName string) time.Duration {
+	field := configFields[fieldName]
+	envValue := os.Getenv(field.envKey)
+
+	// 如果环境变量为空，返回默认值
+	if envValue == "" {
+		return field.defaultValue.(time.Duration)
+	}
+
+	// 尝试解析时长
+	if d, err := time.ParseDuration(envValue); err == nil {
+		// 如果有验证器，验证值的有效性
+		if field.validator == nil || field.validator(envValue) {
+			return d
+		}
+	}
+
+	// 解析失败或验证失败，返回默认值
+	return field.defaultValue.(time.Duration)
+}
+
+// parseDeadlines 解析 "函数名=时长" 以逗号分隔的列表，忽略无效项
+func parseDeadlines(v string) map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for _, item := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || name == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			result[name] = d
+		}
+	}
+	return result
+}
+
+// SlowCallDeadline 返回函数的慢调用截止时间，0 表示该函数不受看门狗监控
+func (c *Config) SlowCallDeadline(name string) time.Duration {
+	if d, ok := c.SlowCallDeadlines[name]; ok {
+		return d
+	}
+	return c.SlowCallThreshold
+}
+
+// WatchdogEnabled 是否配置了任意慢调用截止时间
+func (c *Config) WatchdogEnabled() bool {
+	return c.SlowCallThreshold > 0 || len(c.SlowCallDeadlines) > 0
+}
+
 // getStringSliceEnv 获取字符串切片环境变量
 func (c *Config) getStringSliceEnv(fieldName string) []string {
 	field := configFields[fieldName]
@@ -312,6 +451,9 @@ func (c *Config) String() string {
 		"DBType: " + c.DBType + ", " +
 		"InsertMode: " + c.InsertMode + ", " +
 		"ParamStoreMode: " + c.ParamStoreMode + ", " +
-		"LogFileName: " + c.LogFileName +
+		"LogFileName: " + c.LogFileName + ", " +
+		"StatsEnabled: " + strconv.FormatBool(c.StatsEnabled) + ", " +
+		"InFlightSignal: " + c.InFlightSignal + ", " +
+		"SlowCallThreshold: " + c.SlowCallThreshold.String() +
 		"}"
 }
@@ -41,6 +41,31 @@ const (
 	// 可选值: "none"(不保存参数，默认), "normal"(保存普通参数), "all"(全保存)
 	EnvParamStoreMode = "FUNCTRACE_PARAM_STORE_MODE"
 
+	// EnvStatsEnabled 函数统计开关环境变量
+	EnvStatsEnabled = "FUNCTRACE_STATS_ENABLED"
+
+	// EnvInFlightSignal 在途调用转储信号环境变量，如 SIGQUIT、SIGUSR1
+	EnvInFlightSignal = "FUNCTRACE_INFLIGHT_SIGNAL"
+
+	// EnvSlowCallThreshold 慢调用全局截止时间环境变量，如 "5s"
+	EnvSlowCallThreshold = "FUNCTRACE_SLOW_CALL_THRESHOLD"
+	// EnvSlowCallDeadlines 按函数的慢调用截止时间，如 "main.handler=2s,pkg.Query=500ms"
+	EnvSlowCallDeadlines = "FUNCTRACE_SLOW_CALL_DEADLINES"
+	// EnvWatchdogInterval 慢调用看门狗扫描间隔环境变量
+	EnvWatchdogInterval = "FUNCTRACE_WATCHDOG_INTERVAL"
+	// DefaultWatchdogInterval 默认看门狗扫描间隔
+	DefaultWatchdogInterval = time.Second
+	// EnvSpillDir 重试后仍写入失败的记录所写入的溢出文件目录，设为 "off" 时不写溢出文件
+	EnvSpillDir = "FUNCTRACE_SPILL_DIR"
+	// EnvSpillReplay 启动时是否将溢出目录中遗留的溢出文件导入当前仓储
+	EnvSpillReplay = "FUNCTRACE_SPILL_REPLAY"
+	// EnvSummarizeOnClose 关闭时是否重新计算本次运行的分析汇总表
+	EnvSummarizeOnClose = "FUNCTRACE_SUMMARIZE_ON_CLOSE"
+	// DefaultSpillDir 默认溢出文件目录
+	DefaultSpillDir = "."
+	// SpillDisabled 关闭溢出文件的目录取值
+	SpillDisabled = "off"
+
 	IgnoreNames = "context,string"
 	// 默认最大深度
 	DefaultMaxDepth = 3
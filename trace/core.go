@@ -16,7 +16,7 @@ func (t *TraceInstance) EnterTrace(id uint64, name string, params []interface{})
 	session := t.sessions.GetOrCreate(id)
 	// 确保会话转发器已启动
 	session.EnsureForwarder(t)
-	indent, parentId, traceId := session.PrepareEnter(t)
+	indent, parentId, traceId := session.PrepareEnter(t, name, startTime)
 	// 格式化时间序列，保留2位小数
 	duration := time.Since(currentNow)
 	seq := fmt.Sprintf("%.2f", float64(duration.Milliseconds())/1000.0)
@@ -56,6 +56,9 @@ func (t *TraceInstance) EnterTrace(id uint64, name string, params []interface{})
 		}
 	}
 	traceData.ParamsCount = originalParamsCount
+	if t.observers.wantParams.Load() {
+		traceData.Params = params
+	}
 	session.Enqueue(&DataOp{
 		OpType: OpTypeInsert,
 		Arg:    traceData,
@@ -67,11 +70,44 @@ func (t *TraceInstance) EnterTrace(id uint64, name string, params []interface{})
 
 // ExitTrace 记录函数调用的结束并减少跟踪缩进
 func (t *TraceInstance) ExitTrace(info *GoroutineInfo, traceData *model.TraceData, startTime time.Time) {
+	t.ExitTraceWithError(info, traceData, startTime, nil)
+}
+
+// ExitTraceWithError 记录函数调用的结束，err 非空时计入该函数的错误次数
+func (t *TraceInstance) ExitTraceWithError(info *GoroutineInfo, traceData *model.TraceData, startTime time.Time, err error) {
 	// 计算函数执行时间（无论是否出错都要记录）
 	duration := time.Since(startTime)
 
-	// 更新跟踪信息
-	indent := t.updateTraceIndent(info.ID)
+	// 更新跟踪信息（根调用ID需在出栈前读取）
+	observers := t.observers.load()
+	var rootID int64
+	if len(observers) > 0 {
+		rootID = t.sessions.GetOrCreate(info.ID).RootTraceID()
+	}
+	indent, self := t.updateTraceIndent(info.ID, duration)
+	if t.stats != nil {
+		t.stats.Record(traceData.Name, duration, self, err != nil)
+	}
+	if len(observers) > 0 {
+		if rootID == 0 {
+			rootID = traceData.ID
+		}
+		t.notifyCall(observers, CallRecord{
+			TraceID:     traceData.ID,
+			ParentID:    traceData.ParentId,
+			RootID:      rootID,
+			GoroutineID: info.ID,
+			OriginGID:   info.OriginGID,
+			Name:        traceData.Name,
+			Depth:       traceData.Indent,
+			Start:       startTime,
+			Duration:    duration,
+			Self:        self,
+			ParamsCount: traceData.ParamsCount,
+			Params:      traceData.Params,
+			Err:         err,
+		})
+	}
 	logIndent := indent
 	if indent < 0 {
 		// 如果更新缩进失败，使用默认值继续处理，确保数据完整性
@@ -111,11 +147,11 @@ func (t *TraceInstance) ExitTrace(info *GoroutineInfo, traceData *model.TraceDat
 	}
 }
 
-// updateTraceIndent 更新跟踪缩进并返回当前缩进级别
-func (t *TraceInstance) updateTraceIndent(id uint64) int {
+// updateTraceIndent 更新跟踪缩进并返回当前缩进级别与本次调用的自身耗时
+func (t *TraceInstance) updateTraceIndent(id uint64, cost time.Duration) (int, time.Duration) {
 	// 会话内回退
 	session := t.sessions.GetOrCreate(id)
-	return session.OnExit()
+	return session.OnExit(cost)
 }
 
 // logFunctionEntry 记录函数进入的日志
@@ -154,6 +190,11 @@ func (t *TraceInstance) logFunctionExit(gid uint64, name string, indent int, dur
 
 // isStructMethod 判断函数名是否为结构体方法，并确定接收者类型
 func (t *TraceInstance) isStructMethod(fullName string) FuncInfo {
+	return ParseFuncName(fullName)
+}
+
+// ParseFuncName 解析 runtime 函数全名，得到包路径、接收者类型与函数名
+func ParseFuncName(fullName string) FuncInfo {
 	// 1. 尝试匹配指针接收者
 	if ptrMatches := ptrRegex.FindStringSubmatch(fullName); len(ptrMatches) >= 4 {
 		return FuncInfo{
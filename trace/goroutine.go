@@ -101,12 +101,12 @@ func (t *TraceInstance) finishGoroutineTrace(info *GoroutineInfo) {
 
 	// 从映射中移除
 	t.deleteGoroutineRunning(info.OriginGID)
-	// 同时移除会话
+	// 同时移除会话（会话以协程自增ID为键）
 	if t.sessions != nil {
 		// 优雅关闭会话，确保数据转发完成
-		s := t.sessions.GetOrCreate(info.OriginGID)
+		s := t.sessions.GetOrCreate(info.ID)
 		s.Close()
-		t.sessions.Remove(info.OriginGID)
+		t.sessions.Remove(info.ID)
 	}
 	t.log.WithFields(logrus.Fields{
 		"goroutine identifier": info.OriginGID,
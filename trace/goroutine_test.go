@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// goroutineOnlyFactory 只提供协程仓储的工厂
+type goroutineOnlyFactory struct {
+	domain.RepositoryFactory
+}
+
+func (goroutineOnlyFactory) GetGoroutineRepository() domain.GoroutineRepository {
+	return startedGoroutineRepository{}
+}
+
+// startedGoroutineRepository 查询时返回刚创建的协程
+type startedGoroutineRepository struct {
+	domain.GoroutineRepository
+}
+
+func (startedGoroutineRepository) FindGoroutineByID(id int64) (*model.GoroutineTrace, error) {
+	return &model.GoroutineTrace{ID: id, OriginGID: 100, CreateTime: time.Now().Format(TimeFormat)}, nil
+}
+
+func TestFinishGoroutineTrace_RemovesSessionByID(t *testing.T) {
+	prev := repositoryFactory
+	repositoryFactory = goroutineOnlyFactory{}
+	defer func() { repositoryFactory = prev }()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	inst := &TraceInstance{
+		config:           &Config{InsertMode: AsyncMode},
+		log:              logger,
+		sessions:         NewSessionRegistry(),
+		GoroutineRunning: map[uint64]*GoroutineInfo{},
+		OpChan:           make(chan *DataOp, 1),
+	}
+
+	// 会话以协程自增ID为键，与运行时 gid 不同
+	inst.sessions.GetOrCreate(7)
+	inst.finishGoroutineTrace(&GoroutineInfo{ID: 7, OriginGID: 100})
+	assert.NotContains(t, inst.sessions.Snapshot(), uint64(7))
+	assert.NotContains(t, inst.sessions.Snapshot(), uint64(100))
+	assert.Len(t, inst.OpChan, 1)
+}
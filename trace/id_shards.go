@@ -12,9 +12,10 @@ type IDGenerator interface {
 }
 
 // StripedIDGenerator 基于分片计数器的ID生成器
-// 将竞争分散到N个分片：id = shardIndex + counter*N
+// 将竞争分散到N个分片：id = base + shardIndex + counter*N
 type StripedIDGenerator struct {
 	shardCount  uint64
+	base        int64
 	traceShards []atomic.Int64
 	paramShards []atomic.Int64
 }
@@ -32,6 +33,11 @@ func NewStripedIDGenerator(shardCount uint64) *StripedIDGenerator {
 	return gen
 }
 
+// SetBase 设置ID的起始偏移（如同一个库中的第N次运行），必须在生成ID之前调用
+func (g *StripedIDGenerator) SetBase(base int64) {
+	g.base = base
+}
+
 // shardIndex 依据键选择固定分片
 func (g *StripedIDGenerator) shardIndex(key uint64) uint64 {
 	// 使用FNV哈希增加分布均匀性，同时支持任意key
@@ -53,12 +59,12 @@ func (g *StripedIDGenerator) shardIndex(key uint64) uint64 {
 func (g *StripedIDGenerator) NextTraceID(shardKey uint64) int64 {
 	idx := g.shardIndex(shardKey)
 	n := g.traceShards[idx].Add(1)
-	return int64(n*int64(g.shardCount) + int64(idx))
+	return g.base + int64(n*int64(g.shardCount)+int64(idx))
 }
 
 // NextParamID 返回下一个全局唯一的ParamID
 func (g *StripedIDGenerator) NextParamID(shardKey uint64) int64 {
 	idx := g.shardIndex(shardKey)
 	n := g.paramShards[idx].Add(1)
-	return int64(n*int64(g.shardCount) + int64(idx))
+	return g.base + int64(n*int64(g.shardCount)+int64(idx))
 }
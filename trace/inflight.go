@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// InFlight 返回每个被跟踪 goroutine 当前的逻辑调用栈，按最外层调用的运行时长降序
+func (t *TraceInstance) InFlight() []SessionStack {
+	stacks := t.OpenStacks()
+	sort.SliceStable(stacks, func(i, j int) bool {
+		return stacks[i].Elapsed > stacks[j].Elapsed
+	})
+	return stacks
+}
+
+// FormatInFlight 将在途调用栈格式化为便于阅读的文本
+func FormatInFlight(stacks []SessionStack) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "functrace in-flight calls: %d goroutine(s)\n", len(stacks))
+	for _, s := range stacks {
+		fmt.Fprintf(&b, "\ngoroutine %d (gid %d), running %s:\n", s.GoroutineID, s.OriginGID, s.Elapsed)
+		for _, f := range s.Frames {
+			fmt.Fprintf(&b, "%s%s [trace %d] %s\n", strings.Repeat("  ", f.Depth+1), f.Name, f.TraceID, f.Elapsed)
+		}
+	}
+	return b.String()
+}
+
+// logInFlight 将当前在途调用写入日志
+func (t *TraceInstance) logInFlight() {
+	stacks := t.InFlight()
+	t.log.WithFields(logrus.Fields{"goroutines": len(stacks)}).Warn(FormatInFlight(stacks))
+}
+
+// startInFlightSignal 按配置监听信号，收到后将在途调用写入日志
+func (t *TraceInstance) startInFlightSignal() {
+	if t.config.InFlightSignal == "" {
+		return
+	}
+	sig := lookupSignal(t.config.InFlightSignal)
+	if sig == nil {
+		t.log.WithFields(logrus.Fields{"signal": t.config.InFlightSignal}).Warn("unsupported in-flight dump signal, ignored")
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				t.logInFlight()
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}()
+	t.log.WithFields(logrus.Fields{"signal": t.config.InFlightSignal}).Info("in-flight dump signal installed")
+}
@@ -0,0 +1,68 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInFlightTestInstance() *TraceInstance {
+	return &TraceInstance{
+		idGen:            NewStripedIDGenerator(4),
+		sessions:         NewSessionRegistry(),
+		GoroutineRunning: make(map[uint64]*GoroutineInfo),
+	}
+}
+
+func TestInFlight_SortedByElapsed(t *testing.T) {
+	inst := newInFlightTestInstance()
+	now := time.Now()
+
+	inst.GoroutineRunning[100] = &GoroutineInfo{ID: 1, OriginGID: 100}
+	inst.GoroutineRunning[200] = &GoroutineInfo{ID: 2, OriginGID: 200}
+
+	short := inst.sessions.GetOrCreate(1)
+	short.PrepareEnter(inst, "main.short", now.Add(-time.Second))
+
+	long := inst.sessions.GetOrCreate(2)
+	long.PrepareEnter(inst, "main.handler", now.Add(-time.Minute))
+	long.PrepareEnter(inst, "main.query", now.Add(-30*time.Second))
+
+	// 已全部退出的会话不应出现
+	idle := inst.sessions.GetOrCreate(3)
+	idle.PrepareEnter(inst, "main.done", now)
+	idle.OnExit(0)
+
+	stacks := inst.InFlight()
+	require.Len(t, stacks, 2)
+
+	assert.Equal(t, uint64(2), stacks[0].GoroutineID)
+	assert.Equal(t, uint64(200), stacks[0].OriginGID)
+	require.Len(t, stacks[0].Frames, 2)
+	assert.Equal(t, "main.handler", stacks[0].Frames[0].Name)
+	assert.Equal(t, 1, stacks[0].Frames[1].Depth)
+	assert.GreaterOrEqual(t, stacks[0].Elapsed, time.Minute)
+
+	assert.Equal(t, uint64(1), stacks[1].GoroutineID)
+
+	text := FormatInFlight(stacks)
+	assert.Contains(t, text, "2 goroutine(s)")
+	assert.Contains(t, text, "goroutine 2 (gid 200)")
+	assert.Contains(t, text, "    main.query [trace ")
+}
+
+func TestTraceSession_CloseWithoutForwarder(t *testing.T) {
+	s := NewTraceSession(1)
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close should not block when forwarder was never started")
+	}
+}
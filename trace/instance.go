@@ -2,9 +2,6 @@ package trace
 
 import (
 	"os"
-	"runtime"
-	"strconv"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -43,6 +40,10 @@ type GoroutineInfo struct {
 	ID             uint64 `json:"id"`             // 自增ID
 	OriginGID      uint64 `json:"originGid"`      // 原始Goroutine ID
 	LastUpdateTime string `json:"lastUpdateTime"` // 最后更新时间
+	InitFuncName   string `json:"initFuncName"`   // 初始函数名
+	CreatorGID     uint64 `json:"creatorGid"`     // 创建者的原始Goroutine ID
+	CreatorFunc    string `json:"creatorFunc"`    // 执行 go 语句的函数名
+	ParentTraceID  int64  `json:"parentTraceId"`  // 创建时创建者正在执行的跟踪ID
 }
 
 // DataOp 数据操作
@@ -64,6 +65,7 @@ type TraceInstance struct {
 
 	paramCacheLock sync.RWMutex
 	OpChan         chan *DataOp
+	opSendMu       sync.RWMutex // 发送方持有读锁，关闭 OpChan 时持有写锁
 	dataClose      chan struct{}
 	config         *Config // 统一的配置管理
 
@@ -84,12 +86,31 @@ type TraceInstance struct {
 	// 统一的流水线外观（骨架）
 	pipelines *Pipelines
 
+	// 重试后仍写入失败的记录的溢出文件
+	spill *spillWriter
+
+	// 本次运行的元数据，仓储不支持运行记录时为 nil
+	run *model.Run
+
+	// 按函数聚合的实时统计
+	stats *StatsCollector
+
+	// 慢调用回调
+	slowHooks slowCallHooks
+
+	// 调用完成观察者
+	observers callObservers
+
 	// 关闭标志（原子），用于无锁 sendOp 判断
 	closedFlag atomic.Bool
 
 	// 根上下文与取消函数，用于优雅关闭后台协程
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// 后台导入遗留溢出文件的取消函数与完成信号，未启用导入时为 nil
+	replayCancel context.CancelFunc
+	replayDone   chan struct{}
 }
 
 // 已迁移：Trace 分片逻辑在 TracePipeline 中实现（见 pipeline.go）
@@ -106,6 +127,13 @@ func NewTraceInstance() *TraceInstance {
 			return
 		}
 		instance.log.Info("init database success")
+		// 初始化 Pipelines（内含按 traceId 分片的 trace 写入器），写入的仓储在此时确定
+		instance.pipelines = NewPipelines(instance.ctx, instance, repositoryFactory)
+		instance.pipelines.Start()
+		// 登记本次运行（需在生成任何ID之前）
+		instance.startRun()
+		// 按配置在后台导入此前运行遗留的溢出文件
+		instance.startSpillReplay()
 		instance.log.WithFields(logrus.Fields{"config": instance.config.String()}).Info("trace config initialized")
 		instance.log.WithFields(logrus.Fields{"mode": instance.config.ParamStoreMode}).Info("param store mode initialized")
 
@@ -113,6 +141,11 @@ func NewTraceInstance() *TraceInstance {
 		go instance.monitorGoroutines()
 		instance.log.Info("start goroutine monitor")
 
+		// 按配置监听在途调用转储信号
+		instance.startInFlightSignal()
+		// 按配置启动慢调用看门狗
+		instance.startWatchdog()
+
 		// 根据参数存储模式决定是否启动相关服务
 		if instance.config.ParamStoreMode == ParamStoreModeAll {
 			// 启动TTL缓存管理器
@@ -161,6 +194,10 @@ func initTraceInstance() {
 	instance.idGen = NewStripedIDGenerator(64)
 	// 初始化会话注册表
 	instance.sessions = NewSessionRegistry()
+	// 初始化函数统计
+	if config.StatsEnabled {
+		instance.stats = NewStatsCollector()
+	}
 	// 初始化TTL缓存管理器
 	instance.ttlManager = NewTTLCacheManager(instance.log)
 	// 初始化内存监控器
@@ -172,17 +209,10 @@ func initTraceInstance() {
 	// 设置spew配置
 	instance.SetSpewConfig()
 
-	// 初始化 Trace 分片写入器（按 traceId 分片）
-	shardNum := instance.config.TraceShardNum
-	if shardNum <= 0 {
-		shardNum = 16
-	}
 	// 根上下文
 	instance.ctx, instance.cancel = context.WithCancel(context.Background())
-	// 初始化 Pipelines（内含 trace 分片）
-	instance.pipelines = NewPipelines(instance.ctx, instance)
-	instance.pipelines.Start()
-	instance.log.WithFields(logrus.Fields{"shard_num": shardNum}).Info("trace shards initialized")
+	// 写入失败的溢出文件（首条记录写入时创建）
+	instance.spill = newSpillWriter(config.SpillDir, currentNow)
 }
 
 func (t *TraceInstance) StartOpChan() {
@@ -384,11 +414,16 @@ func (t *TraceInstance) createNewGoroutineAndTrace(gid uint64, name string) (inf
 	start := time.Now()
 	id := t.gGroutineId.Add(1)
 
-	// 创建goroutine信息
+	// 创建goroutine信息，并记录创建者（仅在goroutine首次被跟踪时解析一次）
+	spawn := t.captureSpawn()
 	info = &GoroutineInfo{
 		ID:             id,
 		OriginGID:      gid,
 		LastUpdateTime: start.Format(TimeFormat),
+		InitFuncName:   name,
+		CreatorGID:     spawn.creatorGID,
+		CreatorFunc:    spawn.creatorFunc,
+		ParentTraceID:  spawn.parentTraceID,
 	}
 
 	// 原子化地创建goroutine和trace缩进
@@ -405,7 +440,8 @@ func (t *TraceInstance) createNewGoroutineAndTrace(gid uint64, name string) (inf
 			return
 		}
 		t.sendOp(&DataOp{OpType: OpTypeInsert, Arg: gt})
-	}(&model.GoroutineTrace{ID: int64(id), OriginGID: gid, CreateTime: start.Format(TimeFormat), IsFinished: 0, InitFuncName: name})
+	}(model.NewGoroutineTrace(int64(id), gid, start.Format(TimeFormat), 0, name).
+		WithCreator(spawn.creatorGID, spawn.creatorFunc, spawn.parentTraceID))
 
 	t.log.WithFields(logrus.Fields{"goroutine": id, "initFunc": name}).Info("initialized goroutine trace atomically")
 
@@ -414,38 +450,32 @@ func (t *TraceInstance) createNewGoroutineAndTrace(gid uint64, name string) (inf
 
 // getAllGoroutineIDs 获取当前所有运行中的协程ID
 func (t *TraceInstance) getAllGoroutineIDs() []int {
-	buf := make([]byte, 1<<20) // 分配 1MB 缓冲区
-	n := runtime.Stack(buf, true)
-	stack := string(buf[:n])
 	var ids []int
-
-	for _, line := range strings.Split(stack, "\n") {
-		if strings.HasPrefix(line, "goroutine ") {
-			// 解析行如 "goroutine 123 [running]:"
-			parts := strings.Fields(line)
-			idStr := parts[1]
-			id, err := strconv.Atoi(idStr)
-			if err == nil {
-				ids = append(ids, id)
-			}
-		}
+	for gid := range allGoroutineStacks() {
+		ids = append(ids, int(gid))
 	}
 	return ids
 }
 
 // Close 关闭数据库连接并释放资源
 func (t *TraceInstance) Close() error {
+	// 只在锁内标记关闭，其余耗时操作在释放锁后执行，避免阻塞仍在运行的被跟踪goroutine
 	t.Lock()
-	defer t.Unlock()
-
 	// 如果已经关闭，直接返回
 	if t.closed {
+		t.Unlock()
 		return nil
 	}
-
 	// 标记为已关闭
 	t.closed = true
 	t.closedFlag.Store(true) // 原子化地设置关闭标志
+	t.Unlock()
+
+	// 报告仍存活的被跟踪goroutine（需在停止管道与关闭数据库之前）
+	t.reportLeaks(t.collectLeaks())
+
+	// 刷新并关闭调用观察者
+	t.closeObservers()
 
 	// 发送停止监控信号（确保只关闭一次）
 	stopOnce.Do(func() {
@@ -461,16 +491,30 @@ func (t *TraceInstance) Close() error {
 		t.log.Info("memory monitor stopped")
 	}
 
+	// 中止遗留溢出文件的导入（需在关闭数据库之前）
+	t.stopSpillReplay()
+
 	// 如果是异步模式，关闭OpChan
 	if t.config.InsertMode == AsyncMode {
-		close(t.OpChan)
+		t.closeOpChan()
 		<-t.dataClose
 	}
 
 	// 停止 pipelines（取消 ctx）
 	if t.pipelines != nil {
 		t.pipelines.Stop()
+		t.logPipelineFailures()
 	}
+	if err := t.spill.Close(); err != nil {
+		t.log.WithFields(logrus.Fields{"error": err}).Error("close spill file failed")
+	}
+
+	// 写入函数统计快照
+	t.persistStats()
+	// 记录运行正常结束
+	t.endRun()
+	// 计算分析汇总表
+	t.summarize()
 
 	// 关闭数据库连接
 	return CloseDatabase()
@@ -480,16 +524,12 @@ func (t *TraceInstance) Close() error {
 func initDatabase() error {
 	// 创建仓储工厂
 	var err error
+	// CreateRepositoryFactory 已完成初始化
 	repositoryFactory, err = factory.CreateRepositoryFactory(instance.config.DBType, instance.log)
 	if err != nil {
 		return err
 	}
 
-	// 初始化仓储工厂
-	if err := repositoryFactory.Initialize(); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -536,16 +576,35 @@ func (t *TraceInstance) sendOp(op *DataOp) {
 		t.executeOp(op)
 		return
 	}
-	// 使用select避免阻塞，如果通道满了则同步执行
+	// 通道满了或在判断后开始关闭时，同步执行避免丢失数据
+	if !t.trySendOp(op) {
+		t.executeOp(op)
+	}
+}
+
+// trySendOp 非阻塞地把操作放入 OpChan，已关闭或通道已满时返回 false
+// 发送期间持有 opSendMu 读锁，closeOpChan 等待其释放后才关闭通道
+func (t *TraceInstance) trySendOp(op *DataOp) bool {
+	t.opSendMu.RLock()
+	defer t.opSendMu.RUnlock()
+	if t.closedFlag.Load() {
+		return false
+	}
 	select {
 	case t.OpChan <- op:
-		// ok
+		return true
 	default:
-		// 通道满了，同步执行避免丢失数据
-		t.executeOp(op)
+		return false
 	}
 }
 
+// closeOpChan 等待正在发送的协程完成后关闭 OpChan，需在设置关闭标志之后调用
+func (t *TraceInstance) closeOpChan() {
+	t.opSendMu.Lock()
+	defer t.opSendMu.Unlock()
+	close(t.OpChan)
+}
+
 // GetParamStoreMode 获取当前的参数存储模式
 func (t *TraceInstance) GetParamStoreMode() string {
 	return t.config.ParamStoreMode
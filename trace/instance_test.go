@@ -0,0 +1,48 @@
+package trace
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrySendOp(t *testing.T) {
+	inst := &TraceInstance{OpChan: make(chan *DataOp, 1)}
+
+	assert.True(t, inst.trySendOp(&DataOp{}))
+	// 通道已满
+	assert.False(t, inst.trySendOp(&DataOp{}))
+	<-inst.OpChan
+
+	// 已关闭时不再放入通道
+	inst.closedFlag.Store(true)
+	assert.False(t, inst.trySendOp(&DataOp{}))
+	assert.Empty(t, inst.OpChan)
+}
+
+func TestTrySendOp_RacesWithClose(t *testing.T) {
+	inst := &TraceInstance{OpChan: make(chan *DataOp, 8)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range inst.OpChan {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				inst.trySendOp(&DataOp{})
+			}
+		}()
+	}
+	// 与 Close 相同：先设置关闭标志，再关闭通道；正在发送的协程不会向已关闭的通道发送
+	inst.closedFlag.Store(true)
+	inst.closeOpChan()
+	wg.Wait()
+	<-done
+}
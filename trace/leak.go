@@ -0,0 +1,179 @@
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// createdByRegex 匹配调用栈末尾的创建者信息，如 "created by main.main in goroutine 1"
+var createdByRegex = regexp.MustCompile(`(?m)^created by (\S+)(?: in goroutine (\d+))?`)
+
+// spawnInfo goroutine 的创建者信息
+type spawnInfo struct {
+	creatorGID    uint64
+	creatorFunc   string
+	parentTraceID int64
+}
+
+// allGoroutineStacks 返回全部 goroutine 的运行时调用栈，键为原始 Goroutine ID
+func allGoroutineStacks() map[uint64]string {
+	buf := make([]byte, 1<<20) // 初始 1MB，不足时扩容
+	n := runtime.Stack(buf, true)
+	for n == len(buf) && len(buf) < 64<<20 {
+		buf = make([]byte, len(buf)*2)
+		n = runtime.Stack(buf, true)
+	}
+
+	stacks := make(map[uint64]string)
+	for _, block := range bytes.Split(buf[:n], []byte("\n\n")) {
+		if gid := parseGoroutineHeader(block); gid != 0 {
+			stacks[gid] = string(block)
+		}
+	}
+	return stacks
+}
+
+// parseGoroutineHeader 解析 "goroutine 123 [running]:" 形式的首行，失败时返回0
+func parseGoroutineHeader(block []byte) uint64 {
+	rest, ok := bytes.CutPrefix(block, []byte("goroutine "))
+	if !ok {
+		return 0
+	}
+	if i := bytes.IndexByte(rest, ' '); i >= 0 {
+		rest = rest[:i]
+	}
+	gid, err := strconv.ParseUint(string(rest), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return gid
+}
+
+// parseCreatedBy 从调用栈中解析创建者函数与创建者 Goroutine ID
+func parseCreatedBy(stack string) (fn string, gid uint64) {
+	m := createdByRegex.FindStringSubmatch(stack)
+	if m == nil {
+		return "", 0
+	}
+	if m[2] != "" {
+		gid, _ = strconv.ParseUint(m[2], 10, 64)
+	}
+	return m[1], gid
+}
+
+// captureSpawn 解析当前 goroutine 的创建者，以及创建者当时正在执行的被跟踪调用
+// 注意：调用此方法时必须已经持有写锁
+func (t *TraceInstance) captureSpawn() spawnInfo {
+	buf := make([]byte, 8<<10)
+	n := runtime.Stack(buf, false)
+	fn, gid := parseCreatedBy(string(buf[:n]))
+	info := spawnInfo{creatorGID: gid, creatorFunc: fn}
+	if gid == 0 || t.sessions == nil {
+		return info
+	}
+	if creator, ok := t.GoroutineRunning[gid]; ok {
+		if session, ok := t.sessions.Get(creator.ID); ok {
+			info.parentTraceID = session.CurrentTraceID()
+		}
+	}
+	return info
+}
+
+// currentGID 返回当前 goroutine 的原始ID
+func currentGID() uint64 {
+	buf := make([]byte, 64)
+	return parseGoroutineHeader(buf[:runtime.Stack(buf, false)])
+}
+
+// collectLeaks 找出仍存活的被跟踪 goroutine（不含调用方自身）
+// 注意：调用此方法时必须已经持有锁
+func (t *TraceInstance) collectLeaks() []*model.LeakReport {
+	running := t.GetGoroutineRunning()
+	if len(running) == 0 {
+		return nil
+	}
+	stacks := allGoroutineStacks()
+	self := currentGID()
+	now := time.Now()
+
+	var reports []*model.LeakReport
+	for gid, info := range running {
+		stack, alive := stacks[gid]
+		if !alive || gid == self {
+			continue
+		}
+		report := &model.LeakReport{
+			GoroutineID:  int64(info.ID),
+			OriginGID:    gid,
+			InitFuncName: info.InitFuncName,
+			CreatorGID:   info.CreatorGID,
+			CreatorFunc:  info.CreatorFunc,
+			CreateTime:   info.LastUpdateTime,
+			Stack:        stack,
+			ReportedAt:   now.Format(TimeFormat),
+		}
+		if report.CreatorFunc == "" {
+			report.CreatorFunc, report.CreatorGID = parseCreatedBy(stack)
+		}
+		if created, err := time.Parse(TimeFormat, info.LastUpdateTime); err == nil {
+			report.Age = now.Sub(created).String()
+		}
+		if t.sessions != nil {
+			if session, ok := t.sessions.Get(info.ID); ok {
+				var finished bool
+				report.LastTraceID, report.LastFuncName, finished = session.LastFrame()
+				if finished {
+					report.LastFinished = 1
+				}
+			}
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].GoroutineID < reports[j].GoroutineID
+	})
+	return reports
+}
+
+// FormatLeakReports 将泄漏报告格式化为便于阅读的文本
+func FormatLeakReports(reports []*model.LeakReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "functrace goroutine leak report: %d goroutine(s) still alive\n", len(reports))
+	for _, r := range reports {
+		state := "running"
+		if r.LastFinished == 1 {
+			state = "returned"
+		}
+		fmt.Fprintf(&b, "\ngoroutine %d (gid %d) age %s\n", r.GoroutineID, r.OriginGID, r.Age)
+		fmt.Fprintf(&b, "  init:    %s\n", r.InitFuncName)
+		fmt.Fprintf(&b, "  creator: %s (gid %d)\n", r.CreatorFunc, r.CreatorGID)
+		fmt.Fprintf(&b, "  last:    %s [trace %d, %s]\n", r.LastFuncName, r.LastTraceID, state)
+	}
+	return b.String()
+}
+
+// reportLeaks 将泄漏报告写入日志与支持泄漏报告表的仓储
+func (t *TraceInstance) reportLeaks(reports []*model.LeakReport) {
+	if len(reports) == 0 {
+		return
+	}
+	t.log.WithFields(logrus.Fields{"count": len(reports)}).Warn(FormatLeakReports(reports))
+
+	provider, ok := repositoryFactory.(domain.LeakRepositoryProvider)
+	if !ok {
+		return
+	}
+	if err := provider.GetLeakRepository().SaveLeakReports(reports); err != nil {
+		t.log.WithFields(logrus.Fields{"error": err}).Error("persist leak report failed")
+	}
+}
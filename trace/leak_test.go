@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCreatedBy(t *testing.T) {
+	stack := "goroutine 42 [chan receive]:\nmain.worker()\n\t/app/main.go:20 +0x1d\n" +
+		"created by main.startWorkers in goroutine 7\n\t/app/main.go:12 +0x4f"
+	fn, gid := parseCreatedBy(stack)
+	assert.Equal(t, "main.startWorkers", fn)
+	assert.Equal(t, uint64(7), gid)
+
+	// 旧版本运行时没有 "in goroutine N"
+	fn, gid = parseCreatedBy("goroutine 3 [running]:\ncreated by main.main\n\t/app/main.go:5")
+	assert.Equal(t, "main.main", fn)
+	assert.Equal(t, uint64(0), gid)
+
+	fn, _ = parseCreatedBy("goroutine 1 [running]:\nmain.main()")
+	assert.Empty(t, fn)
+}
+
+func TestCollectLeaks_ReportsAliveGoroutines(t *testing.T) {
+	inst := newInFlightTestInstance()
+
+	started := make(chan uint64)
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		started <- currentGID()
+		<-release
+	}()
+	alive := <-started
+
+	created := time.Now().Add(-time.Minute)
+	inst.GoroutineRunning[alive] = &GoroutineInfo{
+		ID:             5,
+		OriginGID:      alive,
+		LastUpdateTime: created.Format(TimeFormat),
+		InitFuncName:   "main.worker",
+	}
+	session := inst.sessions.GetOrCreate(5)
+	session.PrepareEnter(inst, "main.worker", created)
+	session.PrepareEnter(inst, "main.wait", time.Now())
+
+	// 已退出的goroutine与调用方自身都不应被报告
+	inst.GoroutineRunning[1<<40] = &GoroutineInfo{ID: 6, OriginGID: 1 << 40}
+	inst.GoroutineRunning[currentGID()] = &GoroutineInfo{ID: 7, OriginGID: currentGID()}
+
+	reports := inst.collectLeaks()
+	require.Len(t, reports, 1)
+	r := reports[0]
+	assert.Equal(t, int64(5), r.GoroutineID)
+	assert.Equal(t, alive, r.OriginGID)
+	assert.Equal(t, "main.worker", r.InitFuncName)
+	assert.Contains(t, r.CreatorFunc, "TestCollectLeaks_ReportsAliveGoroutines")
+	assert.Equal(t, "main.wait", r.LastFuncName)
+	assert.Equal(t, 0, r.LastFinished)
+	assert.NotEmpty(t, r.Stack)
+
+	age, err := time.ParseDuration(r.Age)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, age, time.Minute)
+
+	text := FormatLeakReports(reports)
+	assert.Contains(t, text, "1 goroutine(s) still alive")
+	assert.Contains(t, text, "main.wait")
+}
+
+func TestTraceSession_LastFrame(t *testing.T) {
+	inst := newInFlightTestInstance()
+	s := inst.sessions.GetOrCreate(1)
+
+	_, _, finished := s.LastFrame()
+	assert.False(t, finished)
+
+	s.PrepareEnter(inst, "main.a", time.Now())
+	_, name, finished := s.LastFrame()
+	assert.Equal(t, "main.a", name)
+	assert.False(t, finished)
+
+	s.OnExit(0)
+	id, name, finished := s.LastFrame()
+	assert.NotZero(t, id)
+	assert.Equal(t, "main.a", name)
+	assert.True(t, finished)
+}
@@ -0,0 +1,81 @@
+package trace
+
+import (
+	"sort"
+	"time"
+)
+
+// FrameInfo 尚未退出的调用帧
+type FrameInfo struct {
+	TraceID   int64         `json:"traceId"`   // 跟踪ID
+	Name      string        `json:"name"`      // 函数名称
+	Depth     int           `json:"depth"`     // 调用深度（0为根）
+	StartTime time.Time     `json:"startTime"` // 进入时间
+	Elapsed   time.Duration `json:"elapsed"`   // 已运行时长
+}
+
+// SessionStack 单个被跟踪 goroutine 当前打开的调用栈
+type SessionStack struct {
+	GoroutineID uint64        `json:"goroutineId"` // 协程自增ID（GoroutineTrace.id）
+	OriginGID   uint64        `json:"originGid"`   // 原始Goroutine ID
+	Elapsed     time.Duration `json:"elapsed"`     // 最外层调用帧已运行时长
+	Frames      []FrameInfo   `json:"frames"`      // 由外到内的调用帧
+}
+
+// QueueDepths 数据写入队列的积压情况
+type QueueDepths struct {
+	OpChan    int            `json:"opChan"`
+	Pipelines PipelineDepths `json:"pipelines"`
+}
+
+// OpenStacks 返回所有存在未退出调用帧的会话调用栈，按协程ID升序
+func (t *TraceInstance) OpenStacks() []SessionStack {
+	if t.sessions == nil {
+		return nil
+	}
+	origin := make(map[uint64]uint64)
+	for gid, info := range t.GetGoroutineRunning() {
+		origin[info.ID] = gid
+	}
+
+	now := time.Now()
+	var stacks []SessionStack
+	for id, s := range t.sessions.Snapshot() {
+		frames := s.OpenFrames(now)
+		if len(frames) == 0 {
+			continue
+		}
+		stacks = append(stacks, SessionStack{
+			GoroutineID: id,
+			OriginGID:   origin[id],
+			Elapsed:     frames[0].Elapsed,
+			Frames:      frames,
+		})
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		return stacks[i].GoroutineID < stacks[j].GoroutineID
+	})
+	return stacks
+}
+
+// QueueDepths 返回数据写入队列的积压情况
+func (t *TraceInstance) QueueDepths() QueueDepths {
+	d := QueueDepths{OpChan: len(t.OpChan)}
+	if t.pipelines != nil {
+		d.Pipelines = t.pipelines.Depths()
+	}
+	return d
+}
+
+// PipelineStats 返回各写入管道的累计降级、失败、溢出与丢弃计数
+func (t *TraceInstance) PipelineStats() PipelineStats {
+	if t.pipelines == nil {
+		return PipelineStats{}
+	}
+	return t.pipelines.Counters()
+}
+
+// GetConfig 返回当前生效配置的副本
+func (t *TraceInstance) GetConfig() Config {
+	return *t.config
+}
@@ -0,0 +1,21 @@
+package trace
+
+import (
+	"os"
+	"testing"
+
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+// TestMain 将测试过程中创建的数据库与溢出文件放到临时目录，避免留在源码树中
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "functrace-trace-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv(sqlite.EnvDir, dir)
+	os.Setenv(EnvSpillDir, dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CallRecord 一次已返回的被跟踪调用
+type CallRecord struct {
+	TraceID     int64         `json:"traceId"`     // 跟踪ID
+	ParentID    int64         `json:"parentId"`    // 父调用的跟踪ID，根调用为0
+	RootID      int64         `json:"rootId"`      // 所在调用树根调用的跟踪ID
+	GoroutineID uint64        `json:"goroutineId"` // 协程自增ID
+	OriginGID   uint64        `json:"originGid"`   // 原始Goroutine ID
+	Name        string        `json:"name"`        // 函数名称
+	Depth       int           `json:"depth"`       // 调用深度
+	Start       time.Time     `json:"start"`       // 开始时间
+	Duration    time.Duration `json:"duration"`    // 总耗时
+	Self        time.Duration `json:"self"`        // 自身耗时（不含被跟踪的子调用）
+	ParamsCount int           `json:"paramsCount"` // 参数数量
+	Params      []interface{} `json:"-"`           // 原始参数，仅当有观察者需要参数时保留
+	Err         error         `json:"-"`           // 调用返回的错误
+}
+
+// CallObserver 调用完成观察者
+// OnCall 在被跟踪函数返回的 goroutine 上同步调用，实现必须快速返回（耗时工作应转交后台）
+type CallObserver interface {
+	OnCall(rec CallRecord)
+}
+
+// ParamsObserver 可选能力：需要原始参数的观察者
+type ParamsObserver interface {
+	WantParams() bool
+}
+
+// CallObserverFunc 函数形式的调用完成观察者
+type CallObserverFunc func(rec CallRecord)
+
+// OnCall 实现 CallObserver
+func (f CallObserverFunc) OnCall(rec CallRecord) { f(rec) }
+
+// callObservers 写时复制的观察者列表，热路径只做一次原子读取
+type callObservers struct {
+	mu         sync.Mutex
+	list       atomic.Pointer[[]CallObserver]
+	wantParams atomic.Bool
+}
+
+func (o *callObservers) add(obs CallObserver) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var next []CallObserver
+	if cur := o.list.Load(); cur != nil {
+		next = append(next, *cur...)
+	}
+	next = append(next, obs)
+	o.list.Store(&next)
+	if p, ok := obs.(ParamsObserver); ok && p.WantParams() {
+		o.wantParams.Store(true)
+	}
+}
+
+func (o *callObservers) load() []CallObserver {
+	if cur := o.list.Load(); cur != nil {
+		return *cur
+	}
+	return nil
+}
+
+// AddCallObserver 注册调用完成观察者；实现 io.Closer 的观察者会在 Close 时被关闭
+func (t *TraceInstance) AddCallObserver(obs CallObserver) {
+	if obs == nil {
+		return
+	}
+	t.observers.add(obs)
+}
+
+// notifyCall 通知全部观察者，单个观察者 panic 不影响其他观察者与被跟踪程序
+func (t *TraceInstance) notifyCall(list []CallObserver, rec CallRecord) {
+	for _, obs := range list {
+		t.safeExecute(func() {
+			obs.OnCall(rec)
+		})
+	}
+}
+
+// closeObservers 关闭实现了 io.Closer 的观察者（用于刷新缓冲）
+func (t *TraceInstance) closeObservers() {
+	for _, obs := range t.observers.load() {
+		closer, ok := obs.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			t.log.WithFields(logrus.Fields{"error": err}).Error("close call observer failed")
+		}
+	}
+}
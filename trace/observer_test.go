@@ -0,0 +1,58 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type paramsObserver struct{ calls []CallRecord }
+
+func (o *paramsObserver) OnCall(rec CallRecord) { o.calls = append(o.calls, rec) }
+func (o *paramsObserver) WantParams() bool      { return true }
+
+func TestCallObservers(t *testing.T) {
+	var obs callObservers
+	assert.Empty(t, obs.load())
+
+	var plain []string
+	obs.add(CallObserverFunc(func(rec CallRecord) { plain = append(plain, rec.Name) }))
+	assert.False(t, obs.wantParams.Load())
+
+	withParams := &paramsObserver{}
+	obs.add(withParams)
+	assert.True(t, obs.wantParams.Load())
+
+	inst := &TraceInstance{log: logrus.New()}
+	list := obs.load()
+	assert.Len(t, list, 2)
+	inst.notifyCall(list, CallRecord{Name: "main.a"})
+	assert.Equal(t, []string{"main.a"}, plain)
+	assert.Len(t, withParams.calls, 1)
+}
+
+func TestCallObservers_PanicIsolated(t *testing.T) {
+	var obs callObservers
+	obs.add(CallObserverFunc(func(CallRecord) { panic("observer bug") }))
+	called := false
+	obs.add(CallObserverFunc(func(CallRecord) { called = true }))
+
+	inst := &TraceInstance{log: logrus.New()}
+	assert.NotPanics(t, func() { inst.notifyCall(obs.load(), CallRecord{}) })
+	assert.True(t, called)
+}
+
+func TestTraceSession_RootTraceID(t *testing.T) {
+	inst := newInFlightTestInstance()
+	s := inst.sessions.GetOrCreate(1)
+	assert.Zero(t, s.RootTraceID())
+
+	_, _, root := s.PrepareEnter(inst, "main.root", time.Now())
+	_, _, child := s.PrepareEnter(inst, "main.child", time.Now())
+	assert.NotEqual(t, root, child)
+	assert.Equal(t, root, s.RootTraceID())
+	s.OnExit(0)
+	assert.Equal(t, root, s.RootTraceID())
+}
@@ -81,6 +81,11 @@ func decompress(data []byte) string {
 	return string(decompressed)
 }
 
+// DecodeParamData 还原持久化的参数数据（处理压缩），供导出工具读取 ParamStore
+func DecodeParamData(data []byte) string {
+	return decompress(data)
+}
+
 // sdumpSafe 安全地对对象进行转储，避免 objectdump 在 unsafe 反射时触发 checkptr panic
 func sdumpSafe(v interface{}) (s string) {
 	defer func() {
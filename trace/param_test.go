@@ -19,6 +19,11 @@ func (m *MockParamRepository) SaveParam(param *model.ParamStoreData) (int64, err
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	args := m.Called(params)
+	return args.Error(0)
+}
+
 func (m *MockParamRepository) FindParamCacheByAddr(addr string) (*model.ParamCache, error) {
 	args := m.Called(addr)
 	if args.Get(0) == nil {
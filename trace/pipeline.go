@@ -2,10 +2,14 @@ package trace
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
 	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/domain/record"
 )
 
 // TracePipeline 定义 trace 事件的管道接口（插入与更新）
@@ -14,6 +18,10 @@ type TracePipeline interface {
 	Insert(trace *model.TraceData)
 	// Update 提交 trace 更新事件
 	Update(trace *model.TraceData)
+	// Depth 返回尚未处理的事件数量
+	Depth() int
+	// Counters 返回累计的降级与丢弃计数
+	Counters() PipelineCounters
 }
 
 // ParamPipeline 定义参数批量入库管道接口
@@ -22,6 +30,10 @@ type ParamPipeline interface {
 	Enqueue(p *model.ParamStoreData)
 	// EnqueueTask 提交原始任务（由管道线程完成 dump/diff/压缩/入库）
 	EnqueueTask(task interface{})
+	// Depth 返回尚未处理的事件数量
+	Depth() int
+	// Counters 返回累计的降级与丢弃计数
+	Counters() PipelineCounters
 }
 
 // GoroutinePipeline 定义 goroutine 插入与更新管道接口
@@ -30,20 +42,25 @@ type GoroutinePipeline interface {
 	Insert(g *model.GoroutineTrace)
 	// Update 更新 goroutine 记录
 	Update(g *model.GoroutineTrace)
+	// Depth 返回尚未处理的事件数量
+	Depth() int
+	// Counters 返回累计的降级与丢弃计数
+	Counters() PipelineCounters
 }
 
 // Pipelines 聚合三类数据的管道，统一生命周期管理
 type Pipelines struct {
 	ctx    context.Context
 	cancel context.CancelFunc
+	wg     sync.WaitGroup // 需在停止时完成最后一次写出的后台协程
 
 	Trace     TracePipeline
 	Param     ParamPipeline
 	Goroutine GoroutinePipeline
 }
 
-// NewPipelines 创建管道骨架（默认使用空实现，后续逐步替换为真实实现)
-func NewPipelines(parent context.Context, inst *TraceInstance) *Pipelines {
+// NewPipelines 创建写入 repos 的管道，仓储在创建时确定，后台协程不再读取全局仓储工厂
+func NewPipelines(parent context.Context, inst *TraceInstance, repos domain.RepositoryFactory) *Pipelines {
 	ctx, cancel := context.WithCancel(parent)
 	p := &Pipelines{
 		ctx:    ctx,
@@ -54,9 +71,10 @@ func NewPipelines(parent context.Context, inst *TraceInstance) *Pipelines {
 	if shardNum <= 0 {
 		shardNum = 16
 	}
-	p.Trace = newTracePipeline(ctx, shardNum)
-	p.Param = newParamPipeline(ctx, inst)
-	p.Goroutine = newGoroutinePipeline(ctx)
+	p.Trace = newTracePipeline(ctx, repos, shardNum, &p.wg, inst.spill)
+	p.Param = newParamPipeline(ctx, repos, inst, &p.wg)
+	p.Goroutine = newGoroutinePipeline(ctx, repos, &p.wg, inst.spill)
+	inst.log.WithFields(logrus.Fields{"shard_num": shardNum}).Info("trace shards initialized")
 	return p
 }
 
@@ -65,29 +83,275 @@ func (p *Pipelines) Start() {
 	// 未来：在此启动各子管道的后台协程
 }
 
-// Stop 停止所有子管道（骨架版本：仅取消 context）
+// PipelineDepths 各管道当前的积压数量
+type PipelineDepths struct {
+	Trace     int `json:"trace"`
+	Param     int `json:"param"`
+	Goroutine int `json:"goroutine"`
+}
+
+// Depths 返回各子管道的积压数量
+func (p *Pipelines) Depths() PipelineDepths {
+	return PipelineDepths{
+		Trace:     p.Trace.Depth(),
+		Param:     p.Param.Depth(),
+		Goroutine: p.Goroutine.Depth(),
+	}
+}
+
+// PipelineCounters 单个管道的累计计数
+type PipelineCounters struct {
+	Overflow uint64 `json:"overflow"` // 通道满时降级为同步写入的次数
+	Failed   uint64 `json:"failed"`   // 写入仓储失败的次数（含重试）
+	Spilled  uint64 `json:"spilled"`  // 重试后仍失败、已写入溢出文件的记录数
+	Dropped  uint64 `json:"dropped"`  // 无法写入溢出文件而丢失的记录数
+}
+
+// PipelineStats 各管道的累计计数
+type PipelineStats struct {
+	Trace     PipelineCounters `json:"trace"`
+	Param     PipelineCounters `json:"param"`
+	Goroutine PipelineCounters `json:"goroutine"`
+}
+
+// Counters 返回各子管道的累计计数
+func (p *Pipelines) Counters() PipelineStats {
+	return PipelineStats{
+		Trace:     p.Trace.Counters(),
+		Param:     p.Param.Counters(),
+		Goroutine: p.Goroutine.Counters(),
+	}
+}
+
+// 各管道重试队列的参数
+const (
+	retryMaxAttempts = 6                     // 单条写入的最大尝试次数
+	retryBaseDelay   = 50 * time.Millisecond // 首次重试的等待时间，之后逐次翻倍
+	retryMaxDelay    = 2 * time.Second       // 单次重试的最长等待时间
+	retryQueueLimit  = 4096                  // 重试队列的最大长度，超出后直接溢出
+)
+
+// pipelineCounters 管道内部使用的原子计数，放弃重试的记录写入溢出文件
+type pipelineCounters struct {
+	overflow atomic.Uint64
+	failed   atomic.Uint64
+	spilled  atomic.Uint64
+	dropped  atomic.Uint64
+
+	table string       // 溢出记录的表名（record.TableXxx）
+	spill *spillWriter // 为 nil 时放弃的记录直接计为丢失
+}
+
+// fail 记录一次失败的写入，err 为 nil 时返回 false
+func (c *pipelineCounters) fail(err error) bool {
+	if err == nil {
+		return false
+	}
+	c.failed.Add(1)
+	return true
+}
+
+// giveUp 放弃写入仓储，将记录写入溢出文件，溢出文件不可用时计为丢失
+func (c *pipelineCounters) giveUp(op string, data interface{}) {
+	if err := c.spill.Write(op, c.table, data); err != nil {
+		c.dropped.Add(1)
+		return
+	}
+	c.spilled.Add(1)
+}
+
+// settle 调用方协程上的降级直写：失败时不阻塞重试，直接溢出
+func (c *pipelineCounters) settle(err error, op string, data interface{}) {
+	if c.fail(err) {
+		c.giveUp(op, data)
+	}
+}
+
+// retryItem 等待重试的一次写入
+type retryItem struct {
+	op    string
+	data  interface{}
+	key   int64 // 非0时同 key 的写入按提交顺序执行
+	write func() error
+	tries int       // 已尝试次数
+	due   time.Time // 下次尝试的时间
+}
+
+// retryQueue 管道后台协程内由定时器驱动的重试队列：失败的写入按指数退避等待重试，不阻塞后续事件，
+// 超过次数或队列已满时溢出。只在单个后台协程内使用，无需加锁
+type retryQueue struct {
+	counters *pipelineCounters
+	items    []retryItem
+	keys     map[int64]int // 队列中各 key 的写入数
+	timer    *time.Timer
+}
+
+func newRetryQueue(counters *pipelineCounters) *retryQueue {
+	return &retryQueue{counters: counters, keys: make(map[int64]int)}
+}
+
+// C 返回重试定时器的通道，尚未有写入失败时返回 nil（永不就绪）
+func (q *retryQueue) C() <-chan time.Time {
+	if q.timer == nil {
+		return nil
+	}
+	return q.timer.C
+}
+
+// do 执行一次写入；同 key 仍有写入等待重试时排在其后，失败时进入重试队列
+func (q *retryQueue) do(op string, data interface{}, key int64, write func() error) {
+	item := retryItem{op: op, data: data, key: key, write: write}
+	if key != 0 && q.keys[key] > 0 {
+		item.due = time.Now()
+		q.push(item)
+		q.schedule()
+		return
+	}
+	if !q.attempt(item) {
+		q.schedule()
+	}
+}
+
+// attempt 尝试一次写入，失败时按退避时间放回队列，超过次数或队列已满时溢出
+func (q *retryQueue) attempt(item retryItem) bool {
+	if !q.counters.fail(item.write()) {
+		return true
+	}
+	item.tries++
+	if item.tries >= retryMaxAttempts || len(q.items) >= retryQueueLimit {
+		q.counters.giveUp(item.op, item.data)
+		return false
+	}
+	item.due = time.Now().Add(min(retryBaseDelay<<(item.tries-1), retryMaxDelay))
+	q.push(item)
+	return false
+}
+
+func (q *retryQueue) push(item retryItem) {
+	q.items = append(q.items, item)
+	if item.key != 0 {
+		q.keys[item.key]++
+	}
+}
+
+// drain 重试已到期的写入；closing 为 true 时不再等待，每条写入最后尝试一次，失败即溢出
+// 同 key 中较早的写入尚未成功时，较晚的写入保持等待（停止时一并溢出）
+func (q *retryQueue) drain(closing bool) {
+	if len(q.items) == 0 {
+		return
+	}
+	now := time.Now()
+	items := q.items
+	q.items = nil
+	held := make(map[int64]bool)
+	for _, it := range items {
+		if it.key != 0 {
+			q.keys[it.key]--
+		}
+		switch {
+		case it.key != 0 && held[it.key]:
+			if closing {
+				q.counters.giveUp(it.op, it.data)
+			} else {
+				q.push(it)
+			}
+		case !closing && it.due.After(now):
+			q.push(it)
+			held[it.key] = true
+		default:
+			if closing {
+				it.tries = max(it.tries, retryMaxAttempts-1)
+			}
+			if !q.attempt(it) {
+				held[it.key] = true
+			}
+		}
+	}
+	q.schedule()
+}
+
+// schedule 按最早的到期时间设置定时器
+func (q *retryQueue) schedule() {
+	if len(q.items) == 0 {
+		return
+	}
+	next := q.items[0].due
+	for _, it := range q.items[1:] {
+		if it.due.Before(next) {
+			next = it.due
+		}
+	}
+	delay := time.Until(next)
+	if q.timer == nil {
+		q.timer = time.NewTimer(delay)
+		return
+	}
+	if !q.timer.Stop() {
+		select {
+		case <-q.timer.C:
+		default:
+		}
+	}
+	q.timer.Reset(delay)
+}
+
+// stop 停止定时器
+func (q *retryQueue) stop() {
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+}
+
+// Counters 返回当前计数
+func (c *pipelineCounters) Counters() PipelineCounters {
+	return PipelineCounters{
+		Overflow: c.overflow.Load(),
+		Failed:   c.failed.Load(),
+		Spilled:  c.spilled.Load(),
+		Dropped:  c.dropped.Load(),
+	}
+}
+
+// Stop 停止所有子管道，并等待各管道写出通道与缓冲中的数据
 func (p *Pipelines) Stop() {
 	if p.cancel != nil {
 		p.cancel()
 	}
+	p.wg.Wait()
 }
 
 // ---- Trace 分片管道实现 ----
+//
+// 仓储实现 domain.TraceBatchWriter 时，调用在分片内缓存到退出，再与其他已完成的调用一起整行写入（单事务）；
+// 存活超过一个刷新周期的调用先以未完成状态写入，保证进程崩溃时仍可见。
+// 仓储不支持时退化为进入时 INSERT、退出时 UPDATE 的逐条写入。
+// 写入失败的事件进入有界的重试队列，按指数退避重试，超过次数或队列已满时写入溢出文件。
+
+const (
+	traceBatchSize      = 256                    // 单个事务写入的最大行数
+	traceFlushInterval  = 200 * time.Millisecond // 定时刷新间隔
+	traceOrphanMaxTries = 50                     // 退出事件找不到对应调用时的最大重试轮数
+)
 
 type tracePipeline struct {
+	pipelineCounters
 	ctx    context.Context
+	traces domain.TraceRepository
 	shards []*tpShard
 }
 
-func newTracePipeline(ctx context.Context, shardNum int) *tracePipeline {
+func newTracePipeline(ctx context.Context, repos domain.RepositoryFactory, shardNum int, wg *sync.WaitGroup, spill *spillWriter) *tracePipeline {
 	tp := &tracePipeline{
 		ctx:    ctx,
+		traces: repos.GetTraceRepository(),
 		shards: make([]*tpShard, shardNum),
 	}
+	tp.table, tp.spill = record.TableTrace, spill
 	for i := 0; i < shardNum; i++ {
-		sh := newTpShard(i)
+		sh := newTpShard(i, tp.traces, &tp.pipelineCounters)
 		tp.shards[i] = sh
-		sh.start(ctx)
+		wg.Add(1)
+		sh.start(ctx, wg)
 	}
 	return tp
 }
@@ -105,15 +369,18 @@ func (t *tracePipeline) shardIndex(traceID int64) int {
 func (t *tracePipeline) Insert(td *model.TraceData) {
 	idx := t.shardIndex(td.ID)
 	sh := t.shards[idx]
-	if sh == nil || sh.inCh == nil {
-		_, _ = repositoryFactory.GetTraceRepository().SaveTrace(td)
+	if sh == nil || sh.inCh == nil || t.ctx.Err() != nil {
+		_, err := t.traces.SaveTrace(td)
+		t.settle(err, record.OpInsert, td)
 		return
 	}
 	select {
 	case sh.inCh <- td:
 		// ok
 	default:
-		_, _ = repositoryFactory.GetTraceRepository().SaveTrace(td)
+		t.overflow.Add(1)
+		_, err := t.traces.SaveTrace(td)
+		t.settle(err, record.OpInsert, td)
 	}
 }
 
@@ -121,168 +388,325 @@ func (t *tracePipeline) Update(td *model.TraceData) {
 	idx := t.shardIndex(td.ID)
 	sh := t.shards[idx]
 	evt := tpUpdateEvt{id: td.ID, timeCost: td.TimeCost}
-	if sh == nil || sh.inCh == nil {
-		_ = repositoryFactory.GetTraceRepository().UpdateTraceTimeCost(td.ID, td.TimeCost)
+	if sh == nil || sh.inCh == nil || t.ctx.Err() != nil {
+		t.settle(t.traces.UpdateTraceTimeCost(td.ID, td.TimeCost), record.OpUpdate, evt.update())
 		return
 	}
 	select {
 	case sh.inCh <- evt:
 		// ok
 	default:
-		_ = repositoryFactory.GetTraceRepository().UpdateTraceTimeCost(td.ID, td.TimeCost)
+		t.overflow.Add(1)
+		t.settle(t.traces.UpdateTraceTimeCost(td.ID, td.TimeCost), record.OpUpdate, evt.update())
 	}
 }
 
+func (t *tracePipeline) Depth() int {
+	n := 0
+	for _, sh := range t.shards {
+		if sh != nil {
+			n += len(sh.inCh)
+		}
+	}
+	return n
+}
+
 type tpShard struct {
-	index       int
-	inCh        chan interface{}
-	insertedSet map[int64]struct{}
-	retryQueue  []interface{}
-	retryTimer  *time.Timer
+	index    int
+	inCh     chan interface{}
+	counters *pipelineCounters
+	traces   domain.TraceRepository
+	writer   domain.TraceBatchWriter // 仓储不支持整行写入时为 nil
+
+	retries *retryQueue // 两种模式共用，按调用 ID 保持同一调用写入的顺序
+	closing bool        // 停止时不再等待，失败的事件直接溢出
+
+	// 整行写入模式
+	open    map[int64]*tpOpenCall // 尚未退出的调用
+	orphans map[int64]*tpOrphan   // 先于进入事件到达的退出事件
+	batch   []*model.TraceData    // 待写入的行
+}
+
+// tpOpenCall 分片内尚未退出的调用
+type tpOpenCall struct {
+	trace   *model.TraceData
+	ticks   int  // 已经历的刷新周期数
+	written bool // 是否已以未完成状态写入
+}
+
+// tpOrphan 找不到对应调用的退出事件
+type tpOrphan struct {
+	timeCost string
+	tries    int
 }
 
-func newTpShard(index int) *tpShard {
+// tpRowEvt 整行写入失败后进入重试队列的完整行
+type tpRowEvt struct {
+	row *model.TraceData
+}
+
+type tpUpdateEvt struct {
+	id       int64
+	timeCost string
+}
+
+// update 转换为溢出文件中的更新记录
+func (e tpUpdateEvt) update() record.TraceUpdate {
+	return record.TraceUpdate{ID: e.id, TimeCost: e.timeCost, IsFinished: 1}
+}
+
+func newTpShard(index int, traces domain.TraceRepository, counters *pipelineCounters) *tpShard {
+	writer, _ := traces.(domain.TraceBatchWriter)
 	return &tpShard{
-		index:       index,
-		inCh:        make(chan interface{}, 1024),
-		insertedSet: make(map[int64]struct{}),
-		retryQueue:  make([]interface{}, 0),
+		index:    index,
+		inCh:     make(chan interface{}, 1024),
+		counters: counters,
+		traces:   traces,
+		writer:   writer,
+		retries:  newRetryQueue(counters),
+		open:     make(map[int64]*tpOpenCall),
+		orphans:  make(map[int64]*tpOrphan),
 	}
 }
 
-func (s *tpShard) start(ctx context.Context) {
+func (s *tpShard) start(ctx context.Context, wg *sync.WaitGroup) {
 	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(traceFlushInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
+				s.shutdown()
 				return
 			case evt, ok := <-s.inCh:
 				if !ok {
+					s.shutdown()
 					return
 				}
 				s.handleEvent(evt)
-			case <-s.retryTimerC():
-				s.drainRetry()
+			case <-s.retries.C():
+				s.retries.drain(false)
+			case <-ticker.C:
+				s.tick()
 			}
 		}
 	}()
 }
 
-func (s *tpShard) retryTimerC() <-chan time.Time {
-	if s.retryTimer != nil {
-		return s.retryTimer.C
+func (s *tpShard) handleEvent(evt interface{}) {
+	if s.writer != nil {
+		s.bufferEvent(evt)
+		return
 	}
-	return make(<-chan time.Time)
+	s.apply(evt)
 }
 
-func (s *tpShard) scheduleRetry(delay time.Duration) {
-	if s.retryTimer == nil {
-		s.retryTimer = time.NewTimer(delay)
-		return
+// apply 执行一次写入，失败时进入重试队列
+// 同一调用的插入仍在等待重试时，其后的更新排在插入之后；已由降级路径同步写入的插入不需要等待
+func (s *tpShard) apply(evt interface{}) {
+	switch e := evt.(type) {
+	case *model.TraceData:
+		s.retries.do(record.OpInsert, e, e.ID, func() error {
+			_, err := s.traces.SaveTrace(e)
+			return err
+		})
+	case tpUpdateEvt:
+		s.retries.do(record.OpUpdate, e.update(), e.id, func() error {
+			return s.traces.UpdateTraceTimeCost(e.id, e.timeCost)
+		})
+	case tpRowEvt:
+		s.retries.do(record.OpUpsert, e.row, e.row.ID, func() error {
+			return s.writer.SaveTracesBatch([]*model.TraceData{e.row})
+		})
 	}
-	if !s.retryTimer.Stop() {
-		select {
-		case <-s.retryTimer.C:
-		default:
+}
+
+// bufferEvent 整行写入模式：进入事件缓存到退出，退出后合并为完整的行进入批次
+func (s *tpShard) bufferEvent(evt interface{}) {
+	switch e := evt.(type) {
+	case *model.TraceData:
+		row := *e
+		row.Params = nil
+		if o, ok := s.orphans[e.ID]; ok {
+			// 退出事件先到达：直接得到完整的行
+			delete(s.orphans, e.ID)
+			row.TimeCost = o.timeCost
+			row.IsFinished = 1
+			s.batch = append(s.batch, &row)
+		} else {
+			s.open[e.ID] = &tpOpenCall{trace: &row}
+		}
+	case tpUpdateEvt:
+		c, ok := s.open[e.id]
+		if !ok {
+			s.orphans[e.id] = &tpOrphan{timeCost: e.timeCost}
+			return
 		}
+		delete(s.open, e.id)
+		c.trace.TimeCost = e.timeCost
+		c.trace.IsFinished = 1
+		s.batch = append(s.batch, c.trace)
+	default:
+		s.apply(evt)
+		return
+	}
+	if len(s.batch) >= traceBatchSize {
+		s.flush()
 	}
-	s.retryTimer.Reset(delay)
 }
 
-func (s *tpShard) drainRetry() {
-	if len(s.retryQueue) == 0 {
+// tick 定时刷新：写出已完成的调用，以及存活超过一个周期的在途调用
+func (s *tpShard) tick() {
+	if s.writer == nil {
 		return
 	}
-	q := s.retryQueue
-	s.retryQueue = nil
-	for _, evt := range q {
-		s.handleEvent(evt)
+	for _, c := range s.open {
+		if c.written {
+			continue
+		}
+		if c.ticks == 0 {
+			c.ticks++
+			continue
+		}
+		row := *c.trace
+		s.batch = append(s.batch, &row)
+		c.written = true
 	}
-	if len(s.retryQueue) > 0 {
-		s.scheduleRetry(100 * time.Millisecond)
+	s.flush()
+	s.retryOrphans()
+}
+
+// retryOrphans 等待一个周期后进入事件仍未到达的退出事件，按逐条更新处理（对应的行可能已由降级路径写入）
+// 行不存在时的更新失败属于等待而不计为失败，超过轮数后溢出
+func (s *tpShard) retryOrphans() {
+	for id, o := range s.orphans {
+		o.tries++
+		if o.tries < 2 && !s.closing {
+			continue
+		}
+		if s.traces.UpdateTraceTimeCost(id, o.timeCost) == nil {
+			delete(s.orphans, id)
+		} else if o.tries >= traceOrphanMaxTries || s.closing {
+			delete(s.orphans, id)
+			s.counters.giveUp(record.OpUpdate, tpUpdateEvt{id: id, timeCost: o.timeCost}.update())
+		}
 	}
 }
 
-type tpUpdateEvt struct {
-	id       int64
-	timeCost string
+// flush 以单个事务写出批次，失败时逐行重试
+func (s *tpShard) flush() {
+	if len(s.batch) == 0 {
+		return
+	}
+	b := s.batch
+	s.batch = make([]*model.TraceData, 0, len(b))
+	if !s.counters.fail(s.writer.SaveTracesBatch(b)) {
+		return
+	}
+	for _, row := range b {
+		s.apply(tpRowEvt{row: row})
+	}
 }
 
-func (s *tpShard) handleEvent(evt interface{}) {
-	switch e := evt.(type) {
-	case *model.TraceData:
-		if _, ok := s.insertedSet[e.ID]; ok {
-			return
-		}
-		if _, err := repositoryFactory.GetTraceRepository().SaveTrace(e); err != nil {
-			s.retryQueue = append(s.retryQueue, e)
-			s.scheduleRetry(100 * time.Millisecond)
-			return
-		}
-		s.insertedSet[e.ID] = struct{}{}
-	case tpUpdateEvt:
-		id := e.id
-		if _, ok := s.insertedSet[id]; !ok {
-			s.retryQueue = append(s.retryQueue, e)
-			s.scheduleRetry(50 * time.Millisecond)
-			return
+// shutdown 处理通道中剩余的事件，将在途调用以未完成状态写出，仍失败的事件写入溢出文件
+func (s *tpShard) shutdown() {
+	for drained := false; !drained; {
+		select {
+		case evt := <-s.inCh:
+			s.handleEvent(evt)
+		default:
+			drained = true
 		}
-		if err := repositoryFactory.GetTraceRepository().UpdateTraceTimeCost(id, e.timeCost); err != nil {
-			s.retryQueue = append(s.retryQueue, e)
-			s.scheduleRetry(100 * time.Millisecond)
-			return
+	}
+	s.closing = true
+	if s.writer != nil {
+		for _, c := range s.open {
+			s.batch = append(s.batch, c.trace)
 		}
-		delete(s.insertedSet, id)
+		s.open = make(map[int64]*tpOpenCall)
+		s.flush()
+		s.retryOrphans()
 	}
+	s.retries.drain(true)
+	s.retries.stop()
 }
 
 // ---- Param 批量器实现（迁移自 TraceInstance.startParamBatcher） ----
 
 type paramPipeline struct {
-	ctx  context.Context
-	inCh chan interface{}
-	inst *TraceInstance
+	pipelineCounters
+	ctx    context.Context
+	params domain.ParamRepository
+	inCh   chan interface{}
+	inst   *TraceInstance
 }
 
-func newParamPipeline(ctx context.Context, inst *TraceInstance) *paramPipeline {
+func newParamPipeline(ctx context.Context, repos domain.RepositoryFactory, inst *TraceInstance, wg *sync.WaitGroup) *paramPipeline {
 	p := &paramPipeline{
-		ctx:  ctx,
-		inCh: make(chan interface{}, 1000),
-		inst: inst,
+		ctx:    ctx,
+		params: repos.GetParamRepository(),
+		inCh:   make(chan interface{}, 1000),
+		inst:   inst,
 	}
-	go p.loop()
+	p.table, p.spill = record.TableParam, inst.spill
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.loop()
+	}()
 	return p
 }
 
 func (p *paramPipeline) Enqueue(ps *model.ParamStoreData) {
+	if p.ctx.Err() != nil {
+		// 已停止：直接单条写入
+		_, err := p.params.SaveParam(ps)
+		p.settle(err, record.OpInsert, ps)
+		return
+	}
 	select {
 	case p.inCh <- ps:
 		// ok
 	default:
 		// 通道满：直接降级为单条写入
-		_, _ = repositoryFactory.GetParamRepository().SaveParam(ps)
+		p.overflow.Add(1)
+		_, err := p.params.SaveParam(ps)
+		p.settle(err, record.OpInsert, ps)
 	}
 }
 
 func (p *paramPipeline) EnqueueTask(task interface{}) {
-	select {
-	case p.inCh <- task:
-		// ok
-	default:
-		// 通道满：退化到就地处理并直写（避免丢失）
-		switch t := task.(type) {
-		case *processParamTask:
-			ps := p.buildParamFromTask(t)
-			if ps != nil {
-				_, _ = repositoryFactory.GetParamRepository().SaveParam(ps)
-			}
-		case *processPointerReceiverTask:
-			ps := p.buildParamFromReceiverTask(t)
-			if ps != nil {
-				_, _ = repositoryFactory.GetParamRepository().SaveParam(ps)
-			}
+	if p.ctx.Err() == nil {
+		select {
+		case p.inCh <- task:
+			return
+		default:
+			// 通道满：退化到就地处理并直写（避免丢失）
+			p.overflow.Add(1)
 		}
 	}
+	if ps := p.build(task); ps != nil {
+		_, err := p.params.SaveParam(ps)
+		p.settle(err, record.OpInsert, ps)
+	}
+}
+
+func (p *paramPipeline) Depth() int {
+	return len(p.inCh)
+}
+
+// build 将通道中的事件转换为可入库的参数数据
+func (p *paramPipeline) build(evt interface{}) *model.ParamStoreData {
+	switch e := evt.(type) {
+	case *model.ParamStoreData:
+		return e
+	case *processParamTask:
+		return p.buildParamFromTask(e)
+	case *processPointerReceiverTask:
+		return p.buildParamFromReceiverTask(e)
+	}
+	return nil
 }
 
 func (p *paramPipeline) loop() {
@@ -293,37 +717,47 @@ func (p *paramPipeline) loop() {
 	batch := make([]*model.ParamStoreData, 0, maxBatchSize)
 	timer := time.NewTimer(flushInterval)
 	defer timer.Stop()
+	retries := newRetryQueue(&p.pipelineCounters)
+	defer retries.stop()
 	flush := func() {
 		if len(batch) == 0 {
 			return
 		}
 		b := batch
 		batch = make([]*model.ParamStoreData, 0, maxBatchSize)
-		if err := repositoryFactory.GetParamRepository().SaveParamsBatch(b); err != nil {
+		if p.fail(p.params.SaveParamsBatch(b)) {
+			// 批量写入失败时逐条写入，仍失败的进入重试队列
 			for _, it := range b {
-				_, _ = repositoryFactory.GetParamRepository().SaveParam(it)
+				retries.do(record.OpInsert, it, 0, func() error {
+					_, err := p.params.SaveParam(it)
+					return err
+				})
 			}
 		}
 	}
 	for {
 		select {
 		case <-p.ctx.Done():
+			// 写出通道中剩余的参数，重试队列中的写入最后尝试一次
+			for drained := false; !drained; {
+				select {
+				case evt := <-p.inCh:
+					if ps := p.build(evt); ps != nil {
+						batch = append(batch, ps)
+					}
+					if len(batch) >= maxBatchSize {
+						flush()
+					}
+				default:
+					drained = true
+				}
+			}
 			flush()
+			retries.drain(true)
 			return
 		case evt := <-p.inCh:
-			switch e := evt.(type) {
-			case *model.ParamStoreData:
-				batch = append(batch, e)
-			case *processParamTask:
-				ps := p.buildParamFromTask(e)
-				if ps != nil {
-					batch = append(batch, ps)
-				}
-			case *processPointerReceiverTask:
-				ps := p.buildParamFromReceiverTask(e)
-				if ps != nil {
-					batch = append(batch, ps)
-				}
+			if ps := p.build(evt); ps != nil {
+				batch = append(batch, ps)
 			}
 			if len(batch) >= maxBatchSize {
 				flush()
@@ -338,6 +772,8 @@ func (p *paramPipeline) loop() {
 		case <-timer.C:
 			flush()
 			timer.Reset(flushInterval)
+		case <-retries.C():
+			retries.drain(false)
 		}
 	}
 }
@@ -376,7 +812,7 @@ func (p *paramPipeline) buildParamFromReceiverTask(task *processPointerReceiverT
 	)
 	key := "recv:" + task.StableKey
 	_, _, _ = p.inst.recvSFG.Do(key, func() (interface{}, error) {
-		cache, err = repositoryFactory.GetParamRepository().FindParamCacheByAddr(task.StableKey)
+		cache, err = p.params.FindParamCacheByAddr(task.StableKey)
 		return nil, nil
 	})
 
@@ -406,7 +842,7 @@ func (p *paramPipeline) buildParamFromReceiverTask(task *processPointerReceiverT
 			Data:   paramStoreData.Data,
 		}
 		_, _, _ = p.inst.recvSFG.Do(key+":save", func() (interface{}, error) {
-			if _, err := repositoryFactory.GetParamRepository().SaveParamCache(newCache); err != nil {
+			if _, err := p.params.SaveParamCache(newCache); err != nil {
 				p.inst.log.WithFields(logrus.Fields{"error": err, "stableKey": task.StableKey}).Warn("failed to save param cache")
 			}
 			return nil, nil
@@ -418,8 +854,10 @@ func (p *paramPipeline) buildParamFromReceiverTask(task *processPointerReceiverT
 // ---- Goroutine 管道实现：串行处理，ctx 控制退出 ----
 
 type goroutinePipeline struct {
-	ctx  context.Context
-	inCh chan goroutineEvt
+	pipelineCounters
+	ctx        context.Context
+	goroutines domain.GoroutineRepository
+	inCh       chan goroutineEvt
 }
 
 type goroutineEvt struct {
@@ -427,46 +865,91 @@ type goroutineEvt struct {
 	isUpdate bool
 }
 
-func newGoroutinePipeline(ctx context.Context) *goroutinePipeline {
+func newGoroutinePipeline(ctx context.Context, repos domain.RepositoryFactory, wg *sync.WaitGroup, spill *spillWriter) *goroutinePipeline {
 	p := &goroutinePipeline{
-		ctx:  ctx,
-		inCh: make(chan goroutineEvt, 512),
+		ctx:        ctx,
+		goroutines: repos.GetGoroutineRepository(),
+		inCh:       make(chan goroutineEvt, 512),
 	}
-	go p.loop()
+	p.table, p.spill = record.TableGoroutine, spill
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.loop()
+	}()
 	return p
 }
 
+// goroutineUpdate 转换为溢出文件中的更新记录
+func goroutineUpdate(gt *model.GoroutineTrace) record.GoroutineUpdate {
+	return record.GoroutineUpdate{ID: gt.ID, TimeCost: gt.TimeCost, IsFinished: gt.IsFinished}
+}
+
 func (g *goroutinePipeline) Insert(gt *model.GoroutineTrace) {
-	evt := goroutineEvt{g: gt, isUpdate: false}
-	select {
-	case g.inCh <- evt:
-		// ok
-	default:
-		_, _ = repositoryFactory.GetGoroutineRepository().SaveGoroutine(gt)
+	if g.ctx.Err() == nil {
+		select {
+		case g.inCh <- goroutineEvt{g: gt, isUpdate: false}:
+			return
+		default:
+			g.overflow.Add(1)
+		}
 	}
+	_, err := g.goroutines.SaveGoroutine(gt)
+	g.settle(err, record.OpInsert, gt)
 }
 
 func (g *goroutinePipeline) Update(gt *model.GoroutineTrace) {
-	evt := goroutineEvt{g: gt, isUpdate: true}
-	select {
-	case g.inCh <- evt:
-		// ok
-	default:
-		_ = repositoryFactory.GetGoroutineRepository().UpdateGoroutineTimeCost(gt.ID, gt.TimeCost, gt.IsFinished)
+	if g.ctx.Err() == nil {
+		select {
+		case g.inCh <- goroutineEvt{g: gt, isUpdate: true}:
+			return
+		default:
+			g.overflow.Add(1)
+		}
 	}
+	g.settle(g.goroutines.UpdateGoroutineTimeCost(gt.ID, gt.TimeCost, gt.IsFinished), record.OpUpdate, goroutineUpdate(gt))
+}
+
+func (g *goroutinePipeline) Depth() int {
+	return len(g.inCh)
 }
 
+// loop 串行写入；失败的写入进入重试队列，同一协程的插入与更新保持顺序。
+// 停止时写出通道中剩余的事件，重试队列中的写入最后尝试一次，仍失败的写入溢出文件
 func (g *goroutinePipeline) loop() {
+	retries := newRetryQueue(&g.pipelineCounters)
+	defer retries.stop()
 	for {
 		select {
 		case <-g.ctx.Done():
-			return
-		case evt := <-g.inCh:
-			if evt.isUpdate {
-				_ = repositoryFactory.GetGoroutineRepository().UpdateGoroutineTimeCost(evt.g.ID, evt.g.TimeCost, evt.g.IsFinished)
-			} else {
-				_, _ = repositoryFactory.GetGoroutineRepository().SaveGoroutine(evt.g)
+			for {
+				select {
+				case evt := <-g.inCh:
+					g.write(retries, evt)
+				default:
+					retries.drain(true)
+					return
+				}
 			}
+		case evt := <-g.inCh:
+			g.write(retries, evt)
+		case <-retries.C():
+			retries.drain(false)
 		}
 	}
 }
+
+// write 写入一个事件
+func (g *goroutinePipeline) write(retries *retryQueue, evt goroutineEvt) {
+	gt := evt.g
+	if evt.isUpdate {
+		retries.do(record.OpUpdate, goroutineUpdate(gt), gt.ID, func() error {
+			return g.goroutines.UpdateGoroutineTimeCost(gt.ID, gt.TimeCost, gt.IsFinished)
+		})
+		return
+	}
+	retries.do(record.OpInsert, gt, gt.ID, func() error {
+		_, err := g.goroutines.SaveGoroutine(gt)
+		return err
+	})
+}
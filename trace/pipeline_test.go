@@ -0,0 +1,513 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/domain/record"
+	"github.com/toheart/functrace/persistence/memory"
+	"github.com/toheart/functrace/persistence/sqlite"
+)
+
+func discardLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// useRepositoryFactory 在测试期间替换全局仓储工厂
+func useRepositoryFactory(t testing.TB, f domain.RepositoryFactory) {
+	prev := repositoryFactory
+	repositoryFactory = f
+	t.Cleanup(func() { repositoryFactory = prev })
+}
+
+// rowOnlyFactory 隐藏仓储的整行写入能力，使管道退化为逐条 INSERT + UPDATE
+type rowOnlyFactory struct {
+	domain.RepositoryFactory
+}
+
+func (f rowOnlyFactory) GetTraceRepository() domain.TraceRepository {
+	return struct{ domain.TraceRepository }{f.RepositoryFactory.GetTraceRepository()}
+}
+
+// failingFactory 跟踪数据的写入总是失败
+type failingFactory struct {
+	domain.RepositoryFactory
+}
+
+func (f failingFactory) GetTraceRepository() domain.TraceRepository {
+	return failingTraceRepository{f.RepositoryFactory.GetTraceRepository()}
+}
+
+type failingTraceRepository struct {
+	domain.TraceRepository
+}
+
+var errWriteFailed = errors.New("disk I/O error")
+
+func (failingTraceRepository) SaveTrace(*model.TraceData) (int64, error) { return 0, errWriteFailed }
+func (failingTraceRepository) UpdateTraceTimeCost(int64, string) error   { return errWriteFailed }
+func (failingTraceRepository) SaveTracesBatch([]*model.TraceData) error  { return errWriteFailed }
+
+func TestTracePipeline_BatchWritesCompleteRows(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	tp := newTracePipeline(ctx, db, 2, &wg, nil)
+
+	tp.Insert(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "0.00"))
+	tp.Insert(model.NewTraceData(2, "main.a", 1, 1, 1, 1, "t2", "0.01"))
+	tp.Update(&model.TraceData{ID: 2, TimeCost: "5ms", IsFinished: 1})
+	// 退出事件先于进入事件到达
+	tp.Update(&model.TraceData{ID: 3, TimeCost: "1ms", IsFinished: 1})
+	tp.Insert(model.NewTraceData(3, "main.b", 1, 1, 0, 1, "t3", "0.02"))
+
+	cancel()
+	wg.Wait()
+
+	got, err := db.FindTraceByID(2)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "main.a", got.Name)
+	assert.Equal(t, int64(1), got.ParentId)
+	assert.Equal(t, "5ms", got.TimeCost)
+	assert.Equal(t, 1, got.IsFinished)
+
+	got, err = db.FindTraceByID(3)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "1ms", got.TimeCost)
+	assert.Equal(t, 1, got.IsFinished)
+
+	// 停止时仍在执行的调用以未完成状态写出
+	got, err = db.FindTraceByID(1)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 0, got.IsFinished)
+	assert.Zero(t, tp.Counters().Dropped)
+}
+
+func TestTracePipeline_FlushesLongRunningCalls(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	tp := newTracePipeline(ctx, db, 1, &wg, nil)
+
+	tp.Insert(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "0.00"))
+	require.Eventually(t, func() bool {
+		got, _ := db.FindTraceByID(1)
+		return got != nil && got.IsFinished == 0
+	}, 5*traceFlushInterval, 10*time.Millisecond)
+
+	// 退出后整行覆盖先前写入的未完成行
+	tp.Update(&model.TraceData{ID: 1, TimeCost: "2s", IsFinished: 1})
+	require.Eventually(t, func() bool {
+		got, _ := db.FindTraceByID(1)
+		return got != nil && got.IsFinished == 1 && got.TimeCost == "2s"
+	}, 5*traceFlushInterval, 10*time.Millisecond)
+}
+
+func TestTracePipeline_RowModeFallback(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	tp := newTracePipeline(ctx, rowOnlyFactory{db}, 1, &wg, nil)
+
+	tp.Insert(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "0.00"))
+	tp.Update(&model.TraceData{ID: 1, TimeCost: "3ms", IsFinished: 1})
+	require.Eventually(t, func() bool {
+		got, _ := db.FindTraceByID(1)
+		return got != nil && got.IsFinished == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+}
+
+// gatedTraceRepository 不支持整行写入的跟踪仓储：写入 gateID 时通知 entered 并等待 gate 关闭
+type gatedTraceRepository struct {
+	domain.TraceRepository
+	gateID  int64
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (r gatedTraceRepository) SaveTrace(td *model.TraceData) (int64, error) {
+	if td.ID == r.gateID {
+		close(r.entered)
+		<-r.gate
+	}
+	return r.TraceRepository.SaveTrace(td)
+}
+
+type gatedTraceFactory struct {
+	domain.RepositoryFactory
+	repo gatedTraceRepository
+}
+
+func (f gatedTraceFactory) GetTraceRepository() domain.TraceRepository {
+	return f.repo
+}
+
+func TestTracePipeline_RowModeUpdatesOverflowInsert(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	repo := gatedTraceRepository{TraceRepository: db.GetTraceRepository(), gateID: 1, entered: make(chan struct{}), gate: make(chan struct{})}
+
+	spill := newSpillWriter(t.TempDir(), time.Now())
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	tp := newTracePipeline(ctx, gatedTraceFactory{db, repo}, 1, &wg, spill)
+
+	// 分片阻塞在第一条插入上，填满通道后的插入走降级路径同步写入
+	tp.Insert(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "0.00"))
+	<-repo.entered
+	for id := int64(2); trySend(tp, model.NewTraceData(id, "main.a", 1, 1, 1, 1, "t2", "0.01")); id++ {
+	}
+	const overflowID = 1 << 20
+	tp.Insert(model.NewTraceData(overflowID, "main.b", 1, 1, 1, 1, "t3", "0.02"))
+	close(repo.gate)
+
+	// 更新经由分片写入，不应等待分片从未见过的插入
+	require.Eventually(t, func() bool {
+		return trySend(tp, tpUpdateEvt{id: overflowID, timeCost: "7ms"})
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		got, _ := db.FindTraceByID(overflowID)
+		return got != nil && got.IsFinished == 1
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	wg.Wait()
+	require.NoError(t, spill.Close())
+
+	got, err := db.FindTraceByID(overflowID)
+	require.NoError(t, err)
+	assert.Equal(t, "7ms", got.TimeCost)
+	counters := tp.Counters()
+	assert.Equal(t, uint64(1), counters.Overflow)
+	assert.Zero(t, counters.Failed)
+	assert.Zero(t, counters.Spilled+counters.Dropped)
+}
+
+func TestTracePipeline_SpillsFailedWritesForReplay(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+
+	spill := newSpillWriter(t.TempDir(), time.Now())
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	tp := newTracePipeline(ctx, failingFactory{db}, 1, &wg, spill)
+
+	tp.Insert(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "0.00"))
+	tp.Update(&model.TraceData{ID: 1, TimeCost: "4ms", IsFinished: 1})
+	cancel()
+	wg.Wait()
+	require.NoError(t, spill.Close())
+
+	counters := tp.Counters()
+	assert.NotZero(t, counters.Failed)
+	assert.Equal(t, uint64(1), counters.Spilled)
+	assert.Zero(t, counters.Dropped)
+
+	files, err := ListSpillFiles(filepath.Dir(spill.Path()))
+	require.NoError(t, err)
+	require.Equal(t, []string{spill.Path()}, files)
+
+	res, err := ReplaySpillFile(spill.Path(), db)
+	require.NoError(t, err)
+	assert.Equal(t, SpillReplayResult{Applied: 1}, res)
+	got, err := db.FindTraceByID(1)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "main.main", got.Name)
+	assert.Equal(t, "4ms", got.TimeCost)
+	assert.Equal(t, 1, got.IsFinished)
+}
+
+func TestTracePipeline_DropsWithoutSpillFile(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	tp := newTracePipeline(ctx, rowOnlyFactory{failingFactory{db}}, 1, &wg, nil)
+
+	tp.Insert(model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "0.00"))
+	cancel()
+	wg.Wait()
+
+	counters := tp.Counters()
+	assert.NotZero(t, counters.Failed)
+	assert.Zero(t, counters.Spilled)
+	assert.Equal(t, uint64(1), counters.Dropped)
+}
+
+// BenchmarkTracePipeline 对比 SQLite 上逐条 INSERT + UPDATE 与退出后整行批量写入的吞吐
+func BenchmarkTracePipeline(b *testing.B) {
+	for _, mode := range []string{"row", "batch"} {
+		b.Run(mode, func(b *testing.B) {
+			db, err := sqlite.Open(filepath.Join(b.TempDir(), "bench.db"), discardLogger())
+			require.NoError(b, err)
+			defer db.Close()
+			var f domain.RepositoryFactory = db
+			if mode == "row" {
+				f = rowOnlyFactory{db}
+			}
+
+			var wg sync.WaitGroup
+			ctx, cancel := context.WithCancel(context.Background())
+			tp := newTracePipeline(ctx, f, 16, &wg, nil)
+
+			b.ResetTimer()
+			for i := 1; i <= b.N; i++ {
+				id := int64(i)
+				td := model.NewTraceData(id, fmt.Sprintf("main.f%d", i%32), uint64(i%8), 1, 0, 0, "t", "0.00")
+				for !trySend(tp, td) {
+					time.Sleep(time.Microsecond)
+				}
+				for !trySend(tp, tpUpdateEvt{id: id, timeCost: "1µs"}) {
+					time.Sleep(time.Microsecond)
+				}
+			}
+			cancel()
+			wg.Wait()
+			b.StopTimer()
+			assert.Zero(b, tp.Counters().Dropped)
+		})
+	}
+}
+
+// trySend 向分片投递事件，通道满时返回 false，避免基准测试走降级直写路径
+func trySend(tp *tracePipeline, evt interface{}) bool {
+	var id int64
+	switch e := evt.(type) {
+	case *model.TraceData:
+		id = e.ID
+	case tpUpdateEvt:
+		id = e.id
+	}
+	select {
+	case tp.shards[tp.shardIndex(id)].inCh <- evt:
+		return true
+	default:
+		return false
+	}
+}
+
+// flakyGoroutineRepository 协程仓储：gate 非 nil 时每次插入先等待其关闭，failOnce 中的ID首次插入失败
+type flakyGoroutineRepository struct {
+	domain.GoroutineRepository
+	gate     chan struct{}
+	mu       sync.Mutex
+	failOnce map[int64]bool
+}
+
+func (r *flakyGoroutineRepository) SaveGoroutine(g *model.GoroutineTrace) (int64, error) {
+	if r.gate != nil {
+		<-r.gate
+	}
+	r.mu.Lock()
+	fail := r.failOnce[g.ID]
+	delete(r.failOnce, g.ID)
+	r.mu.Unlock()
+	if fail {
+		return 0, errWriteFailed
+	}
+	return r.GoroutineRepository.SaveGoroutine(g)
+}
+
+type flakyGoroutineFactory struct {
+	domain.RepositoryFactory
+	repo *flakyGoroutineRepository
+}
+
+func (f flakyGoroutineFactory) GetGoroutineRepository() domain.GoroutineRepository {
+	return f.repo
+}
+
+func TestGoroutinePipeline_RetriesInOrderWithoutBlocking(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	repo := &flakyGoroutineRepository{GoroutineRepository: db.GetGoroutineRepository(), failOnce: map[int64]bool{1: true}}
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	p := newGoroutinePipeline(ctx, flakyGoroutineFactory{db, repo}, &wg, nil)
+
+	p.Insert(&model.GoroutineTrace{ID: 1, OriginGID: 10})
+	// 插入等待重试期间，同一协程的更新排在其后，其他协程不受影响
+	p.Update(&model.GoroutineTrace{ID: 1, TimeCost: "1s", IsFinished: 1})
+	p.Insert(&model.GoroutineTrace{ID: 2, OriginGID: 20})
+	require.Eventually(t, func() bool {
+		g, _ := db.GetGoroutineRepository().FindGoroutineByID(1)
+		return g != nil && g.IsFinished == 1
+	}, time.Second, 5*time.Millisecond)
+	_, err := db.GetGoroutineRepository().FindGoroutineByID(2)
+	require.NoError(t, err)
+
+	counters := p.Counters()
+	assert.Equal(t, uint64(1), counters.Failed)
+	assert.Zero(t, counters.Spilled+counters.Dropped)
+}
+
+func TestGoroutinePipeline_DrainsQueueOnStop(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	repo := &flakyGoroutineRepository{GoroutineRepository: db.GetGoroutineRepository(), gate: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newGoroutinePipeline(ctx, flakyGoroutineFactory{db, repo}, &wg, nil)
+
+	// 第一条插入阻塞在仓储中，其余事件留在通道里
+	for id := int64(1); id <= 10; id++ {
+		p.Insert(&model.GoroutineTrace{ID: id})
+	}
+	cancel()
+	close(repo.gate)
+	wg.Wait()
+
+	for id := int64(1); id <= 10; id++ {
+		_, err := db.GetGoroutineRepository().FindGoroutineByID(id)
+		require.NoError(t, err)
+	}
+	assert.Zero(t, p.Depth())
+}
+
+// gatedParamRepository 参数批量写入先等待 gate 关闭
+type gatedParamRepository struct {
+	domain.ParamRepository
+	gate chan struct{}
+}
+
+func (r gatedParamRepository) SaveParamsBatch(params []*model.ParamStoreData) error {
+	<-r.gate
+	return r.ParamRepository.SaveParamsBatch(params)
+}
+
+type gatedParamFactory struct {
+	domain.RepositoryFactory
+	params gatedParamRepository
+}
+
+func (f gatedParamFactory) GetParamRepository() domain.ParamRepository {
+	return f.params
+}
+
+func TestParamPipeline_DrainsQueueOnStop(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	repos := gatedParamFactory{db, gatedParamRepository{db.GetParamRepository(), make(chan struct{})}}
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newParamPipeline(ctx, repos, &TraceInstance{}, &wg)
+
+	// 第一批写出阻塞在仓储中，其余参数留在通道里
+	const n = 300
+	for id := int64(1); id <= n; id++ {
+		p.Enqueue(&model.ParamStoreData{ID: id, TraceID: id, Data: []byte("{}")})
+	}
+	cancel()
+	close(repos.params.gate)
+	wg.Wait()
+
+	for id := int64(1); id <= n; id++ {
+		params, err := db.GetParamRepository().FindParamsByTraceID(id)
+		require.NoError(t, err)
+		require.Len(t, params, 1, "trace %d", id)
+	}
+	assert.Zero(t, p.Counters().Overflow)
+}
+
+// writeSpillFile 写入一个溢出文件
+func writeSpillFile(t *testing.T, path string, records ...[3]interface{}) {
+	t.Helper()
+	var buf []byte
+	for _, r := range records {
+		line, err := record.Encode(r[0].(string), r[1].(string), r[2])
+		require.NoError(t, err)
+		buf = append(append(buf, line...), '\n')
+	}
+	require.NoError(t, os.WriteFile(path, buf, 0o644))
+}
+
+func TestFinishSpillFile_KeepsFailedRecords(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	path := filepath.Join(t.TempDir(), "app_20250101000000"+SpillFileExt)
+	writeSpillFile(t, path,
+		[3]interface{}{record.OpInsert, record.TableTrace, model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "")},
+		[3]interface{}{record.OpUpdate, record.TableTrace, record.TraceUpdate{ID: 2, TimeCost: "2ms", IsFinished: 1}},
+	)
+
+	// 对应的行尚不存在，更新失败后只保留该行
+	res, err := ReplaySpillFile(path, db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Applied)
+	assert.Equal(t, 1, res.Failed)
+	require.NoError(t, FinishSpillFile(path, res))
+	files, err := ListSpillFiles(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Equal(t, []string{path}, files)
+
+	// 再次导入时只重试失败的行，全部写入后重命名
+	_, err = db.GetTraceRepository().SaveTrace(model.NewTraceData(2, "main.a", 1, 1, 0, 1, "t2", ""))
+	require.NoError(t, err)
+	res, err = ReplaySpillFile(path, db)
+	require.NoError(t, err)
+	assert.Equal(t, SpillReplayResult{Applied: 1}, res)
+	require.NoError(t, FinishSpillFile(path, res))
+	assert.FileExists(t, path+ReplayedFileExt)
+	assert.NoFileExists(t, path)
+	got, err := db.FindTraceByID(2)
+	require.NoError(t, err)
+	assert.Equal(t, "2ms", got.TimeCost)
+}
+
+func TestSpillReplay_RunsInBackground(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	useRepositoryFactory(t, db)
+	dir := t.TempDir()
+	old := filepath.Join(dir, "app_20250101000000"+SpillFileExt)
+	writeSpillFile(t, old, [3]interface{}{record.OpInsert, record.TableTrace, model.NewTraceData(1, "main.main", 1, 0, 0, 0, "t1", "")})
+
+	inst := &TraceInstance{
+		config: &Config{SpillDir: dir, SpillReplay: true},
+		log:    discardLogger(),
+		ctx:    context.Background(),
+		spill:  newSpillWriter(dir, time.Now()),
+	}
+	// 当前运行的溢出文件不参与导入
+	require.NoError(t, inst.spill.Write(record.OpInsert, record.TableTrace, model.NewTraceData(2, "main.a", 1, 0, 0, 0, "t2", "")))
+	defer inst.spill.Close()
+
+	inst.startSpillReplay()
+	<-inst.replayDone
+	inst.stopSpillReplay()
+
+	assert.FileExists(t, old+ReplayedFileExt)
+	assert.FileExists(t, inst.spill.Path())
+	got, err := db.FindTraceByID(1)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	_, err = db.FindTraceByID(2)
+	assert.Error(t, err)
+}
@@ -32,8 +32,27 @@ func (r *SessionRegistry) GetOrCreate(gid uint64) *TraceSession {
 	return s
 }
 
+// Get 返回已存在的会话，不存在时不创建
+func (r *SessionRegistry) Get(gid uint64) (*TraceSession, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.table[gid]
+	return s, ok
+}
+
 func (r *SessionRegistry) Remove(gid uint64) {
 	r.mu.Lock()
 	delete(r.table, gid)
 	r.mu.Unlock()
 }
+
+// Snapshot 返回当前全部会话的副本，避免遍历时持有注册表锁
+func (r *SessionRegistry) Snapshot() map[uint64]*TraceSession {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[uint64]*TraceSession, len(r.table))
+	for gid, s := range r.table {
+		result[gid] = s
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// newRun 收集本次运行的元数据：可执行文件、构建信息、主机、进程与生效配置
+func newRun(config *Config, startedAt time.Time) *model.Run {
+	run := &model.Run{
+		UUID:      uuid.NewString(),
+		PID:       os.Getpid(),
+		StartedAt: startedAt.Format(TimeFormat),
+		Status:    model.RunStatusRunning,
+	}
+	run.Executable, _ = os.Executable()
+	run.Hostname, _ = os.Hostname()
+	if args, err := json.Marshal(os.Args); err == nil {
+		run.Args = string(args)
+	}
+	if config != nil {
+		if data, err := json.Marshal(config); err == nil {
+			run.Config = string(data)
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		run.GoVersion = info.GoVersion
+		run.ModulePath = info.Main.Path
+		run.ModuleVersion = info.Main.Version
+		run.BuildInfo = info.String()
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				run.VCSRevision = s.Value
+			case "vcs.time":
+				run.VCSTime = s.Value
+			case "vcs.modified":
+				run.VCSModified = s.Value == "true"
+			}
+		}
+	}
+	return run
+}
+
+// startRun 在支持运行记录的仓储中登记本次运行，并把ID生成器移到该运行的ID命名空间
+// 需在生成任何ID之前调用
+func (t *TraceInstance) startRun() {
+	provider, ok := repositoryFactory.(domain.RunRepositoryProvider)
+	if !ok {
+		return
+	}
+	run := newRun(t.config, currentNow)
+	id, err := provider.GetRunRepository().StartRun(run)
+	if err != nil {
+		t.log.WithFields(logrus.Fields{"error": err}).Error("register run failed")
+		return
+	}
+	run.ID = id
+	t.run = run
+	t.setIDBase(run.IDBase())
+	t.log.WithFields(logrus.Fields{"run": run.ID, "uuid": run.UUID}).Info("run registered")
+}
+
+// setIDBase 设置跟踪、参数与协程ID的起始偏移
+func (t *TraceInstance) setIDBase(base int64) {
+	if base == 0 {
+		return
+	}
+	if g, ok := t.idGen.(*StripedIDGenerator); ok {
+		g.SetBase(base)
+	}
+	t.globalId.Store(base)
+	t.gParamId.Store(base)
+	t.gGroutineId.Store(uint64(base))
+}
+
+// endRun 关闭时记录运行结束，未执行到此处的运行保持 running 状态
+func (t *TraceInstance) endRun() {
+	if t.run == nil {
+		return
+	}
+	provider, ok := repositoryFactory.(domain.RunRepositoryProvider)
+	if !ok {
+		return
+	}
+	if err := provider.GetRunRepository().EndRun(t.run.ID, time.Now().Format(TimeFormat), model.RunStatusClean); err != nil {
+		t.log.WithFields(logrus.Fields{"error": err, "run": t.run.ID}).Error("end run failed")
+	}
+}
+
+// Run 返回本次运行的元数据，仓储不支持运行记录时返回 nil
+func (t *TraceInstance) Run() *model.Run {
+	if t.run == nil {
+		return nil
+	}
+	run := *t.run
+	return &run
+}
@@ -0,0 +1,54 @@
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/memory"
+)
+
+func TestNewRun_CollectsMetadata(t *testing.T) {
+	run := newRun(&Config{DBType: "sqlite", MaxDepth: 7}, currentNow)
+	assert.NotEmpty(t, run.UUID)
+	assert.Equal(t, os.Getpid(), run.PID)
+	assert.Equal(t, model.RunStatusRunning, run.Status)
+	assert.NotEmpty(t, run.Executable)
+	assert.NotEmpty(t, run.GoVersion)
+	assert.Contains(t, run.BuildInfo, "go")
+
+	var args []string
+	require.NoError(t, json.Unmarshal([]byte(run.Args), &args))
+	assert.Equal(t, os.Args, args)
+	var config Config
+	require.NoError(t, json.Unmarshal([]byte(run.Config), &config))
+	assert.Equal(t, 7, config.MaxDepth)
+}
+
+func TestStartRun_UsesRunIDSpace(t *testing.T) {
+	db := memory.NewMemDatabase(memory.Config{}, discardLogger())
+	useRepositoryFactory(t, db)
+	_, err := db.GetRunRepository().StartRun(&model.Run{Status: model.RunStatusClean})
+	require.NoError(t, err)
+
+	inst := newInFlightTestInstance()
+	inst.log = discardLogger()
+	inst.config = &Config{}
+	inst.startRun()
+	require.NotNil(t, inst.Run())
+	assert.Equal(t, int64(2), inst.Run().ID)
+
+	// 第二次运行的全部ID都落在该运行的命名空间
+	for _, id := range []int64{inst.nextTraceID(1), inst.idGen.NextParamID(1), int64(inst.gGroutineId.Add(1))} {
+		assert.Equal(t, int64(2), model.RunIDOf(id))
+	}
+
+	inst.endRun()
+	run, err := db.GetRunRepository().FindRunByID(2)
+	require.NoError(t, err)
+	assert.Equal(t, model.RunStatusClean, run.Status)
+	assert.NotEmpty(t, run.EndedAt)
+}
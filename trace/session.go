@@ -2,14 +2,29 @@ package trace
 
 import (
 	"sync"
+	"time"
 )
 
+// traceFrame 会话内尚未退出的调用帧
+type traceFrame struct {
+	traceID   int64
+	name      string
+	startTime time.Time
+	childCost time.Duration // 已退出的直接子调用耗时之和
+	slowFired bool          // 看门狗是否已对该帧触发过
+}
+
 // TraceSession 表示单个goroutine的独享状态
 type TraceSession struct {
 	mu      sync.Mutex
 	gid     uint64
 	indent  int
 	parents map[int]int64
+	frames  []*traceFrame
+
+	// 最近一次进入的调用（用于泄漏报告）
+	lastTraceID int64
+	lastName    string
 
 	// 会话内数据队列与转发器
 	opCh          chan *DataOp
@@ -33,7 +48,7 @@ func NewTraceSession(gid uint64) *TraceSession {
 }
 
 // PrepareEnter 计算进入时所需的信息，并更新本会话状态
-func (s *TraceSession) PrepareEnter(inst *TraceInstance) (indent int, parentId int64, traceId int64) {
+func (s *TraceSession) PrepareEnter(inst *TraceInstance, name string, startTime time.Time) (indent int, parentId int64, traceId int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -52,16 +67,108 @@ func (s *TraceSession) PrepareEnter(inst *TraceInstance) (indent int, parentId i
 	// 更新父映射与缩进
 	s.parents[indent] = traceId
 	s.indent++
+	s.frames = append(s.frames, &traceFrame{traceID: traceId, name: name, startTime: startTime})
+	s.lastTraceID = traceId
+	s.lastName = name
 
 	return indent, parentId, traceId
 }
 
-// OnExit 在退出时回退缩进并返回退出前的缩进值
-func (s *TraceSession) OnExit() int {
+// OpenFrames 返回当前尚未退出的调用帧快照（由外到内），可与热路径并发调用
+func (s *TraceSession) OpenFrames(now time.Time) []FrameInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := make([]FrameInfo, 0, len(s.frames))
+	for depth, f := range s.frames {
+		frames = append(frames, FrameInfo{
+			TraceID:   f.traceID,
+			Name:      f.name,
+			Depth:     depth,
+			StartTime: f.startTime,
+			Elapsed:   now.Sub(f.startTime),
+		})
+	}
+	return frames
+}
+
+// LastFrame 返回最后一个被跟踪的调用：存在未退出帧时为最内层帧，否则为最近一次进入的调用
+func (s *TraceSession) LastFrame() (traceID int64, name string, finished bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.frames); n > 0 {
+		top := s.frames[n-1]
+		return top.traceID, top.name, false
+	}
+	return s.lastTraceID, s.lastName, s.lastTraceID != 0
+}
+
+// RootTraceID 返回最外层未退出帧的跟踪ID，没有时返回0
+func (s *TraceSession) RootTraceID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.frames) > 0 {
+		return s.frames[0].traceID
+	}
+	return 0
+}
+
+// CurrentTraceID 返回最内层未退出帧的跟踪ID，没有时返回0
+func (s *TraceSession) CurrentTraceID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.frames); n > 0 {
+		return s.frames[n-1].traceID
+	}
+	return 0
+}
+
+// CollectOverdue 返回超过截止时间且尚未触发过的调用帧，并标记为已触发
+// deadline 返回函数的截止时间，0 表示不监控
+func (s *TraceSession) CollectOverdue(now time.Time, deadline func(name string) time.Duration) []FrameInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var overdue []FrameInfo
+	for depth, f := range s.frames {
+		if f.slowFired {
+			continue
+		}
+		limit := deadline(f.name)
+		if limit <= 0 || now.Sub(f.startTime) < limit {
+			continue
+		}
+		f.slowFired = true
+		overdue = append(overdue, FrameInfo{
+			TraceID:   f.traceID,
+			Name:      f.name,
+			Depth:     depth,
+			StartTime: f.startTime,
+			Elapsed:   now.Sub(f.startTime),
+		})
+	}
+	return overdue
+}
+
+// OnExit 在退出时回退缩进，返回退出前的缩进值与本帧的自身耗时（扣除子调用）
+func (s *TraceSession) OnExit(cost time.Duration) (int, time.Duration) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	indent := s.indent
+	self := cost
+	if n := len(s.frames); n > 0 {
+		top := s.frames[n-1]
+		s.frames[n-1] = nil
+		s.frames = s.frames[:n-1]
+		self -= top.childCost
+		if self < 0 {
+			self = 0
+		}
+		if n > 1 {
+			s.frames[n-2].childCost += cost
+		}
+	}
 	// 回退缩进，防止变为负数
 	if s.indent > 0 {
 		s.indent--
@@ -71,8 +178,9 @@ func (s *TraceSession) OnExit() int {
 		// 如果已经是0或负数，重置状态
 		s.indent = 0
 		s.parents = make(map[int]int64)
+		s.frames = nil
 	}
-	return indent
+	return indent, self
 }
 
 // EnsureForwarder 确保为该会话启动一个转发器，将会话内的操作转发到实例的发送通道
@@ -126,7 +234,10 @@ func (s *TraceSession) Close() {
 	s.mu.Unlock()
 	// 等待在途入队完成
 	s.wg.Wait()
-	// 关闭通道并等待转发器退出
+	// 关闭通道并等待转发器退出；转发器从未启动时直接标记完成
 	close(s.opCh)
+	s.forwarderOnce.Do(func() {
+		close(s.forwarderDone)
+	})
 	<-s.forwarderDone
 }
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package trace
+
+import "os"
+
+// lookupSignal 非 Unix 平台不支持信号触发
+func lookupSignal(name string) os.Signal {
+	return nil
+}
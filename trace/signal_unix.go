@@ -0,0 +1,24 @@
+//go:build unix
+
+package trace
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// lookupSignal 将配置中的信号名解析为系统信号
+func lookupSignal(name string) os.Signal {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "QUIT":
+		return syscall.SIGQUIT
+	case "USR1":
+		return syscall.SIGUSR1
+	case "USR2":
+		return syscall.SIGUSR2
+	case "HUP":
+		return syscall.SIGHUP
+	}
+	return nil
+}
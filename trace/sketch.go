@@ -0,0 +1,186 @@
+package trace
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// 延迟分布采用 DDSketch 风格的对数分桶：相对误差固定，桶计数可直接相加合并
+const (
+	// sketchRelativeAccuracy 分位数的相对误差
+	sketchRelativeAccuracy = 0.01
+	// sketchMinValue 最小可区分的耗时，更小的值统一落入0号桶
+	sketchMinValue = float64(time.Microsecond)
+	// sketchBucketCount 桶数量，覆盖 1µs ~ 约 7 小时
+	sketchBucketCount = 1200
+	// sketchChunkSize 每个分块的桶数量，分块按需分配以节省内存
+	sketchChunkSize  = 64
+	sketchChunkCount = (sketchBucketCount + sketchChunkSize - 1) / sketchChunkSize
+)
+
+var (
+	sketchGamma    = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+type sketchChunk [sketchChunkSize]atomic.Uint64
+
+// LatencySketch 无锁的可合并延迟分布
+type LatencySketch struct {
+	chunks [sketchChunkCount]atomic.Pointer[sketchChunk]
+	count  atomic.Uint64
+}
+
+// NewLatencySketch 创建空的延迟分布
+func NewLatencySketch() *LatencySketch {
+	return &LatencySketch{}
+}
+
+// sketchIndex 计算耗时对应的桶下标
+func sketchIndex(d time.Duration) int {
+	v := float64(d)
+	if v <= sketchMinValue {
+		return 0
+	}
+	idx := int(math.Ceil(math.Log(v/sketchMinValue) / sketchLogGamma))
+	if idx >= sketchBucketCount {
+		return sketchBucketCount - 1
+	}
+	return idx
+}
+
+// sketchValue 返回桶下标对应的代表值
+func sketchValue(idx int) time.Duration {
+	if idx <= 0 {
+		return time.Duration(sketchMinValue)
+	}
+	return time.Duration(sketchMinValue * math.Pow(sketchGamma, float64(idx)) * 2 / (1 + sketchGamma))
+}
+
+// bucket 返回下标对应的计数器，必要时分配分块
+func (s *LatencySketch) bucket(idx int) *atomic.Uint64 {
+	slot := &s.chunks[idx/sketchChunkSize]
+	chunk := slot.Load()
+	if chunk == nil {
+		fresh := new(sketchChunk)
+		if slot.CompareAndSwap(nil, fresh) {
+			chunk = fresh
+		} else {
+			chunk = slot.Load()
+		}
+	}
+	return &chunk[idx%sketchChunkSize]
+}
+
+// Add 记录一次耗时
+func (s *LatencySketch) Add(d time.Duration) {
+	s.addCount(sketchIndex(d), 1)
+}
+
+func (s *LatencySketch) addCount(idx int, n uint64) {
+	s.bucket(idx).Add(n)
+	s.count.Add(n)
+}
+
+// Count 返回记录的总次数
+func (s *LatencySketch) Count() uint64 {
+	return s.count.Load()
+}
+
+// Merge 将另一个分布合并到当前分布
+func (s *LatencySketch) Merge(other *LatencySketch) {
+	if other == nil {
+		return
+	}
+	other.forEach(func(idx int, n uint64) {
+		s.addCount(idx, n)
+	})
+}
+
+// forEach 按桶下标升序遍历非空桶
+func (s *LatencySketch) forEach(fn func(idx int, n uint64)) {
+	for c := range s.chunks {
+		chunk := s.chunks[c].Load()
+		if chunk == nil {
+			continue
+		}
+		for i := range chunk {
+			if n := chunk[i].Load(); n > 0 {
+				fn(c*sketchChunkSize+i, n)
+			}
+		}
+	}
+}
+
+// Quantile 返回 q 分位的耗时估计值，q 取值 [0, 1]
+func (s *LatencySketch) Quantile(q float64) time.Duration {
+	total := s.count.Load()
+	if total == 0 {
+		return 0
+	}
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+	rank := uint64(q * float64(total-1))
+	var (
+		cum    uint64
+		result = -1
+		last   int
+	)
+	s.forEach(func(idx int, n uint64) {
+		last = idx
+		if result >= 0 {
+			return
+		}
+		cum += n
+		if cum > rank {
+			result = idx
+		}
+	})
+	if result < 0 {
+		// 并发写入导致计数尚未可见时，退化为最大桶
+		result = last
+	}
+	return sketchValue(result)
+}
+
+// Encode 将分布编码为紧凑的二进制（下标增量与计数的 uvarint 序列）
+func (s *LatencySketch) Encode() []byte {
+	buf := make([]byte, 0, 64)
+	prev := 0
+	s.forEach(func(idx int, n uint64) {
+		buf = binary.AppendUvarint(buf, uint64(idx-prev))
+		buf = binary.AppendUvarint(buf, n)
+		prev = idx
+	})
+	return buf
+}
+
+// DecodeLatencySketch 从 Encode 的输出还原延迟分布
+func DecodeLatencySketch(data []byte) (*LatencySketch, error) {
+	s := NewLatencySketch()
+	idx := 0
+	for len(data) > 0 {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid sketch index")
+		}
+		data = data[n:]
+		cnt, m := binary.Uvarint(data)
+		if m <= 0 {
+			return nil, errors.New("invalid sketch count")
+		}
+		data = data[m:]
+		idx += int(delta)
+		if idx >= sketchBucketCount {
+			return nil, errors.New("sketch index out of range")
+		}
+		s.addCount(idx, cnt)
+	}
+	return s, nil
+}
@@ -0,0 +1,282 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/record"
+)
+
+// 溢出文件命名：<可执行文件名>_<启动时间>.spill.jsonl，全部导入后重命名为 *.spill.jsonl.replayed
+const (
+	SpillFileExt     = ".spill.jsonl"
+	ReplayedFileExt  = ".replayed"
+	spillTimeLayout  = "20060102150405"
+	spillNameFormat  = "%s_%s" + SpillFileExt
+	spillReadBufSize = 64 << 10
+)
+
+// errSpillDisabled 未配置溢出文件
+var errSpillDisabled = errors.New("spill file is disabled")
+
+// spillWriter 将重试后仍写入失败的记录追加到本地溢出文件
+// 文件内容与 jsonl 后端的记录格式相同，首条记录写入时才创建文件
+type spillWriter struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	created bool
+}
+
+// newSpillWriter 创建写入 dir 目录的溢出文件写入器，dir 为空或 "off" 时返回 nil
+func newSpillWriter(dir string, startedAt time.Time) *spillWriter {
+	if dir == "" || dir == SpillDisabled {
+		return nil
+	}
+	exe := "functrace"
+	if path, err := os.Executable(); err == nil {
+		exe = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &spillWriter{path: filepath.Join(dir, fmt.Sprintf(spillNameFormat, exe, startedAt.Format(spillTimeLayout)))}
+}
+
+// Write 追加一条记录；直接写入文件而不经过缓冲，进程崩溃时已写入的记录不会丢失
+func (w *spillWriter) Write(op, table string, data interface{}) error {
+	if w == nil {
+		return errSpillDisabled
+	}
+	line, err := record.Encode(op, table, data)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+			return fmt.Errorf("create spill dir error: %w", err)
+		}
+		f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("open spill file error: %w", err)
+		}
+		w.file = f
+		w.created = true
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write spill file error: %w", err)
+	}
+	return nil
+}
+
+// Path 返回溢出文件路径，未写入过记录时返回空字符串
+func (w *spillWriter) Path() string {
+	if w == nil {
+		return ""
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.created {
+		return ""
+	}
+	return w.path
+}
+
+// Close 刷盘并关闭溢出文件
+func (w *spillWriter) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	return err
+}
+
+// ListSpillFiles 返回目录下尚未导入的溢出文件，按文件名即写入时间排序
+func ListSpillFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read spill dir error: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), SpillFileExt) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// SpillReplayResult 导入溢出文件的结果
+type SpillReplayResult struct {
+	Applied  int      // 成功写入的记录数
+	Failed   int      // 写入失败被跳过的记录数
+	Rejected [][]byte // 写入失败或因中止而未导入的行，由 FinishSpillFile 写回溢出文件
+}
+
+// ReplaySpillFile 将溢出文件中的记录逐条写入目标仓储
+// 单条记录失败不会中止导入（如行已由其他途径写入），失败数计入结果
+func ReplaySpillFile(path string, target domain.RepositoryFactory) (SpillReplayResult, error) {
+	return replaySpillFile(context.Background(), path, target)
+}
+
+// replaySpillFile 导入溢出文件，ctx 取消后剩余的行不再写入，计入 Rejected
+// 每行单独写出，失败的行（含参数）可以准确地保留下来
+func replaySpillFile(ctx context.Context, path string, target domain.RepositoryFactory) (SpillReplayResult, error) {
+	var res SpillReplayResult
+	f, err := os.Open(path)
+	if err != nil {
+		return res, fmt.Errorf("open spill file error: %w", err)
+	}
+	defer f.Close()
+
+	a := record.NewApplier(target)
+	apply := func(line []byte) error {
+		if err := a.Apply(line); err != nil {
+			return err
+		}
+		return a.Flush()
+	}
+	br := bufio.NewReaderSize(f, spillReadBufSize)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			switch {
+			case ctx.Err() != nil:
+				res.Rejected = append(res.Rejected, line)
+			case apply(line) != nil:
+				res.Failed++
+				res.Rejected = append(res.Rejected, line)
+				// 丢弃写入失败后仍留在缓冲中的参数
+				a = record.NewApplier(target)
+			default:
+				res.Applied++
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("read spill file error: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// FinishSpillFile 处理导入后的溢出文件：全部写入时重命名为 *.replayed，
+// 否则改写为只包含未写入的行，下次导入时重试
+func FinishSpillFile(path string, res SpillReplayResult) error {
+	if len(res.Rejected) == 0 {
+		if err := os.Rename(path, path+ReplayedFileExt); err != nil {
+			return fmt.Errorf("rename replayed spill file error: %w", err)
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, line := range res.Rejected {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write spill file error: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace spill file error: %w", err)
+	}
+	return nil
+}
+
+// startSpillReplay 按配置在后台导入此前运行遗留的溢出文件，不阻塞首次被跟踪的调用
+func (t *TraceInstance) startSpillReplay() {
+	dir := t.config.SpillDir
+	if !t.config.SpillReplay || dir == "" || dir == SpillDisabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.ctx)
+	done := make(chan struct{})
+	t.replayCancel, t.replayDone = cancel, done
+	target := repositoryFactory
+	go func() {
+		defer close(done)
+		t.replaySpillFiles(ctx, dir, target)
+	}()
+}
+
+// stopSpillReplay 中止尚未完成的导入并等待其退出，未导入的行留在溢出文件中
+func (t *TraceInstance) stopSpillReplay() {
+	if t.replayDone == nil {
+		return
+	}
+	t.replayCancel()
+	<-t.replayDone
+}
+
+// replaySpillFiles 将溢出目录中遗留的溢出文件导入 target，跳过当前运行的溢出文件
+func (t *TraceInstance) replaySpillFiles(ctx context.Context, dir string, target domain.RepositoryFactory) {
+	files, err := ListSpillFiles(dir)
+	if err != nil {
+		t.log.WithFields(logrus.Fields{"error": err, "dir": dir}).Warn("list spill files failed")
+		return
+	}
+	for _, path := range files {
+		if t.spill != nil && path == t.spill.path {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		res, err := replaySpillFile(ctx, path, target)
+		if err != nil {
+			t.log.WithFields(logrus.Fields{"error": err, "file": path}).Error("replay spill file failed")
+			continue
+		}
+		if err := FinishSpillFile(path, res); err != nil {
+			t.log.WithFields(logrus.Fields{"error": err, "file": path}).Warn("finish replayed spill file failed")
+		}
+		t.log.WithFields(logrus.Fields{"file": path, "applied": res.Applied, "failed": res.Failed, "remaining": len(res.Rejected)}).Info("spill file replayed")
+	}
+}
+
+// logPipelineFailures 关闭时汇总各管道的写入失败，提示溢出文件位置
+func (t *TraceInstance) logPipelineFailures() {
+	stats := t.pipelines.Counters()
+	var failed, spilled, dropped uint64
+	for _, c := range []PipelineCounters{stats.Trace, stats.Param, stats.Goroutine} {
+		failed += c.Failed
+		spilled += c.Spilled
+		dropped += c.Dropped
+	}
+	if failed == 0 && spilled == 0 && dropped == 0 {
+		return
+	}
+	t.log.WithFields(logrus.Fields{
+		"failed":     failed,
+		"spilled":    spilled,
+		"dropped":    dropped,
+		"spill_file": t.spill.Path(),
+		"trace":      stats.Trace,
+		"param":      stats.Param,
+		"goroutine":  stats.Goroutine,
+	}).Warn("some records could not be written to the repository")
+}
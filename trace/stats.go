@@ -0,0 +1,148 @@
+package trace
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// funcStat 单个函数的实时聚合，全部字段均为原子操作
+type funcStat struct {
+	name     string
+	count    atomic.Int64
+	errCount atomic.Int64
+	total    atomic.Int64
+	self     atomic.Int64
+	max      atomic.Int64
+	sketch   *LatencySketch
+}
+
+// StatsCollector 进程内按函数聚合的延迟统计
+// 热路径只在首次遇到函数时写 sync.Map，其余均为原子操作，不持有全局锁
+type StatsCollector struct {
+	funcs sync.Map // name -> *funcStat
+}
+
+// NewStatsCollector 创建统计收集器
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{}
+}
+
+// get 获取或创建函数的聚合项
+func (c *StatsCollector) get(name string) *funcStat {
+	if v, ok := c.funcs.Load(name); ok {
+		return v.(*funcStat)
+	}
+	v, _ := c.funcs.LoadOrStore(name, &funcStat{name: name, sketch: NewLatencySketch()})
+	return v.(*funcStat)
+}
+
+// Record 记录一次函数调用
+func (c *StatsCollector) Record(name string, cost, self time.Duration, failed bool) {
+	fs := c.get(name)
+	fs.count.Add(1)
+	if failed {
+		fs.errCount.Add(1)
+	}
+	fs.total.Add(int64(cost))
+	fs.self.Add(int64(self))
+	for {
+		cur := fs.max.Load()
+		if int64(cost) <= cur || fs.max.CompareAndSwap(cur, int64(cost)) {
+			break
+		}
+	}
+	fs.sketch.Add(cost)
+}
+
+// Snapshot 返回当前全部函数的统计快照，按总耗时降序
+func (c *StatsCollector) Snapshot() []*model.FuncStats {
+	now := time.Now().Format(TimeFormat)
+	var result []*model.FuncStats
+	c.funcs.Range(func(_, v interface{}) bool {
+		fs := v.(*funcStat)
+		result = append(result, &model.FuncStats{
+			Name:       fs.name,
+			Count:      fs.count.Load(),
+			ErrorCount: fs.errCount.Load(),
+			TotalTime:  fs.total.Load(),
+			SelfTime:   fs.self.Load(),
+			MaxTime:    fs.max.Load(),
+			P50:        int64(fs.sketch.Quantile(0.50)),
+			P95:        int64(fs.sketch.Quantile(0.95)),
+			P99:        int64(fs.sketch.Quantile(0.99)),
+			Sketch:     fs.sketch.Encode(),
+			UpdatedAt:  now,
+		})
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalTime != result[j].TotalTime {
+			return result[i].TotalTime > result[j].TotalTime
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Stats 返回当前进程内各函数的统计快照
+func (t *TraceInstance) Stats() []*model.FuncStats {
+	if t.stats == nil {
+		return nil
+	}
+	return t.stats.Snapshot()
+}
+
+// persistStats 将统计快照写入支持统计表的仓储
+func (t *TraceInstance) persistStats() {
+	if t.stats == nil || repositoryFactory == nil {
+		return
+	}
+	provider, ok := repositoryFactory.(domain.StatsRepositoryProvider)
+	if !ok {
+		return
+	}
+	snapshot := t.stats.Snapshot()
+	if len(snapshot) == 0 {
+		return
+	}
+	if t.run != nil {
+		for _, s := range snapshot {
+			s.RunID = t.run.ID
+		}
+	}
+	if err := provider.GetStatsRepository().SaveFuncStats(snapshot); err != nil {
+		t.log.WithFields(logrus.Fields{"error": err}).Error("persist function stats failed")
+		return
+	}
+	t.log.WithFields(logrus.Fields{"count": len(snapshot)}).Info("function stats persisted")
+}
+
+// summarize 关闭时在支持汇总表的仓储中重新计算本次运行的分析汇总，其他运行由 functrace-export summarize 重建
+// 仓储不支持运行记录时重新计算全部汇总
+func (t *TraceInstance) summarize() {
+	if !t.config.SummarizeOnClose || repositoryFactory == nil {
+		return
+	}
+	summarizer, ok := repositoryFactory.(domain.Summarizer)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	var err error
+	if t.run != nil {
+		err = summarizer.SummarizeRun(t.run.ID)
+	} else {
+		err = summarizer.Summarize()
+	}
+	if err != nil {
+		t.log.WithFields(logrus.Fields{"error": err}).Error("summarize traces failed")
+		return
+	}
+	t.log.WithFields(logrus.Fields{"elapsed": time.Since(start).String()}).Info("summary tables written")
+}
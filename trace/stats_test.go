@@ -0,0 +1,146 @@
+package trace
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toheart/functrace/domain/model"
+	"github.com/toheart/functrace/persistence/memory"
+)
+
+func TestLatencySketch_Quantile(t *testing.T) {
+	s := NewLatencySketch()
+	for i := 1; i <= 1000; i++ {
+		s.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		name     string
+		q        float64
+		expected time.Duration
+	}{
+		{name: "p50", q: 0.50, expected: 500 * time.Millisecond},
+		{name: "p95", q: 0.95, expected: 950 * time.Millisecond},
+		{name: "p99", q: 0.99, expected: 990 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Quantile(tt.q)
+			assert.InEpsilon(t, float64(tt.expected), float64(got), 2*sketchRelativeAccuracy)
+		})
+	}
+	assert.Equal(t, uint64(1000), s.Count())
+}
+
+func TestLatencySketch_Empty(t *testing.T) {
+	s := NewLatencySketch()
+	assert.Equal(t, time.Duration(0), s.Quantile(0.5))
+	assert.Empty(t, s.Encode())
+}
+
+func TestLatencySketch_MergeAndEncode(t *testing.T) {
+	a := NewLatencySketch()
+	b := NewLatencySketch()
+	for i := 0; i < 100; i++ {
+		a.Add(time.Millisecond)
+		b.Add(time.Second)
+	}
+	a.Merge(b)
+	assert.Equal(t, uint64(200), a.Count())
+	assert.InEpsilon(t, float64(time.Second), float64(a.Quantile(0.99)), 2*sketchRelativeAccuracy)
+
+	decoded, err := DecodeLatencySketch(a.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, a.Count(), decoded.Count())
+	assert.Equal(t, a.Quantile(0.5), decoded.Quantile(0.5))
+
+	_, err = DecodeLatencySketch([]byte{0xff})
+	assert.Error(t, err)
+}
+
+func TestStatsCollector_Record(t *testing.T) {
+	c := NewStatsCollector()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c.Record("pkg.Fast", time.Millisecond, time.Millisecond, false)
+			}
+		}()
+	}
+	wg.Wait()
+	c.Record("pkg.Slow", 10*time.Second, 2*time.Second, true)
+
+	snapshot := c.Snapshot()
+	require.Len(t, snapshot, 2)
+
+	// 按总耗时降序
+	assert.Equal(t, "pkg.Slow", snapshot[0].Name)
+	assert.Equal(t, int64(1), snapshot[0].ErrorCount)
+	assert.Equal(t, int64(2*time.Second), snapshot[0].SelfTime)
+	assert.Equal(t, int64(10*time.Second), snapshot[0].MaxTime)
+
+	fast := snapshot[1]
+	assert.Equal(t, int64(800), fast.Count)
+	assert.Equal(t, int64(800*time.Millisecond), fast.TotalTime)
+	assert.Equal(t, int64(time.Millisecond), fast.AvgTime())
+	assert.NotEmpty(t, fast.Sketch)
+}
+
+func TestTraceSession_SelfCost(t *testing.T) {
+	inst := &TraceInstance{idGen: NewStripedIDGenerator(4)}
+	s := NewTraceSession(1)
+	now := time.Now()
+
+	s.PrepareEnter(inst, "parent", now)
+	s.PrepareEnter(inst, "child", now)
+
+	indent, self := s.OnExit(30 * time.Millisecond)
+	assert.Equal(t, 2, indent)
+	assert.Equal(t, 30*time.Millisecond, self)
+
+	indent, self = s.OnExit(100 * time.Millisecond)
+	assert.Equal(t, 1, indent)
+	assert.Equal(t, 70*time.Millisecond, self)
+}
+
+// summarizingFactory 记录 Summarize 调用的仓储
+type summarizingFactory struct {
+	*memory.MemDatabase
+	calls int
+	runs  []int64
+}
+
+func (f *summarizingFactory) Summarize() error {
+	f.calls++
+	return nil
+}
+
+func (f *summarizingFactory) SummarizeRun(runID int64) error {
+	f.runs = append(f.runs, runID)
+	return nil
+}
+
+func TestSummarize_OnlyWhenEnabled(t *testing.T) {
+	f := &summarizingFactory{MemDatabase: memory.NewMemDatabase(memory.Config{}, discardLogger())}
+	useRepositoryFactory(t, f)
+	inst := &TraceInstance{log: discardLogger(), config: &Config{}}
+
+	inst.summarize()
+	assert.Equal(t, 0, f.calls)
+	inst.config.SummarizeOnClose = true
+	inst.summarize()
+	assert.Equal(t, 1, f.calls)
+
+	// 已登记运行时只计算本次运行
+	inst.run = &model.Run{ID: 3}
+	inst.summarize()
+	assert.Equal(t, 1, f.calls)
+	assert.Equal(t, []int64{3}, f.runs)
+}
@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/toheart/functrace/domain"
+	"github.com/toheart/functrace/domain/model"
+)
+
+// SlowCallEvent 调用在返回前超过截止时间时产生的事件
+type SlowCallEvent struct {
+	GoroutineID uint64        `json:"goroutineId"` // 协程自增ID
+	OriginGID   uint64        `json:"originGid"`   // 原始Goroutine ID
+	Frame       FrameInfo     `json:"frame"`       // 超时的调用帧
+	Deadline    time.Duration `json:"deadline"`    // 触发的截止时间
+	Stack       string        `json:"stack"`       // 触发时该goroutine的运行时调用栈
+}
+
+// SlowCallHandler 慢调用回调
+type SlowCallHandler func(event SlowCallEvent)
+
+// slowCallHooks 慢调用回调列表
+type slowCallHooks struct {
+	mu       sync.RWMutex
+	handlers []SlowCallHandler
+}
+
+func (h *slowCallHooks) add(fn SlowCallHandler) {
+	h.mu.Lock()
+	h.handlers = append(h.handlers, fn)
+	h.mu.Unlock()
+}
+
+func (h *slowCallHooks) list() []SlowCallHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]SlowCallHandler(nil), h.handlers...)
+}
+
+// OnSlowCall 注册慢调用回调，每次调用至多触发一次
+func (t *TraceInstance) OnSlowCall(fn SlowCallHandler) {
+	if fn == nil {
+		return
+	}
+	t.slowHooks.add(fn)
+}
+
+// startWatchdog 按配置启动慢调用看门狗
+func (t *TraceInstance) startWatchdog() {
+	if !t.config.WatchdogEnabled() {
+		return
+	}
+	interval := t.config.WatchdogInterval
+	if interval <= 0 {
+		interval = DefaultWatchdogInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				t.checkSlowCalls(now)
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}()
+	t.log.WithFields(logrus.Fields{
+		"threshold": t.config.SlowCallThreshold,
+		"functions": len(t.config.SlowCallDeadlines),
+		"interval":  interval,
+	}).Info("slow call watchdog started")
+}
+
+// checkSlowCalls 扫描所有会话的打开帧，对超时的调用记录事件并触发回调
+// 运行时调用栈在每次扫描发现第一个超时调用时统一抓取一次，再按 goroutine 查找
+func (t *TraceInstance) checkSlowCalls(now time.Time) {
+	if t.sessions == nil {
+		return
+	}
+	var origin map[uint64]uint64
+	var stacks map[uint64]string
+	for id, s := range t.sessions.Snapshot() {
+		overdue := s.CollectOverdue(now, t.config.SlowCallDeadline)
+		if len(overdue) == 0 {
+			continue
+		}
+		if origin == nil {
+			origin = make(map[uint64]uint64)
+			for gid, info := range t.GetGoroutineRunning() {
+				origin[info.ID] = gid
+			}
+			stacks = allGoroutineStacks()
+		}
+		stack := stacks[origin[id]]
+		for _, f := range overdue {
+			t.fireSlowCall(SlowCallEvent{
+				GoroutineID: id,
+				OriginGID:   origin[id],
+				Frame:       f,
+				Deadline:    t.config.SlowCallDeadline(f.Name),
+				Stack:       stack,
+			})
+		}
+	}
+}
+
+// fireSlowCall 记录慢调用事件并依次调用回调
+func (t *TraceInstance) fireSlowCall(evt SlowCallEvent) {
+	msg := fmt.Sprintf("still running after %s (deadline %s)", evt.Frame.Elapsed, evt.Deadline)
+	t.log.WithFields(logrus.Fields{
+		"goroutine": evt.GoroutineID,
+		"traceId":   evt.Frame.TraceID,
+		"name":      evt.Frame.Name,
+	}).Warn(msg)
+
+	if provider, ok := repositoryFactory.(domain.EventRepositoryProvider); ok {
+		event := model.NewTraceEvent(evt.Frame.TraceID, evt.GoroutineID, model.EventKindSlowCall, msg, time.Now().Format(TimeFormat)).
+			WithStack(evt.Stack)
+		if _, err := provider.GetEventRepository().SaveEvent(event); err != nil {
+			t.log.WithFields(logrus.Fields{"error": err, "traceId": evt.Frame.TraceID}).Error("save slow call event failed")
+		}
+	}
+
+	for _, fn := range t.slowHooks.list() {
+		t.safeExecute(func() { fn(evt) })
+	}
+}
@@ -0,0 +1,89 @@
+package trace
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeadlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]time.Duration
+	}{
+		{name: "empty", input: "", expected: map[string]time.Duration{}},
+		{
+			name:  "multiple",
+			input: "main.handler=2s, pkg.(*DB).Query=500ms",
+			expected: map[string]time.Duration{
+				"main.handler":    2 * time.Second,
+				"pkg.(*DB).Query": 500 * time.Millisecond,
+			},
+		},
+		{name: "invalid items skipped", input: "a=abc,b,=1s,c=-1s", expected: map[string]time.Duration{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseDeadlines(tt.input))
+		})
+	}
+
+	c := &Config{SlowCallThreshold: time.Second, SlowCallDeadlines: parseDeadlines("main.fast=10ms")}
+	assert.Equal(t, 10*time.Millisecond, c.SlowCallDeadline("main.fast"))
+	assert.Equal(t, time.Second, c.SlowCallDeadline("main.other"))
+	assert.True(t, c.WatchdogEnabled())
+	assert.False(t, (&Config{}).WatchdogEnabled())
+}
+
+func TestCheckSlowCalls_FiresOncePerCall(t *testing.T) {
+	inst := newInFlightTestInstance()
+	inst.log = logrus.New()
+	inst.config = &Config{
+		SlowCallThreshold: time.Hour,
+		SlowCallDeadlines: map[string]time.Duration{"main.slow": time.Second},
+	}
+	now := time.Now()
+	inst.GoroutineRunning[100] = &GoroutineInfo{ID: 1, OriginGID: 100}
+
+	s := inst.sessions.GetOrCreate(1)
+	s.PrepareEnter(inst, "main.outer", now.Add(-time.Minute))
+	s.PrepareEnter(inst, "main.slow", now.Add(-2*time.Second))
+
+	var events []SlowCallEvent
+	inst.OnSlowCall(func(evt SlowCallEvent) {
+		events = append(events, evt)
+	})
+
+	inst.checkSlowCalls(now)
+	inst.checkSlowCalls(now.Add(time.Second))
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "main.slow", events[0].Frame.Name)
+	assert.Equal(t, time.Second, events[0].Deadline)
+	assert.Equal(t, uint64(100), events[0].OriginGID)
+
+	// 外层调用超过全局截止时间后同样只触发一次
+	inst.checkSlowCalls(now.Add(2 * time.Hour))
+	inst.checkSlowCalls(now.Add(3 * time.Hour))
+	require.Len(t, events, 2)
+	assert.Equal(t, "main.outer", events[1].Frame.Name)
+}
+
+func TestAllGoroutineStacks(t *testing.T) {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	gid, err := strconv.ParseUint(string(buf[:bytes.IndexByte(buf, ' ')]), 10, 64)
+	require.NoError(t, err)
+
+	stacks := allGoroutineStacks()
+	assert.Contains(t, stacks[gid], "TestAllGoroutineStacks")
+	assert.NotContains(t, stacks, uint64(0))
+}